@@ -0,0 +1,112 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	alertRuleGroupName = "redfish-exporter"
+	alertRuleWindow    = "5m"
+	alertRuleFor       = "0m"
+)
+
+// AlertSeverityMap maps a redfish event Severity (e.g. "Critical") to the
+// severity label attached to the generated Prometheus alert (e.g. "page")
+type AlertSeverityMap map[string]string
+
+// DefaultAlertSeverityMap is used when no explicit mapping is configured
+var DefaultAlertSeverityMap = AlertSeverityMap{
+	"Critical": "critical",
+	"Warning":  "warning",
+}
+
+var registryPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// GenerateAlertingRules builds a Prometheus rule file with one alert per
+// subscribed registry prefix and mapped redfish severity. Each alert fires
+// when redfish_events_total increased for a matching message ID.
+func GenerateAlertingRules(subscriptions []SubscriptionPayload, severity AlertSeverityMap) (string, error) {
+	if len(severity) == 0 {
+		return "", fmt.Errorf("alert severity map is empty")
+	}
+
+	// Collect the distinct registry prefixes across all subscriptions. A
+	// subscription without prefixes receives every registry.
+	prefixSet := make(map[string]struct{})
+	for _, subscription := range subscriptions {
+		if len(subscription.RegistryPrefixes) == 0 {
+			prefixSet[""] = struct{}{}
+		}
+		for _, prefix := range subscription.RegistryPrefixes {
+			if !registryPrefixPattern.MatchString(prefix) {
+				return "", fmt.Errorf("invalid registry prefix %q", prefix)
+			}
+			prefixSet[prefix] = struct{}{}
+		}
+	}
+	if len(prefixSet) == 0 {
+		return "", fmt.Errorf("no subscriptions to generate alerting rules for")
+	}
+
+	prefixes := make([]string, 0, len(prefixSet))
+	for prefix := range prefixSet {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	severities := make([]string, 0, len(severity))
+	for redfishSeverity := range severity {
+		severities = append(severities, redfishSeverity)
+	}
+	sort.Strings(severities)
+
+	var b strings.Builder
+	b.WriteString("groups:\n")
+	fmt.Fprintf(&b, "  - name: %s\n", alertRuleGroupName)
+	b.WriteString("    rules:\n")
+	for _, prefix := range prefixes {
+		messageIdPattern := ".+"
+		alertName := "RedfishEvent"
+		if prefix != "" {
+			messageIdPattern = prefix + "[.].*"
+			alertName = "Redfish" + prefix
+		}
+		for _, redfishSeverity := range severities {
+			expr := fmt.Sprintf(`increase(redfish_events_total{message_id=~"%s",severity="%s"}[%s]) > 0`,
+				messageIdPattern, redfishSeverity, alertRuleWindow)
+
+			fmt.Fprintf(&b, "      - alert: %s%s\n", alertName, redfishSeverity)
+			fmt.Fprintf(&b, "        expr: %s\n", strconv.Quote(expr))
+			fmt.Fprintf(&b, "        for: %s\n", alertRuleFor)
+			b.WriteString("        labels:\n")
+			fmt.Fprintf(&b, "          severity: %s\n", strconv.Quote(severity[redfishSeverity]))
+			b.WriteString("        annotations:\n")
+			fmt.Fprintf(&b, "          summary: %s\n", strconv.Quote(
+				fmt.Sprintf("%s redfish event received from {{ $labels.server }}", redfishSeverity)))
+			fmt.Fprintf(&b, "          description: %s\n", strconv.Quote(
+				"Redfish event {{ $labels.message_id }} was received from {{ $labels.server }}"))
+		}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,110 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+type alertRuleFile struct {
+	Groups []struct {
+		Name  string `json:"name"`
+		Rules []struct {
+			Alert  string            `json:"alert"`
+			Expr   string            `json:"expr"`
+			Labels map[string]string `json:"labels"`
+		} `json:"rules"`
+	} `json:"groups"`
+}
+
+func TestGenerateAlertingRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		subscriptions []SubscriptionPayload
+		severity      AlertSeverityMap
+		wantAlerts    map[string]string
+		wantErr       bool
+	}{
+		{
+			name:          "one alert per prefix and severity",
+			subscriptions: []SubscriptionPayload{{RegistryPrefixes: []string{"Base", "ResourceEvent"}}, {RegistryPrefixes: []string{"Base"}}},
+			severity:      DefaultAlertSeverityMap,
+			wantAlerts: map[string]string{
+				"RedfishBaseCritical":          `increase(redfish_events_total{message_id=~"Base[.].*",severity="Critical"}[5m]) > 0`,
+				"RedfishBaseWarning":           `increase(redfish_events_total{message_id=~"Base[.].*",severity="Warning"}[5m]) > 0`,
+				"RedfishResourceEventCritical": `increase(redfish_events_total{message_id=~"ResourceEvent[.].*",severity="Critical"}[5m]) > 0`,
+				"RedfishResourceEventWarning":  `increase(redfish_events_total{message_id=~"ResourceEvent[.].*",severity="Warning"}[5m]) > 0`,
+			},
+		},
+		{
+			name:          "all registries without prefixes",
+			subscriptions: []SubscriptionPayload{{}},
+			severity:      AlertSeverityMap{"Critical": "page"},
+			wantAlerts: map[string]string{
+				"RedfishEventCritical": `increase(redfish_events_total{message_id=~".+",severity="Critical"}[5m]) > 0`,
+			},
+		},
+		{
+			name:          "invalid prefix",
+			subscriptions: []SubscriptionPayload{{RegistryPrefixes: []string{"Base\"}"}}},
+			severity:      DefaultAlertSeverityMap,
+			wantErr:       true,
+		},
+		{
+			name:          "no severities",
+			subscriptions: []SubscriptionPayload{{}},
+			wantErr:       true,
+		},
+		{
+			name:     "no subscriptions",
+			severity: DefaultAlertSeverityMap,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := GenerateAlertingRules(tt.subscriptions, tt.severity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateAlertingRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var file alertRuleFile
+			if err := yaml.Unmarshal([]byte(rules), &file); err != nil {
+				t.Fatalf("invalid rule file: %v\n%s", err, rules)
+			}
+			if len(file.Groups) != 1 || file.Groups[0].Name != alertRuleGroupName {
+				t.Fatalf("groups = %+v, want a single %s group", file.Groups, alertRuleGroupName)
+			}
+			if got := len(file.Groups[0].Rules); got != len(tt.wantAlerts) {
+				t.Errorf("%d rules, want %d", got, len(tt.wantAlerts))
+			}
+			for _, rule := range file.Groups[0].Rules {
+				if want, ok := tt.wantAlerts[rule.Alert]; !ok || rule.Expr != want {
+					t.Errorf("alert %s expr = %s, want %s", rule.Alert, rule.Expr, want)
+				}
+				if rule.Labels["severity"] == "" {
+					t.Errorf("alert %s has no severity label", rule.Alert)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+#!/bin/bash
+# Compare the server lookup benchmarks and fail when the index is more than
+# twice as slow as the linear scan of getServerInfo with 10 servers
+set -e
+
+COUNT=${COUNT:-10}
+OUTPUT=${OUTPUT:-bench_output.txt}
+
+go test -run '^$' -bench 'GetServerInfo' -benchmem -count "$COUNT" . | tee "$OUTPUT"
+
+if command -v benchstat >/dev/null; then
+    benchstat "$OUTPUT"
+fi
+
+# Median ns/op of a benchmark over all runs
+median() {
+    grep "^$1-\?[0-9]* " "$OUTPUT" | awk '{print $3}' | sort -n | awk '{v[NR]=$1} END {if (NR == 0) exit 1; print (NR % 2) ? v[(NR+1)/2] : (v[NR/2] + v[NR/2+1]) / 2}'
+}
+
+linear=$(median "BenchmarkGetServerInfo_Linear/servers=10")
+indexed=$(median "BenchmarkGetServerInfo_Map/servers=10")
+echo "servers=10: linear ${linear} ns/op, map ${indexed} ns/op"
+
+if awk -v l="$linear" -v m="$indexed" 'BEGIN {exit !(m > 2 * l)}'; then
+    echo "The server index is more than 2x slower than the linear scan at 10 servers"
+    exit 1
+fi
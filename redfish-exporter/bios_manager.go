@@ -0,0 +1,181 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// Kinds of difference between two sets of BIOS settings
+const (
+	BIOSSettingAdded   = "added"
+	BIOSSettingRemoved = "removed"
+	BIOSSettingChanged = "changed"
+)
+
+// BIOSSettingDiff is a single difference found by CompareSettings. Nested
+// values are compared key by key, their Key is the dotted path.
+type BIOSSettingDiff struct {
+	Key      string
+	Kind     string
+	Expected interface{}
+	Actual   interface{}
+}
+
+// BIOSManager reads and writes the BIOS attributes of the servers' first system
+type BIOSManager struct {
+	// Finds the jobs that keep a node from being rebooted
+	jobs SlurmJobLookup
+}
+
+// Create the manager, looking up the jobs of the nodes with squeue when
+// jobs is nil
+func NewBIOSManager(jobs SlurmJobLookup) *BIOSManager {
+	if jobs == nil {
+		jobs = squeueJobLookup{}
+	}
+	return &BIOSManager{jobs: jobs}
+}
+
+// Get the current BIOS attributes of the server's first system
+func (m *BIOSManager) GetSettings(ctx context.Context, server RedfishServer) (map[string]interface{}, error) {
+	system, logout, err := getFirstSystem(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	defer logout()
+
+	bios, err := system.Bios()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BIOS on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	return map[string]interface{}(bios.Attributes), nil
+}
+
+// Patch BIOS attributes of the server's first system. Only attributes that
+// differ from the current ones are sent, the BMC stores them as pending
+// settings applied on the next reset, see CommitSettings.
+func (m *BIOSManager) PatchSettings(ctx context.Context, server RedfishServer, settings map[string]interface{}) error {
+	for key, value := range settings {
+		switch value.(type) {
+		case string, bool, int, int64, float64, nil:
+		default:
+			return fmt.Errorf("BIOS attribute %s must be a string, number, boolean or null", key)
+		}
+	}
+
+	system, logout, err := getFirstSystem(ctx, server)
+	if err != nil {
+		return err
+	}
+	defer logout()
+
+	bios, err := system.Bios()
+	if err != nil {
+		return fmt.Errorf("failed to get BIOS on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	if err := bios.UpdateBiosAttributes(redfish.SettingsAttributes(settings)); err != nil {
+		return fmt.Errorf("failed to patch BIOS settings on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+
+	log.Printf("Patched %d BIOS settings on server %s", len(settings), server.IP)
+	return nil
+}
+
+// Apply pending BIOS settings. Without reboot they stay pending until the
+// server's first system next restarts. With reboot a system that is on is
+// restarted right away, which fails with ErrNodeNotDrained while jobs run
+// on its slurm node. A system that is off applies them on its next power
+// on, so it is left alone. This is a system reset, not the Bios ResetBios
+// action, which would restore the default settings instead.
+func (m *BIOSManager) CommitSettings(ctx context.Context, server RedfishServer, reboot bool) error {
+	if !reboot {
+		log.Printf("BIOS settings of server %s apply on its next restart", server.IP)
+		return nil
+	}
+	if server.SlurmNode != "" {
+		jobs, err := m.jobs.RunningJobs(ctx, server.SlurmNode)
+		if err != nil {
+			return fmt.Errorf("failed to find the jobs running on node %s: %w", server.SlurmNode, err)
+		}
+		if len(jobs) > 0 {
+			return fmt.Errorf("can't restart server %s, %d jobs are running on node %s: %w", server.IP, len(jobs), server.SlurmNode, ErrNodeNotDrained)
+		}
+	}
+
+	system, logout, err := getFirstSystem(ctx, server)
+	if err != nil {
+		return err
+	}
+	defer logout()
+
+	if system.PowerState != redfish.OnPowerState {
+		log.Printf("Server %s is %s, BIOS settings apply on next power on", server.IP, system.PowerState)
+		return nil
+	}
+	if err := system.Reset(redfish.GracefulRestartResetType); err != nil {
+		return fmt.Errorf("failed to restart server %s to apply BIOS settings: %w", server.IP, normalizeRedfishError(err))
+	}
+
+	log.Printf("Restarted server %s to apply BIOS settings", server.IP)
+	return nil
+}
+
+// Compare two sets of BIOS settings and return the keys only in expected
+// (removed from actual), only in actual (added) and with different values,
+// sorted by key
+func CompareSettings(expected, actual map[string]interface{}) []BIOSSettingDiff {
+	diffs := compareSettings("", expected, actual)
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Key < diffs[j].Key
+	})
+	return diffs
+}
+
+func compareSettings(prefix string, expected, actual map[string]interface{}) []BIOSSettingDiff {
+	var diffs []BIOSSettingDiff
+	for key, expectedValue := range expected {
+		path := prefix + key
+		actualValue, ok := actual[key]
+		if !ok {
+			diffs = append(diffs, BIOSSettingDiff{Key: path, Kind: BIOSSettingRemoved, Expected: expectedValue})
+			continue
+		}
+
+		expectedMap, expectedIsMap := expectedValue.(map[string]interface{})
+		actualMap, actualIsMap := actualValue.(map[string]interface{})
+		if expectedIsMap && actualIsMap {
+			diffs = append(diffs, compareSettings(path+".", expectedMap, actualMap)...)
+			continue
+		}
+		if !reflect.DeepEqual(expectedValue, actualValue) {
+			diffs = append(diffs, BIOSSettingDiff{Key: path, Kind: BIOSSettingChanged, Expected: expectedValue, Actual: actualValue})
+		}
+	}
+	for key, actualValue := range actual {
+		if _, ok := expected[key]; !ok {
+			diffs = append(diffs, BIOSSettingDiff{Key: prefix + key, Kind: BIOSSettingAdded, Actual: actualValue})
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,69 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCommitSettings(t *testing.T) {
+	const resetRequest = "POST /redfish/v1/Systems/1/Actions/ComputerSystem.Reset"
+	tests := []struct {
+		name       string
+		reboot     bool
+		powerState string
+		slurmNode  string
+		wantErr    error
+		wantResets int
+	}{
+		{name: "settings stay pending without reboot", powerState: "On", slurmNode: "node1"},
+		{name: "reboot of a drained node", reboot: true, powerState: "On", slurmNode: "node2", wantResets: 1},
+		{name: "reboot of a node running jobs", reboot: true, powerState: "On", slurmNode: "node1", wantErr: ErrNodeNotDrained},
+		{name: "reboot of a server without slurm node", reboot: true, powerState: "On", wantResets: 1},
+		{name: "reboot of a system that is off", reboot: true, powerState: "Off", slurmNode: "node2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.resources["/redfish/v1/Systems"] = map[string]interface{}{
+				"@odata.id": "/redfish/v1/Systems",
+				"Members":   []map[string]string{{"@odata.id": "/redfish/v1/Systems/1"}},
+			}
+			bmc.resources["/redfish/v1/Systems/1"] = map[string]interface{}{
+				"@odata.id":  "/redfish/v1/Systems/1",
+				"Id":         "1",
+				"PowerState": tt.powerState,
+				"Actions": map[string]interface{}{
+					"#ComputerSystem.Reset": map[string]string{"target": "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset"},
+				},
+			}
+			server := bmc.server()
+			server.SlurmNode = tt.slurmNode
+
+			manager := NewBIOSManager(fakeJobLookup{"node1": {"100"}})
+			err := manager.CommitSettings(context.Background(), server, tt.reboot)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CommitSettings() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := bmc.count(resetRequest); got != tt.wantResets {
+				t.Errorf("system reset %d times, want %d", got, tt.wantResets)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// BulkOptions controls a fan-out over many servers
+type BulkOptions struct {
+	// Number of servers handled in parallel, the exporter's maxConcurrency
+	// when zero
+	MaxConcurrency int
+}
+
+// BootOverrideManager sets the one-time or continuous boot source of the
+// servers' first system, e.g. to force a PXE boot for reinstallation
+type BootOverrideManager struct{}
+
+func NewBootOverrideManager() *BootOverrideManager {
+	return &BootOverrideManager{}
+}
+
+// Get the first computer system of a server, along with a function to
+// close the connection
+func getFirstSystem(ctx context.Context, server RedfishServer) (*redfish.ComputerSystem, func(), error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+
+	systems, err := c.Service.Systems()
+	if err != nil {
+		c.Logout()
+		return nil, nil, fmt.Errorf("failed to get systems on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	if len(systems) == 0 {
+		c.Logout()
+		return nil, nil, fmt.Errorf("no systems found on server %s", server.IP)
+	}
+	return systems[0], c.Logout, nil
+}
+
+// Set the boot source override of the server's first system
+func (m *BootOverrideManager) SetBootOverride(ctx context.Context, server RedfishServer, target redfish.BootSourceOverrideTarget, enabled redfish.BootSourceOverrideEnabled) error {
+	system, logout, err := getFirstSystem(ctx, server)
+	if err != nil {
+		return err
+	}
+	defer logout()
+
+	boot := redfish.Boot{
+		BootSourceOverrideTarget:  target,
+		BootSourceOverrideEnabled: enabled,
+	}
+	if err := system.SetBoot(boot); err != nil {
+		return fmt.Errorf("failed to set boot override on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+
+	log.Printf("Set boot override on server %s to %s (%s)", server.IP, target, enabled)
+	return nil
+}
+
+// Get the current boot settings of the server's first system
+func (m *BootOverrideManager) GetBootOverride(ctx context.Context, server RedfishServer) (*redfish.Boot, error) {
+	system, logout, err := getFirstSystem(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	defer logout()
+
+	boot := system.Boot
+	return &boot, nil
+}
+
+// Disable the boot source override of the server's first system
+func (m *BootOverrideManager) ClearBootOverride(ctx context.Context, server RedfishServer) error {
+	system, logout, err := getFirstSystem(ctx, server)
+	if err != nil {
+		return err
+	}
+	defer logout()
+
+	boot := redfish.Boot{BootSourceOverrideEnabled: redfish.DisabledBootSourceOverrideEnabled}
+	if err := system.SetBoot(boot); err != nil {
+		return fmt.Errorf("failed to clear boot override on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+
+	log.Printf("Cleared boot override on server %s", server.IP)
+	return nil
+}
+
+// Set the boot source override on all servers in parallel. Servers that
+// failed are returned in the error map, unlike subscriptions nothing is
+// rolled back since a boot override is harmless until the next reboot.
+func (m *BootOverrideManager) BulkSetBootOverride(ctx context.Context, servers []RedfishServer, target redfish.BootSourceOverrideTarget, enabled redfish.BootSourceOverrideEnabled, opts BulkOptions) map[string]error {
+	return bulkRun(ctx, servers, opts, func(server RedfishServer) error {
+		return m.SetBootOverride(ctx, server, target, enabled)
+	})
+}
+
+// Run an operation on all servers in parallel and return the failures by
+// server IP. Servers not reached before the context is done fail with the
+// context error.
+func bulkRun(ctx context.Context, servers []RedfishServer, opts BulkOptions, operation func(RedfishServer) error) map[string]error {
+	concurrency := opts.MaxConcurrency
+	if concurrency == 0 {
+		concurrency = serversConcurrency(servers)
+	}
+
+	type bulkResult struct {
+		// Whether the operation ran, tasks are skipped once the context is done
+		ran bool
+		err error
+	}
+
+	pool := NewWorkerPool[bulkResult](concurrency)
+	for _, server := range servers {
+		pool.Add(func() bulkResult {
+			return bulkResult{ran: true, err: operation(server)}
+		})
+	}
+	results := pool.Run(ctx)
+
+	errs := make(map[string]error)
+	for i, result := range results {
+		switch {
+		case !result.ran:
+			errs[servers[i].IP] = fmt.Errorf("server %s not attempted: %w", servers[i].IP, ctx.Err())
+		case result.err != nil:
+			errs[servers[i].IP] = result.err
+		}
+	}
+	return errs
+}
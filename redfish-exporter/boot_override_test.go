@@ -0,0 +1,90 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func TestBootOverrideManager(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  redfish.BootSourceOverrideTarget
+		enabled redfish.BootSourceOverrideEnabled
+	}{
+		{"one-time PXE boot", redfish.PxeBootSourceOverrideTarget, redfish.OnceBootSourceOverrideEnabled},
+		{"continuous disk boot", redfish.HddBootSourceOverrideTarget, redfish.ContinuousBootSourceOverrideEnabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.addSystem(map[string]interface{}{
+				"Boot": map[string]interface{}{"BootSourceOverrideEnabled": "Disabled", "BootSourceOverrideTarget": "None"},
+			})
+			manager := NewBootOverrideManager()
+
+			if err := manager.SetBootOverride(context.Background(), bmc.server(), tt.target, tt.enabled); err != nil {
+				t.Fatalf("SetBootOverride() error = %v", err)
+			}
+			boot, err := manager.GetBootOverride(context.Background(), bmc.server())
+			if err != nil || boot.BootSourceOverrideTarget != tt.target || boot.BootSourceOverrideEnabled != tt.enabled {
+				t.Errorf("GetBootOverride() = %+v, %v, want %s (%s)", boot, err, tt.target, tt.enabled)
+			}
+
+			if err := manager.ClearBootOverride(context.Background(), bmc.server()); err != nil {
+				t.Fatalf("ClearBootOverride() error = %v", err)
+			}
+			boot, err = manager.GetBootOverride(context.Background(), bmc.server())
+			if err != nil || boot.BootSourceOverrideEnabled != redfish.DisabledBootSourceOverrideEnabled {
+				t.Errorf("GetBootOverride() after clearing = %+v, %v, want it disabled", boot, err)
+			}
+		})
+	}
+}
+
+func TestBulkRun(t *testing.T) {
+	servers := []RedfishServer{{IP: "https://10.0.0.1"}, {IP: "https://10.0.0.2"}, {IP: "https://10.0.0.3"}}
+	failure := errors.New("boot override rejected")
+
+	errs := bulkRun(context.Background(), servers, BulkOptions{MaxConcurrency: 2}, func(server RedfishServer) error {
+		if server.IP == "https://10.0.0.2" {
+			return failure
+		}
+		return nil
+	})
+	if len(errs) != 1 || !errors.Is(errs["https://10.0.0.2"], failure) {
+		t.Errorf("bulkRun() = %v, want only 10.0.0.2 failing", errs)
+	}
+
+	// Servers not reached before the context is done fail with its error
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	errs = bulkRun(ctx, servers, BulkOptions{MaxConcurrency: 1}, func(server RedfishServer) error {
+		<-ctx.Done()
+		return nil
+	})
+	for _, server := range servers[1:] {
+		if !errors.Is(errs[server.IP], context.DeadlineExceeded) {
+			t.Errorf("error of %s = %v, want %v", server.IP, errs[server.IP], context.DeadlineExceeded)
+		}
+	}
+}
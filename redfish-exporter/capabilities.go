@@ -0,0 +1,206 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// EventServiceCapabilities describes what a server's event service supports,
+// used to decide how to build its subscription payload
+type EventServiceCapabilities struct {
+	RedfishVersion string `json:"redfishVersion"`
+	// Subscriptions can filter on RegistryPrefixes and ResourceTypes
+	// instead of the EventTypes deprecated in Redfish 1.5
+	SupportsV1_5     bool     `json:"supportsV1_5"`
+	EventFormatTypes []string `json:"eventFormatTypes"`
+	RegistryPrefixes []string `json:"registryPrefixes"`
+	ResourceTypes    []string `json:"resourceTypes"`
+	// Subscriptions accept a DeliveryRetryPolicy, added in Redfish 1.6
+	SupportsDeliveryRetryPolicy bool `json:"supportsDeliveryRetryPolicy"`
+	SupportsMetricReports       bool `json:"supportsMetricReports"`
+	SupportsSSE                 bool `json:"supportsSSE"`
+	// Properties the SSE stream can be filtered on with $filter
+	SSEFilterProperties []string `json:"sseFilterProperties,omitempty"`
+	// Destination protocols the service advertises, see supportedProtocols
+	Protocols []string `json:"protocols"`
+	// SNMP and Syslog destinations of any version or transport
+	SupportsSNMP   bool `json:"supportsSNMP"`
+	SupportsSyslog bool `json:"supportsSyslog"`
+	ServiceEnabled bool `json:"serviceEnabled"`
+	// HTTP protocol negotiated with the BMC, "h2" or "http/1.1"
+	HTTPProtocol string `json:"httpProtocol,omitempty"`
+}
+
+// Detect the event service capabilities of all servers. Servers that could
+// not be inspected are left out of the map and their errors joined.
+func InspectCapabilities(servers []RedfishServer) (map[string]EventServiceCapabilities, error) {
+	type inspectResult struct {
+		capabilities EventServiceCapabilities
+		err          error
+	}
+
+	pool := NewWorkerPool[inspectResult](serversConcurrency(servers))
+	for _, server := range servers {
+		pool.Add(func() inspectResult {
+			capabilities, err := inspectServerCapabilities(server)
+			return inspectResult{capabilities: capabilities, err: err}
+		})
+	}
+	results := pool.Run(context.Background())
+
+	capabilities := make(map[string]EventServiceCapabilities)
+	var errs []error
+	for i, result := range results {
+		if result.err != nil {
+			errs = append(errs, result.err)
+			continue
+		}
+		capabilities[servers[i].IP] = result.capabilities
+	}
+	return capabilities, errors.Join(errs...)
+}
+
+func inspectServerCapabilities(server RedfishServer) (EventServiceCapabilities, error) {
+	c, err := getRedfishClient(server)
+	if err != nil {
+		return EventServiceCapabilities{}, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	eventService, err := getEventService(c)
+	if err != nil {
+		return EventServiceCapabilities{}, fmt.Errorf("failed to get event service on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	capabilities := eventServiceCapabilities(c.Service.RedfishVersion, eventService)
+	if protocol, err := ProtocolVersion(context.Background(), server); err == nil {
+		capabilities.HTTPProtocol = protocol
+	}
+	return capabilities, nil
+}
+
+// Build the capabilities from the service's Redfish version and event service
+func eventServiceCapabilities(redfishVersion string, eventService *redfish.EventService) EventServiceCapabilities {
+	capabilities := EventServiceCapabilities{
+		RedfishVersion:              redfishVersion,
+		SupportsV1_5:                redfishVersionAtLeast(redfishVersion, 1, 5),
+		RegistryPrefixes:            eventService.RegistryPrefixes,
+		ResourceTypes:               eventService.ResourceTypes,
+		SupportsDeliveryRetryPolicy: redfishVersionAtLeast(redfishVersion, 1, 6),
+		SupportsSSE:                 eventService.ServerSentEventURI != "",
+		ServiceEnabled:              eventService.ServiceEnabled,
+	}
+	for _, protocol := range supportedProtocols(eventService) {
+		capabilities.Protocols = append(capabilities.Protocols, string(protocol))
+		capabilities.SupportsSNMP = capabilities.SupportsSNMP || isSNMPProtocol(protocol)
+		capabilities.SupportsSyslog = capabilities.SupportsSyslog || isSyslogProtocol(protocol)
+	}
+	if capabilities.SupportsSSE {
+		filters := eventService.SSEFilterPropertiesSupported
+		for name, supported := range map[string]bool{
+			"EventFormatType":        filters.EventFormatType,
+			"MessageId":              filters.MessageID,
+			"MetricReportDefinition": filters.MetricReportDefinition,
+			"OriginResource":         filters.OriginResource,
+			"RegistryPrefix":         filters.RegistryPrefix,
+			"ResourceType":           filters.ResourceType,
+		} {
+			if supported {
+				capabilities.SSEFilterProperties = append(capabilities.SSEFilterProperties, name)
+			}
+		}
+		slices.Sort(capabilities.SSEFilterProperties)
+	}
+	for _, formatType := range eventService.EventFormatTypes {
+		capabilities.EventFormatTypes = append(capabilities.EventFormatTypes, string(formatType))
+		if formatType == redfish.MetricReportEventFormatType {
+			capabilities.SupportsMetricReports = true
+		}
+	}
+	return capabilities
+}
+
+// EventServiceDump is the event service of a server as attached to vendor
+// support tickets
+type EventServiceDump struct {
+	Server         string                   `json:"server"`
+	RedfishVersion string                   `json:"redfishVersion"`
+	CollectedAt    time.Time                `json:"collectedAt"`
+	Capabilities   EventServiceCapabilities `json:"capabilities"`
+	// The EventService resource as returned by the BMC
+	EventService json.RawMessage `json:"eventService"`
+}
+
+// Read the server's EventService resource and return it, pretty-printed,
+// along with the service root's Redfish version and the capabilities
+// detected from it. The event service holds no secrets, nothing is
+// redacted.
+func DumpEventService(server RedfishServer) ([]byte, error) {
+	c, err := getRedfishClient(server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	eventService, err := getEventService(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event service on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	resp, err := c.Get(eventService.ODataID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event service on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	defer resp.Body.Close()
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode event service on server %s: %v", server.IP, err)
+	}
+
+	return json.MarshalIndent(EventServiceDump{
+		Server:         server.ID(),
+		RedfishVersion: c.Service.RedfishVersion,
+		CollectedAt:    time.Now().UTC(),
+		Capabilities:   eventServiceCapabilities(c.Service.RedfishVersion, eventService),
+		EventService:   raw,
+	}, "", "  ")
+}
+
+// Whether a Redfish version like "1.6.0" is at least major.minor
+func redfishVersionAtLeast(version string, major, minor int) bool {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	versionMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	versionMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return versionMajor > major || (versionMajor == major && versionMinor >= minor)
+}
@@ -0,0 +1,175 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInspectCapabilities(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      string
+		eventService map[string]interface{}
+		want         EventServiceCapabilities
+	}{
+		{
+			name:    "Redfish 1.0 service",
+			version: "1.0.2",
+			want: EventServiceCapabilities{
+				RedfishVersion: "1.0.2",
+				Protocols:      []string{"Redfish"},
+				ServiceEnabled: true,
+				HTTPProtocol:   "http/1.1",
+			},
+		},
+		{
+			name:    "Redfish 1.5 service with registry prefixes",
+			version: "1.5.0",
+			eventService: map[string]interface{}{
+				"RegistryPrefixes": []string{"Base", "ResourceEvent"},
+				"ResourceTypes":    []string{"Chassis"},
+				"EventFormatTypes": []string{"Event"},
+			},
+			want: EventServiceCapabilities{
+				RedfishVersion:   "1.5.0",
+				SupportsV1_5:     true,
+				EventFormatTypes: []string{"Event"},
+				RegistryPrefixes: []string{"Base", "ResourceEvent"},
+				ResourceTypes:    []string{"Chassis"},
+				Protocols:        []string{"Redfish"},
+				ServiceEnabled:   true,
+				HTTPProtocol:     "http/1.1",
+			},
+		},
+		{
+			name:    "current service with SSE, metric reports and SNMP",
+			version: "1.15.0",
+			eventService: map[string]interface{}{
+				"ServiceEnabled":     false,
+				"ServerSentEventUri": "/redfish/v1/EventService/SSE",
+				"SSEFilterPropertiesSupported": map[string]bool{
+					"RegistryPrefix": true,
+					"MessageId":      true,
+				},
+				"EventFormatTypes":   []string{"Event", "MetricReport"},
+				"SupportedProtocols": []string{"Redfish", "SNMPv2c", "SyslogUDP"},
+			},
+			want: EventServiceCapabilities{
+				RedfishVersion:              "1.15.0",
+				SupportsV1_5:                true,
+				EventFormatTypes:            []string{"Event", "MetricReport"},
+				SupportsDeliveryRetryPolicy: true,
+				SupportsMetricReports:       true,
+				SupportsSSE:                 true,
+				SSEFilterProperties:         []string{"MessageId", "RegistryPrefix"},
+				Protocols:                   []string{"Redfish", "SNMPv2c", "SyslogUDP"},
+				SupportsSNMP:                true,
+				SupportsSyslog:              true,
+				HTTPProtocol:                "http/1.1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.root["RedfishVersion"] = tt.version
+			for key, value := range tt.eventService {
+				bmc.eventService[key] = value
+			}
+
+			capabilities, err := InspectCapabilities([]RedfishServer{bmc.server()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(capabilities[bmc.URL])
+			want, _ := json.Marshal(tt.want)
+			if string(got) != string(want) {
+				t.Errorf("capabilities = %s, want %s", got, want)
+			}
+			if bmc.openSessions() != 0 {
+				t.Errorf("%d sessions left open", bmc.openSessions())
+			}
+		})
+	}
+}
+
+func TestInspectCapabilitiesLeavesOutFailedServers(t *testing.T) {
+	healthy := newFakeBMC(t)
+	broken := newFakeBMC(t)
+	broken.failures["GET /redfish/v1/EventService"] = 500
+
+	capabilities, err := InspectCapabilities([]RedfishServer{healthy.server(), broken.server()})
+	if err == nil {
+		t.Error("expected the broken server's error")
+	}
+	if _, ok := capabilities[healthy.URL]; !ok {
+		t.Error("healthy server left out")
+	}
+	if _, ok := capabilities[broken.URL]; ok {
+		t.Error("broken server included")
+	}
+}
+
+func TestDumpEventService(t *testing.T) {
+	bmc := newFakeBMC(t)
+	bmc.eventService["DeliveryRetryAttempts"] = 3
+
+	data, err := DumpEventService(bmc.server())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dump struct {
+		EventServiceDump
+		EventService map[string]interface{} `json:"eventService"`
+	}
+	if err := json.Unmarshal(data, &dump); err != nil {
+		t.Fatal(err)
+	}
+	if dump.RedfishVersion != "1.15.0" || !dump.Capabilities.SupportsV1_5 {
+		t.Errorf("dump = %+v, want version 1.15.0 with its capabilities", dump.EventServiceDump)
+	}
+	if dump.EventService["DeliveryRetryAttempts"] != float64(3) {
+		t.Errorf("eventService = %v, want the resource as returned by the BMC", dump.EventService)
+	}
+	if bmc.openSessions() != 0 {
+		t.Errorf("%d sessions left open", bmc.openSessions())
+	}
+}
+
+func TestRedfishVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version      string
+		major, minor int
+		want         bool
+	}{
+		{"1.6.0", 1, 6, true},
+		{"1.15.1", 1, 6, true},
+		{"1.5.3", 1, 6, false},
+		{"2.0.0", 1, 6, true},
+		{"0.9", 1, 0, false},
+		{"1", 1, 0, false},
+		{"", 1, 0, false},
+		{"v1.6", 1, 0, false},
+	}
+	for _, tt := range tests {
+		if got := redfishVersionAtLeast(tt.version, tt.major, tt.minor); got != tt.want {
+			t.Errorf("redfishVersionAtLeast(%q, %d, %d) = %v, want %v", tt.version, tt.major, tt.minor, got, tt.want)
+		}
+	}
+}
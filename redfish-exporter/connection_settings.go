@@ -0,0 +1,99 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+// connectionSettings are the settings and shared state of the redfish
+// connections of one exporter. Each server carries the settings of the
+// exporter it is configured in, so two exporters in one process don't
+// share them.
+type connectionSettings struct {
+	client RedfishClientConfig
+	// Number of servers contacted in parallel by fan-out operations
+	maxConcurrency int
+	// Resolves the DeliveryRetryPolicy of the subscriptions, the
+	// payload's is kept when nil
+	deliveryPolicy      DeliveryPolicyResolver
+	firmwareWorkarounds []FirmwareWorkaround
+	// Tokens of the OAuth servers, nil without a token source
+	tokens   *cachedTokenSource
+	breakers *ServerBreakers
+	// Records the open BMC sessions, nil when they aren't tracked
+	sessions *SessionTracker
+	// Connections of the servers, shared by their clients
+	transports *serverTransports
+	// Serializes the subscription changes on each BMC, so a reconcile and
+	// a resubscribe or shutdown never delete what the other just created.
+	// The locks are not reentrant, they are taken by the top-level
+	// operations only: creating or deleting across servers and
+	// reconciling a server.
+	locks *KeyedMutex
+	// Metrics of the exporter, not exported unless the exporter registers
+	// them
+	metrics *Metrics
+}
+
+// Settings with the given client config and the defaults for the rest
+func newConnectionSettings(client RedfishClientConfig) *connectionSettings {
+	metrics := NewMetrics()
+	return &connectionSettings{
+		client:         client,
+		maxConcurrency: DefaultMaxConcurrency,
+		breakers:       NewServerBreakers(DefaultServerBreakerThreshold, DefaultServerBreakerCooldown, metrics),
+		transports:     newServerTransports(),
+		locks:          &KeyedMutex{},
+		metrics:        metrics,
+	}
+}
+
+// Used for servers that weren't configured through an exporter
+var defaultConnectionSettings = newConnectionSettings(RedfishClientConfig{})
+
+// The settings of the exporter the server is configured in
+func (s RedfishServer) settings() *connectionSettings {
+	if s.connection == nil {
+		return defaultConnectionSettings
+	}
+	return s.connection
+}
+
+// Copy the servers, connecting to them with the settings
+func withConnectionSettings(servers []RedfishServer, settings *connectionSettings) []RedfishServer {
+	configured := make([]RedfishServer, len(servers))
+	for i, server := range servers {
+		server.connection = settings
+		configured[i] = server
+	}
+	return configured
+}
+
+// Number of the servers contacted in parallel, all servers of a fan-out
+// come from the same exporter
+func serversConcurrency(servers []RedfishServer) int {
+	if len(servers) == 0 {
+		return DefaultMaxConcurrency
+	}
+	return servers[0].settings().maxConcurrency
+}
+
+// Metrics of the servers' exporter, all servers of a fan-out come from the
+// same exporter
+func serversMetrics(servers []RedfishServer) *Metrics {
+	if len(servers) == 0 {
+		return defaultConnectionSettings.metrics
+	}
+	return servers[0].settings().metrics
+}
@@ -0,0 +1,86 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestServersConcurrency(t *testing.T) {
+	settings := newConnectionSettings(RedfishClientConfig{})
+	settings.maxConcurrency = 4
+	tests := []struct {
+		name    string
+		servers []RedfishServer
+		want    int
+	}{
+		{"no servers", nil, DefaultMaxConcurrency},
+		{"servers without an exporter", []RedfishServer{{IP: "10.0.0.1"}}, DefaultMaxConcurrency},
+		{"exporter servers", withConnectionSettings([]RedfishServer{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}}, settings), 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serversConcurrency(tt.servers); got != tt.want {
+				t.Errorf("serversConcurrency() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithConnectionSettings(t *testing.T) {
+	servers := []RedfishServer{{IP: "10.0.8.1"}, {IP: "10.0.8.2"}}
+	first := newConnectionSettings(RedfishClientConfig{UserAgent: "first"})
+	second := newConnectionSettings(RedfishClientConfig{UserAgent: "second"})
+
+	firstServers := withConnectionSettings(servers, first)
+	secondServers := withConnectionSettings(servers, second)
+	for i, server := range servers {
+		if server.settings() != defaultConnectionSettings {
+			t.Errorf("server %s: configured servers were modified", server.IP)
+		}
+		if firstServers[i].settings() != first || secondServers[i].settings() != second {
+			t.Errorf("server %s: settings not those of its exporter", server.IP)
+		}
+	}
+
+	// The state of one exporter's connections doesn't leak into the other's
+	unreachable := errors.New("connection refused")
+	for i := 0; i < DefaultServerBreakerThreshold; i++ {
+		firstServers[0].settings().breakers.Record(firstServers[0].ID(), unreachable)
+	}
+	if firstServers[0].settings().breakers.Allow(firstServers[0].ID()) {
+		t.Error("first exporter: breaker not open")
+	}
+	if !secondServers[0].settings().breakers.Allow(secondServers[0].ID()) {
+		t.Error("second exporter: breaker opened by the first exporter's failures")
+	}
+
+	unlock := firstServers[0].settings().locks.Lock(firstServers[0].IP)
+	defer unlock()
+	locked := make(chan struct{})
+	go func() {
+		secondServers[0].settings().locks.Lock(secondServers[0].IP)()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Error("second exporter: server locked by the first exporter")
+	}
+}
@@ -0,0 +1,78 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// Random per exporter run, so the tokens of one run can't be guessed from
+// the server addresses alone
+var contextTokenNonce = func() string {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(nonce)
+}()
+
+// The UniqueToken of a server for this run, the first 8 hex digits of
+// sha256(server IP + nonce). It is registered in contextTokens.
+func uniqueToken(server RedfishServer) string {
+	sum := sha256.Sum256([]byte(server.IP + contextTokenNonce))
+	token := hex.EncodeToString(sum[:])[:8]
+	contextTokens.Register(token, server.IP)
+	return token
+}
+
+// ContextTokenRegistry maps the UniqueTokens rendered into subscription
+// Contexts back to the servers they were rendered for
+type ContextTokenRegistry struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+func NewContextTokenRegistry() *ContextTokenRegistry {
+	return &ContextTokenRegistry{tokens: make(map[string]string)}
+}
+
+// Tokens of the payloads rendered by this exporter
+var contextTokens = NewContextTokenRegistry()
+
+func (r *ContextTokenRegistry) Register(token, serverIP string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[token] = serverIP
+}
+
+// Find the server IP of the token contained in an event's Context
+func (r *ContextTokenRegistry) Lookup(eventContext string) (string, bool) {
+	if eventContext == "" {
+		return "", false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for token, serverIP := range r.tokens {
+		if strings.Contains(eventContext, token) {
+			return serverIP, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,56 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import "testing"
+
+func TestUniqueToken(t *testing.T) {
+	first := uniqueToken(RedfishServer{IP: "https://10.0.0.1"})
+	second := uniqueToken(RedfishServer{IP: "https://10.0.0.2"})
+	if len(first) != 8 || first == second {
+		t.Errorf("tokens %q and %q, want distinct 8 digit tokens", first, second)
+	}
+	if again := uniqueToken(RedfishServer{IP: "https://10.0.0.1"}); again != first {
+		t.Errorf("token %q rendered again as %q", first, again)
+	}
+	if serverIP, ok := contextTokens.Lookup("scrapefish-" + first); !ok || serverIP != "https://10.0.0.1" {
+		t.Errorf("Lookup() = %q, %v, want the token's server", serverIP, ok)
+	}
+}
+
+func TestContextTokenRegistryLookup(t *testing.T) {
+	registry := NewContextTokenRegistry()
+	registry.Register("1a2b3c4d", "https://10.0.0.1")
+	registry.Register("5e6f7a8b", "https://10.0.0.2")
+	tests := []struct {
+		eventContext string
+		wantIP       string
+		wantOK       bool
+	}{
+		{"scrapefish-1a2b3c4d", "https://10.0.0.1", true},
+		{"5e6f7a8b", "https://10.0.0.2", true},
+		{"scrapefish", "", false},
+		{"scrapefish-1a2b", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		serverIP, ok := registry.Lookup(tt.eventContext)
+		if serverIP != tt.wantIP || ok != tt.wantOK {
+			t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.eventContext, serverIP, ok, tt.wantIP, tt.wantOK)
+		}
+	}
+}
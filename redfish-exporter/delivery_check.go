@@ -0,0 +1,161 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Time VerifyDelivery waits for the test event by default
+const defaultDeliveryTimeout = 30 * time.Second
+
+// Returned when the test event sent to check a new subscription doesn't
+// reach the listener, e.g. because a firewall blocks the BMC
+var ErrDeliveryNotVerified = errors.New("event delivery not verified")
+
+// testEventWaiters hands the test events received by the listener to the
+// delivery checks waiting for them
+type testEventWaiters struct {
+	mu      sync.Mutex
+	waiters map[string]chan struct{}
+}
+
+// Waiters of the test events of all listeners in this process
+var deliveryWaiters = &testEventWaiters{waiters: make(map[string]chan struct{})}
+
+// Wait for the test event with the EventId. The returned func stops waiting.
+func (w *testEventWaiters) expect(eventID string) (<-chan struct{}, func()) {
+	received := make(chan struct{})
+	w.mu.Lock()
+	w.waiters[eventID] = received
+	w.mu.Unlock()
+
+	return received, func() {
+		w.mu.Lock()
+		delete(w.waiters, eventID)
+		w.mu.Unlock()
+	}
+}
+
+// Pass a received event to the check waiting for it. Some BMCs replace the
+// EventId of test events, so the Message, which carries it too, is matched
+// as well.
+func (w *testEventWaiters) deliver(event Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for eventID, received := range w.waiters {
+		if event.EventId == eventID || strings.Contains(event.Message, eventID) {
+			close(received)
+			delete(w.waiters, eventID)
+			return
+		}
+	}
+}
+
+// Have the server send a test event and wait for the listener to receive
+// it, proving the server can reach the listener through its subscriptions
+func verifyDelivery(ctx context.Context, server RedfishServer, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultDeliveryTimeout
+	}
+
+	eventID := newTestEventID()
+	received, stop := deliveryWaiters.expect(eventID)
+	defer stop()
+
+	result, err := sendTestEvent(server, "Delivery check "+eventID, eventID)
+	if err != nil {
+		return fmt.Errorf("%w on server %s: %v", ErrDeliveryNotVerified, server.IP, err)
+	}
+	if !result.Accepted {
+		return fmt.Errorf("%w on server %s: test event rejected: %s", ErrDeliveryNotVerified, server.IP, result.Message)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-received:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("%w on server %s: test event not received within %v", ErrDeliveryNotVerified, server.IP, timeout)
+	case <-ctx.Done():
+		return fmt.Errorf("%w on server %s: %v", ErrDeliveryNotVerified, server.IP, ctx.Err())
+	}
+}
+
+// receivedEventTimes keeps the time the last event of each server reached
+// a listener, test events included
+type receivedEventTimes struct {
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+}
+
+// Receipt times of the events of all listeners in this process
+var lastReceivedEvents = &receivedEventTimes{lastSeen: make(map[string]time.Time)}
+
+func (r *receivedEventTimes) record(key string, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if at.After(r.lastSeen[key]) {
+		r.lastSeen[key] = at
+	}
+}
+
+// A copy of the receipt times by server ID, or by host for events of
+// servers that aren't configured
+func (r *receivedEventTimes) snapshot() map[string]time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lastSeen := make(map[string]time.Time, len(r.lastSeen))
+	for key, at := range r.lastSeen {
+		lastSeen[key] = at
+	}
+	return lastSeen
+}
+
+// Report for each server whether a listener of this process received one
+// of its events, or a test event, within maxSilence. Servers that only
+// send events on faults can be quiet for long, pair this with test events
+// or a maxSilence beyond the test event interval.
+func VerifyDelivery(servers []RedfishServer, maxSilence time.Duration) (map[string]bool, error) {
+	return deliveryStatus(lastReceivedEvents.snapshot(), servers, maxSilence, time.Now())
+}
+
+// Check the servers against the receipt times by server ID or host
+func deliveryStatus(lastSeen map[string]time.Time, servers []RedfishServer, maxSilence time.Duration, now time.Time) (map[string]bool, error) {
+	if len(servers) == 0 {
+		return nil, ErrNoServers
+	}
+	if maxSilence <= 0 {
+		return nil, fmt.Errorf("maxSilence must be positive, got %v", maxSilence)
+	}
+	delivering := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		at, ok := lastSeen[server.ID()]
+		if !ok {
+			at, ok = lastSeen[serverHost(server)]
+		}
+		delivering[server.ID()] = ok && now.Sub(at) <= maxSilence
+	}
+	return delivering, nil
+}
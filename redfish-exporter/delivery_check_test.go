@@ -0,0 +1,97 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVerifyDeliveryOfTestEvent(t *testing.T) {
+	tests := []struct {
+		name           string
+		delivered      bool
+		replaceEventId bool
+		actionStatus   int
+		wantErr        bool
+	}{
+		{"test event received", true, false, 0, false},
+		{"EventId replaced by the BMC", true, true, 0, false},
+		{"test event not received", false, false, 0, true},
+		{"test event rejected", false, false, http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			if tt.actionStatus != 0 {
+				bmc.failures["POST /redfish/v1/EventService/Actions/EventService.SubmitTestEvent"] = tt.actionStatus
+			}
+			if tt.delivered {
+				bmc.onTestEvent = func(event map[string]interface{}) {
+					delivered := Event{EventId: event["EventId"].(string), Message: event["Message"].(string)}
+					if tt.replaceEventId {
+						delivered.EventId = "1"
+					}
+					deliveryWaiters.deliver(delivered)
+				}
+			}
+
+			err := verifyDelivery(context.Background(), bmc.server(), 50*time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyDelivery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrDeliveryNotVerified) {
+				t.Errorf("verifyDelivery() error = %v, want %v", err, ErrDeliveryNotVerified)
+			}
+		})
+	}
+}
+
+func TestDeliveryStatus(t *testing.T) {
+	now := time.Now()
+	servers := []RedfishServer{
+		{IP: "https://10.0.0.1", Name: "node-1"},
+		{IP: "https://10.0.0.2"},
+		{IP: "https://10.0.0.3"},
+		{IP: "https://10.0.0.4"},
+	}
+	lastSeen := map[string]time.Time{
+		"node-1":   now.Add(-time.Minute),
+		"10.0.0.2": now.Add(-2 * time.Minute),
+		"10.0.0.3": now.Add(-time.Hour),
+	}
+	delivering, err := deliveryStatus(lastSeen, servers, 5*time.Minute, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"node-1": true, "https://10.0.0.2": true, "https://10.0.0.3": false, "https://10.0.0.4": false}
+	for serverID, wantDelivering := range want {
+		if got, ok := delivering[serverID]; !ok || got != wantDelivering {
+			t.Errorf("delivering[%s] = %v, %v, want %v", serverID, got, ok, wantDelivering)
+		}
+	}
+
+	if _, err := deliveryStatus(lastSeen, nil, time.Minute, now); !errors.Is(err, ErrNoServers) {
+		t.Errorf("deliveryStatus() error = %v without servers, want %v", err, ErrNoServers)
+	}
+	if _, err := deliveryStatus(lastSeen, servers, 0, now); err == nil {
+		t.Error("deliveryStatus() error = nil without a maxSilence")
+	}
+}
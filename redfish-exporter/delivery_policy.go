@@ -0,0 +1,103 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// DeliveryPolicyResolver picks the DeliveryRetryPolicy of a server's
+// subscriptions, BMC vendors differ in how they retry. An empty policy
+// keeps the payload's.
+type DeliveryPolicyResolver interface {
+	Resolve(server RedfishServer) redfish.DeliveryRetryPolicy
+}
+
+// StaticPolicyResolver gives all servers the same policy
+type StaticPolicyResolver struct {
+	Policy redfish.DeliveryRetryPolicy
+}
+
+func (r StaticPolicyResolver) Resolve(server RedfishServer) redfish.DeliveryRetryPolicy {
+	return r.Policy
+}
+
+// VendorPolicyResolver picks the policy by the Vendor of the server's
+// service root. The vendor is read once per server.
+type VendorPolicyResolver struct {
+	// Policies by vendor, matched case-insensitively
+	policies map[string]redfish.DeliveryRetryPolicy
+	// Policy of the servers whose vendor has none or can't be read
+	fallback redfish.DeliveryRetryPolicy
+	// Vendor by server ID
+	vendors sync.Map
+}
+
+func NewVendorPolicyResolver(policies map[string]redfish.DeliveryRetryPolicy, fallback redfish.DeliveryRetryPolicy) *VendorPolicyResolver {
+	r := &VendorPolicyResolver{
+		policies: make(map[string]redfish.DeliveryRetryPolicy),
+		fallback: fallback,
+	}
+	for vendor, policy := range policies {
+		r.policies[strings.ToLower(vendor)] = policy
+	}
+	return r
+}
+
+func (r *VendorPolicyResolver) Resolve(server RedfishServer) redfish.DeliveryRetryPolicy {
+	vendor, ok := r.vendors.Load(server.ID())
+	if !ok {
+		c, err := getRedfishClient(server)
+		if err != nil {
+			log.Printf("Failed to read the vendor of server %s, using the default delivery retry policy: %v", server.IP, err)
+			return r.fallback
+		}
+		vendor = c.Service.Vendor
+		c.Logout()
+		r.vendors.Store(server.ID(), vendor)
+	}
+	if policy, ok := r.policies[strings.ToLower(vendor.(string))]; ok {
+		return policy
+	}
+	return r.fallback
+}
+
+// Check that a policy is one the Redfish schema defines
+func validDeliveryRetryPolicy(policy redfish.DeliveryRetryPolicy) error {
+	switch policy {
+	case redfish.TerminateAfterRetriesDeliveryRetryPolicy, redfish.SuspendRetriesDeliveryRetryPolicy,
+		redfish.RetryForeverDeliveryRetryPolicy, redfish.RetryForeverWithBackoffDeliveryRetryPolicy:
+		return nil
+	}
+	return fmt.Errorf("unknown DeliveryRetryPolicy %q", policy)
+}
+
+// The payload with the server's delivery retry policy
+func applyDeliveryPolicy(server RedfishServer, payload SubscriptionPayload) SubscriptionPayload {
+	resolver := server.settings().deliveryPolicy
+	if resolver == nil {
+		return payload
+	}
+	if policy := resolver.Resolve(server); policy != "" {
+		payload.DeliveryRetryPolicy = policy
+	}
+	return payload
+}
@@ -0,0 +1,92 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func TestVendorPolicyResolver(t *testing.T) {
+	policies := map[string]redfish.DeliveryRetryPolicy{
+		"Dell": redfish.RetryForeverWithBackoffDeliveryRetryPolicy,
+		"HPE":  redfish.SuspendRetriesDeliveryRetryPolicy,
+	}
+	tests := []struct {
+		name   string
+		vendor string
+		down   bool
+		want   redfish.DeliveryRetryPolicy
+	}{
+		{"vendor with a policy", "Dell", false, redfish.RetryForeverWithBackoffDeliveryRetryPolicy},
+		{"matched case-insensitively", "hpe", false, redfish.SuspendRetriesDeliveryRetryPolicy},
+		{"vendor without a policy", "Supermicro", false, redfish.TerminateAfterRetriesDeliveryRetryPolicy},
+		{"unreachable server", "Dell", true, redfish.TerminateAfterRetriesDeliveryRetryPolicy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.root["Vendor"] = tt.vendor
+			server := bmc.server()
+			if tt.down {
+				bmc.Close()
+			}
+			resolver := NewVendorPolicyResolver(policies, redfish.TerminateAfterRetriesDeliveryRetryPolicy)
+			if got := resolver.Resolve(server); got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVendorPolicyResolverReadsVendorOnce(t *testing.T) {
+	bmc := newFakeBMC(t)
+	bmc.root["Vendor"] = "Dell"
+	resolver := NewVendorPolicyResolver(map[string]redfish.DeliveryRetryPolicy{"Dell": redfish.RetryForeverDeliveryRetryPolicy}, "")
+	resolver.Resolve(bmc.server())
+	logins := bmc.count("POST /redfish/v1/SessionService/Sessions")
+	if got := resolver.Resolve(bmc.server()); got != redfish.RetryForeverDeliveryRetryPolicy {
+		t.Errorf("Resolve() = %q, want the cached vendor's policy", got)
+	}
+	if again := bmc.count("POST /redfish/v1/SessionService/Sessions"); again != logins {
+		t.Errorf("%d logins after resolving again, want %d", again, logins)
+	}
+}
+
+func TestApplyDeliveryPolicy(t *testing.T) {
+	payload := SubscriptionPayload{Destination: "https://10.0.0.100:8080", DeliveryRetryPolicy: redfish.TerminateAfterRetriesDeliveryRetryPolicy}
+	tests := []struct {
+		name     string
+		resolver DeliveryPolicyResolver
+		want     redfish.DeliveryRetryPolicy
+	}{
+		{"no resolver", nil, redfish.TerminateAfterRetriesDeliveryRetryPolicy},
+		{"resolved policy", StaticPolicyResolver{Policy: redfish.SuspendRetriesDeliveryRetryPolicy}, redfish.SuspendRetriesDeliveryRetryPolicy},
+		{"empty policy keeps the payload's", StaticPolicyResolver{}, redfish.TerminateAfterRetriesDeliveryRetryPolicy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := newConnectionSettings(RedfishClientConfig{})
+			settings.deliveryPolicy = tt.resolver
+			server := withConnectionSettings([]RedfishServer{{IP: "https://10.0.0.1"}}, settings)[0]
+			if got := applyDeliveryPolicy(server, payload).DeliveryRetryPolicy; got != tt.want {
+				t.Errorf("DeliveryRetryPolicy = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
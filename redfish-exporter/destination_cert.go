@@ -0,0 +1,97 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+// Ask the BMC to verify the receiver's certificate for a subscription that
+// was created without VerifyCertificate. BMCs without support for the
+// property are skipped.
+func setVerifyCertificate(c common.Client, subscriptionURI string, verify bool) error {
+	resp, err := c.Patch(subscriptionURI, map[string]bool{"VerifyCertificate": verify})
+	if err != nil {
+		if isUnsupportedStatus(redfishStatusCode(err)) {
+			log.Printf("Subscription %s does not support VerifyCertificate, skipping", subscriptionURI)
+			return nil
+		}
+		return fmt.Errorf("failed to set VerifyCertificate on subscription %s: %w", subscriptionURI, normalizeRedfishError(err))
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Install the receiver's certificate on a subscription so the BMC can pin
+// it. BMCs whose subscriptions have no Certificates collection are skipped.
+func installDestinationCertificate(c common.Client, subscriptionURI string, certFile string) error {
+	resp, err := c.Get(subscriptionURI)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription %s: %w", subscriptionURI, normalizeRedfishError(err))
+	}
+	defer resp.Body.Close()
+
+	var subscription struct {
+		Certificates common.Link
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&subscription); err != nil {
+		return fmt.Errorf("failed to decode subscription %s: %v", subscriptionURI, err)
+	}
+	if subscription.Certificates.String() == "" {
+		log.Printf("Subscription %s does not support destination certificates, skipping", subscriptionURI)
+		return nil
+	}
+
+	certificate, err := os.ReadFile(certFile)
+	if err != nil {
+		return fmt.Errorf("failed to read destination certificate: %v", err)
+	}
+
+	certResp, err := c.Post(subscription.Certificates.String(), map[string]string{
+		"CertificateString": string(certificate),
+		"CertificateType":   "PEM",
+	})
+	if err != nil {
+		if isUnsupportedStatus(redfishStatusCode(err)) {
+			log.Printf("Subscription %s does not accept destination certificates, skipping", subscriptionURI)
+			return nil
+		}
+		return fmt.Errorf("failed to install destination certificate on subscription %s: %w", subscriptionURI, normalizeRedfishError(err))
+	}
+	certResp.Body.Close()
+
+	log.Printf("Installed destination certificate on subscription %s", subscriptionURI)
+	return nil
+}
+
+// Status codes BMCs return for properties and actions they don't implement.
+// A 400 is not one of them, it rejects the value, e.g. a bad certificate.
+func isUnsupportedStatus(statusCode int) bool {
+	return statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented
+}
+
+// Status codes of a PATCH the BMC refused, for a property it doesn't
+// implement or that isn't writable, which most BMCs answer with a 400
+func isRejectedStatus(statusCode int) bool {
+	return statusCode == http.StatusBadRequest || isUnsupportedStatus(statusCode)
+}
@@ -0,0 +1,104 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetVerifyCertificate(t *testing.T) {
+	tests := []struct {
+		name        string
+		patchStatus int
+		wantErr     bool
+	}{
+		{"accepted", http.StatusOK, false},
+		{"rejected value", http.StatusBadRequest, true},
+		{"PATCH not allowed", http.StatusMethodNotAllowed, false},
+		{"not implemented", http.StatusNotImplemented, false},
+		{"server error", http.StatusInternalServerError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.patchStatus = tt.patchStatus
+			uri := bmc.addSubscription(map[string]interface{}{"Destination": "https://10.0.0.9:8080"})
+			c, err := getRedfishClient(bmc.server())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Logout()
+
+			err = setVerifyCertificate(c, uri, true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setVerifyCertificate() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.patchStatus == http.StatusOK && bmc.subscription(uri)["VerifyCertificate"] != true {
+				t.Error("VerifyCertificate not set on the subscription")
+			}
+		})
+	}
+}
+
+func TestInstallDestinationCertificate(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "receiver.pem")
+	if err := os.WriteFile(certFile, []byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// No Certificates collection on the subscription when zero
+		postStatus int
+		wantErr    bool
+		wantPost   bool
+	}{
+		{name: "no Certificates collection"},
+		{name: "rejected certificate", postStatus: http.StatusBadRequest, wantErr: true, wantPost: true},
+		{name: "POST not allowed", postStatus: http.StatusMethodNotAllowed, wantPost: true},
+		{name: "not implemented", postStatus: http.StatusNotImplemented, wantPost: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			uri := bmc.addSubscription(map[string]interface{}{"Destination": "https://10.0.0.9:8080"})
+			certificates := uri + "/Certificates"
+			if tt.postStatus != 0 {
+				bmc.setSubscriptionProperty(uri, "Certificates", map[string]string{"@odata.id": certificates})
+				bmc.failures["POST "+certificates] = tt.postStatus
+			}
+			c, err := getRedfishClient(bmc.server())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Logout()
+
+			err = installDestinationCertificate(c, uri, certFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("installDestinationCertificate() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := bmc.count("POST "+certificates) > 0; got != tt.wantPost {
+				t.Errorf("certificate posted = %v, want %v", got, tt.wantPost)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Data the payload templates are executed with: the RedfishServer's
+// fields and the server's UniqueToken
+type payloadTemplateData struct {
+	RedfishServer
+	UniqueToken string
+}
+
+// Render the subscription payload for a server. When the payload has a
+// DestinationTemplate it is executed with the RedfishServer, e.g.
+// "http://receiver:8080/events/{{.SlurmNode}}", and replaces Destination.
+// A Context containing "{{" is executed the same way, e.g.
+// "exporter-{{.UniqueToken}}", so events can be traced back to their
+// server, see ContextTokenRegistry.
+func RenderPayload(payload SubscriptionPayload, server RedfishServer) (SubscriptionPayload, error) {
+	renderContext := strings.Contains(payload.Context, "{{")
+	if payload.DestinationTemplate == "" && !renderContext {
+		return payload, nil
+	}
+	data := payloadTemplateData{RedfishServer: server, UniqueToken: uniqueToken(server)}
+
+	rendered := payload
+	if renderContext {
+		eventContext, err := executePayloadTemplate("context", payload.Context, data)
+		if err != nil {
+			return payload, fmt.Errorf("failed to render Context for server %s: %v", server.IP, err)
+		}
+		rendered.Context = eventContext
+	}
+	if payload.DestinationTemplate == "" {
+		return rendered, nil
+	}
+
+	destination, err := executePayloadTemplate("destination", payload.DestinationTemplate, data)
+	if err != nil {
+		return payload, fmt.Errorf("failed to render DestinationTemplate for server %s: %v", server.IP, err)
+	}
+	rendered.Destination = destination
+	if err := ValidateSubscriptionPayload(rendered); err != nil {
+		return payload, fmt.Errorf("invalid destination for server %s: %v", server.IP, err)
+	}
+	return rendered, nil
+}
+
+func executePayloadTemplate(name, text string, data payloadTemplateData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
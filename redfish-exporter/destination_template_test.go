@@ -0,0 +1,82 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import "testing"
+
+func TestRenderPayload(t *testing.T) {
+	server := RedfishServer{IP: "https://10.0.0.1", Name: "bmc-1", SlurmNode: "node-1", Labels: map[string]string{"rack": "r12"}}
+	tests := []struct {
+		name            string
+		payload         SubscriptionPayload
+		wantDestination string
+		wantContext     string
+		wantErr         bool
+	}{
+		{
+			name:            "nothing to render",
+			payload:         SubscriptionPayload{Destination: "https://10.0.0.100:8080", Context: "scrapefish"},
+			wantDestination: "https://10.0.0.100:8080",
+			wantContext:     "scrapefish",
+		},
+		{
+			name:            "destination from the server",
+			payload:         SubscriptionPayload{DestinationTemplate: "https://10.0.0.100:8080/events/{{.SlurmNode}}?rack={{index .Labels \"rack\"}}"},
+			wantDestination: "https://10.0.0.100:8080/events/node-1?rack=r12",
+		},
+		{
+			name:            "context with the unique token",
+			payload:         SubscriptionPayload{Destination: "https://10.0.0.100:8080", Context: "scrapefish-{{.UniqueToken}}"},
+			wantDestination: "https://10.0.0.100:8080",
+			wantContext:     "scrapefish-" + uniqueToken(server),
+		},
+		{
+			name:    "unknown field",
+			payload: SubscriptionPayload{DestinationTemplate: "https://10.0.0.100:8080/{{.Switch}}"},
+			wantErr: true,
+		},
+		{
+			name:    "missing label",
+			payload: SubscriptionPayload{DestinationTemplate: "https://10.0.0.100:8080/{{.Labels.row}}"},
+			wantErr: true,
+		},
+		{
+			name:    "rendered destination invalid",
+			payload: SubscriptionPayload{DestinationTemplate: "{{.SlurmNode}}/events"},
+			wantErr: true,
+		},
+		{
+			name:    "malformed context template",
+			payload: SubscriptionPayload{Destination: "https://10.0.0.100:8080", Context: "scrapefish-{{.UniqueToken"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := RenderPayload(tt.payload, server)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderPayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if rendered.Destination != tt.wantDestination || rendered.Context != tt.wantContext {
+				t.Errorf("rendered Destination %q, Context %q, want %q, %q", rendered.Destination, rendered.Context, tt.wantDestination, tt.wantContext)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var emailTemplate = template.Must(template.New("email").Parse(`<html>
+<body>
+<h2>Redfish {{.Severity}} event on {{.ServerIP}}</h2>
+<table>
+<tr><td><b>Server</b></td><td>{{.ServerIP}}</td></tr>
+<tr><td><b>Slurm Node</b></td><td>{{.SlurmNode}}</td></tr>
+{{- with .Location.String}}
+<tr><td><b>Location</b></td><td>{{.}}</td></tr>
+{{- end}}
+{{- with .Location.AssetTag}}
+<tr><td><b>Asset Tag</b></td><td>{{.}}</td></tr>
+{{- end}}
+<tr><td><b>Message ID</b></td><td>{{.MessageId}}</td></tr>
+<tr><td><b>Message</b></td><td>{{.Message}}</td></tr>
+<tr><td><b>Timestamp</b></td><td>{{.EventTimestamp}}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+type EmailConfig struct {
+	SMTPHost         string
+	SMTPPort         int
+	Username         string
+	Password         string
+	FromAddress      string
+	ToAddresses      []string
+	STARTTLSRequired bool
+	// Severities of the events to send, defaults to Critical only
+	Severities []string
+}
+
+// EmailAlertSink sends an HTML email for events of the configured severities
+type EmailAlertSink struct {
+	config EmailConfig
+}
+
+func NewEmailAlertSink(config EmailConfig) (*EmailAlertSink, error) {
+	if config.SMTPHost == "" {
+		return nil, fmt.Errorf("SMTP host is required")
+	}
+	if config.FromAddress == "" {
+		return nil, fmt.Errorf("from address is required")
+	}
+	if len(config.ToAddresses) == 0 {
+		return nil, fmt.Errorf("at least one to address is required")
+	}
+	if len(config.Severities) == 0 {
+		config.Severities = []string{"Critical"}
+	}
+	return &EmailAlertSink{config: config}, nil
+}
+
+// Send emails the event if its severity is selected. A failed delivery is
+// retried once before the error is returned.
+func (s *EmailAlertSink) Send(event *EnrichedEvent) error {
+	if !containsString(s.config.Severities, event.Severity) {
+		return nil
+	}
+
+	msg, err := s.buildMessage(event)
+	if err != nil {
+		return err
+	}
+
+	err = s.deliver(msg)
+	if err != nil {
+		log.Printf("Failed to send email for event %s, retrying: %v", event.EventId, err)
+		err = s.deliver(msg)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send email for event %s: %w", event.EventId, err)
+	}
+	return nil
+}
+
+func (s *EmailAlertSink) Close() error {
+	return nil
+}
+
+func (s *EmailAlertSink) buildMessage(event *EnrichedEvent) ([]byte, error) {
+	var body bytes.Buffer
+	if err := emailTemplate.Execute(&body, event); err != nil {
+		return nil, fmt.Errorf("failed to render email: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.config.FromAddress)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.config.ToAddresses, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", emailSubject(event))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// The Subject header of an event's email. Severity and MessageId come from
+// the BMC, line breaks are removed so they can't add headers and anything
+// but printable ASCII is Q-encoded.
+func emailSubject(event *EnrichedEvent) string {
+	subject := fmt.Sprintf("[%s] Redfish event %s on %s", event.Severity, event.MessageId, event.ServerIP)
+	subject = strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, subject)
+	return mime.QEncoding.Encode("UTF-8", subject)
+}
+
+// Run one SMTP transaction
+func (s *EmailAlertSink) deliver(msg []byte) error {
+	addr := net.JoinHostPort(s.config.SMTPHost, strconv.Itoa(s.config.SMTPPort))
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.config.SMTPHost}); err != nil {
+			return err
+		}
+	} else if s.config.STARTTLSRequired {
+		return fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+	}
+
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.SMTPHost)
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.config.FromAddress); err != nil {
+		return err
+	}
+	for _, to := range s.config.ToAddresses {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestEmailSubject(t *testing.T) {
+	tests := []struct {
+		name  string
+		event EnrichedEvent
+		want  string
+	}{
+		{
+			name:  "plain",
+			event: EnrichedEvent{Event: Event{Severity: "Critical", MessageId: "Base.1.0.Fault"}, ServerIP: "10.0.0.1"},
+			want:  "[Critical] Redfish event Base.1.0.Fault on 10.0.0.1",
+		},
+		{
+			name:  "header injection",
+			event: EnrichedEvent{Event: Event{Severity: "Critical\r\nBcc: victim@example.com", MessageId: "X\nY"}, ServerIP: "10.0.0.1"},
+			want:  "[CriticalBcc: victim@example.com] Redfish event XY on 10.0.0.1",
+		},
+		{
+			name:  "non-ASCII",
+			event: EnrichedEvent{Event: Event{Severity: "Critical", MessageId: "Température"}, ServerIP: "10.0.0.1"},
+			want:  "=?UTF-8?q?[Critical]_Redfish_event_Temp=C3=A9rature_on_10.0.0.1?=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := emailSubject(&tt.event); got != tt.want {
+				t.Errorf("emailSubject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailMessageHeaders(t *testing.T) {
+	sink := &EmailAlertSink{config: EmailConfig{FromAddress: "exporter@example.com", ToAddresses: []string{"ops@example.com"}}}
+	msg, err := sink.buildMessage(&EnrichedEvent{
+		Event:    Event{Severity: "Critical\r\nBcc: victim@example.com", MessageId: "Base.1.0.Fault", Message: "<b>fan</b>"},
+		ServerIP: "10.0.0.1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(msg))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcc := header.Get("Bcc"); bcc != "" {
+		t.Errorf("event injected a Bcc header: %q", bcc)
+	}
+	if to := header.Get("To"); to != "ops@example.com" {
+		t.Errorf("To = %q", to)
+	}
+	if strings.Contains(string(msg), "<b>fan</b>") {
+		t.Error("event message not escaped in the HTML body")
+	}
+}
@@ -0,0 +1,110 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// How long the jobs found on a node are trusted
+	DefaultWorkloadCacheTTL = 30 * time.Second
+	workloadLookupTimeout   = 10 * time.Second
+)
+
+// EventFilter decides which events are forwarded to the sinks
+type EventFilter interface {
+	Allow(event *EnrichedEvent) bool
+}
+
+// filteredSink drops the events any of its filters rejects before they
+// reach the sink
+type filteredSink struct {
+	sink    EventSink
+	filters []EventFilter
+}
+
+func (s *filteredSink) Send(event *EnrichedEvent) error {
+	for _, filter := range s.filters {
+		if !filter.Allow(event) {
+			return nil
+		}
+	}
+	return s.sink.Send(event)
+}
+
+func (s *filteredSink) Close() error {
+	return s.sink.Close()
+}
+
+type workloadEntry struct {
+	busy      bool
+	checkedAt time.Time
+}
+
+// WorkloadAwareFilter drops the OK and informational events of slurm nodes
+// that run no jobs, e.g. fan speed changes nobody is waiting on. Warning
+// and Critical events always pass, as do the events of servers without a
+// slurm node or whose jobs can't be looked up.
+type WorkloadAwareFilter struct {
+	lookup SlurmJobLookup
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	nodes map[string]workloadEntry
+}
+
+func NewWorkloadAwareFilter(lookup SlurmJobLookup, ttl time.Duration) *WorkloadAwareFilter {
+	if ttl <= 0 {
+		ttl = DefaultWorkloadCacheTTL
+	}
+	return &WorkloadAwareFilter{lookup: lookup, ttl: ttl, nodes: make(map[string]workloadEntry)}
+}
+
+func (f *WorkloadAwareFilter) Allow(event *EnrichedEvent) bool {
+	if severityRank(event.Severity) > 0 || event.SlurmNode == "" {
+		return true
+	}
+	return f.busy(event.SlurmNode, time.Now())
+}
+
+// Whether the node runs a job, from the cache while it is fresh
+func (f *WorkloadAwareFilter) busy(node string, now time.Time) bool {
+	f.mu.Lock()
+	entry, ok := f.nodes[node]
+	f.mu.Unlock()
+	if ok && now.Sub(entry.checkedAt) < f.ttl {
+		return entry.busy
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), workloadLookupTimeout)
+	defer cancel()
+	jobs, err := f.lookup.RunningJobs(ctx, node)
+	if err != nil {
+		log.Printf("Failed to find the jobs running on node %s, forwarding its events: %v", node, err)
+		return true
+	}
+
+	entry = workloadEntry{busy: len(jobs) > 0, checkedAt: now}
+	f.mu.Lock()
+	f.nodes[node] = entry
+	f.mu.Unlock()
+	return entry.busy
+}
@@ -0,0 +1,88 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Counts the lookups of a fakeJobLookup, failing them when err is set
+type countingJobLookup struct {
+	jobs    fakeJobLookup
+	err     error
+	lookups int
+}
+
+func (l *countingJobLookup) RunningJobs(ctx context.Context, node string) ([]string, error) {
+	l.lookups++
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l.jobs.RunningJobs(ctx, node)
+}
+
+func TestWorkloadAwareFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		severity  string
+		node      string
+		lookupErr error
+		want      bool
+	}{
+		{"informational event of an idle node", "OK", "idle", nil, false},
+		{"informational event of a busy node", "OK", "busy", nil, true},
+		{"warning of an idle node", "Warning", "idle", nil, true},
+		{"critical event of an idle node", "Critical", "idle", nil, true},
+		{"server without a slurm node", "OK", "", nil, true},
+		{"failed lookup", "OK", "idle", errors.New("slurmrestd unavailable"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := &countingJobLookup{jobs: fakeJobLookup{"busy": {"100"}}, err: tt.lookupErr}
+			sink := &countingSink{}
+			filtered := &filteredSink{sink: sink, filters: []EventFilter{NewWorkloadAwareFilter(lookup, time.Minute)}}
+			event := &EnrichedEvent{Event: Event{Severity: tt.severity}, SlurmNode: tt.node}
+			if err := filtered.Send(event); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if got := len(sink.events) == 1; got != tt.want {
+				t.Errorf("forwarded = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkloadAwareFilterCachesLookups(t *testing.T) {
+	lookup := &countingJobLookup{jobs: fakeJobLookup{}}
+	filter := NewWorkloadAwareFilter(lookup, time.Minute)
+	now := time.Now()
+
+	filter.busy("node1", now)
+	filter.busy("node1", now.Add(30*time.Second))
+	if lookup.lookups != 1 {
+		t.Errorf("%d lookups within the TTL, want 1", lookup.lookups)
+	}
+
+	// The node got a job after the entry expired
+	lookup.jobs["node1"] = []string{"100"}
+	if !filter.busy("node1", now.Add(2*time.Minute)) || lookup.lookups != 2 {
+		t.Errorf("busy = false after %d lookups, want the expired entry looked up again", lookup.lookups)
+	}
+}
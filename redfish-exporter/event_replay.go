@@ -0,0 +1,133 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	replayPageSize         = 500
+	replayProgressInterval = 1000
+)
+
+// StoredEvent is an event as kept by an EventStore, ordered by ID
+type StoredEvent struct {
+	ID         int64
+	ServerIP   string
+	Severity   string
+	ReceivedAt time.Time
+	// The EnrichedEvent as JSON
+	Payload json.RawMessage
+}
+
+// EventQuery selects stored events. Zero fields match everything.
+type EventQuery struct {
+	// Received at or after Since and before Until
+	Since      time.Time
+	Until      time.Time
+	ServerIP   string
+	Severities []string
+	// Only the events after this ID, to page through the results
+	AfterID int64
+	// Maximum number of events returned, 0 for the store's default
+	Limit int
+}
+
+// Matches reports whether the event matches the query, ignoring paging
+func (q EventQuery) Matches(event *StoredEvent) bool {
+	if !q.Since.IsZero() && event.ReceivedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !event.ReceivedAt.Before(q.Until) {
+		return false
+	}
+	if q.ServerIP != "" && event.ServerIP != q.ServerIP {
+		return false
+	}
+	return len(q.Severities) == 0 || slices.Contains(q.Severities, event.Severity)
+}
+
+// EventStore is implemented by the sinks that keep the events they receive
+// so they can be queried later
+type EventStore interface {
+	// The events matching the query in ID order
+	QueryEvents(ctx context.Context, query EventQuery) ([]StoredEvent, error)
+}
+
+func (e *StoredEvent) enrichedEvent() (*EnrichedEvent, error) {
+	var event EnrichedEvent
+	if err := json.Unmarshal(e.Payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode stored event %d: %w", e.ID, err)
+	}
+	return &event, nil
+}
+
+// ReplayEvents sends the stored events matching the filter to the sink in
+// order, e.g. to fill a sink added after the events were received. Stops
+// at the first event the sink fails to take, the error names it so the
+// replay can be resumed with AfterID.
+func ReplayEvents(ctx context.Context, store EventStore, sink EventSink, filter EventQuery) (replayed int, err error) {
+	return replayEvents(ctx, store, filter, sink.Send)
+}
+
+// DryReplayEvents counts the events ReplayEvents would send
+func DryReplayEvents(ctx context.Context, store EventStore, filter EventQuery) (int, error) {
+	return replayEvents(ctx, store, filter, func(*EnrichedEvent) error { return nil })
+}
+
+func replayEvents(ctx context.Context, store EventStore, filter EventQuery, send func(*EnrichedEvent) error) (int, error) {
+	query := filter
+	if query.Limit <= 0 {
+		query.Limit = replayPageSize
+	}
+
+	replayed := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return replayed, err
+		}
+		records, err := store.QueryEvents(ctx, query)
+		if err != nil {
+			return replayed, fmt.Errorf("failed to query events after %d: %w", query.AfterID, err)
+		}
+
+		for i := range records {
+			event, err := records[i].enrichedEvent()
+			if err != nil {
+				return replayed, err
+			}
+			if err := send(event); err != nil {
+				return replayed, fmt.Errorf("failed to replay event %d: %w", records[i].ID, err)
+			}
+			replayed++
+			if replayed%replayProgressInterval == 0 {
+				log.Printf("Replayed %d events, up to event %d", replayed, records[i].ID)
+			}
+		}
+
+		if len(records) < query.Limit {
+			return replayed, nil
+		}
+		query.AfterID = records[len(records)-1].ID
+	}
+}
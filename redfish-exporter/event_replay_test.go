@@ -0,0 +1,186 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryEventStore keeps events in memory, in ID order
+type memoryEventStore struct {
+	events  []StoredEvent
+	queries []EventQuery
+	err     error
+}
+
+func (s *memoryEventStore) QueryEvents(ctx context.Context, query EventQuery) ([]StoredEvent, error) {
+	s.queries = append(s.queries, query)
+	if s.err != nil {
+		return nil, s.err
+	}
+	var matched []StoredEvent
+	for i := range s.events {
+		if s.events[i].ID > query.AfterID && query.Matches(&s.events[i]) {
+			matched = append(matched, s.events[i])
+		}
+		if query.Limit > 0 && len(matched) == query.Limit {
+			break
+		}
+	}
+	return matched, nil
+}
+
+func newMemoryEventStore(t *testing.T, n int) *memoryEventStore {
+	t.Helper()
+	store := &memoryEventStore{}
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		event := EnrichedEvent{Event: Event{MessageId: fmt.Sprintf("Base.1.0.Event%d", i)}, ServerIP: "10.0.0.1"}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.events = append(store.events, StoredEvent{
+			ID:         int64(i),
+			ServerIP:   "10.0.0.1",
+			Severity:   "Warning",
+			ReceivedAt: start.Add(time.Duration(i) * time.Hour),
+			Payload:    payload,
+		})
+	}
+	return store
+}
+
+// failingSink fails to take the event with the message ID
+type failingSink struct {
+	countingSink
+	failMessageID string
+}
+
+func (s *failingSink) Send(event *EnrichedEvent) error {
+	if event.MessageId == s.failMessageID {
+		return errors.New("sink unavailable")
+	}
+	return s.countingSink.Send(event)
+}
+
+func TestEventQueryMatches(t *testing.T) {
+	received := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	event := &StoredEvent{ServerIP: "10.0.0.1", Severity: "Critical", ReceivedAt: received}
+	tests := []struct {
+		name  string
+		query EventQuery
+		want  bool
+	}{
+		{"empty query", EventQuery{}, true},
+		{"since the event", EventQuery{Since: received}, true},
+		{"since after the event", EventQuery{Since: received.Add(time.Second)}, false},
+		{"until the event", EventQuery{Until: received}, false},
+		{"until after the event", EventQuery{Until: received.Add(time.Second)}, true},
+		{"same server", EventQuery{ServerIP: "10.0.0.1"}, true},
+		{"other server", EventQuery{ServerIP: "10.0.0.2"}, false},
+		{"listed severity", EventQuery{Severities: []string{"Warning", "Critical"}}, true},
+		{"unlisted severity", EventQuery{Severities: []string{"OK"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.Matches(event); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayEvents(t *testing.T) {
+	tests := []struct {
+		name          string
+		events        int
+		filter        EventQuery
+		failMessageID string
+		storeErr      error
+		wantReplayed  int
+		wantQueries   int
+		wantErr       string
+	}{
+		{"single page", 3, EventQuery{}, "", nil, 3, 1, ""},
+		{"pages through the events", 5, EventQuery{Limit: 2}, "", nil, 5, 3, ""},
+		{"full last page", 4, EventQuery{Limit: 2}, "", nil, 4, 3, ""},
+		{"resumes after an ID", 5, EventQuery{AfterID: 3}, "", nil, 2, 1, ""},
+		{"stops at the failed event", 5, EventQuery{Limit: 2}, "Base.1.0.Event4", nil, 3, 2, "failed to replay event 4"},
+		{"query failure", 5, EventQuery{}, "", errors.New("database locked"), 0, 1, "failed to query events after 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryEventStore(t, tt.events)
+			store.err = tt.storeErr
+			sink := &failingSink{failMessageID: tt.failMessageID}
+
+			replayed, err := ReplayEvents(context.Background(), store, sink, tt.filter)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("ReplayEvents() error = %v, want %q", err, tt.wantErr)
+			}
+			if replayed != tt.wantReplayed || len(sink.events) != tt.wantReplayed {
+				t.Errorf("replayed %d events, sink got %d, want %d", replayed, len(sink.events), tt.wantReplayed)
+			}
+			if len(store.queries) != tt.wantQueries {
+				t.Errorf("%d queries, want %d", len(store.queries), tt.wantQueries)
+			}
+			for i := 1; i < len(sink.events); i++ {
+				if sink.events[i-1].MessageId >= sink.events[i].MessageId {
+					t.Errorf("events replayed out of order: %s before %s", sink.events[i-1].MessageId, sink.events[i].MessageId)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayEventsUndecodableEvent(t *testing.T) {
+	store := newMemoryEventStore(t, 2)
+	store.events[1].Payload = json.RawMessage("{")
+
+	replayed, err := ReplayEvents(context.Background(), store, &countingSink{}, EventQuery{})
+	if err == nil || !strings.Contains(err.Error(), "stored event 2") || replayed != 1 {
+		t.Errorf("ReplayEvents() = %d, %v, want 1 event and an error naming event 2", replayed, err)
+	}
+}
+
+func TestReplayEventsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store := newMemoryEventStore(t, 2)
+
+	replayed, err := ReplayEvents(ctx, store, &countingSink{}, EventQuery{})
+	if !errors.Is(err, context.Canceled) || replayed != 0 || len(store.queries) != 0 {
+		t.Errorf("ReplayEvents() = %d, %v after %d queries, want nothing replayed", replayed, err, len(store.queries))
+	}
+}
+
+func TestDryReplayEvents(t *testing.T) {
+	store := newMemoryEventStore(t, 5)
+	store.events[4].Severity = "Critical"
+
+	count, err := DryReplayEvents(context.Background(), store, EventQuery{Severities: []string{"Warning"}, Limit: 2})
+	if err != nil || count != 4 {
+		t.Errorf("DryReplayEvents() = %d, %v, want 4", count, err)
+	}
+}
@@ -0,0 +1,67 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//go:embed schemas/Event.json
+var bundledSchemas embed.FS
+
+// Returned for event payloads that don't match the Redfish Event schema
+var ErrSchemaViolation = errors.New("event schema violation")
+
+// EventSchemaValidator checks event payloads against the bundled subset of
+// the DMTF Event schema, see schemas/Event.json
+type EventSchemaValidator struct {
+	schema *gojsonschema.Schema
+}
+
+func NewEventSchemaValidator() (*EventSchemaValidator, error) {
+	data, err := bundledSchemas.ReadFile("schemas/Event.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event schema: %v", err)
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile event schema: %v", err)
+	}
+	return &EventSchemaValidator{schema: schema}, nil
+}
+
+// Check an event payload, returning ErrSchemaViolation with the list of
+// violations if it doesn't match the schema
+func (v *EventSchemaValidator) Validate(data []byte) error {
+	result, err := v.schema.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaViolation, err)
+	}
+	if result.Valid() {
+		return nil
+	}
+	violations := make([]string, 0, len(result.Errors()))
+	for _, violation := range result.Errors() {
+		violations = append(violations, violation.String())
+	}
+	return fmt.Errorf("%w: %s", ErrSchemaViolation, strings.Join(violations, "; "))
+}
@@ -0,0 +1,153 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEventSchemaValidator(t *testing.T) {
+	validator, err := NewEventSchemaValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const record = `"MemberId": "0", "MessageId": "ResourceEvent.1.0.ResourceErrorsDetected"`
+	tests := []struct {
+		name string
+		body string
+		// Substrings of the violations, none when empty
+		want []string
+	}{
+		{
+			name: "valid event",
+			body: `{"@odata.type": "#Event.v1_7_0.Event", "Id": "1", "Name": "Event", "Context": "scrapefish",
+				"Events": [{` + record + `, "EventType": "Alert", "Severity": "Critical", "MessageArgs": ["GPU0"],
+				"OriginOfCondition": {"@odata.id": "/redfish/v1/Systems/1"}, "EventId": "1", "EventGroupId": 3}]}`,
+		},
+		{
+			name: "without the deprecated EventType",
+			body: `{"@odata.type": "#Event.v1_7_0.Event", "Id": "1", "Name": "Event",
+				"Events": [{` + record + `, "MessageSeverity": "Warning"}]}`,
+		},
+		{
+			name: "annotations allowed",
+			body: `{"@odata.type": "#Event.v1_7_0.Event", "Id": "1", "Name": "Event", "Events@odata.count": 1,
+				"Events": [{` + record + `, "Message@Message.ExtendedInfo": []}]}`,
+		},
+		{
+			name: "missing required properties",
+			body: `{"@odata.type": "#Event.v1_7_0.Event", "Name": "Event", "Events": [{"MemberId": "0"}]}`,
+			want: []string{"(root): Id is required", "Events.0: MessageId is required"},
+		},
+		{
+			name: "wrong types",
+			body: `{"@odata.type": "#Event.v1_7_0.Event", "Id": 1, "Name": "Event",
+				"Events": [{` + record + `, "EventGroupId": 1.5, "MessageArgs": [1]}]}`,
+			want: []string{"Id: Invalid type. Expected: string", "Events.0.EventGroupId: Invalid type. Expected: integer", "Events.0.MessageArgs.0: Invalid type. Expected: string"},
+		},
+		{
+			name: "value not in the enum",
+			body: `{"@odata.type": "#Event.v1_7_0.Event", "Id": "1", "Name": "Event",
+				"Events": [{` + record + `, "MessageSeverity": "Fatal"}]}`,
+			want: []string{`Events.0.MessageSeverity must be one of the following: "OK", "Warning", "Critical"`},
+		},
+		{
+			name: "malformed MessageId",
+			body: `{"@odata.type": "#Event.v1_7_0.Event", "Id": "1", "Name": "Event",
+				"Events": [{"MemberId": "0", "MessageId": "GeneralError"}]}`,
+			want: []string{"Events.0.MessageId: Does not match pattern"},
+		},
+		{
+			name: "unknown property",
+			body: `{"@odata.type": "#Event.v1_7_0.Event", "Id": "1", "Name": "Event",
+				"Events": [{` + record + `, "Vendor": "x"}]}`,
+			want: []string{"Events.0: Additional property Vendor is not allowed"},
+		},
+		{
+			name: "not JSON",
+			body: `{"Events": [`,
+			want: []string{"unexpected EOF"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate([]byte(tt.body))
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSchemaViolation) {
+				t.Fatalf("Validate() error = %v, want %v", err, ErrSchemaViolation)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to report %s", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestListenerSchemaViolations(t *testing.T) {
+	validator, err := NewEventSchemaValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Parses, but misses the required Id and Name
+	const payload = `{"@odata.type": "#Event.v1_7_0.Event", "Events": [{"MemberId": "0", "MessageId": "ResourceEvent.1.0.ResourceErrorsDetected"}]}`
+	tests := []struct {
+		name          string
+		strict        bool
+		wantStatus    int
+		wantForwarded bool
+	}{
+		{name: "forwarded flagged", wantStatus: http.StatusOK, wantForwarded: true},
+		{name: "rejected when strict", strict: true, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &countingSink{}
+			s := NewServer("127.0.0.1", "0", nil, []EventSink{sink})
+			s.SetSchemaValidator(validator, tt.strict)
+			address := startTestListener(t, s)
+
+			resp, err := http.Post("http://"+address+"/", "application/json", strings.NewReader(payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := counterValue(t, s.metrics.eventSchemaViolations.WithLabelValues("127.0.0.1")); got != 1 {
+				t.Errorf("schema violations metric increased by %v, want 1", got)
+			}
+			if tt.wantForwarded {
+				if len(sink.events) != 1 || !sink.events[0].SchemaViolation {
+					t.Errorf("sink received %+v, want the event flagged as a schema violation", sink.events)
+				}
+			} else if len(sink.events) != 0 {
+				t.Errorf("sink received %d events, want none", len(sink.events))
+			}
+		})
+	}
+}
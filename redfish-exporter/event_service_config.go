@@ -0,0 +1,175 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+// EventServiceSettings are the EventService properties ConfigureEventService
+// can set, nil ones are left unchanged
+type EventServiceSettings struct {
+	ServiceEnabled               *bool
+	DeliveryRetryAttempts        *int
+	DeliveryRetryIntervalSeconds *int
+}
+
+// Build the PATCH body with the properties that are set
+func (s EventServiceSettings) properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	if s.ServiceEnabled != nil {
+		properties["ServiceEnabled"] = *s.ServiceEnabled
+	}
+	if s.DeliveryRetryAttempts != nil {
+		properties["DeliveryRetryAttempts"] = *s.DeliveryRetryAttempts
+	}
+	if s.DeliveryRetryIntervalSeconds != nil {
+		properties["DeliveryRetryIntervalSeconds"] = *s.DeliveryRetryIntervalSeconds
+	}
+	return properties
+}
+
+// EventServiceSettingsError is returned by ConfigureEventService when some
+// of the settings were not applied. The other settings were.
+type EventServiceSettingsError struct {
+	Server string
+	// Properties the EventService of the server doesn't have
+	Unsupported []string
+	// Properties the server refused to change
+	Rejected []string
+}
+
+func (e *EventServiceSettingsError) Error() string {
+	var parts []string
+	if len(e.Unsupported) > 0 {
+		parts = append(parts, fmt.Sprintf("unsupported: %s", strings.Join(e.Unsupported, ", ")))
+	}
+	if len(e.Rejected) > 0 {
+		parts = append(parts, fmt.Sprintf("rejected: %s", strings.Join(e.Rejected, ", ")))
+	}
+	return fmt.Sprintf("event service settings not applied on server %s (%s)", e.Server, strings.Join(parts, "; "))
+}
+
+// Set the given properties on the server's EventService, e.g. to enable it
+// before subscribing. Properties the EventService doesn't have are skipped,
+// and if the server rejects the PATCH each property is tried on its own.
+// Skipped and rejected properties are reported in an
+// *EventServiceSettingsError.
+func ConfigureEventService(server RedfishServer, settings EventServiceSettings) error {
+	properties := settings.properties()
+	if len(properties) == 0 {
+		return nil
+	}
+
+	c, err := getRedfishClient(server)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	eventService, err := getEventService(c)
+	if err != nil {
+		return fmt.Errorf("failed to get event service on server %s: %w", server.IP, err)
+	}
+	current, err := getRawProperties(c, eventService.ODataID)
+	if err != nil {
+		return fmt.Errorf("failed to read event service on server %s: %w", server.IP, err)
+	}
+
+	settingsErr := &EventServiceSettingsError{Server: server.IP}
+	for name := range properties {
+		if _, ok := current[name]; !ok {
+			settingsErr.Unsupported = append(settingsErr.Unsupported, name)
+			delete(properties, name)
+		}
+	}
+
+	if len(properties) > 0 {
+		rejected, err := patchEventService(c, eventService.ODataID, properties)
+		if err != nil {
+			return fmt.Errorf("failed to configure event service on server %s: %w", server.IP, normalizeRedfishError(err))
+		}
+		settingsErr.Rejected = rejected
+	}
+
+	if len(settingsErr.Unsupported) == 0 && len(settingsErr.Rejected) == 0 {
+		log.Printf("Configured event service on server %s", server.IP)
+		return nil
+	}
+	sort.Strings(settingsErr.Unsupported)
+	sort.Strings(settingsErr.Rejected)
+	return settingsErr
+}
+
+// PATCH the properties on the EventService and return the ones the server
+// refused. When the server refuses several, each is tried on its own.
+func patchEventService(c common.Client, uri string, properties map[string]interface{}) ([]string, error) {
+	err := patchProperties(c, uri, properties)
+	if err == nil {
+		return nil, nil
+	}
+	if !isRejectedStatus(redfishStatusCode(err)) {
+		return nil, err
+	}
+
+	var rejected []string
+	if len(properties) == 1 {
+		for name := range properties {
+			rejected = append(rejected, name)
+		}
+		return rejected, nil
+	}
+	for name, value := range properties {
+		if err := patchProperties(c, uri, map[string]interface{}{name: value}); err != nil {
+			if !isRejectedStatus(redfishStatusCode(err)) {
+				return nil, err
+			}
+			rejected = append(rejected, name)
+		}
+	}
+	return rejected, nil
+}
+
+// Read a resource as raw properties by name
+func getRawProperties(c common.Client, uri string) (map[string]json.RawMessage, error) {
+	resp, err := c.Get(uri)
+	if err != nil {
+		return nil, normalizeRedfishError(err)
+	}
+	defer resp.Body.Close()
+
+	var properties map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&properties); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", uri, err)
+	}
+	return properties, nil
+}
+
+func patchProperties(c common.Client, uri string, properties map[string]interface{}) error {
+	resp, err := c.Patch(uri, properties)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,131 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestConfigureEventService(t *testing.T) {
+	tests := []struct {
+		name            string
+		current         map[string]interface{}
+		readOnly        map[string]bool
+		settings        EventServiceSettings
+		wantPatches     int
+		wantApplied     map[string]interface{}
+		wantUnsupported []string
+		wantRejected    []string
+	}{
+		{
+			name:        "nothing to set",
+			settings:    EventServiceSettings{},
+			wantPatches: 0,
+		},
+		{
+			name:        "all settings applied",
+			current:     map[string]interface{}{"ServiceEnabled": false, "DeliveryRetryAttempts": 3, "DeliveryRetryIntervalSeconds": 60},
+			settings:    EventServiceSettings{ServiceEnabled: boolPtr(true), DeliveryRetryAttempts: intPtr(5), DeliveryRetryIntervalSeconds: intPtr(30)},
+			wantPatches: 1,
+			wantApplied: map[string]interface{}{"ServiceEnabled": true, "DeliveryRetryAttempts": 5, "DeliveryRetryIntervalSeconds": 30},
+		},
+		{
+			name:            "property the event service doesn't have",
+			current:         map[string]interface{}{"DeliveryRetryAttempts": 3},
+			settings:        EventServiceSettings{DeliveryRetryAttempts: intPtr(5), DeliveryRetryIntervalSeconds: intPtr(30)},
+			wantPatches:     1,
+			wantApplied:     map[string]interface{}{"DeliveryRetryAttempts": 5},
+			wantUnsupported: []string{"DeliveryRetryIntervalSeconds"},
+		},
+		{
+			name:         "rejected property retried on its own",
+			current:      map[string]interface{}{"ServiceEnabled": false, "DeliveryRetryAttempts": 3},
+			readOnly:     map[string]bool{"DeliveryRetryAttempts": true},
+			settings:     EventServiceSettings{ServiceEnabled: boolPtr(true), DeliveryRetryAttempts: intPtr(5)},
+			wantPatches:  3,
+			wantApplied:  map[string]interface{}{"ServiceEnabled": true, "DeliveryRetryAttempts": 3},
+			wantRejected: []string{"DeliveryRetryAttempts"},
+		},
+		{
+			name:         "single rejected property",
+			current:      map[string]interface{}{"DeliveryRetryAttempts": 3},
+			readOnly:     map[string]bool{"DeliveryRetryAttempts": true},
+			settings:     EventServiceSettings{DeliveryRetryAttempts: intPtr(5)},
+			wantPatches:  1,
+			wantRejected: []string{"DeliveryRetryAttempts"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			for key, value := range tt.current {
+				bmc.eventService[key] = value
+			}
+			bmc.readOnlyEventService = tt.readOnly
+
+			err := ConfigureEventService(bmc.server(), tt.settings)
+			var settingsErr *EventServiceSettingsError
+			if tt.wantUnsupported == nil && tt.wantRejected == nil {
+				if err != nil {
+					t.Fatalf("ConfigureEventService() error = %v", err)
+				}
+			} else if !errors.As(err, &settingsErr) {
+				t.Fatalf("ConfigureEventService() error = %v, want an *EventServiceSettingsError", err)
+			} else if !slices.Equal(settingsErr.Unsupported, tt.wantUnsupported) || !slices.Equal(settingsErr.Rejected, tt.wantRejected) {
+				t.Errorf("unsupported %v, rejected %v, want %v, %v", settingsErr.Unsupported, settingsErr.Rejected, tt.wantUnsupported, tt.wantRejected)
+			}
+			if got := bmc.count("PATCH /redfish/v1/EventService"); got != tt.wantPatches {
+				t.Errorf("%d PATCH requests, want %d", got, tt.wantPatches)
+			}
+			for name, want := range tt.wantApplied {
+				if got := bmc.eventService[name]; fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("%s = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigureEventServicePatchFailure(t *testing.T) {
+	bmc := newFakeBMC(t)
+	bmc.failures["PATCH /redfish/v1/EventService"] = 500
+
+	err := ConfigureEventService(bmc.server(), EventServiceSettings{ServiceEnabled: boolPtr(true)})
+	var settingsErr *EventServiceSettingsError
+	if err == nil || errors.As(err, &settingsErr) {
+		t.Errorf("ConfigureEventService() error = %v, want the server error", err)
+	}
+}
+
+func TestEventServiceSettingsErrorMessage(t *testing.T) {
+	err := &EventServiceSettingsError{
+		Server:      "10.0.0.1",
+		Unsupported: []string{"DeliveryRetryIntervalSeconds"},
+		Rejected:    []string{"DeliveryRetryAttempts", "ServiceEnabled"},
+	}
+	want := "event service settings not applied on server 10.0.0.1 (unsupported: DeliveryRetryIntervalSeconds; rejected: DeliveryRetryAttempts, ServiceEnabled)"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Returned for event payloads that are not valid Redfish events
+var ErrMalformedEvent = errors.New("malformed event payload")
+
+// Parse an event payload, checking the MessageId events are routed by. The
+// firmware of some BMCs sends events without it, which would otherwise be
+// passed on with an empty value. EventType and Severity are deprecated in
+// the Event schema and optional, Severity is taken from MessageSeverity
+// when only that is sent.
+func parsePayload(data []byte) (Payload, error) {
+	var p Payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+	}
+	if len(p.Events) == 0 {
+		return p, fmt.Errorf("%w: no Events", ErrMalformedEvent)
+	}
+
+	for i, event := range p.Events {
+		if event.MessageId == "" {
+			return p, fmt.Errorf("%w: Events[%d] is missing MessageId", ErrMalformedEvent, i)
+		}
+		if event.Severity == "" {
+			p.Events[i].Severity = event.MessageSeverity
+		}
+	}
+	return p, nil
+}
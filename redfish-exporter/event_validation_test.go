@@ -0,0 +1,95 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePayload(t *testing.T) {
+	tests := []struct {
+		name         string
+		payload      string
+		wantErr      bool
+		wantSeverity []string
+	}{
+		{
+			name:         "complete event",
+			payload:      `{"Events": [{"EventType": "Alert", "Severity": "Critical", "MessageId": "Base.1.0.Fault"}]}`,
+			wantSeverity: []string{"Critical"},
+		},
+		{
+			name:         "MessageSeverity only",
+			payload:      `{"Events": [{"MessageSeverity": "Warning", "MessageId": "Base.1.0.Fault"}]}`,
+			wantSeverity: []string{"Warning"},
+		},
+		{
+			name:         "Severity preferred over MessageSeverity",
+			payload:      `{"Events": [{"Severity": "OK", "MessageSeverity": "Critical", "MessageId": "Base.1.0.Fault"}]}`,
+			wantSeverity: []string{"OK"},
+		},
+		{
+			name:         "no severity at all",
+			payload:      `{"Events": [{"MessageId": "Base.1.0.Fault"}]}`,
+			wantSeverity: []string{""},
+		},
+		{
+			name:         "several events",
+			payload:      `{"Events": [{"MessageId": "A.1.0.X", "Severity": "OK"}, {"MessageId": "A.1.0.Y", "MessageSeverity": "Critical"}]}`,
+			wantSeverity: []string{"OK", "Critical"},
+		},
+		{
+			name:    "missing MessageId",
+			payload: `{"Events": [{"MessageId": "A.1.0.X"}, {"Severity": "OK"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "no events",
+			payload: `{"Events": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "not JSON",
+			payload: `Events`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parsePayload([]byte(tt.payload))
+			if tt.wantErr {
+				if !errors.Is(err, ErrMalformedEvent) {
+					t.Fatalf("parsePayload() error = %v, want ErrMalformedEvent", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePayload() error = %v", err)
+			}
+			if len(p.Events) != len(tt.wantSeverity) {
+				t.Fatalf("parsePayload() returned %d events, want %d", len(p.Events), len(tt.wantSeverity))
+			}
+			for i, event := range p.Events {
+				if event.Severity != tt.wantSeverity[i] {
+					t.Errorf("Events[%d].Severity = %q, want %q", i, event.Severity, tt.wantSeverity[i])
+				}
+			}
+		})
+	}
+}
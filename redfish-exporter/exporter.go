@@ -0,0 +1,1013 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nod-ai/ADA/redfish-exporter/slurm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stmcginnis/gofish/redfish"
+	"sigs.k8s.io/yaml"
+)
+
+// Default time allowed for the exporter to shut down
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Duration is a time.Duration read from a string like "30s" in config files
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %v", err)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+// ReceiverConfig is the address the BMCs deliver events to
+type ReceiverConfig struct {
+	ListenIP   string `json:"listenIP"`
+	ListenPort string `json:"listenPort"`
+	UseSSL     bool   `json:"useSSL"`
+	CertFile   string `json:"certFile"`
+	KeyFile    string `json:"keyFile"`
+	// Path the events are posted to when the receiver sits behind a
+	// reverse proxy, e.g. /redfish-events. Any path is accepted when empty.
+	PathPrefix string `json:"pathPrefix"`
+}
+
+// RetryPolicy is the retry policy of the initial subscription creation and,
+// unless the redfish client has its own, of the transient failures of
+// single redfish requests
+type RetryPolicy struct {
+	MaxAttempts    int      `json:"maxAttempts"`
+	InitialBackoff Duration `json:"initialBackoff"`
+	MaxBackoff     Duration `json:"maxBackoff"`
+}
+
+// BreakerPolicy skips a server in the reconcile loop for the cooldown after
+// threshold consecutive failures
+type BreakerPolicy struct {
+	Threshold int      `json:"threshold"`
+	Cooldown  Duration `json:"cooldown"`
+}
+
+// ExporterConfig holds everything the exporter needs to manage the
+// subscriptions of a set of servers and receive their events
+type ExporterConfig struct {
+	Servers []RedfishServer `json:"servers"`
+	// Only operate on the servers matching this label selector, see Filter
+	ServerSelector      string              `json:"serverSelector"`
+	SubscriptionPayload SubscriptionPayload `json:"subscriptionPayload"`
+	// Context prefix marking the subscriptions owned by the exporter
+	OwnerContext   string         `json:"ownerContext"`
+	TriggerEvents  []TriggerEvent `json:"triggerEvents"`
+	Receiver       ReceiverConfig `json:"receiver"`
+	MaxConcurrency int            `json:"maxConcurrency"`
+	// Timeout of a single redfish request, no timeout when zero
+	RequestTimeout        Duration `json:"requestTimeout"`
+	ReconcileInterval     Duration `json:"reconcileInterval"`
+	RestartDetectInterval Duration `json:"restartDetectInterval"`
+	// Resolve the events' MessageIds with the message registries
+	ResolveMessages bool `json:"resolveMessages"`
+	// Check the posted events against the Redfish Event schema, events
+	// that don't match are flagged, or rejected when strict
+	ValidateEventSchema    bool `json:"validateEventSchema"`
+	StrictSchemaValidation bool `json:"strictSchemaValidation"`
+	// Interval of the NTP status checks, disabled when zero
+	TimeSyncInterval Duration `json:"timeSyncInterval"`
+	// Interval of the AMD GPU health checks, disabled when zero
+	GPUHealthInterval Duration `json:"gpuHealthInterval"`
+	// Interval of the system and manager health checks, disabled when zero
+	SystemHealthInterval Duration `json:"systemHealthInterval"`
+	// Prediction of DIMM failures from their correctable error trends
+	MemoryTrend MemoryTrendConfig `json:"memoryTrend"`
+	// Pass criteria of the Slurm node health checks
+	HealthThresholds HealthThresholds `json:"healthThresholds"`
+	// Limits of the events forwarded to the sinks
+	RateLimit RateLimitConfig `json:"rateLimit"`
+	// Poll interval of the servers with pollEvents set, DefaultPollInterval when zero
+	LogPollInterval Duration `json:"logPollInterval"`
+	// Time allowed for unsubscribing and stopping the listener on shutdown,
+	// DefaultShutdownTimeout when zero
+	ShutdownTimeout Duration            `json:"shutdownTimeout"`
+	Retry           RetryPolicy         `json:"retry"`
+	CircuitBreaker  BreakerPolicy       `json:"circuitBreaker"`
+	RedfishClient   RedfishClientConfig `json:"redfishClient"`
+	// Identity provider of the servers with loginType oauth
+	OAuth OAuthConfig `json:"oauth"`
+	// JSON file keeping the state that survives restarts, like the
+	// maintenance windows. The state is only kept in memory when empty
+	StateFile string `json:"stateFile"`
+	// Timing of the reconcile loop's checks, its PollInterval defaults to
+	// ReconcileInterval
+	Watchdog WatchdogConfig `json:"watchdog"`
+	// File recording the open BMC sessions, so the sessions left by a crash
+	// are deleted on the next start. Not recorded when empty
+	SessionLockFile string `json:"sessionLockFile"`
+	// Recreate subscriptions before BMCs expire them, disabled without a TTL
+	SubscriptionTTL SubscriptionTTLConfig `json:"subscriptionTTL"`
+
+	// DeliveryRetryPolicy of the subscriptions by BMC vendor, e.g.
+	// {"Dell": "SuspendRetries"}, the payload's for other vendors
+	DeliveryRetryPolicies map[string]redfish.DeliveryRetryPolicy `json:"deliveryRetryPolicies"`
+	// Events buffered for each sink before new ones are dropped,
+	// DefaultSinkQueueSize when zero
+	SinkQueueSize int `json:"sinkQueueSize"`
+	// Group related events into incidents reported to the alert sinks
+	CorrelationRules []CorrelationRule `json:"correlationRules"`
+	// Preempt the slurm jobs of servers reporting hardware faults
+	Preemption PreemptionConfig `json:"preemption"`
+	// Drop the OK events of slurm nodes that run no jobs
+	WorkloadAwareFilter bool `json:"workloadAwareFilter"`
+	// Request changes for BMC firmware departing from the Redfish schema
+	FirmwareWorkarounds []FirmwareWorkaround `json:"firmwareWorkarounds"`
+
+	// Set by embedders, can't be read from a config file
+	Sinks      []EventSink       `json:"-"`
+	SlurmQueue *slurm.SlurmQueue `json:"-"`
+	// Replaces the resolver built from DeliveryRetryPolicies
+	DeliveryPolicyResolver DeliveryPolicyResolver `json:"-"`
+	// Replaces the token source built from OAuth
+	TokenSource TokenSource `json:"-"`
+	// Replace squeue and scancel for the preemption rules and the
+	// workload aware filter
+	JobLookup    SlurmJobLookup `json:"-"`
+	JobPreemptor JobPreemptor   `json:"-"`
+	// Applied to the events before the sinks and the rate limits
+	EventFilters []EventFilter `json:"-"`
+	// Registers the exporter's metrics, they aren't exported when nil
+	Registerer prometheus.Registerer `json:"-"`
+}
+
+// Read the exporter config from a JSON or YAML file. YAML uses the same
+// keys as JSON.
+func LoadConfig(path string) (ExporterConfig, error) {
+	var cfg ExporterConfig
+
+	var unmarshal func(data []byte, v interface{}) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		unmarshal = json.Unmarshal
+	case ".yaml", ".yml":
+		unmarshal = func(data []byte, v interface{}) error {
+			return yaml.Unmarshal(data, v)
+		}
+	default:
+		return cfg, fmt.Errorf("failed to load config %s: unknown config file type", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to load config: %v", err)
+	}
+	if err := unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Check the config for errors, returning all of them at once
+func (cfg ExporterConfig) Validate() error {
+	var errs []error
+
+	seenIPs, seenNames := make(map[string]bool), make(map[string]bool)
+	for _, server := range cfg.Servers {
+		serverURL, err := url.Parse(server.IP)
+		if err != nil || serverURL.Scheme == "" || serverURL.Host == "" {
+			errs = append(errs, fmt.Errorf("server %q: ip must be a URL like https://10.0.0.1", server.IP))
+		}
+		if seenIPs[server.IP] {
+			errs = append(errs, fmt.Errorf("server %q is configured more than once", server.IP))
+		}
+		seenIPs[server.IP] = true
+		if server.Name != "" {
+			if seenNames[server.Name] {
+				errs = append(errs, fmt.Errorf("server name %q is used more than once", server.Name))
+			}
+			seenNames[server.Name] = true
+		}
+	}
+
+	if _, err := parseSelector(cfg.ServerSelector); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := ValidateSubscriptionPayload(cfg.SubscriptionPayload); err != nil {
+		errs = append(errs, err)
+	} else if cfg.Receiver.PathPrefix != "" {
+		if err := checkDestinationPrefix(cfg.SubscriptionPayload, cfg.Servers, cfg.Receiver.PathPrefix); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if _, err := cfg.RedfishClient.TLSConfig(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid redfishClient TLS settings: %w", err))
+	}
+	if err := cfg.RedfishClient.DefaultAuthMode.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid redfishClient DefaultAuthMode: %w", err))
+	}
+	for _, server := range cfg.Servers {
+		if err := server.AuthMode.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid authMode of server %s: %w", server.IP, err))
+		}
+		if server.TLSConfig == nil {
+			continue
+		}
+		if err := server.TLSConfig.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid TLS settings of server %s: %w", server.IP, err))
+		}
+	}
+
+	if port, err := strconv.Atoi(cfg.Receiver.ListenPort); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid receiver listenPort %q", cfg.Receiver.ListenPort))
+	}
+	if cfg.Receiver.UseSSL && (cfg.Receiver.CertFile == "" || cfg.Receiver.KeyFile == "") {
+		errs = append(errs, errors.New("receiver certFile and keyFile are required with useSSL"))
+	}
+
+	if cfg.RateLimit.Rate < 0 || cfg.RateLimit.GlobalRate < 0 {
+		errs = append(errs, errors.New("rateLimit rates can't be negative"))
+	}
+	if cfg.MaxConcurrency < 0 {
+		errs = append(errs, errors.New("maxConcurrency can't be negative"))
+	}
+	if cfg.CircuitBreaker.Threshold < 0 {
+		errs = append(errs, errors.New("circuitBreaker threshold can't be negative"))
+	}
+	if cfg.Retry.MaxAttempts < 0 {
+		errs = append(errs, errors.New("retry maxAttempts can't be negative"))
+	}
+	if cfg.SinkQueueSize < 0 {
+		errs = append(errs, errors.New("sinkQueueSize can't be negative"))
+	}
+	for name, duration := range map[string]Duration{
+		"requestTimeout":        cfg.RequestTimeout,
+		"reconcileInterval":     cfg.ReconcileInterval,
+		"restartDetectInterval": cfg.RestartDetectInterval,
+		"logPollInterval":       cfg.LogPollInterval,
+		"timeSyncInterval":      cfg.TimeSyncInterval,
+		"gpuHealthInterval":     cfg.GPUHealthInterval,
+		"systemHealthInterval":  cfg.SystemHealthInterval,
+		"shutdownTimeout":       cfg.ShutdownTimeout,
+		"retry initialBackoff":  cfg.Retry.InitialBackoff,
+		"retry maxBackoff":      cfg.Retry.MaxBackoff,
+		"breaker cooldown":      cfg.CircuitBreaker.Cooldown,
+	} {
+		if duration.Duration < 0 {
+			errs = append(errs, fmt.Errorf("%s can't be negative", name))
+		}
+	}
+	watchdogs := map[string]WatchdogConfig{"watchdog": cfg.Watchdog}
+	for _, server := range cfg.Servers {
+		if server.Watchdog != nil {
+			watchdogs["server "+server.IP+" watchdog"] = *server.Watchdog
+		}
+	}
+	for vendor, policy := range cfg.DeliveryRetryPolicies {
+		if err := validDeliveryRetryPolicy(policy); err != nil {
+			errs = append(errs, fmt.Errorf("vendor %s: %w", vendor, err))
+		}
+	}
+	if err := cfg.SubscriptionTTL.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.MemoryTrend.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.HealthThresholds.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.TokenSource == nil && cfg.OAuth.TokenURL == "" {
+		for _, server := range cfg.Servers {
+			if isOAuthLogin(server) {
+				errs = append(errs, fmt.Errorf("server %s: %w", server.IP, ErrNoTokenSource))
+			}
+		}
+	}
+	for i, workaround := range cfg.FirmwareWorkarounds {
+		if err := workaround.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("firmware workaround %d: %w", i, err))
+		}
+	}
+	for name, watchdog := range watchdogs {
+		if watchdog.PollInterval.Duration < 0 || watchdog.MinRecheckInterval.Duration < 0 {
+			errs = append(errs, fmt.Errorf("%s intervals can't be negative", name))
+		}
+		if watchdog.MaxConsecutiveFailures < 0 {
+			errs = append(errs, fmt.Errorf("%s maxConsecutiveFailures can't be negative", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Exporter manages the subscriptions of a set of servers and receives
+// their events
+type Exporter struct {
+	cfg ExporterConfig
+	// Servers that are subscribed to, the others are polled or streamed
+	pushServers []RedfishServer
+	pollServers []RedfishServer
+	sseServers  []RedfishServer
+	// Settings and shared state of the connections to the servers
+	connection  *connectionSettings
+	retry       RetryConfig
+	listener    *Server
+	reconciler  *Reconciler
+	maintenance *MaintenanceRegistry
+	// Groups events into incidents sent to the alert sinks, optional
+	incidents  *IncidentCorrelator
+	alertSinks []EventSink
+	// Queues in front of the configured sinks, drained on shutdown
+	sinkQueues []*queuedSink
+	// Preempts the jobs of faulty servers, optional
+	preemption *PreemptionTrigger
+	// Hardware baselines of the servers
+	baselines *BaselineManager
+}
+
+// Create an exporter from a validated config
+func New(cfg ExporterConfig) (*Exporter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid exporter config: %w", err)
+	}
+
+	if cfg.MaxConcurrency == 0 {
+		cfg.MaxConcurrency = DefaultMaxConcurrency
+	}
+	if cfg.LogPollInterval.Duration == 0 {
+		pollInterval, _ := time.ParseDuration(DefaultPollInterval)
+		cfg.LogPollInterval.Duration = pollInterval
+	}
+	if cfg.ShutdownTimeout.Duration == 0 {
+		cfg.ShutdownTimeout.Duration = DefaultShutdownTimeout
+	}
+	if cfg.CircuitBreaker.Threshold == 0 {
+		cfg.CircuitBreaker.Threshold = DefaultServerBreakerThreshold
+	}
+	if cfg.CircuitBreaker.Cooldown.Duration == 0 {
+		cfg.CircuitBreaker.Cooldown.Duration = DefaultServerBreakerCooldown
+	}
+	if cfg.DeliveryPolicyResolver == nil {
+		if len(cfg.DeliveryRetryPolicies) > 0 {
+			cfg.DeliveryPolicyResolver = NewVendorPolicyResolver(cfg.DeliveryRetryPolicies, cfg.SubscriptionPayload.DeliveryRetryPolicy)
+		} else {
+			cfg.DeliveryPolicyResolver = StaticPolicyResolver{Policy: cfg.SubscriptionPayload.DeliveryRetryPolicy}
+		}
+	}
+	if cfg.Watchdog.PollInterval.Duration == 0 {
+		cfg.Watchdog.PollInterval = cfg.ReconcileInterval
+	}
+	if cfg.TokenSource == nil && cfg.OAuth.TokenURL != "" {
+		cfg.TokenSource = &ClientCredentialsTokenSource{Config: cfg.OAuth}
+	}
+
+	clientConfig := cfg.RedfishClient
+	if cfg.RequestTimeout.Duration > 0 {
+		clientConfig.Timeout = cfg.RequestTimeout.Duration
+	}
+
+	retry := DefaultRetryConfig
+	if cfg.Retry.MaxAttempts > 0 {
+		retry.MaxAttempts = cfg.Retry.MaxAttempts
+	}
+	if cfg.Retry.InitialBackoff.Duration > 0 {
+		retry.InitialBackoff = cfg.Retry.InitialBackoff.Duration
+	}
+	if cfg.Retry.MaxBackoff.Duration > 0 {
+		retry.MaxBackoff = cfg.Retry.MaxBackoff.Duration
+	}
+	// Transient failures of single requests are retried with the same
+	// policy unless the client has its own
+	if clientConfig.Retry.MaxAttempts == 0 {
+		clientConfig.Retry = retry
+	}
+
+	// The exporter's servers are contacted with its own settings
+	connection := newConnectionSettings(clientConfig)
+	connection.maxConcurrency = cfg.MaxConcurrency
+	connection.deliveryPolicy = cfg.DeliveryPolicyResolver
+	connection.firmwareWorkarounds = cfg.FirmwareWorkarounds
+	if cfg.TokenSource != nil {
+		connection.tokens = newCachedTokenSource(cfg.TokenSource)
+	}
+	metrics := NewMetrics()
+	connection.metrics = metrics
+	connection.breakers = NewServerBreakers(cfg.CircuitBreaker.Threshold, cfg.CircuitBreaker.Cooldown.Duration, metrics)
+	if cfg.SessionLockFile != "" {
+		tracker, err := NewSessionTracker(cfg.SessionLockFile)
+		if err != nil {
+			return nil, err
+		}
+		connection.sessions = tracker
+	}
+	cfg.Servers = withConnectionSettings(cfg.Servers, connection)
+
+	servers, err := Filter(cfg.Servers, cfg.ServerSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exporter config: %w", err)
+	}
+	if cfg.ServerSelector != "" {
+		log.Printf("Server selector %q matches %d of %d servers", cfg.ServerSelector, len(servers), len(cfg.Servers))
+	}
+	if len(servers) == 0 {
+		log.Println("No servers configured, only listening for events")
+	}
+	cfg.Servers = servers
+
+	// Servers that can't reach the listener are polled or streamed instead of subscribed
+	var pushServers, pollServers, sseServers []RedfishServer
+	for _, server := range cfg.Servers {
+		if server.UseSSE {
+			sseServers = append(sseServers, server)
+		} else if server.PollEvents {
+			pollServers = append(pollServers, server)
+		} else {
+			pushServers = append(pushServers, server)
+		}
+	}
+
+	store, err := NewStateStore(cfg.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	maintenance, err := NewMaintenanceRegistry(store)
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers in maintenance don't page anyone, their events only reach
+	// the audit sinks. Each sink has its own queue so a slow one doesn't
+	// hold up the listener.
+	var sinks, alertSinks []EventSink
+	var sinkQueues []*queuedSink
+	for _, sink := range cfg.Sinks {
+		name, alert := sinkName(sink), isAlertSink(sink)
+		if alert {
+			sink = &maintenanceSink{sink: sink, maintenance: maintenance}
+		}
+		queue := newQueuedSink(sink, name, cfg.SinkQueueSize, metrics)
+		sinkQueues = append(sinkQueues, queue)
+		if alert {
+			alertSinks = append(alertSinks, queue)
+		}
+		sinks = append(sinks, queue)
+	}
+	filters := cfg.EventFilters
+	if cfg.WorkloadAwareFilter {
+		lookup := cfg.JobLookup
+		if lookup == nil {
+			lookup = squeueJobLookup{}
+		}
+		filters = append(filters, NewWorkloadAwareFilter(lookup, DefaultWorkloadCacheTTL))
+	}
+	if len(filters) > 0 && len(sinks) > 0 {
+		sinks = []EventSink{&filteredSink{sink: fanoutSink(sinks), filters: filters}}
+	}
+	if cfg.RateLimit.Enabled() && len(sinks) > 0 {
+		sinks = []EventSink{NewRateLimiter(fanoutSink(sinks), cfg.RateLimit, cfg.Servers, metrics)}
+	}
+	// The correlator sees every event, rate limited or not
+	var incidents *IncidentCorrelator
+	if len(cfg.CorrelationRules) > 0 {
+		var err error
+		incidents, err = NewIncidentCorrelator(cfg.CorrelationRules, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exporter config: %w", err)
+		}
+		sinks = append(sinks, incidents)
+	}
+	var preemption *PreemptionTrigger
+	if len(cfg.Preemption.Rules) > 0 {
+		lookup, preemptor := cfg.JobLookup, cfg.JobPreemptor
+		if lookup == nil {
+			lookup = squeueJobLookup{}
+		}
+		if preemptor == nil {
+			preemptor = scancelPreemptor{}
+		}
+		var err error
+		preemption, err = NewPreemptionTrigger(cfg.Preemption, lookup, preemptor, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exporter config: %w", err)
+		}
+		// Jobs aren't preempted for the faults of servers in maintenance
+		sinks = append(sinks, &maintenanceSink{sink: preemption, maintenance: maintenance})
+	}
+
+	listener := NewServer(cfg.Receiver.ListenIP, cfg.Receiver.ListenPort, cfg.SlurmQueue, sinks)
+	listener.SetMetrics(metrics)
+	if cfg.ResolveMessages {
+		listener.SetRegistryCache(NewRegistryCache())
+	}
+	if cfg.Receiver.PathPrefix != "" {
+		listener.SetPathPrefix(cfg.Receiver.PathPrefix)
+	}
+	if cfg.ValidateEventSchema || cfg.StrictSchemaValidation {
+		validator, err := NewEventSchemaValidator()
+		if err != nil {
+			return nil, err
+		}
+		listener.SetSchemaValidator(validator, cfg.StrictSchemaValidation)
+	}
+
+	reconciler := NewReconciler(pushServers, cfg.SubscriptionPayload, make(map[string]ServerSubscriptions))
+	reconciler.SetMaintenance(maintenance)
+	reconciler.SetWatchdog(cfg.Watchdog)
+	listener.SetReconciler(reconciler)
+
+	e := &Exporter{
+		cfg:         cfg,
+		pushServers: pushServers,
+		pollServers: pollServers,
+		sseServers:  sseServers,
+		connection:  connection,
+		retry:       retry,
+		listener:    listener,
+		reconciler:  reconciler,
+		maintenance: maintenance,
+		incidents:   incidents,
+		alertSinks:  alertSinks,
+		sinkQueues:  sinkQueues,
+		preemption:  preemption,
+	}
+	e.baselines, err = NewBaselineManager(cfg.Servers, store, e.sendConfigDriftAlert)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Registerer != nil {
+		if err := metrics.Register(cfg.Registerer); err != nil {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+	}
+	return e, nil
+}
+
+// Build the exporter config from the environment config
+func exporterConfig(AppConfig Config) ExporterConfig {
+	return ExporterConfig{
+		Servers:             AppConfig.RedfishServers,
+		ServerSelector:      AppConfig.ServerSelector,
+		SubscriptionPayload: AppConfig.SubscriptionPayload,
+		OwnerContext:        AppConfig.OwnerContext,
+		TriggerEvents:       AppConfig.TriggerEvents,
+		Receiver: ReceiverConfig{
+			ListenIP:   AppConfig.SystemInformation.ListenerIP,
+			ListenPort: AppConfig.SystemInformation.ListenerPort,
+			UseSSL:     AppConfig.SystemInformation.UseSSL,
+			PathPrefix: AppConfig.SystemInformation.PathPrefix,
+			CertFile:   AppConfig.CertificateDetails.CertFile,
+			KeyFile:    AppConfig.CertificateDetails.KeyFile,
+		},
+		MaxConcurrency:        AppConfig.MaxConcurrency,
+		ReconcileInterval:     Duration{AppConfig.ReconcileInterval},
+		RestartDetectInterval: Duration{AppConfig.RestartDetectInterval},
+		LogPollInterval:       Duration{AppConfig.LogPollInterval},
+		TimeSyncInterval:      Duration{AppConfig.TimeSyncInterval},
+		GPUHealthInterval:     Duration{AppConfig.GPUHealthInterval},
+		ResolveMessages:       AppConfig.ResolveMessages,
+		RateLimit:             AppConfig.RateLimit,
+		RedfishClient:         AppConfig.RedfishClient,
+		DeliveryRetryPolicies: AppConfig.DeliveryRetryPolicies,
+		CorrelationRules:      AppConfig.CorrelationRules,
+		FirmwareWorkarounds:   AppConfig.FirmwareWorkarounds,
+		Preemption:            AppConfig.Preemption,
+		WorkloadAwareFilter:   AppConfig.WorkloadAwareFilter,
+		MemoryTrend:           AppConfig.MemoryTrend,
+		HealthThresholds:      AppConfig.HealthThresholds,
+		SystemHealthInterval:  Duration{AppConfig.SystemHealthInterval},
+		CircuitBreaker: BreakerPolicy{
+			Threshold: AppConfig.BreakerThreshold,
+			Cooldown:  Duration{AppConfig.BreakerCooldown},
+		},
+		StateFile:              AppConfig.StateFile,
+		SessionLockFile:        AppConfig.SessionLockFile,
+		OAuth:                  AppConfig.OAuth,
+		ValidateEventSchema:    AppConfig.ValidateEventSchema,
+		StrictSchemaValidation: AppConfig.StrictSchema,
+		SubscriptionTTL: SubscriptionTTLConfig{
+			TTL:           Duration{AppConfig.SubscriptionTTL},
+			RefreshMargin: Duration{AppConfig.RefreshMargin},
+		},
+		Watchdog: WatchdogConfig{
+			MinRecheckInterval:     Duration{AppConfig.ReconcileMinRecheck},
+			MaxConsecutiveFailures: AppConfig.ReconcileMaxFailures,
+		},
+	}
+}
+
+// The configured servers, contacted with the exporter's connection settings
+func (e *Exporter) Servers() []RedfishServer {
+	return e.cfg.Servers
+}
+
+// The listener config for the exporter's servers
+func (e *Exporter) appConfig() Config {
+	var appConfig Config
+	appConfig.SystemInformation.ListenerIP = e.cfg.Receiver.ListenIP
+	appConfig.SystemInformation.ListenerPort = e.cfg.Receiver.ListenPort
+	appConfig.SystemInformation.UseSSL = e.cfg.Receiver.UseSSL
+	appConfig.CertificateDetails.CertFile = e.cfg.Receiver.CertFile
+	appConfig.CertificateDetails.KeyFile = e.cfg.Receiver.KeyFile
+	appConfig.RedfishServers = e.cfg.Servers
+	appConfig.SubscriptionPayload = e.cfg.SubscriptionPayload
+	appConfig.TriggerEvents = e.cfg.TriggerEvents
+	appConfig.OwnerContext = e.cfg.OwnerContext
+	return appConfig
+}
+
+// Run the exporter until the context is done: subscribe to all servers,
+// start the listener, keep the subscriptions in place and, on the way out,
+// unsubscribe and stop the listener within the shutdown timeout. Returns
+// the error that stopped the exporter along with any shutdown errors.
+func (e *Exporter) Run(ctx context.Context) error {
+	if sessions := e.connection.sessions; sessions != nil {
+		if err := sessions.Recover(ctx, e.cfg.Servers); err != nil {
+			log.Printf("Failed to delete some leftover BMC sessions: %v", err)
+		}
+	}
+
+	appConfig := e.appConfig()
+
+	subscriptionMap, err := e.subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	e.reconciler.SetSubscriptions(subscriptionMap)
+
+	listenerErr := make(chan error, 1)
+	go func() {
+		listenerErr <- e.listener.Start(appConfig)
+	}()
+
+	loopCtx, stopLoops := context.WithCancel(ctx)
+	var loops sync.WaitGroup
+	e.startLoops(loopCtx, &loops, appConfig)
+
+	// Wait for shutdown or a listener failure
+	var runErr error
+	listenerStopped := false
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down exporter...")
+	case err := <-listenerErr:
+		listenerStopped = true
+		if err != nil {
+			runErr = fmt.Errorf("listener failed: %w", err)
+			log.Printf("Shutting down exporter: %v", runErr)
+		}
+	}
+
+	stopLoops()
+	loops.Wait()
+
+	return errors.Join(runErr, e.shutdown(listenerErr, listenerStopped))
+}
+
+// Create the initial subscriptions, retrying the whole fan-out since a
+// failure rolls back every subscription
+func (e *Exporter) subscribe(ctx context.Context) (map[string]ServerSubscriptions, error) {
+	var lastErr error
+	for attempt := 0; attempt < e.retry.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			backoff := e.retry.Backoff(attempt)
+			log.Printf("Retrying subscriptions in %v: %v", backoff, lastErr)
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("failed to create subscriptions: %w", ctx.Err())
+			case <-time.After(backoff):
+			}
+		}
+
+		// All servers may be polled or streamed instead
+		result, err := batchCreate(ctx, e.pushServers, e.cfg.SubscriptionPayload, BatchCreateOptions{AtomicityLevel: AllOrNothing, AllowEmpty: true})
+		if err == nil {
+			return result.SubscriptionMap(), nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to create subscriptions: %w", lastErr)
+}
+
+// Start the reconcile loop, restart detector, log poller and event streams
+func (e *Exporter) startLoops(ctx context.Context, loops *sync.WaitGroup, appConfig Config) {
+	loops.Add(1)
+	go func() {
+		defer loops.Done()
+		e.maintenance.Run(ctx)
+	}()
+
+	if e.incidents != nil {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case incident, ok := <-e.incidents.Incidents():
+					if !ok {
+						return
+					}
+					if err := fanoutSink(e.alertSinks).Send(incidentEvent(incident)); err != nil {
+						log.Printf("Error sending incident %s to alert sinks: %v", incident.ID, err)
+					}
+				}
+			}
+		}()
+	}
+
+	if e.preemption != nil {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case preemption, ok := <-e.preemption.Events():
+					if !ok {
+						return
+					}
+					if err := fanoutSink(e.alertSinks).Send(preemptionEvent(preemption)); err != nil {
+						log.Printf("Error sending preemption of job %s to alert sinks: %v", preemption.JobID, err)
+					}
+				}
+			}
+		}()
+	}
+
+	if e.cfg.Watchdog.PollInterval.Duration > 0 {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			e.reconciler.Run(ctx)
+		}()
+	}
+
+	if e.cfg.SubscriptionTTL.TTL.Duration > 0 {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			e.reconciler.RunRefresher(ctx, e.cfg.SubscriptionTTL)
+		}()
+	}
+
+	if e.cfg.RestartDetectInterval.Duration > 0 && len(e.pushServers) > 0 {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			RunRestartDetector(ctx, e.pushServers, e.cfg.RestartDetectInterval.Duration, func(server RedfishServer) {
+				// The BMC answers again, don't wait for its breaker's cooldown
+				e.connection.breakers.Record(server.ID(), nil)
+				report, err := e.reconciler.ReconcileServer(server, e.cfg.SubscriptionPayload)
+				if err != nil {
+					log.Printf("Failed to resubscribe after restart: %v", err)
+					return
+				}
+				if len(report.Recreated) > 0 || len(report.Created) > 0 {
+					log.Printf("Resubscribed to server %s after restart", server.IP)
+				}
+			})
+		}()
+	}
+
+	if len(e.pollServers) > 0 {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			RunLogPoller(ctx, e.pollServers, e.cfg.LogPollInterval.Duration, func(server RedfishServer, event Event) {
+				e.listener.processEvent(appConfig, serverHost(server), "", event, false)
+			})
+		}()
+	}
+
+	if e.cfg.TimeSyncInterval.Duration > 0 {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			NewTimeSyncCollector(e.cfg.Servers, e.sendTimeSyncAlert).Run(ctx, e.cfg.TimeSyncInterval.Duration)
+		}()
+	}
+
+	if e.cfg.GPUHealthInterval.Duration > 0 {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			NewGPUHealthCollector(e.cfg.Servers).Run(ctx, e.cfg.GPUHealthInterval.Duration)
+		}()
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			NewXGMITopologyCollector(e.cfg.Servers, e.sendXGMILinkAlert).Run(ctx, e.cfg.GPUHealthInterval.Duration)
+		}()
+	}
+
+	if e.cfg.SystemHealthInterval.Duration > 0 {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			NewSystemHealthCollector(e.cfg.Servers).Run(ctx, e.cfg.SystemHealthInterval.Duration)
+		}()
+	}
+
+	if e.cfg.MemoryTrend.Interval.Duration > 0 {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			NewMemoryTrendCollector(e.cfg.Servers, e.cfg.MemoryTrend, e.sendPredictedFailureAlert).Run(ctx, e.cfg.MemoryTrend.Interval.Duration)
+		}()
+	}
+
+	for _, server := range e.sseServers {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			err := SubscribeSSE(ctx, server, func(payload Payload) {
+				for _, event := range payload.Events {
+					e.listener.processEvent(appConfig, serverHost(server), payload.Context, event, false)
+				}
+			})
+			if err != nil {
+				log.Printf("Event stream of server %s stopped: %v", server.IP, err)
+			}
+		}()
+	}
+}
+
+// Send an NTP alert to the event sinks like a warning event from the server
+func (e *Exporter) sendTimeSyncAlert(alert TimeSyncDriftAlert) {
+	log.Println(alert.Message)
+	e.listener.sendToSinks(&EnrichedEvent{
+		Event: Event{
+			EventType:      "Alert",
+			EventId:        "TimeSyncDriftAlert",
+			EventTimestamp: alert.CheckedAt.Format(time.RFC3339),
+			Severity:       "Warning",
+			Message:        alert.Message,
+			MessageId:      "TimeSyncDriftAlert",
+		},
+		ServerIP:   serverHost(alert.Server),
+		SlurmNode:  alert.Server.SlurmNode,
+		ReceivedAt: alert.CheckedAt,
+		Location:   alert.Server.ServerLocation,
+	})
+}
+
+// Send an XGMI link alert to the event sinks like a critical event from the
+// server
+func (e *Exporter) sendXGMILinkAlert(alert XGMILinkDownAlert) {
+	log.Println(alert.Message)
+	e.listener.sendToSinks(&EnrichedEvent{
+		Event: Event{
+			EventType:      "Alert",
+			EventId:        "XGMILinkDownAlert",
+			EventTimestamp: alert.CheckedAt.Format(time.RFC3339),
+			Severity:       "Critical",
+			Message:        alert.Message,
+			MessageId:      "XGMILinkDownAlert",
+		},
+		ServerIP:   serverHost(alert.Server),
+		SlurmNode:  alert.Server.SlurmNode,
+		ReceivedAt: alert.CheckedAt,
+		Location:   alert.Server.ServerLocation,
+	})
+}
+
+// Send a DIMM failure prediction to the event sinks like a warning event from
+// the server
+func (e *Exporter) sendPredictedFailureAlert(alert PredictedFailureAlert) {
+	log.Println(alert.Message)
+	e.listener.sendToSinks(&EnrichedEvent{
+		Event: Event{
+			EventType:      "Alert",
+			EventId:        "PredictedFailureAlert",
+			EventTimestamp: alert.CheckedAt.Format(time.RFC3339),
+			Severity:       "Warning",
+			Message:        alert.Message,
+			MessageId:      "PredictedFailureAlert",
+		},
+		ServerIP:   serverHost(alert.Server),
+		SlurmNode:  alert.Server.SlurmNode,
+		ReceivedAt: alert.CheckedAt,
+		Location:   alert.Server.ServerLocation,
+	})
+}
+
+// Send a hardware drift to the event sinks like a warning event from the
+// server
+func (e *Exporter) sendConfigDriftAlert(event ConfigDriftEvent) {
+	e.listener.sendToSinks(&EnrichedEvent{
+		Event: Event{
+			EventType:      "Alert",
+			EventId:        "ConfigDriftEvent",
+			EventTimestamp: event.DetectedAt.Format(time.RFC3339),
+			Severity:       "Warning",
+			Message:        event.Message,
+			MessageId:      "ConfigDriftEvent",
+		},
+		ServerIP:   serverHost(event.Server),
+		SlurmNode:  event.Server.SlurmNode,
+		ReceivedAt: event.DetectedAt,
+		Location:   event.Server.ServerLocation,
+	})
+}
+
+// Unsubscribe from all servers, stop the listener and drain the sink queues
+// within the shutdown timeout
+func (e *Exporter) shutdown(listenerErr <-chan error, listenerStopped bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.ShutdownTimeout.Duration)
+	defer cancel()
+
+	// Each step is waited for until the deadline, a step timing out doesn't
+	// skip the following ones
+	var errs []error
+	step := func(doing string, stop func() error) {
+		done := make(chan error, 1)
+		go func() {
+			done <- stop()
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("shutdown timed out while %s", doing))
+		}
+	}
+
+	step("unsubscribing", func() error {
+		log.Println("Unsubscribing from servers...")
+		err := e.reconciler.DeleteAll(e.cfg.Servers)
+		if e.cfg.OwnerContext != "" {
+			// Also remove owned subscriptions left behind by a previous crash
+			DeleteOwnedSubscriptions(e.pushServers, e.cfg.OwnerContext)
+		}
+		return err
+	})
+
+	if !listenerStopped {
+		close(e.listener.shutdownChan)
+		step("stopping the listener", func() error {
+			if err := <-listenerErr; err != nil {
+				return fmt.Errorf("listener failed: %w", err)
+			}
+			return nil
+		})
+	}
+	if e.incidents != nil {
+		step("closing the incident correlator", e.incidents.Close)
+	}
+	if e.preemption != nil {
+		step("closing the preemption trigger", e.preemption.Close)
+	}
+
+	// Deliver the events still queued for the sinks
+	step("draining the sink queues", func() error {
+		for _, queue := range e.sinkQueues {
+			queue.Close()
+		}
+		return nil
+	})
+
+	return errors.Join(errs...)
+}
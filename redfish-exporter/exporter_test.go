@@ -0,0 +1,332 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// A valid config with a single server
+func testExporterConfig(serverIP string) ExporterConfig {
+	return ExporterConfig{
+		Servers:             []RedfishServer{{IP: serverIP, Username: "admin", Password: "password"}},
+		Receiver:            ReceiverConfig{ListenIP: "127.0.0.1", ListenPort: "8080"},
+		SubscriptionPayload: SubscriptionPayload{Destination: "https://10.0.0.100:8080"},
+	}
+}
+
+func TestNewKeepsConnectionSettingsPerExporter(t *testing.T) {
+	first := testExporterConfig("https://10.0.0.1")
+	first.MaxConcurrency = 2
+	first.RedfishClient.Timeout = 5 * time.Second
+	second := testExporterConfig("https://10.0.0.2")
+	second.MaxConcurrency = 4
+	second.RequestTimeout = Duration{time.Minute}
+
+	firstExporter, err := New(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondExporter, err := New(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		exporter        *Exporter
+		wantConcurrency int
+		wantTimeout     time.Duration
+	}{
+		{name: "client timeout kept without requestTimeout", exporter: firstExporter, wantConcurrency: 2, wantTimeout: 5 * time.Second},
+		{name: "requestTimeout applied", exporter: secondExporter, wantConcurrency: 4, wantTimeout: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servers := tt.exporter.Servers()
+			if got := serversConcurrency(servers); got != tt.wantConcurrency {
+				t.Errorf("concurrency = %d, want %d", got, tt.wantConcurrency)
+			}
+			settings := servers[0].settings()
+			if settings != tt.exporter.connection {
+				t.Error("server isn't contacted with its exporter's settings")
+			}
+			if settings.client.Timeout != tt.wantTimeout {
+				t.Errorf("client timeout = %v, want %v", settings.client.Timeout, tt.wantTimeout)
+			}
+		})
+	}
+	if firstExporter.connection.breakers == secondExporter.connection.breakers {
+		t.Error("exporters share their server circuit breakers")
+	}
+}
+
+func TestNewRegistersMetricsPerExporter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	cfg := testExporterConfig("https://10.0.0.1")
+	cfg.Registerer = registry
+	exporter, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter.connection.metrics.reconcileCreated.Inc()
+	if got := testutil.ToFloat64(exporter.connection.metrics.reconcileCreated); got != 1 {
+		t.Errorf("redfish_reconcile_created_total = %v, want 1", got)
+	}
+	if exporter.listener.metrics != exporter.connection.metrics {
+		t.Error("listener doesn't record its exporter's metrics")
+	}
+	if _, err := New(cfg); err == nil {
+		t.Error("New() registered the metrics twice with one registerer")
+	}
+
+	// Without a registerer the metrics are kept but not exported
+	unregistered, err := New(testExporterConfig("https://10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unregistered.connection.metrics == exporter.connection.metrics {
+		t.Error("exporters share their metrics")
+	}
+	count, err := testutil.GatherAndCount(registry, "redfish_reconcile_created_total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("registry gathered %d reconcile created series, want 1", count)
+	}
+}
+
+func TestShutdownContinuesAfterUnsubscribeTimeout(t *testing.T) {
+	bmc := newFakeBMC(t)
+	cfg := testExporterConfig(bmc.URL)
+	cfg.SubscriptionPayload = batchPayload()
+	cfg.ShutdownTimeout = Duration{200 * time.Millisecond}
+	e, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptionMap, err := e.subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	e.reconciler.SetSubscriptions(subscriptionMap)
+	// Deleting the subscription hangs until the test ends
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	for uri := range bmc.subscriptions {
+		bmc.handlers[uri] = func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}
+	}
+	queue := newQueuedSink(&countingSink{}, "shutdown-test", 1, NewMetrics())
+	e.sinkQueues = []*queuedSink{queue}
+
+	start := time.Now()
+	err = e.shutdown(make(chan error), false)
+	if err == nil || !strings.Contains(err.Error(), "while unsubscribing") {
+		t.Errorf("shutdown() error = %v, want an unsubscribe timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("shutdown took %v, want about the shutdown timeout", elapsed)
+	}
+	select {
+	case <-e.listener.shutdownChan:
+	default:
+		t.Error("listener wasn't stopped")
+	}
+	// Past the deadline the queues are closed without waiting for them
+	for !errors.Is(queue.Send(&EnrichedEvent{}), errSinkQueueClosed) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatal("sink queue wasn't closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	want := ExporterConfig{
+		Servers:             []RedfishServer{{IP: "https://10.0.0.1", Name: "node1", Username: "admin", Password: "password"}},
+		Receiver:            ReceiverConfig{ListenIP: "0.0.0.0", ListenPort: "8080"},
+		SubscriptionPayload: SubscriptionPayload{Destination: "https://10.0.0.100:8080"},
+		ReconcileInterval:   Duration{5 * time.Minute},
+	}
+	yamlConfig := `
+servers:
+  - ip: https://10.0.0.1
+    name: node1
+    username: admin
+    password: password
+receiver:
+  listenIP: 0.0.0.0
+  listenPort: "8080"
+subscriptionPayload:
+  Destination: https://10.0.0.100:8080
+reconcileInterval: 5m
+`
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "json",
+			file: "config.json",
+			content: `{
+				"servers": [{"ip": "https://10.0.0.1", "name": "node1", "username": "admin", "password": "password"}],
+				"receiver": {"listenIP": "0.0.0.0", "listenPort": "8080"},
+				"subscriptionPayload": {"Destination": "https://10.0.0.100:8080"},
+				"reconcileInterval": "5m"
+			}`,
+		},
+		{name: "yaml", file: "config.yaml", content: yamlConfig},
+		{name: "yml extension", file: "config.yml", content: yamlConfig},
+		{name: "invalid yaml", file: "config.yaml", content: "servers: [", wantErr: "failed to parse config"},
+		{name: "unknown file type", file: "config.toml", content: "", wantErr: "unknown config file type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(cfg, want) {
+				t.Errorf("LoadConfig() = %+v, want %+v", cfg, want)
+			}
+		})
+	}
+}
+
+func TestExporterConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *ExporterConfig)
+		wantErr string
+	}{
+		{name: "valid", modify: func(cfg *ExporterConfig) {}},
+		{name: "no servers", modify: func(cfg *ExporterConfig) { cfg.Servers = nil }},
+		{
+			name: "server IP is not a URL",
+			modify: func(cfg *ExporterConfig) {
+				cfg.Servers[0].IP = "10.0.0.1"
+			},
+			wantErr: "ip must be a URL",
+		},
+		{
+			name: "duplicate server",
+			modify: func(cfg *ExporterConfig) {
+				cfg.Servers = append(cfg.Servers, cfg.Servers[0])
+			},
+			wantErr: "configured more than once",
+		},
+		{
+			name: "duplicate name",
+			modify: func(cfg *ExporterConfig) {
+				cfg.Servers[0].Name = "node1"
+				cfg.Servers = append(cfg.Servers, RedfishServer{IP: "https://10.0.0.2", Name: "node1"})
+			},
+			wantErr: "name \"node1\" is used more than once",
+		},
+		{
+			name: "name equal to another server's IP",
+			modify: func(cfg *ExporterConfig) {
+				cfg.Servers = append(cfg.Servers, RedfishServer{IP: "https://10.0.0.2", Name: "https://10.0.0.1"})
+			},
+		},
+		{
+			name:    "invalid listen port",
+			modify:  func(cfg *ExporterConfig) { cfg.Receiver.ListenPort = "http" },
+			wantErr: "invalid receiver listenPort",
+		},
+		{
+			name:    "SSL without certificate",
+			modify:  func(cfg *ExporterConfig) { cfg.Receiver.UseSSL = true },
+			wantErr: "certFile and keyFile are required",
+		},
+		{
+			name:    "empty payload",
+			modify:  func(cfg *ExporterConfig) { cfg.SubscriptionPayload = SubscriptionPayload{} },
+			wantErr: ErrEmptyPayload.Error(),
+		},
+		{
+			name:    "negative duration",
+			modify:  func(cfg *ExporterConfig) { cfg.ReconcileInterval = Duration{-time.Second} },
+			wantErr: "reconcileInterval can't be negative",
+		},
+		{
+			name:    "negative sink queue size",
+			modify:  func(cfg *ExporterConfig) { cfg.SinkQueueSize = -1 },
+			wantErr: "sinkQueueSize can't be negative",
+		},
+		{
+			name: "OAuth server without token source",
+			modify: func(cfg *ExporterConfig) {
+				cfg.Servers[0].LoginType = "OAuth"
+			},
+			wantErr: ErrNoTokenSource.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testExporterConfig("https://10.0.0.1")
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// All errors are reported at once
+	cfg := testExporterConfig("https://10.0.0.1")
+	cfg.Receiver.ListenPort = ""
+	cfg.MaxConcurrency = -1
+	err := cfg.Validate()
+	for _, want := range []string{"invalid receiver listenPort", "maxConcurrency can't be negative"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,459 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeBMC is an httptest Redfish service with sessions, an event service
+// and a subscription collection. Other resources are served from
+// resources, and single requests can be made to fail with failures.
+type fakeBMC struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// "METHOD path" of every request, without the service root
+	requests []string
+	// Subscriptions by URI
+	subscriptions map[string]map[string]interface{}
+	nextID        int
+	sessions      map[string]bool
+	// Extra properties of the service root and the event service
+	root         map[string]interface{}
+	eventService map[string]interface{}
+	// Resources served on GET by path
+	resources map[string]interface{}
+	// Status code answered to "METHOD path" instead of handling it
+	failures map[string]int
+	// Times "METHOD path" fails with 503 before it is handled
+	transientFailures map[string]int
+
+	// Subscriptions report Status.State and accept PATCHing it
+	subscriptionStatus bool
+	// Status code of PATCH on subscriptions, 200 when zero
+	patchStatus int
+	// Create subscriptions without answering a Location header
+	omitLocation bool
+	// Create subscriptions at the Id of the request, as BMCs taking
+	// client-specified Ids do. Others ignore it or, with rejectIDs,
+	// refuse the request with 400.
+	honorIDs  bool
+	rejectIDs bool
+	// Subscriptions recorded through the TestEvent action
+	testEvents []map[string]interface{}
+	// Called with each test event, e.g. to deliver it to the listener
+	onTestEvent func(event map[string]interface{})
+	// EventService properties a PATCH is refused with 400 for
+	readOnlyEventService map[string]bool
+	// Handlers of paths served otherwise, e.g. streams. They run without
+	// holding mu, so they can block.
+	handlers map[string]http.HandlerFunc
+}
+
+func newFakeBMC(t *testing.T) *fakeBMC {
+	t.Helper()
+	b := &fakeBMC{
+		subscriptions:     make(map[string]map[string]interface{}),
+		nextID:            1,
+		sessions:          make(map[string]bool),
+		root:              make(map[string]interface{}),
+		eventService:      make(map[string]interface{}),
+		resources:         make(map[string]interface{}),
+		failures:          make(map[string]int),
+		transientFailures: make(map[string]int),
+		handlers:          make(map[string]http.HandlerFunc),
+	}
+	b.Server = httptest.NewServer(http.HandlerFunc(b.serveHTTP))
+	t.Cleanup(b.Close)
+	return b
+}
+
+// The server to configure for the fake, logging in with a session
+func (b *fakeBMC) server() RedfishServer {
+	return RedfishServer{IP: b.URL, Username: "admin", Password: "password"}
+}
+
+// Requests made so far, without those for the service root
+func (b *fakeBMC) requestLog() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.requests...)
+}
+
+// Number of requests matching "METHOD path"
+func (b *fakeBMC) count(request string) int {
+	n := 0
+	for _, r := range b.requestLog() {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
+
+// Add a subscription as if created earlier, returning its URI
+func (b *fakeBMC) addSubscription(properties map[string]interface{}) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.storeSubscription(properties)
+}
+
+func (b *fakeBMC) storeSubscription(properties map[string]interface{}) string {
+	uri := fmt.Sprintf("/redfish/v1/EventService/Subscriptions/%d", b.nextID)
+	b.nextID++
+	if id, ok := properties["Id"].(string); ok && b.honorIDs {
+		uri = "/redfish/v1/EventService/Subscriptions/" + id
+	}
+	subscription := map[string]interface{}{
+		"@odata.id":   uri,
+		"@odata.type": "#EventDestination.v1_10_0.EventDestination",
+	}
+	for key, value := range properties {
+		subscription[key] = value
+	}
+	// The BMC's Id, not necessarily the requested one
+	subscription["Id"] = subscriptionIDFromURI(uri)
+	if b.subscriptionStatus {
+		subscription["Status"] = map[string]string{"State": "Enabled"}
+	}
+	b.subscriptions[uri] = subscription
+	return uri
+}
+
+// Serve a system at /redfish/v1/Systems/1 with the properties, the only
+// member of the systems collection
+func (b *fakeBMC) addSystem(properties map[string]interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resources["/redfish/v1/Systems"] = map[string]interface{}{
+		"@odata.id": "/redfish/v1/Systems",
+		"Members":   []map[string]string{{"@odata.id": "/redfish/v1/Systems/1"}},
+	}
+	system := map[string]interface{}{
+		"@odata.id": "/redfish/v1/Systems/1",
+		"Id":        "1",
+	}
+	for key, value := range properties {
+		system[key] = value
+	}
+	b.resources["/redfish/v1/Systems/1"] = system
+}
+
+// Serve a collection at path with the members, each at path/<Id>
+func (b *fakeBMC) addCollection(path string, members ...map[string]interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	refs := make([]map[string]string, 0, len(members))
+	for _, member := range members {
+		uri := fmt.Sprintf("%s/%v", path, member["Id"])
+		resource := map[string]interface{}{"@odata.id": uri}
+		for key, value := range member {
+			resource[key] = value
+		}
+		b.resources[uri] = resource
+		refs = append(refs, map[string]string{"@odata.id": uri})
+	}
+	b.resources[path] = map[string]interface{}{
+		"@odata.id":           path,
+		"Members":             refs,
+		"Members@odata.count": len(refs),
+	}
+}
+
+// Get a resource served on GET, with the changes PATCHed since
+func (b *fakeBMC) resource(path string) map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	resource, _ := b.resources[path].(map[string]interface{})
+	return resource
+}
+
+// Set a property of a resource served on GET, e.g. to change its health
+func (b *fakeBMC) setResourceProperty(path, name string, value interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resources[path].(map[string]interface{})[name] = value
+}
+
+// Set a property of a subscription, e.g. a link known only after creating it
+func (b *fakeBMC) setSubscriptionProperty(uri, name string, value interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions[uri][name] = value
+}
+
+// Get a copy of a subscription, nil when there is none at the URI
+func (b *fakeBMC) subscription(uri string) map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subscription, ok := b.subscriptions[uri]
+	if !ok {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(subscription))
+	for key, value := range subscription {
+		copied[key] = value
+	}
+	return copied
+}
+
+func (b *fakeBMC) subscriptionCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscriptions)
+}
+
+// End all sessions, as a BMC does when they time out
+func (b *fakeBMC) expireSessions() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sessions = make(map[string]bool)
+}
+
+// Number of sessions logged in and not deleted
+func (b *fakeBMC) openSessions() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.sessions)
+}
+
+func (b *fakeBMC) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	request := r.Method + " " + path
+	if path != "/redfish/v1" {
+		b.requests = append(b.requests, request)
+	}
+	if status, ok := b.failures[request]; ok {
+		writeFakeError(w, status)
+		return
+	}
+	if b.transientFailures[request] > 0 {
+		b.transientFailures[request]--
+		writeFakeError(w, http.StatusServiceUnavailable)
+		return
+	}
+	if token := r.Header.Get("X-Auth-Token"); token != "" && !b.sessions[strings.TrimPrefix(token, "token-")] {
+		writeFakeError(w, http.StatusUnauthorized)
+		return
+	}
+	if handler, ok := b.handlers[path]; ok {
+		b.mu.Unlock()
+		handler(w, r)
+		b.mu.Lock()
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case path == "/redfish/v1" && r.Method == http.MethodGet:
+		root := map[string]interface{}{
+			"@odata.id":      "/redfish/v1/",
+			"Id":             "RootService",
+			"RedfishVersion": "1.15.0",
+			"EventService":   map[string]string{"@odata.id": "/redfish/v1/EventService"},
+			"SessionService": map[string]string{"@odata.id": "/redfish/v1/SessionService"},
+			"Systems":        map[string]string{"@odata.id": "/redfish/v1/Systems"},
+			"Managers":       map[string]string{"@odata.id": "/redfish/v1/Managers"},
+			"Links": map[string]interface{}{
+				"Sessions": map[string]string{"@odata.id": "/redfish/v1/SessionService/Sessions"},
+			},
+		}
+		for key, value := range b.root {
+			root[key] = value
+		}
+		writeFakeJSON(w, http.StatusOK, root)
+
+	case path == "/redfish/v1/SessionService/Sessions" && r.Method == http.MethodPost:
+		var login struct{ UserName, Password string }
+		json.Unmarshal(body, &login)
+		if login.UserName != "admin" || login.Password != "password" {
+			writeFakeError(w, http.StatusUnauthorized)
+			return
+		}
+		id := fmt.Sprintf("%d", len(b.requests))
+		b.sessions[id] = true
+		w.Header().Set("X-Auth-Token", "token-"+id)
+		w.Header().Set("Location", "/redfish/v1/SessionService/Sessions/"+id)
+		writeFakeJSON(w, http.StatusCreated, map[string]string{"Id": id})
+
+	case strings.HasPrefix(path, "/redfish/v1/SessionService/Sessions/") && r.Method == http.MethodDelete:
+		id := strings.TrimPrefix(path, "/redfish/v1/SessionService/Sessions/")
+		if !b.sessions[id] {
+			writeFakeError(w, http.StatusNotFound)
+			return
+		}
+		delete(b.sessions, id)
+		w.WriteHeader(http.StatusNoContent)
+
+	case path == "/redfish/v1/EventService" && r.Method == http.MethodGet:
+		eventService := map[string]interface{}{
+			"@odata.id":      "/redfish/v1/EventService",
+			"Id":             "EventService",
+			"ServiceEnabled": true,
+			"Subscriptions":  map[string]string{"@odata.id": "/redfish/v1/EventService/Subscriptions"},
+			"Actions": map[string]interface{}{
+				"#EventService.SubmitTestEvent": map[string]string{"target": "/redfish/v1/EventService/Actions/EventService.SubmitTestEvent"},
+			},
+		}
+		for key, value := range b.eventService {
+			eventService[key] = value
+		}
+		writeFakeJSON(w, http.StatusOK, eventService)
+
+	case path == "/redfish/v1/EventService" && r.Method == http.MethodPatch:
+		var patch map[string]interface{}
+		if err := json.Unmarshal(body, &patch); err != nil {
+			writeFakeError(w, http.StatusBadRequest)
+			return
+		}
+		for key := range patch {
+			if b.readOnlyEventService[key] {
+				writeFakeError(w, http.StatusBadRequest)
+				return
+			}
+		}
+		for key, value := range patch {
+			b.eventService[key] = value
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case path == "/redfish/v1/EventService/Actions/EventService.SubmitTestEvent" && r.Method == http.MethodPost:
+		var event map[string]interface{}
+		json.Unmarshal(body, &event)
+		b.testEvents = append(b.testEvents, event)
+		if b.onTestEvent != nil {
+			b.onTestEvent(event)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case path == "/redfish/v1/EventService/Subscriptions" && r.Method == http.MethodGet:
+		members := make([]map[string]string, 0, len(b.subscriptions))
+		for uri := range b.subscriptions {
+			members = append(members, map[string]string{"@odata.id": uri})
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":           path,
+			"Members":             members,
+			"Members@odata.count": len(members),
+		})
+
+	case path == "/redfish/v1/EventService/Subscriptions" && r.Method == http.MethodPost:
+		var properties map[string]interface{}
+		if err := json.Unmarshal(body, &properties); err != nil {
+			writeFakeError(w, http.StatusBadRequest)
+			return
+		}
+		if _, ok := properties["Id"]; ok && b.rejectIDs {
+			writeFakeError(w, http.StatusBadRequest)
+			return
+		}
+		uri := b.storeSubscription(properties)
+		if !b.omitLocation {
+			w.Header().Set("Location", uri)
+		}
+		w.WriteHeader(http.StatusCreated)
+
+	case strings.HasPrefix(path, "/redfish/v1/EventService/Subscriptions/"):
+		subscription, ok := b.subscriptions[path]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeFakeJSON(w, http.StatusOK, subscription)
+		case http.MethodPatch:
+			if b.patchStatus != 0 && b.patchStatus != http.StatusOK {
+				writeFakeError(w, b.patchStatus)
+				return
+			}
+			var patch map[string]interface{}
+			json.Unmarshal(body, &patch)
+			if _, ok := patch["Status"]; ok && !b.subscriptionStatus {
+				writeFakeError(w, http.StatusMethodNotAllowed)
+				return
+			}
+			for key, value := range patch {
+				subscription[key] = value
+			}
+			writeFakeJSON(w, http.StatusOK, subscription)
+		case http.MethodDelete:
+			delete(b.subscriptions, path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeFakeError(w, http.StatusMethodNotAllowed)
+		}
+
+	case strings.Contains(path, "/Actions/") && r.Method == http.MethodPost:
+		// Other actions only show up in the request log
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodGet:
+		resource, ok := b.resources[path]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound)
+			return
+		}
+		writeFakeJSON(w, http.StatusOK, resource)
+
+	case r.Method == http.MethodPatch:
+		resource, ok := b.resources[path].(map[string]interface{})
+		if !ok {
+			writeFakeError(w, http.StatusNotFound)
+			return
+		}
+		var patch map[string]interface{}
+		if err := json.Unmarshal(body, &patch); err != nil {
+			writeFakeError(w, http.StatusBadRequest)
+			return
+		}
+		for key, value := range patch {
+			resource[key] = value
+		}
+		writeFakeJSON(w, http.StatusOK, resource)
+
+	default:
+		writeFakeError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Answer a Redfish error with the status code
+func writeFakeError(w http.ResponseWriter, status int) {
+	writeFakeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    "Base.1.0.GeneralError",
+			"message": http.StatusText(status),
+		},
+	})
+}
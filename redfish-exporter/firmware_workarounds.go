@@ -0,0 +1,100 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/stmcginnis/gofish"
+)
+
+// FirmwareWorkaround adapts the requests sent to BMC firmware that departs
+// from the Redfish schema. It matches the Vendor of the service root and
+// the FirmwareVersion of the first manager.
+type FirmwareWorkaround struct {
+	// Matched case-insensitively
+	Vendor string `json:"vendor"`
+	// Matches the firmware versions starting with it, all when empty
+	FirmwareVersionPrefix string `json:"firmwareVersionPrefix"`
+	// Properties of the subscription create request sent under another
+	// name, e.g. {"HttpHeaders": "HTTPHeaders"} for firmware that ignores
+	// the headers otherwise
+	SubscriptionFieldNames map[string]string `json:"subscriptionFieldNames"`
+}
+
+func (w FirmwareWorkaround) validate() error {
+	if w.Vendor == "" {
+		return errors.New("vendor is empty")
+	}
+	for from, to := range w.SubscriptionFieldNames {
+		if from == "" || to == "" {
+			return fmt.Errorf("subscription field name %q renamed to %q", from, to)
+		}
+	}
+	return nil
+}
+
+func (w FirmwareWorkaround) matches(vendor, firmwareVersion string) bool {
+	return strings.EqualFold(w.Vendor, vendor) && strings.HasPrefix(firmwareVersion, w.FirmwareVersionPrefix)
+}
+
+// Get the workaround for the server the client is connected to, or nil.
+// The managers are only read when there are workarounds.
+func findFirmwareWorkaround(c *gofish.APIClient, server RedfishServer) *FirmwareWorkaround {
+	firmwareWorkarounds := server.settings().firmwareWorkarounds
+	if len(firmwareWorkarounds) == 0 || c.Service == nil {
+		return nil
+	}
+
+	var firmwareVersion string
+	managers, err := c.Service.Managers()
+	if err != nil {
+		log.Printf("Failed to read the firmware version of %s, matching firmware workarounds by vendor only: %v", c.Service.ODataID, err)
+	} else if len(managers) > 0 {
+		firmwareVersion = managers[0].FirmwareVersion
+	}
+
+	for i := range firmwareWorkarounds {
+		if firmwareWorkarounds[i].matches(c.Service.Vendor, firmwareVersion) {
+			return &firmwareWorkarounds[i]
+		}
+	}
+	return nil
+}
+
+// Marshal v with its top level properties renamed
+func renameJSONFields(v any, names map[string]string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(data, &properties); err != nil {
+		return nil, err
+	}
+	for from, to := range names {
+		if value, ok := properties[from]; ok {
+			delete(properties, from)
+			properties[to] = value
+		}
+	}
+	return properties, nil
+}
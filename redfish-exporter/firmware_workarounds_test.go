@@ -0,0 +1,90 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func TestFirmwareWorkaroundRenamesSubscriptionFields(t *testing.T) {
+	workaround := FirmwareWorkaround{
+		Vendor:                 "AMI",
+		FirmwareVersionPrefix:  "12.",
+		SubscriptionFieldNames: map[string]string{"HttpHeaders": "HTTPHeaders"},
+	}
+	tests := []struct {
+		name            string
+		vendor          string
+		firmwareVersion string
+		wantProperty    string
+	}{
+		{"matching firmware", "AMI", "12.60.3", "HTTPHeaders"},
+		{"vendor matched case-insensitively", "ami", "12.1", "HTTPHeaders"},
+		{"other firmware version", "AMI", "13.0", "HttpHeaders"},
+		{"other vendor", "Dell", "12.60.3", "HttpHeaders"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.root["Vendor"] = tt.vendor
+			bmc.resources["/redfish/v1/Managers"] = map[string]interface{}{
+				"Members": []map[string]string{{"@odata.id": "/redfish/v1/Managers/1"}},
+			}
+			bmc.resources["/redfish/v1/Managers/1"] = map[string]interface{}{
+				"@odata.id":       "/redfish/v1/Managers/1",
+				"Id":              "1",
+				"FirmwareVersion": tt.firmwareVersion,
+			}
+			settings := newConnectionSettings(RedfishClientConfig{})
+			settings.firmwareWorkarounds = []FirmwareWorkaround{workaround}
+			server := withConnectionSettings([]RedfishServer{bmc.server()}, settings)[0]
+
+			payload := SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", Context: "ctx", EventTypes: []redfish.EventType{redfish.AlertEventType}, HTTPHeaders: map[string]string{"X-Api-Key": "secret"}}
+			created, err := createSubscription(server, payload)
+			if err != nil {
+				t.Fatalf("createSubscription() error = %v", err)
+			}
+			subscription := bmc.subscription(created[payload.Destination].URI)
+			if subscription == nil {
+				t.Fatalf("subscription %s not created on the BMC", created[payload.Destination].URI)
+			}
+			headers, ok := subscription[tt.wantProperty].(map[string]interface{})
+			if !ok || headers["X-Api-Key"] != "secret" {
+				t.Errorf("subscription %v, want the headers sent as %s", subscription, tt.wantProperty)
+			}
+		})
+	}
+}
+
+func TestFirmwareWorkaroundValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		workaround FirmwareWorkaround
+		wantErr    bool
+	}{
+		{"valid", FirmwareWorkaround{Vendor: "AMI", SubscriptionFieldNames: map[string]string{"HttpHeaders": "HTTPHeaders"}}, false},
+		{"no vendor", FirmwareWorkaround{SubscriptionFieldNames: map[string]string{"HttpHeaders": "HTTPHeaders"}}, true},
+		{"renamed to nothing", FirmwareWorkaround{Vendor: "AMI", SubscriptionFieldNames: map[string]string{"HttpHeaders": ""}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.workaround.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
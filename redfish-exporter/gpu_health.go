@@ -0,0 +1,175 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/stmcginnis/gofish/common"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// AmdGPUOEM is the ROCm health data AMD Instinct GPUs report in the Oem
+// property of their processor resource
+type AmdGPUOEM struct {
+	ROCmVersion          string `json:"ROCmVersion"`
+	HBMCorrectedErrors   int64  `json:"HBMCorrectedErrors"`
+	HBMUncorrectedErrors int64  `json:"HBMUncorrectedErrors"`
+	XGMILinkStatus       string `json:"XGMILinkStatus"`
+}
+
+// The Oem property of a processor, only the AMD extension is read
+type processorOEM struct {
+	Oem struct {
+		Amd *AmdGPUOEM `json:"Amd"`
+	} `json:"Oem"`
+}
+
+// Parse the AMD extension of a processor resource, nil without one
+func parseAmdGPUOEM(data []byte) (*AmdGPUOEM, error) {
+	var oem processorOEM
+	if err := json.Unmarshal(data, &oem); err != nil {
+		return nil, err
+	}
+	return oem.Oem.Amd, nil
+}
+
+// Whether an XGMI link status means the links are up
+func xgmiLinkUp(status string) bool {
+	switch strings.ToLower(status) {
+	case "up", "ok", "enabled", "linkup":
+		return true
+	}
+	return false
+}
+
+// GPUHealthCollector reads the health data of the AMD GPUs of the servers'
+// systems and exports it as metrics
+type GPUHealthCollector struct {
+	servers []RedfishServer
+}
+
+func NewGPUHealthCollector(servers []RedfishServer) *GPUHealthCollector {
+	return &GPUHealthCollector{servers: servers}
+}
+
+// Check all servers every interval until the context is done
+func (g *GPUHealthCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting GPU health checks every %v", interval)
+	for {
+		g.Collect(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Context done, stopping GPU health checks")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Read the GPU health of all servers once
+func (g *GPUHealthCollector) Collect(ctx context.Context) {
+	errs := bulkRun(ctx, g.servers, BulkOptions{}, func(server RedfishServer) error {
+		gpus, err := readGPUHealth(ctx, server)
+		if err != nil {
+			return err
+		}
+		for _, gpu := range gpus {
+			if gpu.amd != nil {
+				g.record(server, gpu.id, gpu.amd)
+			}
+		}
+		return nil
+	})
+	for serverIP, err := range errs {
+		log.Printf("Failed to check GPU health on server %s: %v", serverIP, err)
+	}
+}
+
+// The health of a GPU, amd is nil for GPUs without the AMD extension
+type gpuReading struct {
+	id     string
+	health common.Health
+	amd    *AmdGPUOEM
+}
+
+// Read the health of the GPUs of all systems of a server
+func readGPUHealth(ctx context.Context, server RedfishServer) ([]gpuReading, error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	systems, err := c.Service.Systems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systems on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	var gpus []gpuReading
+	for _, system := range systems {
+		processors, err := system.Processors()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get processors of %s on server %s: %w", system.ODataID, server.IP, normalizeRedfishError(err))
+		}
+		for _, processor := range processors {
+			if processor.ProcessorType != redfish.GPUProcessorType {
+				continue
+			}
+			// gofish drops the Oem property, so the resource is read again
+			resp, err := c.Get(processor.ODataID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get processor %s on server %s: %w", processor.ODataID, server.IP, normalizeRedfishError(err))
+			}
+			var data json.RawMessage
+			err = json.NewDecoder(resp.Body).Decode(&data)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode processor %s on server %s: %v", processor.ODataID, server.IP, err)
+			}
+			amd, err := parseAmdGPUOEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse processor %s on server %s: %v", processor.ODataID, server.IP, err)
+			}
+			gpus = append(gpus, gpuReading{id: processor.ID, health: processor.Status.Health, amd: amd})
+		}
+	}
+	return gpus, nil
+}
+
+// Update the metrics of a GPU
+func (g *GPUHealthCollector) record(server RedfishServer, processorID string, amd *AmdGPUOEM) {
+	server.settings().metrics.amdGPUHBMErrors.WithLabelValues("corrected", server.ID(), processorID).Set(float64(amd.HBMCorrectedErrors))
+	server.settings().metrics.amdGPUHBMErrors.WithLabelValues("uncorrected", server.ID(), processorID).Set(float64(amd.HBMUncorrectedErrors))
+
+	linkUp := 0.0
+	if xgmiLinkUp(amd.XGMILinkStatus) {
+		linkUp = 1
+	}
+	server.settings().metrics.amdGPUXGMILinkStatus.WithLabelValues(server.ID(), processorID).Set(linkUp)
+
+	if amd.ROCmVersion != "" {
+		server.settings().metrics.amdGPUInfo.WithLabelValues(server.ID(), processorID, amd.ROCmVersion).Set(1)
+	}
+}
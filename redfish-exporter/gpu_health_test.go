@@ -0,0 +1,148 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func gaugeValue(t *testing.T, gauge prometheus.Gauge) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := gauge.Write(&metric); err != nil {
+		t.Fatalf("failed to read gauge: %v", err)
+	}
+	return metric.GetGauge().GetValue()
+}
+
+// Serve the processors as those of the fake's system
+func addProcessors(bmc *fakeBMC, processors ...map[string]interface{}) {
+	bmc.addSystem(map[string]interface{}{
+		"Processors": map[string]string{"@odata.id": "/redfish/v1/Systems/1/Processors"},
+	})
+	bmc.addCollection("/redfish/v1/Systems/1/Processors", processors...)
+}
+
+func amdGPU(id string, amd map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"Id":            id,
+		"ProcessorType": "GPU",
+		"Status":        map[string]string{"State": "Enabled", "Health": "OK"},
+		"Oem":           map[string]interface{}{"Amd": amd},
+	}
+}
+
+func TestParseAmdGPUOEM(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *AmdGPUOEM
+		wantErr bool
+	}{
+		{"AMD extension", `{"Oem":{"Amd":{"ROCmVersion":"6.1.0","HBMCorrectedErrors":2,"XGMILinkStatus":"Up"}}}`, &AmdGPUOEM{ROCmVersion: "6.1.0", HBMCorrectedErrors: 2, XGMILinkStatus: "Up"}, false},
+		{"other vendor", `{"Oem":{"Nvidia":{}}}`, nil, false},
+		{"no Oem property", `{"Id":"GPU0"}`, nil, false},
+		{"malformed", `{"Oem":`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAmdGPUOEM([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAmdGPUOEM() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("parseAmdGPUOEM() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXGMILinkUp(t *testing.T) {
+	tests := map[string]bool{
+		"Up":       true,
+		"OK":       true,
+		"enabled":  true,
+		"LinkUp":   true,
+		"Down":     false,
+		"Degraded": false,
+		"":         false,
+	}
+	for status, want := range tests {
+		if got := xgmiLinkUp(status); got != want {
+			t.Errorf("xgmiLinkUp(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestGPUHealthCollector(t *testing.T) {
+	bmc := newFakeBMC(t)
+	addProcessors(bmc,
+		map[string]interface{}{"Id": "CPU0", "ProcessorType": "CPU"},
+		amdGPU("GPU0", map[string]interface{}{"ROCmVersion": "6.1.0", "HBMCorrectedErrors": 7, "HBMUncorrectedErrors": 1, "XGMILinkStatus": "Up"}),
+		amdGPU("GPU1", map[string]interface{}{"XGMILinkStatus": "Down"}),
+		map[string]interface{}{"Id": "GPU2", "ProcessorType": "GPU"},
+	)
+	server := bmc.server()
+
+	gpus, err := readGPUHealth(context.Background(), server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gpus) != 3 {
+		t.Fatalf("read %d GPUs, want the 3 GPUs without the CPU", len(gpus))
+	}
+	for _, gpu := range gpus {
+		if (gpu.amd == nil) != (gpu.id == "GPU2") {
+			t.Errorf("GPU %s: AMD extension %+v", gpu.id, gpu.amd)
+		}
+	}
+
+	NewGPUHealthCollector([]RedfishServer{server}).Collect(context.Background())
+	tests := []struct {
+		name  string
+		gauge prometheus.Gauge
+		want  float64
+	}{
+		{"GPU0 corrected HBM errors", server.settings().metrics.amdGPUHBMErrors.WithLabelValues("corrected", server.ID(), "GPU0"), 7},
+		{"GPU0 uncorrected HBM errors", server.settings().metrics.amdGPUHBMErrors.WithLabelValues("uncorrected", server.ID(), "GPU0"), 1},
+		{"GPU0 XGMI links", server.settings().metrics.amdGPUXGMILinkStatus.WithLabelValues(server.ID(), "GPU0"), 1},
+		{"GPU0 ROCm version", server.settings().metrics.amdGPUInfo.WithLabelValues(server.ID(), "GPU0", "6.1.0"), 1},
+		{"GPU1 XGMI links", server.settings().metrics.amdGPUXGMILinkStatus.WithLabelValues(server.ID(), "GPU1"), 0},
+	}
+	for _, tt := range tests {
+		if got := gaugeValue(t, tt.gauge); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if bmc.openSessions() != 0 {
+		t.Errorf("%d sessions left open", bmc.openSessions())
+	}
+}
+
+func TestReadGPUHealthProcessorsFailure(t *testing.T) {
+	bmc := newFakeBMC(t)
+	addProcessors(bmc, amdGPU("GPU0", nil))
+	bmc.failures["GET /redfish/v1/Systems/1/Processors"] = 500
+
+	if _, err := readGPUHealth(context.Background(), bmc.server()); err == nil {
+		t.Error("expected an error when the processors can't be read")
+	}
+}
@@ -0,0 +1,374 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+const baselineStateKey = "hardwareBaselines"
+
+// InventoryComponent is a DIMM, processor or PCIe device of a server
+type InventoryComponent struct {
+	// Memory, Processor or PCIeDevice
+	Kind         string `json:"kind"`
+	ID           string `json:"id"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty"`
+	PartNumber   string `json:"partNumber,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// Size of a DIMM
+	CapacityMiB int `json:"capacityMiB,omitempty"`
+}
+
+// HardwareInventory is the hardware installed in the systems of a server,
+// by component key, see inventoryKey
+type HardwareInventory struct {
+	CollectedAt time.Time                     `json:"collectedAt"`
+	Components  map[string]InventoryComponent `json:"components"`
+}
+
+// Kinds of InventoryChange
+const (
+	ComponentAdded    = "Added"
+	ComponentRemoved  = "Removed"
+	ComponentReplaced = "Replaced"
+)
+
+// InventoryChange is a component that differs from the baseline
+type InventoryChange struct {
+	Type      string `json:"type"`
+	Component string `json:"component"`
+	// Nil for an added component
+	Before *InventoryComponent `json:"before,omitempty"`
+	// Nil for a removed component
+	After *InventoryComponent `json:"after,omitempty"`
+}
+
+func (c InventoryChange) String() string {
+	switch c.Type {
+	case ComponentAdded:
+		return fmt.Sprintf("%s added (serial %q)", c.Component, c.After.SerialNumber)
+	case ComponentRemoved:
+		return fmt.Sprintf("%s removed (serial %q)", c.Component, c.Before.SerialNumber)
+	}
+	return fmt.Sprintf("%s replaced (serial %q, now %q)", c.Component, c.Before.SerialNumber, c.After.SerialNumber)
+}
+
+// ConfigDriftEvent is raised when the hardware of a server differs from its
+// baseline
+type ConfigDriftEvent struct {
+	Server     RedfishServer
+	Changes    []InventoryChange
+	Message    string
+	DetectedAt time.Time
+}
+
+// Key of a component in a HardwareInventory, e.g. Memory/1/DIMM_A1
+func inventoryKey(kind, systemID, id string) string {
+	return kind + "/" + systemID + "/" + id
+}
+
+// Compare an inventory against a baseline. Components are matched by key,
+// a component with another model, part or serial number than in the
+// baseline is replaced. The changes are sorted by component.
+func DiffInventory(baseline, current *HardwareInventory) []InventoryChange {
+	var changes []InventoryChange
+	for key, before := range baseline.Components {
+		after, ok := current.Components[key]
+		switch {
+		case !ok:
+			changes = append(changes, InventoryChange{Type: ComponentRemoved, Component: key, Before: &before})
+		case after != before:
+			changes = append(changes, InventoryChange{Type: ComponentReplaced, Component: key, Before: &before, After: &after})
+		}
+	}
+	for key, after := range current.Components {
+		if _, ok := baseline.Components[key]; !ok {
+			changes = append(changes, InventoryChange{Type: ComponentAdded, Component: key, After: &after})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Component < changes[j].Component })
+	return changes
+}
+
+// Read the DIMMs, processors and PCIe devices installed in all systems of
+// a server. Empty slots reported as Absent are left out.
+func GetHardwareInventory(ctx context.Context, server RedfishServer) (*HardwareInventory, error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	systems, err := c.Service.Systems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systems on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	inventory := &HardwareInventory{CollectedAt: time.Now(), Components: make(map[string]InventoryComponent)}
+	add := func(systemID string, component InventoryComponent, state common.State) {
+		if state == common.AbsentState {
+			return
+		}
+		inventory.Components[inventoryKey(component.Kind, systemID, component.ID)] = component
+	}
+	for _, system := range systems {
+		memory, err := system.Memory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get memory of %s on server %s: %w", system.ODataID, server.IP, normalizeRedfishError(err))
+		}
+		for _, dimm := range memory {
+			add(system.ID, InventoryComponent{
+				Kind:         "Memory",
+				ID:           dimm.ID,
+				Manufacturer: dimm.Manufacturer,
+				Model:        dimm.Model,
+				PartNumber:   dimm.PartNumber,
+				SerialNumber: dimm.SerialNumber,
+				CapacityMiB:  dimm.CapacityMiB,
+			}, dimm.Status.State)
+		}
+
+		processors, err := system.Processors()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get processors of %s on server %s: %w", system.ODataID, server.IP, normalizeRedfishError(err))
+		}
+		for _, processor := range processors {
+			add(system.ID, InventoryComponent{
+				Kind:         "Processor",
+				ID:           processor.ID,
+				Manufacturer: processor.Manufacturer,
+				Model:        processor.Model,
+				PartNumber:   processor.PartNumber,
+				SerialNumber: processor.SerialNumber,
+			}, processor.Status.State)
+		}
+
+		devices, err := system.PCIeDevices()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get PCIe devices of %s on server %s: %w", system.ODataID, server.IP, normalizeRedfishError(err))
+		}
+		for _, device := range devices {
+			add(system.ID, InventoryComponent{
+				Kind:         "PCIeDevice",
+				ID:           device.ID,
+				Manufacturer: device.Manufacturer,
+				Model:        device.Model,
+				PartNumber:   device.PartNumber,
+				SerialNumber: device.SerialNumber,
+			}, device.Status.State)
+		}
+	}
+	return inventory, nil
+}
+
+// BaselineManager detects unauthorized hardware changes, like removed DIMMs
+// or swapped PCIe cards, by comparing the servers' hardware against a
+// baseline captured after the last planned change. The baselines are kept
+// in the state store so they survive restarts.
+type BaselineManager struct {
+	servers []RedfishServer
+	store   *StateStore
+	// Called for every server whose hardware drifted
+	onDrift func(ConfigDriftEvent)
+	// Reads the hardware of a server, GetHardwareInventory
+	inventory func(context.Context, RedfishServer) (*HardwareInventory, error)
+
+	mu sync.Mutex
+	// Baseline by server ID
+	baselines map[string]*HardwareInventory
+}
+
+// Create the manager with the baselines kept in the store
+func NewBaselineManager(servers []RedfishServer, store *StateStore, onDrift func(ConfigDriftEvent)) (*BaselineManager, error) {
+	b := &BaselineManager{
+		servers:   servers,
+		store:     store,
+		onDrift:   onDrift,
+		inventory: GetHardwareInventory,
+		baselines: make(map[string]*HardwareInventory),
+	}
+	if _, err := store.Load(baselineStateKey, &b.baselines); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Capture the hardware of the servers as their baseline. The servers whose
+// hardware couldn't be read keep their previous baseline.
+func (b *BaselineManager) SaveBaseline(servers []RedfishServer) error {
+	inventories, errs := b.collect(servers)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for serverID, inventory := range inventories {
+		b.baselines[serverID] = inventory
+		log.Printf("Saved hardware baseline of server %s with %d components", serverID, len(inventory.Components))
+	}
+	if err := b.store.Save(baselineStateKey, b.baselines); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Compare the hardware of the servers against their baselines and return
+// the changes by server ID. Servers without a baseline are skipped. Every
+// server that drifted is logged and reported to onDrift.
+func (b *BaselineManager) DetectDrift(servers []RedfishServer) (map[string][]InventoryChange, error) {
+	var withBaseline []RedfishServer
+	b.mu.Lock()
+	for _, server := range servers {
+		if _, ok := b.baselines[server.ID()]; ok {
+			withBaseline = append(withBaseline, server)
+		} else {
+			log.Printf("No hardware baseline of server %s, skipping drift check", server.ID())
+		}
+	}
+	b.mu.Unlock()
+
+	inventories, errs := b.collect(withBaseline)
+	drift := make(map[string][]InventoryChange)
+	for _, server := range withBaseline {
+		inventory, ok := inventories[server.ID()]
+		if !ok {
+			continue
+		}
+		b.mu.Lock()
+		baseline := b.baselines[server.ID()]
+		b.mu.Unlock()
+		if baseline == nil {
+			continue
+		}
+		changes := DiffInventory(baseline, inventory)
+		if len(changes) == 0 {
+			continue
+		}
+		drift[server.ID()] = changes
+
+		descriptions := make([]string, len(changes))
+		for i, change := range changes {
+			descriptions[i] = change.String()
+		}
+		event := ConfigDriftEvent{
+			Server:     server,
+			Changes:    changes,
+			Message:    fmt.Sprintf("Hardware of server %s differs from its baseline of %s: %s", server.ID(), baseline.CollectedAt.Format(time.RFC3339), strings.Join(descriptions, ", ")),
+			DetectedAt: inventory.CollectedAt,
+		}
+		log.Println(event.Message)
+		if b.onDrift != nil {
+			b.onDrift(event)
+		}
+	}
+	return drift, errors.Join(errs...)
+}
+
+// Replace the baseline of a server by its current hardware, after a
+// planned change
+func (b *BaselineManager) PromoteBaseline(serverIP string) error {
+	server := getServerInfo(b.servers, serverIP)
+	if server.IP == "" {
+		return fmt.Errorf("%w: %s", ErrServerNotFound, serverIP)
+	}
+	return b.SaveBaseline([]RedfishServer{server})
+}
+
+// Baselines returns a copy of the baselines by server ID
+func (b *BaselineManager) Baselines() map[string]*HardwareInventory {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	baselines := make(map[string]*HardwareInventory, len(b.baselines))
+	for serverID, baseline := range b.baselines {
+		baselines[serverID] = baseline
+	}
+	return baselines
+}
+
+// Read the hardware of the servers, by server ID
+func (b *BaselineManager) collect(servers []RedfishServer) (map[string]*HardwareInventory, []error) {
+	var mu sync.Mutex
+	inventories := make(map[string]*HardwareInventory)
+	failures := bulkRun(context.Background(), servers, BulkOptions{}, func(server RedfishServer) error {
+		inventory, err := b.inventory(context.Background(), server)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		inventories[server.ID()] = inventory
+		mu.Unlock()
+		return nil
+	})
+	var errs []error
+	for serverIP, err := range failures {
+		log.Printf("Failed to read hardware inventory of server %s: %v", serverIP, err)
+		errs = append(errs, err)
+	}
+	return inventories, errs
+}
+
+// Serve the hardware baselines. GET compares the servers against their
+// baselines and returns the changes, POST captures the baseline of all
+// servers, or promotes the current hardware of the one in ?server=.
+func baselineHandler(baselines *BaselineManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var response any
+		switch r.Method {
+		case http.MethodGet:
+			drift, err := baselines.DetectDrift(baselines.servers)
+			if err != nil && len(drift) == 0 {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			response = drift
+		case http.MethodPost:
+			var err error
+			if server := r.URL.Query().Get("server"); server != "" {
+				err = baselines.PromoteBaseline(server)
+			} else {
+				err = baselines.SaveBaseline(baselines.servers)
+			}
+			if errors.Is(err, ErrServerNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			response = baselines.Baselines()
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("Failed to write baseline response: %v", err)
+		}
+	}
+}
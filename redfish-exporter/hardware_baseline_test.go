@@ -0,0 +1,229 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+const dimmA1 = "/redfish/v1/Systems/1/Memory/DIMM_A1"
+
+// Serve a system with a DIMM, an empty DIMM slot, a CPU and a GPU card
+func addInventory(bmc *fakeBMC) {
+	enabled := map[string]string{"State": "Enabled", "Health": "OK"}
+	bmc.addSystem(map[string]interface{}{
+		"Memory":      link("/redfish/v1/Systems/1/Memory"),
+		"Processors":  link("/redfish/v1/Systems/1/Processors"),
+		"PCIeDevices": []map[string]string{link("/redfish/v1/Chassis/1/PCIeDevices/GPU1")},
+	})
+	bmc.addCollection("/redfish/v1/Systems/1/Memory",
+		map[string]interface{}{"Id": "DIMM_A1", "Manufacturer": "Samsung", "PartNumber": "M321R8GA0BB0", "SerialNumber": "S1", "CapacityMiB": 65536, "Status": enabled},
+		map[string]interface{}{"Id": "DIMM_A2", "Status": map[string]string{"State": "Absent"}},
+	)
+	bmc.addCollection("/redfish/v1/Systems/1/Processors",
+		map[string]interface{}{"Id": "CPU0", "Model": "AMD EPYC 9654", "SerialNumber": "C1", "Status": enabled},
+	)
+	bmc.addCollection("/redfish/v1/Chassis/1/PCIeDevices",
+		map[string]interface{}{"Id": "GPU1", "Manufacturer": "AMD", "Model": "MI300X", "SerialNumber": "G1", "Status": enabled},
+	)
+}
+
+func TestGetHardwareInventory(t *testing.T) {
+	bmc := newFakeBMC(t)
+	addInventory(bmc)
+
+	inventory, err := GetHardwareInventory(context.Background(), bmc.server())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]InventoryComponent{
+		"Memory/1/DIMM_A1":  {Kind: "Memory", ID: "DIMM_A1", Manufacturer: "Samsung", PartNumber: "M321R8GA0BB0", SerialNumber: "S1", CapacityMiB: 65536},
+		"Processor/1/CPU0":  {Kind: "Processor", ID: "CPU0", Model: "AMD EPYC 9654", SerialNumber: "C1"},
+		"PCIeDevice/1/GPU1": {Kind: "PCIeDevice", ID: "GPU1", Manufacturer: "AMD", Model: "MI300X", SerialNumber: "G1"},
+	}
+	if len(inventory.Components) != len(want) {
+		t.Errorf("components = %+v, want %d without the empty slot", inventory.Components, len(want))
+	}
+	for key, component := range want {
+		if got := inventory.Components[key]; got != component {
+			t.Errorf("%s = %+v, want %+v", key, got, component)
+		}
+	}
+	if bmc.openSessions() != 0 {
+		t.Errorf("%d sessions left open", bmc.openSessions())
+	}
+}
+
+func TestDiffInventory(t *testing.T) {
+	dimm := InventoryComponent{Kind: "Memory", ID: "DIMM_A1", SerialNumber: "S1"}
+	swapped := dimm
+	swapped.SerialNumber = "S2"
+	cpu := InventoryComponent{Kind: "Processor", ID: "CPU0", SerialNumber: "C1"}
+	inventory := func(components ...InventoryComponent) *HardwareInventory {
+		inventory := &HardwareInventory{Components: make(map[string]InventoryComponent)}
+		for _, component := range components {
+			inventory.Components[inventoryKey(component.Kind, "1", component.ID)] = component
+		}
+		return inventory
+	}
+
+	tests := []struct {
+		name     string
+		baseline *HardwareInventory
+		current  *HardwareInventory
+		want     []string
+	}{
+		{"unchanged", inventory(dimm, cpu), inventory(dimm, cpu), nil},
+		{"removed", inventory(dimm, cpu), inventory(cpu), []string{`Memory/1/DIMM_A1 removed (serial "S1")`}},
+		{"added", inventory(cpu), inventory(dimm, cpu), []string{`Memory/1/DIMM_A1 added (serial "S1")`}},
+		{"replaced", inventory(dimm), inventory(swapped), []string{`Memory/1/DIMM_A1 replaced (serial "S1", now "S2")`}},
+		{
+			"sorted by component",
+			inventory(swapped, cpu),
+			inventory(dimm),
+			[]string{`Memory/1/DIMM_A1 replaced (serial "S2", now "S1")`, `Processor/1/CPU0 removed (serial "C1")`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := DiffInventory(tt.baseline, tt.current)
+			if len(changes) != len(tt.want) {
+				t.Fatalf("changes = %v, want %q", changes, tt.want)
+			}
+			for i, change := range changes {
+				if change.String() != tt.want[i] {
+					t.Errorf("change %d = %q, want %q", i, change, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBaselineManager(t *testing.T) {
+	bmc := newFakeBMC(t)
+	addInventory(bmc)
+	other := newFakeBMC(t)
+	addInventory(other)
+	servers := []RedfishServer{bmc.server(), other.server()}
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := NewStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []ConfigDriftEvent
+	manager, err := NewBaselineManager(servers, store, func(event ConfigDriftEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first server gets a baseline, the other is skipped
+	if err := manager.SaveBaseline(servers[:1]); err != nil {
+		t.Fatal(err)
+	}
+	bmc.setResourceProperty(dimmA1, "SerialNumber", "S2")
+	drift, err := manager.DetectDrift(servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := drift[bmc.URL]
+	if len(drift) != 1 || len(changes) != 1 || changes[0].Type != ComponentReplaced {
+		t.Fatalf("drift = %+v, want the replaced DIMM of the first server", drift)
+	}
+	if len(events) != 1 || events[0].Server.IP != bmc.URL {
+		t.Errorf("events = %+v, want one for the first server", events)
+	}
+
+	// The baselines survive a restart, promoting one ends the drift
+	reopened, err := NewStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted, err := NewBaselineManager(servers, reopened, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restarted.Baselines()[bmc.URL]; !ok {
+		t.Fatal("baseline not loaded from the store")
+	}
+	if err := restarted.PromoteBaseline(bmc.URL); err != nil {
+		t.Fatal(err)
+	}
+	if drift, err := restarted.DetectDrift(servers); err != nil || len(drift) != 0 {
+		t.Errorf("DetectDrift() = %+v, %v after promoting the baseline", drift, err)
+	}
+	if err := restarted.PromoteBaseline("https://10.0.0.99"); !errors.Is(err, ErrServerNotFound) {
+		t.Errorf("PromoteBaseline() of an unknown server error = %v", err)
+	}
+}
+
+func TestBaselineHandler(t *testing.T) {
+	bmc := newFakeBMC(t)
+	addInventory(bmc)
+	store, _ := NewStateStore("")
+	manager, err := NewBaselineManager([]RedfishServer{bmc.server()}, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := baselineHandler(manager)
+
+	// The steps run in order against the same manager
+	steps := []struct {
+		name       string
+		method     string
+		target     string
+		setup      func()
+		wantStatus int
+		wantKeys   int
+	}{
+		{"no drift without a baseline", http.MethodGet, "/baseline", nil, http.StatusOK, 0},
+		{"capture all baselines", http.MethodPost, "/baseline", nil, http.StatusOK, 1},
+		{"drift", http.MethodGet, "/baseline", func() { bmc.setResourceProperty(dimmA1, "SerialNumber", "S2") }, http.StatusOK, 1},
+		{"promote unknown server", http.MethodPost, "/baseline?server=https://10.0.0.99", nil, http.StatusNotFound, 0},
+		{"promote", http.MethodPost, "/baseline?server=" + bmc.URL, nil, http.StatusOK, 1},
+		{"no drift after promoting", http.MethodGet, "/baseline", nil, http.StatusOK, 0},
+		{"BMC failure", http.MethodGet, "/baseline", func() { bmc.failures["GET /redfish/v1/Systems"] = 500 }, http.StatusBadGateway, 0},
+		{"wrong method", http.MethodDelete, "/baseline", nil, http.StatusMethodNotAllowed, 0},
+	}
+	for _, step := range steps {
+		if step.setup != nil {
+			step.setup()
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(step.method, step.target, nil))
+		if rec.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, rec.Code, step.wantStatus, rec.Body)
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		var response map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if len(response) != step.wantKeys {
+			t.Errorf("%s: response = %s, want %d servers", step.name, rec.Body, step.wantKeys)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	httpSinkRequestTimeout   = 10 * time.Second
+	httpSinkBreakerThreshold = 5
+	httpSinkBreakerCooldown  = time.Minute
+)
+
+// HTTPEventSink POSTs every event as JSON to a URL, retrying transient
+// failures and backing off from a destination that keeps failing
+type HTTPEventSink struct {
+	url         string
+	contentType string
+	retry       RetryConfig
+	secret      []byte
+	client      *http.Client
+	breaker     *circuitBreaker
+}
+
+type HTTPSinkOption func(*HTTPEventSink)
+
+func WithContentType(contentType string) HTTPSinkOption {
+	return func(s *HTTPEventSink) {
+		s.contentType = contentType
+	}
+}
+
+func WithRetryConfig(retry RetryConfig) HTTPSinkOption {
+	return func(s *HTTPEventSink) {
+		s.retry = retry
+	}
+}
+
+// WithRequestSigner signs each request with HMAC-SHA256 over the timestamp
+// and body, sent in the X-Signature-Timestamp and X-Signature-256 headers
+func WithRequestSigner(secret []byte) HTTPSinkOption {
+	return func(s *HTTPEventSink) {
+		s.secret = secret
+	}
+}
+
+func NewHTTPEventSink(url string, opts ...HTTPSinkOption) (*HTTPEventSink, error) {
+	if url == "" {
+		return nil, fmt.Errorf("HTTP sink URL is required")
+	}
+	s := &HTTPEventSink{
+		url:         url,
+		contentType: "application/json",
+		retry:       DefaultRetryConfig,
+		client:      &http.Client{Timeout: httpSinkRequestTimeout},
+		breaker:     newCircuitBreaker(httpSinkBreakerThreshold, httpSinkBreakerCooldown),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+func (s *HTTPEventSink) Send(event *EnrichedEvent) error {
+	if !s.breaker.Allow() {
+		return fmt.Errorf("circuit breaker open for %s, dropping event %s", s.url, event.EventId)
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	attempts := s.retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		retryable, err := s.post(body)
+		if err == nil {
+			s.breaker.RecordSuccess()
+			return nil
+		}
+		if !retryable || attempt >= attempts {
+			s.breaker.RecordFailure()
+			return fmt.Errorf("failed to post event %s to %s after %d attempts: %w", event.EventId, s.url, attempt, err)
+		}
+		time.Sleep(s.retry.Backoff(attempt))
+	}
+}
+
+func (s *HTTPEventSink) Close() error {
+	return nil
+}
+
+// Make a single POST, returning whether a failure may be retried
+func (s *HTTPEventSink) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	if s.secret != nil {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Signature-Timestamp", timestamp)
+		req.Header.Set("X-Signature-256", "sha256="+signPayload(s.secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("destination returned status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("destination returned status %d", resp.StatusCode)
+	}
+}
+
+func signPayload(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,151 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// An HTTP destination answering the statuses in turn, the last one
+// repeatedly, and recording the requests it got
+type fakeDestination struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newFakeDestination(t *testing.T, statuses ...int) *fakeDestination {
+	t.Helper()
+	d := &fakeDestination{statuses: statuses}
+	d.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.requests = append(d.requests, r)
+		d.bodies = append(d.bodies, body)
+		status := d.statuses[0]
+		if len(d.statuses) > 1 {
+			d.statuses = d.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(d.Close)
+	return d
+}
+
+func (d *fakeDestination) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.requests)
+}
+
+var fastRetryConfig = RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func TestHTTPEventSinkRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantErr      bool
+		wantRequests int
+	}{
+		{"delivered", []int{http.StatusNoContent}, false, 1},
+		{"server error retried", []int{http.StatusBadGateway, http.StatusOK}, false, 2},
+		{"throttled retried", []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusAccepted}, false, 3},
+		{"gives up after the attempts", []int{http.StatusServiceUnavailable}, true, 3},
+		{"client error not retried", []int{http.StatusBadRequest}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destination := newFakeDestination(t, tt.statuses...)
+			sink, err := NewHTTPEventSink(destination.URL, WithRetryConfig(fastRetryConfig))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = sink.Send(&EnrichedEvent{Event: Event{EventId: "1", MessageId: "Base.1.0.GeneralError"}, ServerIP: "10.0.0.1"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := destination.count(); got != tt.wantRequests {
+				t.Errorf("%d requests made, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestHTTPEventSinkRequest(t *testing.T) {
+	destination := newFakeDestination(t, http.StatusOK)
+	secret := []byte("shared-secret")
+	sink, err := NewHTTPEventSink(destination.URL, WithContentType("application/vnd.redfish+json"), WithRequestSigner(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(&EnrichedEvent{Event: Event{EventId: "1", MessageId: "Base.1.0.GeneralError"}, ServerIP: "10.0.0.1", SlurmNode: "node-1"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	request, body := destination.requests[0], destination.bodies[0]
+	if request.Method != http.MethodPost || request.Header.Get("Content-Type") != "application/vnd.redfish+json" {
+		t.Errorf("request %s with Content-Type %s, want a POST with the configured type", request.Method, request.Header.Get("Content-Type"))
+	}
+	var event EnrichedEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.SlurmNode != "node-1" || event.MessageId != "Base.1.0.GeneralError" {
+		t.Errorf("body %s, %v, want the event", body, err)
+	}
+	timestamp := request.Header.Get("X-Signature-Timestamp")
+	signature := request.Header.Get("X-Signature-256")
+	if want := "sha256=" + signPayload(secret, timestamp, body); timestamp == "" || signature != want {
+		t.Errorf("signature %q at %q, want %q", signature, timestamp, want)
+	}
+	if signature == "sha256="+signPayload([]byte("other-secret"), timestamp, body) {
+		t.Error("signature matches another secret")
+	}
+}
+
+func TestHTTPEventSinkBreaker(t *testing.T) {
+	destination := newFakeDestination(t, http.StatusInternalServerError)
+	sink, err := NewHTTPEventSink(destination.URL, WithRetryConfig(RetryConfig{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := &EnrichedEvent{Event: Event{EventId: "1"}, ServerIP: "10.0.0.1"}
+	for i := 0; i < httpSinkBreakerThreshold; i++ {
+		if err := sink.Send(event); err == nil {
+			t.Fatalf("Send() %d error = nil, want the status reported", i)
+		}
+	}
+	if !sink.breaker.IsOpen() {
+		t.Fatalf("breaker closed after %d failures", httpSinkBreakerThreshold)
+	}
+
+	// Events are dropped without contacting the destination while open
+	if err := sink.Send(event); err == nil || !strings.Contains(err.Error(), "circuit breaker open") {
+		t.Errorf("Send() error = %v, want the event dropped", err)
+	}
+	if got := destination.count(); got != httpSinkBreakerThreshold {
+		t.Errorf("%d requests made, want %d", got, httpSinkBreakerThreshold)
+	}
+}
@@ -0,0 +1,216 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of completed incidents held until they are read
+const incidentBufferSize = 64
+
+// CorrelationRule groups the events following a trigger event on the same
+// server into one incident, e.g. a drive going offline and a storage pool
+// degrading after a disk controller error
+type CorrelationRule struct {
+	// Regular expression matching the MessageId of the event opening the incident
+	TriggerMessageIdPattern string `json:"triggerMessageIdPattern"`
+	// Regular expressions matching the MessageIds of the related events
+	RelatedMessageIdPatterns []string `json:"relatedMessageIdPatterns"`
+	// Time after the trigger event related events are grouped with it
+	CorrelationWindow Duration `json:"correlationWindow"`
+	IncidentType      string   `json:"incidentType"`
+}
+
+// Incident is a trigger event and the related events that followed it
+type Incident struct {
+	ID         string
+	Type       string
+	ServerIP   string
+	Events     []*EnrichedEvent
+	DetectedAt time.Time
+}
+
+type correlationRule struct {
+	CorrelationRule
+	trigger *regexp.Regexp
+	related []*regexp.Regexp
+}
+
+func (r correlationRule) isRelated(messageId string) bool {
+	for _, pattern := range r.related {
+		if pattern.MatchString(messageId) {
+			return true
+		}
+	}
+	return false
+}
+
+// An incident still inside its correlation window
+type openIncident struct {
+	incident *Incident
+	until    time.Time
+	timer    *time.Timer
+}
+
+// IncidentCorrelator is an event sink grouping events into incidents by
+// its rules. An incident is complete once its window has passed and is
+// only emitted if related events joined it, a trigger event alone is
+// forwarded by the other sinks as it is. A trigger arriving while an
+// incident of the same rule is open on the server joins that incident.
+type IncidentCorrelator struct {
+	rules     []correlationRule
+	incidents chan *Incident
+	metrics   *Metrics
+
+	mu sync.Mutex
+	// Open incidents by server IP and rule index
+	open   map[string]*openIncident
+	nextID int
+	closed bool
+}
+
+func NewIncidentCorrelator(rules []CorrelationRule, metrics *Metrics) (*IncidentCorrelator, error) {
+	c := &IncidentCorrelator{
+		incidents: make(chan *Incident, incidentBufferSize),
+		metrics:   metrics,
+		open:      make(map[string]*openIncident),
+	}
+	for i, rule := range rules {
+		if rule.CorrelationWindow.Duration <= 0 {
+			return nil, fmt.Errorf("correlation rule %d: correlationWindow must be positive", i)
+		}
+		trigger, err := regexp.Compile(rule.TriggerMessageIdPattern)
+		if err != nil {
+			return nil, fmt.Errorf("correlation rule %d: invalid trigger pattern: %v", i, err)
+		}
+		compiled := correlationRule{CorrelationRule: rule, trigger: trigger}
+		for _, pattern := range rule.RelatedMessageIdPatterns {
+			related, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("correlation rule %d: invalid related pattern: %v", i, err)
+			}
+			compiled.related = append(compiled.related, related)
+		}
+		c.rules = append(c.rules, compiled)
+	}
+	return c, nil
+}
+
+// The completed incidents, closed by Close
+func (c *IncidentCorrelator) Incidents() <-chan *Incident {
+	return c.incidents
+}
+
+func (c *IncidentCorrelator) Send(event *EnrichedEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	for i, rule := range c.rules {
+		key := fmt.Sprintf("%s|%d", event.ServerIP, i)
+		if open, ok := c.open[key]; ok && !event.ReceivedAt.After(open.until) {
+			if rule.trigger.MatchString(event.MessageId) || rule.isRelated(event.MessageId) {
+				open.incident.Events = append(open.incident.Events, event)
+			}
+			continue
+		}
+		if !rule.trigger.MatchString(event.MessageId) {
+			continue
+		}
+		c.nextID++
+		open := &openIncident{
+			incident: &Incident{
+				ID:         fmt.Sprintf("incident-%d-%d", event.ReceivedAt.Unix(), c.nextID),
+				Type:       rule.IncidentType,
+				ServerIP:   event.ServerIP,
+				Events:     []*EnrichedEvent{event},
+				DetectedAt: event.ReceivedAt,
+			},
+			until: event.ReceivedAt.Add(rule.CorrelationWindow.Duration),
+		}
+		c.open[key] = open
+		open.timer = time.AfterFunc(rule.CorrelationWindow.Duration, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.open[key] == open {
+				delete(c.open, key)
+				c.emit(open.incident)
+			}
+		})
+	}
+	return nil
+}
+
+// Emit an incident that has related events, must be called with mu held
+func (c *IncidentCorrelator) emit(incident *Incident) {
+	if c.closed || len(incident.Events) < 2 {
+		return
+	}
+	log.Printf("Incident %s (%s) on server %s: %d events", incident.ID, incident.Type, incident.ServerIP, len(incident.Events))
+	c.metrics.incidents.WithLabelValues(incident.ServerIP, incident.Type).Inc()
+	select {
+	case c.incidents <- incident:
+	default:
+		log.Printf("Incident buffer full, dropping incident %s", incident.ID)
+	}
+}
+
+// Emit the open incidents and close the incident channel
+func (c *IncidentCorrelator) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	for key, open := range c.open {
+		open.timer.Stop()
+		delete(c.open, key)
+		c.emit(open.incident)
+	}
+	c.closed = true
+	close(c.incidents)
+	return nil
+}
+
+// The event an incident is reported to the alert sinks as, with the
+// highest severity of its events
+func incidentEvent(incident *Incident) *EnrichedEvent {
+	trigger := incident.Events[0]
+	severity := "OK"
+	messageIds := make([]string, 0, len(incident.Events))
+	for _, event := range incident.Events {
+		if severityRank(event.Severity) > severityRank(severity) {
+			severity = event.Severity
+		}
+		messageIds = append(messageIds, event.MessageId)
+	}
+	event := *trigger
+	event.EventId = incident.ID
+	event.Severity = severity
+	event.MessageId = "Incident." + incident.Type
+	event.Message = fmt.Sprintf("%s incident with %d events: %s", incident.Type, len(incident.Events), strings.Join(messageIds, ", "))
+	event.MessageArgs = nil
+	event.ResolvedMessage = ""
+	event.ReceivedAt = time.Now()
+	return &event
+}
@@ -0,0 +1,181 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var storageRule = CorrelationRule{
+	TriggerMessageIdPattern:  `^StorageDevice\.\d+\.\d+\.ControllerFailure$`,
+	RelatedMessageIdPatterns: []string{`^StorageDevice\.\d+\.\d+\.DriveOffline$`, `^StorageDevice\.\d+\.\d+\.VolumeDegraded$`},
+	CorrelationWindow:        Duration{time.Minute},
+	IncidentType:             "StorageFailure",
+}
+
+type incidentTestEvent struct {
+	serverIP  string
+	messageId string
+	after     time.Duration
+}
+
+func TestIncidentCorrelator(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []incidentTestEvent
+		// MessageIds of each incident emitted
+		want [][]string
+	}{
+		{
+			name: "related events grouped",
+			events: []incidentTestEvent{
+				{"https://10.0.0.1", "StorageDevice.1.0.ControllerFailure", 0},
+				{"https://10.0.0.1", "StorageDevice.1.0.DriveOffline", 10 * time.Second},
+				{"https://10.0.0.1", "Base.1.0.GeneralError", 20 * time.Second},
+				{"https://10.0.0.1", "StorageDevice.1.0.VolumeDegraded", 30 * time.Second},
+			},
+			want: [][]string{{"StorageDevice.1.0.ControllerFailure", "StorageDevice.1.0.DriveOffline", "StorageDevice.1.0.VolumeDegraded"}},
+		},
+		{
+			name: "trigger alone",
+			events: []incidentTestEvent{
+				{"https://10.0.0.1", "StorageDevice.1.0.ControllerFailure", 0},
+			},
+		},
+		{
+			name: "related event without a trigger",
+			events: []incidentTestEvent{
+				{"https://10.0.0.1", "StorageDevice.1.0.DriveOffline", 0},
+			},
+		},
+		{
+			name: "related event on another server",
+			events: []incidentTestEvent{
+				{"https://10.0.0.1", "StorageDevice.1.0.ControllerFailure", 0},
+				{"https://10.0.0.2", "StorageDevice.1.0.DriveOffline", time.Second},
+			},
+		},
+		{
+			name: "repeated trigger joins the open incident",
+			events: []incidentTestEvent{
+				{"https://10.0.0.1", "StorageDevice.1.0.ControllerFailure", 0},
+				{"https://10.0.0.1", "StorageDevice.1.0.ControllerFailure", 5 * time.Second},
+			},
+			want: [][]string{{"StorageDevice.1.0.ControllerFailure", "StorageDevice.1.0.ControllerFailure"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			correlator, err := NewIncidentCorrelator([]CorrelationRule{storageRule}, NewMetrics())
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Now()
+			for _, e := range tt.events {
+				event := &EnrichedEvent{Event: Event{MessageId: e.messageId}, ServerIP: e.serverIP, ReceivedAt: start.Add(e.after)}
+				if err := correlator.Send(event); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Closing emits the incidents still inside their window
+			correlator.Close()
+
+			var got [][]string
+			for incident := range correlator.Incidents() {
+				if incident.Type != "StorageFailure" {
+					t.Errorf("incident type %q, want StorageFailure", incident.Type)
+				}
+				var messageIds []string
+				for _, event := range incident.Events {
+					messageIds = append(messageIds, event.MessageId)
+				}
+				got = append(got, messageIds)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal[[]string]) {
+				t.Errorf("incidents %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncidentCorrelatorWindow(t *testing.T) {
+	rule := storageRule
+	rule.CorrelationWindow = Duration{20 * time.Millisecond}
+	correlator, err := NewIncidentCorrelator([]CorrelationRule{rule}, NewMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer correlator.Close()
+
+	now := time.Now()
+	correlator.Send(&EnrichedEvent{Event: Event{MessageId: "StorageDevice.1.0.ControllerFailure"}, ServerIP: "https://10.0.0.1", ReceivedAt: now})
+	correlator.Send(&EnrichedEvent{Event: Event{MessageId: "StorageDevice.1.0.DriveOffline"}, ServerIP: "https://10.0.0.1", ReceivedAt: now})
+
+	select {
+	case incident := <-correlator.Incidents():
+		if len(incident.Events) != 2 {
+			t.Errorf("incident with %d events, want 2", len(incident.Events))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no incident emitted after its window passed")
+	}
+
+	// Events past the window don't join the emitted incident
+	correlator.Send(&EnrichedEvent{Event: Event{MessageId: "StorageDevice.1.0.DriveOffline"}, ServerIP: "https://10.0.0.1", ReceivedAt: now.Add(time.Second)})
+	select {
+	case incident := <-correlator.Incidents():
+		t.Errorf("incident %s emitted for an event outside of any window", incident.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNewIncidentCorrelatorInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule CorrelationRule
+	}{
+		{"no window", CorrelationRule{TriggerMessageIdPattern: "Base"}},
+		{"invalid trigger", CorrelationRule{TriggerMessageIdPattern: "(", CorrelationWindow: Duration{time.Minute}}},
+		{"invalid related pattern", CorrelationRule{TriggerMessageIdPattern: "Base", RelatedMessageIdPatterns: []string{"["}, CorrelationWindow: Duration{time.Minute}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewIncidentCorrelator([]CorrelationRule{tt.rule}, NewMetrics()); err == nil {
+			t.Errorf("%s: NewIncidentCorrelator() error = nil", tt.name)
+		}
+	}
+}
+
+func TestIncidentEvent(t *testing.T) {
+	incident := &Incident{
+		ID:   "incident-1",
+		Type: "StorageFailure",
+		Events: []*EnrichedEvent{
+			{Event: Event{MessageId: "StorageDevice.1.0.ControllerFailure", Severity: "Warning"}, ServerIP: "https://10.0.0.1"},
+			{Event: Event{MessageId: "StorageDevice.1.0.DriveOffline", Severity: "Critical"}, ServerIP: "https://10.0.0.1"},
+		},
+	}
+	event := incidentEvent(incident)
+	if event.Severity != "Critical" || event.MessageId != "Incident.StorageFailure" || event.EventId != "incident-1" || event.ServerIP != "https://10.0.0.1" {
+		t.Errorf("incidentEvent() = %+v, want the incident as a Critical event", event)
+	}
+	if !strings.Contains(event.Message, "StorageDevice.1.0.DriveOffline") {
+		t.Errorf("Message = %q, want the incident's MessageIds", event.Message)
+	}
+}
@@ -0,0 +1,86 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+// LEDController switches the indicator LED of the servers' first system,
+// used by field engineers to find a server in a rack
+type LEDController struct{}
+
+func NewLEDController() *LEDController {
+	return &LEDController{}
+}
+
+// Set the indicator LED of the server's first system
+func (l *LEDController) SetLED(ctx context.Context, server RedfishServer, state common.IndicatorLED) error {
+	system, logout, err := getFirstSystem(ctx, server)
+	if err != nil {
+		return err
+	}
+	defer logout()
+
+	system.IndicatorLED = state
+	if err := system.Update(); err != nil {
+		return fmt.Errorf("failed to set indicator LED on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+
+	log.Printf("Set indicator LED on server %s to %s", server.IP, state)
+	return nil
+}
+
+// Get the indicator LED state of the server's first system
+func (l *LEDController) GetLED(ctx context.Context, server RedfishServer) (common.IndicatorLED, error) {
+	system, logout, err := getFirstSystem(ctx, server)
+	if err != nil {
+		return "", err
+	}
+	defer logout()
+
+	return system.IndicatorLED, nil
+}
+
+// Light the indicator LED for the given duration, then switch it off. The
+// LED is switched off early when the context is done.
+func (l *LEDController) BlinkLED(ctx context.Context, server RedfishServer, duration time.Duration) error {
+	if err := l.SetLED(ctx, server, common.LitIndicatorLED); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(duration):
+	}
+
+	// Still switch the LED off after cancellation
+	return l.SetLED(context.WithoutCancel(ctx), server, common.OffIndicatorLED)
+}
+
+// Set the indicator LED on all servers in parallel, returning the failures
+// by server IP
+func (l *LEDController) BulkSetLED(ctx context.Context, servers []RedfishServer, state common.IndicatorLED, opts BulkOptions) map[string]error {
+	return bulkRun(ctx, servers, opts, func(server RedfishServer) error {
+		return l.SetLED(ctx, server, state)
+	})
+}
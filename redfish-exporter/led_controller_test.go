@@ -0,0 +1,93 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+func TestLEDController(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   common.IndicatorLED
+		failing bool
+	}{
+		{"lit", common.LitIndicatorLED, false},
+		{"blinking", common.BlinkingIndicatorLED, false},
+		{"off", common.OffIndicatorLED, false},
+		{"rejected", common.LitIndicatorLED, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.addSystem(map[string]interface{}{"IndicatorLED": "Off"})
+			if tt.failing {
+				bmc.failures["PATCH /redfish/v1/Systems/1"] = http.StatusBadRequest
+			}
+			controller := NewLEDController()
+
+			err := controller.SetLED(context.Background(), bmc.server(), tt.state)
+			if (err != nil) != tt.failing {
+				t.Fatalf("SetLED() error = %v, want failure %v", err, tt.failing)
+			}
+			want := tt.state
+			if tt.failing {
+				want = common.OffIndicatorLED
+			}
+			state, err := controller.GetLED(context.Background(), bmc.server())
+			if err != nil || state != want {
+				t.Errorf("GetLED() = %q, %v, want %q", state, err, want)
+			}
+		})
+	}
+}
+
+func TestBlinkLED(t *testing.T) {
+	bmc := newFakeBMC(t)
+	bmc.addSystem(map[string]interface{}{"IndicatorLED": "Off"})
+
+	// Canceled before the duration, the LED is still switched off
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := NewLEDController().BlinkLED(ctx, bmc.server(), time.Minute); err != nil {
+		t.Fatalf("BlinkLED() error = %v", err)
+	}
+	if patches := bmc.count("PATCH /redfish/v1/Systems/1"); patches != 2 {
+		t.Errorf("%d PATCH requests, want the LED lit and switched off", patches)
+	}
+	if state := bmc.resource("/redfish/v1/Systems/1")["IndicatorLED"]; state != "Off" {
+		t.Errorf("IndicatorLED = %v after blinking, want Off", state)
+	}
+}
+
+func TestBulkSetLED(t *testing.T) {
+	up, withoutSystem := newFakeBMC(t), newFakeBMC(t)
+	up.addSystem(map[string]interface{}{"IndicatorLED": "Off"})
+
+	errs := NewLEDController().BulkSetLED(context.Background(), []RedfishServer{up.server(), withoutSystem.server()}, common.LitIndicatorLED, BulkOptions{MaxConcurrency: 2})
+	if len(errs) != 1 || errs[withoutSystem.URL] == nil {
+		t.Errorf("BulkSetLED() = %v, want only the server without a system failing", errs)
+	}
+	if state := up.resource("/redfish/v1/Systems/1")["IndicatorLED"]; state != "Lit" {
+		t.Errorf("IndicatorLED = %v, want Lit", state)
+	}
+}
@@ -20,13 +20,13 @@ import (
 	"bufio"
 	"bytes"
 	"crypto/tls"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -43,10 +43,15 @@ type Payload struct {
 }
 
 type Event struct {
-	EventType         string            `json:"EventType"`
-	EventId           string            `json:"EventId"`
-	EventTimestamp    string            `json:"EventTimestamp"`
+	// Position of the event in the payload's Events array
+	MemberId       string `json:"MemberId,omitempty"`
+	EventType      string `json:"EventType"`
+	EventId        string `json:"EventId"`
+	EventTimestamp string `json:"EventTimestamp"`
+	// Deprecated in the Event schema in favor of MessageSeverity, filled
+	// from it by parsePayload when the BMC only sends MessageSeverity
 	Severity          string            `json:"Severity"`
+	MessageSeverity   string            `json:"MessageSeverity,omitempty"`
 	Message           string            `json:"Message"`
 	MessageId         string            `json:"MessageId"`
 	MessageArgs       []string          `json:"MessageArgs"`
@@ -63,14 +68,29 @@ type Server struct {
 	listener     net.Listener
 	shutdownChan chan struct{}
 	slurmQueue   *slurm.SlurmQueue
+	sinks        []EventSink
+	// Keeps the subscriptions in place, used to link events to their
+	// subscription when set
+	reconciler *Reconciler
+	// Resolves the events' MessageIds when set
+	registries *RegistryCache
+	// Only events posted to this path or below are accepted, any path when empty
+	pathPrefix string
+	// Checks the payloads against the Event schema when set, rejecting the
+	// ones that don't match when strict
+	schemaValidator *EventSchemaValidator
+	strictSchema    bool
+	metrics         *Metrics
 }
 
-func NewServer(listenIP string, listenPort string, slurmQueue *slurm.SlurmQueue) *Server {
+func NewServer(listenIP string, listenPort string, slurmQueue *slurm.SlurmQueue, sinks []EventSink) *Server {
 	return &Server{
 		listenIP:     listenIP,
 		listenPort:   listenPort,
 		shutdownChan: make(chan struct{}),
 		slurmQueue:   slurmQueue,
+		sinks:        sinks,
+		metrics:      NewMetrics(),
 	}
 }
 
@@ -163,6 +183,10 @@ func (s *Server) handleConnection(AppConfig Config, conn net.Conn) {
 		}
 
 		err = s.processRequest(AppConfig, conn, req, eventCount, dataBuffer)
+		if errors.Is(err, errBodyNotDiscarded) {
+			log.Printf("Closing connection from %s: %v", conn.RemoteAddr(), err)
+			break
+		}
 		if err != nil {
 			log.Printf("Error processing request: %v", err)
 			sendErrorResponse(conn, req)
@@ -181,6 +205,18 @@ func (s *Server) processRequest(AppConfig Config, conn net.Conn, req *http.Reque
 	if err != nil {
 		ip = conn.RemoteAddr().String() // Fallback to full address if splitting fails
 	}
+	serverID := metricsServerID(getServerInfo(AppConfig.RedfishServers, fmt.Sprintf("https://%v", ip)), ip)
+
+	if !matchesPathPrefix(req.URL.Path, s.pathPrefix) {
+		log.Printf("Rejected request from %s to %s, outside of %s", ip, req.URL.Path, s.pathPrefix)
+		// The next request on the connection starts after this one's body
+		discarded := discardBody(req)
+		sendNotFoundResponse(conn, req)
+		if !discarded {
+			return errBodyNotDiscarded
+		}
+		return nil
+	}
 
 	// Read the payload
 	payload, err := io.ReadAll(req.Body)
@@ -189,55 +225,51 @@ func (s *Server) processRequest(AppConfig Config, conn net.Conn, req *http.Reque
 	}
 	req.Body.Close()
 
-	// Unmarshal the JSON payload into the struct
-	var p Payload
-	err = json.Unmarshal(payload, &p)
+	// Unmarshal the JSON payload into the struct, rejecting malformed events
+	p, err := parsePayload(payload)
 	if err != nil {
-		return fmt.Errorf("error unmarshaling JSON: %w", err)
+		log.Printf("Rejected event from %s: %v", ip, err)
+		s.metrics.eventsMalformed.WithLabelValues(serverID).Inc()
+		sendBadRequestResponse(conn, req, err.Error())
+		return nil
+	}
+
+	schemaViolation := false
+	if s.schemaValidator != nil {
+		if err := s.schemaValidator.Validate(payload); err != nil {
+			s.metrics.eventSchemaViolations.WithLabelValues(serverID).Inc()
+			if s.strictSchema {
+				log.Printf("Rejected event from %s: %v", ip, err)
+				sendBadRequestResponse(conn, req, err.Error())
+				return nil
+			}
+			log.Printf("Event from %s: %v", ip, err)
+			schemaViolation = true
+		}
 	}
 
 	// Log the extracted information
-	var eventType string
 	log.Printf("Method: %s", method)
 	log.Printf("Headers: %v", headers)
+	log.Printf("Received %d events from %s", len(p.Events), ip)
+
+	// A payload can carry several events, each one is handled and counted
+	// on its own with the payload's Context
 	for _, event := range p.Events {
-		eventType = event.EventType
-		eventId := event.EventId
-		severity := event.Severity
-		message := event.Message
-		messageId := event.MessageId
-		messageArgs := event.MessageArgs
-		originOfCondition := event.OriginOfCondition.OdataId
-
-		log.Printf("Event Type: %s", eventType)
-		log.Printf("Event ID: %s", eventId)
-		log.Printf("Severity: %s", severity)
-		log.Printf("Message: %s", message)
-		log.Printf("Message ID: %s", messageId)
-		log.Printf("Message Args: %v", messageArgs)
-		log.Printf("Origin Of Condition: %s", originOfCondition)
-		for _, triggerEvent := range AppConfig.TriggerEvents {
-			if strings.Contains(messageId, triggerEvent.MessageId) {
-				log.Printf("Matched Trigger Event: %s with action %s", triggerEvent.MessageId, triggerEvent.Action)
-				// Sending event belongs to redfish_utils. Each server may have different slurm node associated, and redfish_servers has the info/map.
-				if s.slurmQueue != nil {
-					redfishServerInfo := getServerInfo(AppConfig.RedfishServers, fmt.Sprintf("https://%v", ip))
-					s.slurmQueue.Add(triggerEvent.Action, redfishServerInfo.SlurmNode)
-				}
-				break
-			}
-		}
+		s.processEvent(AppConfig, ip, p.Context, event, schemaViolation)
+
+		// Update metrics using variables from metrics.go
+		now := time.Now()
+		timestamp := float64(now.Unix())
+		s.metrics.eventCount.WithLabelValues(serverID, event.EventType).Inc()
+		s.metrics.eventProcessingTime.WithLabelValues(serverID, event.EventType).Set(timestamp)
+		s.metrics.recordDeliveryLatency(serverID, event, now)
 	}
 
 	// Append data to dataBuffer and increment eventCount
 	*dataBuffer = append(*dataBuffer, payload...)
 	*eventCount++
 
-	// Update metrics using variables from metrics.go
-	timestamp := float64(time.Now().Unix())
-	eventCountMetric.WithLabelValues(ip, eventType).Inc()
-	eventProcessingTimeMetric.WithLabelValues(ip, eventType).Set(timestamp)
-
 	// Send a 200 OK response
 	response := &http.Response{
 		Status:        "200 OK",
@@ -257,6 +289,220 @@ func (s *Server) processRequest(AppConfig Config, conn net.Conn, req *http.Reque
 	return nil
 }
 
+// Largest body read and dropped from a rejected request, the connection is
+// closed after a larger one
+const maxDiscardedBodySize = 1 << 20
+
+var errBodyNotDiscarded = errors.New("body of the rejected request too large to discard")
+
+// Read the rest of a request's body, false when it was too large or
+// couldn't be read
+func discardBody(req *http.Request) bool {
+	n, err := io.Copy(io.Discard, io.LimitReader(req.Body, maxDiscardedBodySize+1))
+	req.Body.Close()
+	return err == nil && n <= maxDiscardedBodySize
+}
+
+// The server label of the listener's metrics, the server's name when it has
+// one and otherwise the address the events come from
+func metricsServerID(server RedfishServer, ip string) string {
+	if server.Name != "" {
+		return server.Name
+	}
+	return ip
+}
+
+// Record the time between an event's EventTimestamp and its receipt. A
+// timestamp in the future means the BMC's clock is ahead of ours, that is
+// counted as skew and recorded as no latency.
+func (m *Metrics) recordDeliveryLatency(ip string, event Event, received time.Time) {
+	if event.EventTimestamp == "" {
+		return
+	}
+	generated, err := time.Parse(time.RFC3339, event.EventTimestamp)
+	if err != nil {
+		return
+	}
+	latency := received.Sub(generated).Seconds()
+	if latency < 0 {
+		m.eventClockSkew.WithLabelValues(ip).Inc()
+		latency = 0
+	}
+	m.eventDeliveryLatency.WithLabelValues(ip).Observe(latency)
+}
+
+// Log a single event and hand it to the metrics, sinks and trigger actions
+func (s *Server) processEvent(AppConfig Config, ip string, eventContext string, event Event, schemaViolation bool) {
+	eventType := event.EventType
+	eventId := event.EventId
+	severity := event.Severity
+	message := event.Message
+	messageId := event.MessageId
+	messageArgs := event.MessageArgs
+	originOfCondition := event.OriginOfCondition.OdataId
+
+	log.Printf("Event Type: %s", eventType)
+	log.Printf("Event ID: %s", eventId)
+	log.Printf("Severity: %s", severity)
+	log.Printf("Message: %s", message)
+	log.Printf("Message ID: %s", messageId)
+	log.Printf("Message Args: %v", messageArgs)
+	log.Printf("Origin Of Condition: %s", originOfCondition)
+
+	deliveryWaiters.deliver(event)
+	deliverToReceivers(ip, eventContext, event)
+
+	redfishServerInfo := getServerInfo(AppConfig.RedfishServers, fmt.Sprintf("https://%v", ip))
+	// A UniqueToken in the Context names the server even behind NAT or a proxy
+	if serverIP, ok := contextTokens.Lookup(eventContext); ok {
+		redfishServerInfo = getServerInfo(AppConfig.RedfishServers, serverIP)
+	}
+	s.metrics.redfishEvents.WithLabelValues(metricsServerID(redfishServerInfo, ip), messageId, severity).Inc()
+	if redfishServerInfo.IP != "" {
+		lastReceivedEvents.record(redfishServerInfo.ID(), time.Now())
+	} else {
+		lastReceivedEvents.record(ip, time.Now())
+	}
+
+	var subscriptionURI string
+	if uri := s.subscriptionURI(redfishServerInfo.ID()); uri != "" {
+		subscriptionURI = redfishServerInfo.IP + uri
+	}
+	var resolvedMessage string
+	if s.registries != nil {
+		if redfishServerInfo.IP != "" {
+			s.registries.EnsureLoaded(redfishServerInfo)
+		}
+		if message, _, err := s.registries.Resolve(redfishServerInfo.ID(), messageId, messageArgs); err == nil {
+			resolvedMessage = message
+		}
+	}
+	s.sendToSinks(&EnrichedEvent{
+		Event:           event,
+		ServerIP:        ip,
+		SlurmNode:       redfishServerInfo.SlurmNode,
+		Context:         eventContext,
+		ReceivedAt:      time.Now(),
+		SubscriptionURI: subscriptionURI,
+		ResolvedMessage: resolvedMessage,
+		Location:        redfishServerInfo.ServerLocation,
+		SchemaViolation: schemaViolation,
+	})
+
+	for _, triggerEvent := range AppConfig.TriggerEvents {
+		if strings.Contains(messageId, triggerEvent.MessageId) {
+			log.Printf("Matched Trigger Event: %s with action %s", triggerEvent.MessageId, triggerEvent.Action)
+			// Sending event belongs to redfish_utils. Each server may have different slurm node associated, and redfish_servers has the info/map.
+			if s.slurmQueue != nil {
+				s.slurmQueue.Add(triggerEvent.Action, redfishServerInfo.SlurmNode)
+			}
+			break
+		}
+	}
+}
+
+// LastSeen returns the time the last event of each server reached a
+// listener of this process, by server ID, or by host for events of
+// servers that aren't configured
+func (s *Server) LastSeen() map[string]time.Time {
+	return lastReceivedEvents.snapshot()
+}
+
+// Link events to the subscriptions of the reconciler, which are read as
+// they are when each event arrives, must be called before Start
+func (s *Server) SetReconciler(reconciler *Reconciler) {
+	s.reconciler = reconciler
+}
+
+// Only accept events posted to the prefix or below, must be called before Start
+func (s *Server) SetPathPrefix(prefix string) {
+	s.pathPrefix = normalizePathPrefix(prefix)
+}
+
+// Check the event payloads against the Event schema, must be called before
+// Start. Payloads that don't match are rejected when strict, and passed on
+// flagged otherwise.
+func (s *Server) SetSchemaValidator(validator *EventSchemaValidator, strict bool) {
+	s.schemaValidator = validator
+	s.strictSchema = strict
+}
+
+// Record the events in the metrics of the exporter, must be called before
+// Start. The listener's own metrics aren't registered otherwise.
+func (s *Server) SetMetrics(metrics *Metrics) {
+	s.metrics = metrics
+}
+
+// Resolve the MessageIds of the events with the registry cache, must be called before Start
+func (s *Server) SetRegistryCache(registries *RegistryCache) {
+	s.registries = registries
+}
+
+// Get the URI of the subscription delivering a server's events to this
+// listener. With several destinations it is the one on the listener's port.
+func (s *Server) subscriptionURI(serverID string) string {
+	if s.reconciler == nil {
+		return ""
+	}
+	subscriptions := s.reconciler.SubscriptionsOf(serverID)
+	if len(subscriptions) == 1 {
+		for _, subscription := range subscriptions {
+			return subscription.URI
+		}
+	}
+	for destination, subscription := range subscriptions {
+		if destinationURL, err := url.Parse(destination); err == nil && destinationURL.Port() == s.listenPort {
+			return subscription.URI
+		}
+	}
+	return ""
+}
+
+// Forward an event to all configured sinks
+func (s *Server) sendToSinks(event *EnrichedEvent) {
+	for _, sink := range s.sinks {
+		if err := sink.Send(event); err != nil {
+			log.Printf("Error sending event %s to sink: %v", event.EventId, err)
+		}
+	}
+}
+
+func sendNotFoundResponse(conn net.Conn, req *http.Request) {
+	response := &http.Response{
+		Status:        "404 Not Found",
+		StatusCode:    http.StatusNotFound,
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewBufferString("Not Found")),
+		ContentLength: int64(len("Not Found")),
+	}
+	response.Header.Set("Content-Type", "text/plain")
+	err := response.Write(conn)
+	if err != nil {
+		log.Printf("Error writing not found response: %v", err)
+	}
+}
+
+func sendBadRequestResponse(conn net.Conn, req *http.Request, message string) {
+	response := &http.Response{
+		Status:        "400 Bad Request",
+		StatusCode:    http.StatusBadRequest,
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewBufferString(message)),
+		ContentLength: int64(len(message)),
+	}
+	response.Header.Set("Content-Type", "text/plain")
+	err := response.Write(conn)
+	if err != nil {
+		log.Printf("Error writing bad request response: %v", err)
+	}
+}
+
 func sendErrorResponse(conn net.Conn, req *http.Request) {
 	response := &http.Response{
 		Status:        "500 Internal Server Error",
@@ -0,0 +1,177 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Number and sum of the delivery latencies observed for the server
+func deliveryLatency(t *testing.T, metrics *Metrics, server string) (uint64, float64) {
+	t.Helper()
+	var metric dto.Metric
+	if err := metrics.eventDeliveryLatency.WithLabelValues(server).(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatalf("failed to read histogram: %v", err)
+	}
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
+
+func TestRecordDeliveryLatency(t *testing.T) {
+	received := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		timestamp string
+		wantCount uint64
+		wantSum   float64
+		wantSkew  float64
+	}{
+		{name: "delivered late", timestamp: "2024-05-01T11:59:57Z", wantCount: 1, wantSum: 3},
+		{name: "delivered right away", timestamp: "2024-05-01T12:00:00Z", wantCount: 1},
+		{name: "timestamp with offset", timestamp: "2024-05-01T13:59:30+02:00", wantCount: 1, wantSum: 30},
+		{name: "clock skew clamped", timestamp: "2024-05-01T12:00:10Z", wantCount: 1, wantSkew: 1},
+		{name: "no timestamp"},
+		{name: "malformed timestamp", timestamp: "yesterday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := NewMetrics()
+			metrics.recordDeliveryLatency("10.0.0.1", Event{EventTimestamp: tt.timestamp}, received)
+
+			count, sum := deliveryLatency(t, metrics, "10.0.0.1")
+			if count != tt.wantCount || sum != tt.wantSum {
+				t.Errorf("latency observations = %d, sum %v, want %d, sum %v", count, sum, tt.wantCount, tt.wantSum)
+			}
+			if got := counterValue(t, metrics.eventClockSkew.WithLabelValues("10.0.0.1")); got != tt.wantSkew {
+				t.Errorf("redfish_event_clock_skew_total = %v, want %v", got, tt.wantSkew)
+			}
+		})
+	}
+}
+
+func TestListenerRecordsDeliveryLatency(t *testing.T) {
+	s := NewServer("127.0.0.1", "0", nil, nil)
+	address := startTestListener(t, s)
+
+	generated := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	payload := fmt.Sprintf(`{"Id": "1", "Name": "Event", "Events": [
+		{"MemberId": "0", "EventType": "Alert", "EventTimestamp": %q, "MessageId": "Base.1.0.Success"},
+		{"MemberId": "1", "EventType": "Alert", "MessageId": "Base.1.0.Success"}]}`, generated)
+	resp, err := http.Post("http://"+address+"/", "application/json", strings.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// Only the event with a timestamp is observed
+	count, sum := deliveryLatency(t, s.metrics, "127.0.0.1")
+	if count != 1 {
+		t.Fatalf("latency observations = %d, want 1", count)
+	}
+	if sum < 59 || sum > 120 {
+		t.Errorf("latency = %vs, want about a minute", sum)
+	}
+}
+
+func TestProcessEventLocation(t *testing.T) {
+	sink := &countingSink{}
+	s := NewServer("127.0.0.1", "0", nil, []EventSink{sink})
+	location := ServerLocation{Datacenter: "dc1", Row: "r2", Rack: "3", AssetTag: "SRV-0001"}
+	config := Config{RedfishServers: []RedfishServer{{IP: "https://10.0.0.1", ServerLocation: location}}}
+
+	s.processEvent(config, "10.0.0.1", "", Event{EventType: "Alert", MessageId: "Base.1.0.Success"}, false)
+	s.processEvent(config, "10.0.0.2", "", Event{EventType: "Alert", MessageId: "Base.1.0.Success"}, false)
+
+	if len(sink.events) != 2 {
+		t.Fatalf("%d events sent, want 2", len(sink.events))
+	}
+	if got := sink.events[0].Location; got != location {
+		t.Errorf("location = %+v, want %+v", got, location)
+	}
+	if got := sink.events[1].Location; got != (ServerLocation{}) {
+		t.Errorf("location of an unknown server = %+v, want none", got)
+	}
+}
+
+// Records the events it receives, from the listener's goroutines
+type recordingSink struct {
+	mu     sync.Mutex
+	events []EnrichedEvent
+}
+
+func (s *recordingSink) Send(event *EnrichedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	return nil
+}
+
+func (s *recordingSink) received() []EnrichedEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]EnrichedEvent(nil), s.events...)
+}
+
+func TestListenerHandlesEveryEventOfPayload(t *testing.T) {
+	sink := &recordingSink{}
+	s := NewServer("127.0.0.1", "0", nil, []EventSink{sink})
+	address := startTestListener(t, s)
+
+	payload := `{"Id": "1", "Name": "Event", "Context": "scrapefish", "Events": [
+		{"MemberId": "0", "EventType": "Alert", "MessageId": "Base.1.0.Success"},
+		{"MemberId": "1", "EventType": "StatusChange", "MessageId": "Base.1.0.Success"},
+		{"MemberId": "2", "EventType": "Alert", "MessageId": "Base.1.0.Success"}]}`
+	resp, err := http.Post("http://"+address+"/", "application/json", strings.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	events := sink.received()
+	if len(events) != 3 {
+		t.Fatalf("%d events sent to the sinks, want 3", len(events))
+	}
+	for i, event := range events {
+		if event.MemberId != fmt.Sprint(i) || event.Context != "scrapefish" {
+			t.Errorf("event %d = MemberId %q, Context %q, want MemberId %q with the payload's Context", i, event.MemberId, event.Context, fmt.Sprint(i))
+		}
+	}
+	// Counted per event with its own type, not once with the last type
+	if got := counterValue(t, s.metrics.eventCount.WithLabelValues("127.0.0.1", "Alert")); got != 2 {
+		t.Errorf("Alert events counted = %v, want 2", got)
+	}
+	if got := counterValue(t, s.metrics.eventCount.WithLabelValues("127.0.0.1", "StatusChange")); got != 1 {
+		t.Errorf("StatusChange events counted = %v, want 1", got)
+	}
+}
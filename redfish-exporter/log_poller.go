@@ -0,0 +1,183 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/stmcginnis/gofish"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// Entries created up to this long before the newest entry already seen are
+// fetched again on each poll and de-duplicated, so entries sharing the
+// timestamp of the last poll are not lost to timestamp granularity.
+const logPollOverlap = time.Minute
+
+// Read the log services of a redfish server and return the entries created
+// after since as events
+func PollLogService(server RedfishServer, since time.Time) ([]Event, error) {
+	c, err := getRedfishClient(server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	return pollLogService(c, server, since)
+}
+
+// Read the log entries created after since with an open connection
+func pollLogService(c *gofish.APIClient, server RedfishServer, since time.Time) ([]Event, error) {
+	entries, err := getLogEntries(c, server)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, entry := range entries {
+		created, err := time.Parse(time.RFC3339, entry.Created)
+		if err != nil {
+			log.Printf("Skipping log entry %s on server %s with invalid timestamp %q", entry.ODataID, server.IP, entry.Created)
+			continue
+		}
+		if created.After(since) {
+			events = append(events, logEntryToEvent(entry))
+		}
+	}
+	return events, nil
+}
+
+// Poll the log services of the given servers every interval and pass each new
+// entry to handler until the context is done. This is the event source for
+// servers that can't reach the listener to push events.
+func RunLogPoller(ctx context.Context, servers []RedfishServer, interval time.Duration, handler func(server RedfishServer, event Event)) {
+	state := make(map[string]*logPollState)
+	startTime := time.Now()
+	for _, server := range servers {
+		state[server.IP] = &logPollState{start: startTime, newest: startTime, seen: make(map[string]time.Time)}
+	}
+
+	// Sessions are kept open between polls
+	sessions := NewSessionManager()
+	defer sessions.Close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting log poller for %d servers every %v", len(servers), interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Context done, stopping log poller")
+			return
+		case <-ticker.C:
+			for _, server := range servers {
+				c, err := sessions.Get(ctx, server)
+				if err != nil {
+					log.Printf("Failed to connect to server %s: %v", server.IP, err)
+					continue
+				}
+				events, err := pollLogService(c, server, state[server.IP].newest.Add(-logPollOverlap))
+				if err != nil {
+					log.Printf("Failed to poll log service on server %s: %v", server.IP, err)
+					sessions.Evict(server)
+					continue
+				}
+				for _, event := range state[server.IP].filterNew(events) {
+					handler(server, event)
+				}
+			}
+		}
+	}
+}
+
+// Keeps track of the entries already handled for one server
+type logPollState struct {
+	start  time.Time
+	newest time.Time
+	seen   map[string]time.Time
+}
+
+// Return the events not seen in previous polls. Entries are keyed by ID and
+// timestamp, so IDs reused after the log is cleared or rotated are still
+// reported, and entries older than the overlap window are forgotten.
+func (s *logPollState) filterNew(events []Event) []Event {
+	var newEvents []Event
+	for _, event := range events {
+		created, _ := time.Parse(time.RFC3339, event.EventTimestamp)
+		key := fmt.Sprintf("%s@%s", event.EventId, event.EventTimestamp)
+		if _, ok := s.seen[key]; ok || created.Before(s.start) {
+			continue
+		}
+		s.seen[key] = created
+		if created.After(s.newest) {
+			s.newest = created
+		}
+		newEvents = append(newEvents, event)
+	}
+
+	for key, created := range s.seen {
+		if created.Before(s.newest.Add(-logPollOverlap)) {
+			delete(s.seen, key)
+		}
+	}
+	return newEvents
+}
+
+// Gets the entries of all log services of the server's systems
+func getLogEntries(c *gofish.APIClient, server RedfishServer) ([]*redfish.LogEntry, error) {
+	systems, err := c.Service.Systems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systems on server %s: %v", server.IP, err)
+	}
+
+	var entries []*redfish.LogEntry
+	for _, system := range systems {
+		logServices, err := system.LogServices()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get log services on server %s: %v", server.IP, err)
+		}
+		for _, logService := range logServices {
+			logEntries, err := logService.Entries()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get log entries of %s on server %s: %v", logService.ODataID, server.IP, err)
+			}
+			entries = append(entries, logEntries...)
+		}
+	}
+	return entries, nil
+}
+
+func logEntryToEvent(entry *redfish.LogEntry) Event {
+	eventId := entry.EventID
+	if eventId == "" {
+		eventId = entry.ODataID
+	}
+	return Event{
+		EventType:         "Alert",
+		EventId:           eventId,
+		EventTimestamp:    entry.Created,
+		Severity:          string(entry.Severity),
+		Message:           entry.Message,
+		MessageId:         entry.MessageID,
+		MessageArgs:       entry.MessageArgs,
+		OriginOfCondition: OriginOfCondition{OdataId: entry.OriginOfCondition},
+	}
+}
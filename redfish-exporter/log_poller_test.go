@@ -0,0 +1,148 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Serve a system log service with the entries
+func addLogEntries(bmc *fakeBMC, entries ...map[string]interface{}) {
+	bmc.addSystem(map[string]interface{}{"LogServices": link("/redfish/v1/Systems/1/LogServices")})
+	bmc.addCollection("/redfish/v1/Systems/1/LogServices",
+		map[string]interface{}{"Id": "SEL", "Entries": link("/redfish/v1/Systems/1/LogServices/SEL/Entries")},
+	)
+	bmc.addCollection("/redfish/v1/Systems/1/LogServices/SEL/Entries", entries...)
+}
+
+func logEntry(id, eventID string, created time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"Id":        id,
+		"EventId":   eventID,
+		"Created":   created.Format(time.RFC3339),
+		"Severity":  "Critical",
+		"Message":   "CPU 1 temperature is critical",
+		"MessageId": "ResourceEvent.1.0.ResourceErrorThresholdExceeded",
+	}
+}
+
+func TestPollLogService(t *testing.T) {
+	since := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	bmc := newFakeBMC(t)
+	invalid := logEntry("4", "", since)
+	invalid["Created"] = "yesterday"
+	addLogEntries(bmc,
+		logEntry("1", "100", since.Add(-time.Minute)),
+		logEntry("2", "101", since),
+		logEntry("3", "", since.Add(time.Second)),
+		invalid,
+	)
+
+	events, err := PollLogService(bmc.server(), since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("events = %+v, want the entry created after since", events)
+	}
+	event := events[0]
+	if event.EventId != "/redfish/v1/Systems/1/LogServices/SEL/Entries/3" || event.EventType != "Alert" || event.Severity != "Critical" || event.MessageId != "ResourceEvent.1.0.ResourceErrorThresholdExceeded" {
+		t.Errorf("event = %+v, want the entry's URI as its ID", event)
+	}
+	if bmc.openSessions() != 0 {
+		t.Errorf("%d sessions left open", bmc.openSessions())
+	}
+}
+
+func TestLogPollStateFilterNew(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	event := func(id string, created time.Time) Event {
+		return Event{EventId: id, EventTimestamp: created.Format(time.RFC3339)}
+	}
+
+	// Each poll runs against the state of the previous ones
+	polls := []struct {
+		name   string
+		events []Event
+		want   []string
+	}{
+		{"before the poller started", []Event{event("1", start.Add(-time.Second))}, nil},
+		{"new entries", []Event{event("2", start), event("3", start.Add(time.Second))}, []string{"2", "3"}},
+		{"overlap fetched again", []Event{event("2", start), event("3", start.Add(time.Second)), event("4", start.Add(time.Second))}, []string{"4"}},
+		{"ID reused after the log was cleared", []Event{event("2", start.Add(10*time.Second))}, []string{"2"}},
+		{"forgotten entries are before the overlap", []Event{event("5", start.Add(2*time.Minute))}, []string{"5"}},
+	}
+	state := &logPollState{start: start, newest: start, seen: make(map[string]time.Time)}
+	for _, poll := range polls {
+		var got []string
+		for _, event := range state.filterNew(poll.events) {
+			got = append(got, event.EventId)
+		}
+		if !slices.Equal(got, poll.want) {
+			t.Errorf("%s: new events = %v, want %v", poll.name, got, poll.want)
+		}
+	}
+	if len(state.seen) != 1 {
+		t.Errorf("seen = %v, want only the entry within the overlap of the newest", state.seen)
+	}
+}
+
+func TestRunLogPoller(t *testing.T) {
+	bmc := newFakeBMC(t)
+	created := time.Now().Add(time.Minute)
+	addLogEntries(bmc, logEntry("1", "100", created), logEntry("2", "101", created))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handled := make(chan Event, 10)
+	done := make(chan struct{})
+	go func() {
+		RunLogPoller(ctx, []RedfishServer{bmc.server()}, 10*time.Millisecond, func(server RedfishServer, event Event) {
+			handled <- event
+		})
+		close(done)
+	}()
+
+	// Both entries are handled once however many polls run
+	var ids []string
+	for len(ids) < 2 {
+		select {
+		case event := <-handled:
+			ids = append(ids, event.EventId)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("handled %v, want both entries", ids)
+		}
+	}
+	for bmc.count("GET /redfish/v1/Systems/1/LogServices/SEL/Entries") < 3 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if len(handled) != 0 {
+		t.Errorf("%d entries handled again", len(handled))
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"100", "101"}) {
+		t.Errorf("handled %v, want 100 and 101", ids)
+	}
+	if bmc.openSessions() != 0 {
+		t.Errorf("%d sessions left open after stopping", bmc.openSessions())
+	}
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"log"
 	"net/http"
@@ -26,15 +27,17 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
-	"time"
 
 	"github.com/nod-ai/ADA/redfish-exporter/slurm"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
 	var (
-		enableSlurm = flag.Bool("enable-slurm", false, "Enable slurm")
+		enableSlurm        = flag.Bool("enable-slurm", false, "Enable slurm")
+		generateAlertRules = flag.String("generate-alert-rules", "", "Write Prometheus alerting rules for the subscription to the given file and exit")
+		inspect            = flag.Bool("inspect-capabilities", false, "Print the event service capabilities of all servers as JSON and exit")
 	)
 	flag.Parse()
 
@@ -47,8 +50,36 @@ func main() {
 	// Log the initialized config
 	log.Printf("Initialized Config: %+v", AppConfig)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if *generateAlertRules != "" {
+		rules, err := GenerateAlertingRules([]SubscriptionPayload{AppConfig.SubscriptionPayload}, DefaultAlertSeverityMap)
+		if err != nil {
+			log.Fatalf("Failed to generate alerting rules: %v", err)
+		}
+		if err := os.WriteFile(*generateAlertRules, []byte(rules), 0644); err != nil {
+			log.Fatalf("Failed to write alerting rules: %v", err)
+		}
+		log.Printf("Alerting rules written to %s", *generateAlertRules)
+		return
+	}
+
+	if *inspect {
+		servers := withConnectionSettings(AppConfig.RedfishServers, newConnectionSettings(AppConfig.RedfishClient))
+		capabilities, err := InspectCapabilities(servers)
+		if err != nil {
+			log.Printf("Failed to inspect some servers: %v", err)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(capabilities); err != nil {
+			log.Fatalf("Failed to write capabilities: %v", err)
+		}
+		return
+	}
+
+	// Shut down on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	var slurmQueue *slurm.SlurmQueue
 	if *enableSlurm {
 		if len(strings.TrimSpace(AppConfig.SlurmToken)) == 0 {
@@ -66,26 +97,24 @@ func main() {
 		go slurmQueue.ProcessEventActionQueue()
 	}
 
-	// Subscribe the listener to the event stream for all servers
-	subscriptionMap, err := CreateSubscriptionsForAllServers(AppConfig.RedfishServers, AppConfig.SubscriptionPayload)
+	// Set up the event sinks
+	sinks := setupEventSinks(AppConfig)
+
+	cfg := exporterConfig(AppConfig)
+	cfg.Sinks = sinks
+	cfg.SlurmQueue = slurmQueue
+	cfg.Registerer = prometheus.DefaultRegisterer
+	exporter, err := New(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create subscriptions: %v", err)
+		log.Fatalf("Failed to create exporter: %v", err)
 	}
 
-	// Set up signal handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start the listener
-	listener := NewServer(AppConfig.SystemInformation.ListenerIP, AppConfig.SystemInformation.ListenerPort, slurmQueue)
-	go func() {
-		if err := listener.Start(AppConfig); err != nil {
-			log.Printf("Server error: %v", err)
-			close(sigChan)
-		}
-	}()
-
 	http.Handle("/metrics", promhttp.Handler())
+	http.Handle("/subscriptions", subscriptionsHandler(exporter.reconciler))
+	http.Handle("/maintenance", maintenanceHandler(exporter.maintenance, AppConfig.AdminToken))
+	http.Handle("/baseline", baselineHandler(exporter.baselines))
+	http.Handle("/node-health/", NewNodeHealthAPI(exporter.Servers(), cfg.HealthThresholds))
+	http.Handle("/topology/", topologyHandler(NewNUMATopologyCollector(exporter.Servers())))
 	go func() {
 		log.Printf("Starting metrics server on :%d", AppConfig.SystemInformation.MetricsPort)
 		portStr := strconv.Itoa(AppConfig.SystemInformation.MetricsPort)
@@ -94,24 +123,13 @@ func main() {
 		}
 	}()
 
-	// Wait for shutdown signal
-	<-sigChan
-	log.Println("Received shutdown signal. Closing listener...")
+	// Subscribe, listen for events and unsubscribe on shutdown
+	runErr := exporter.Run(ctx)
 
-	// Signal shutdown to all components
-	close(listener.shutdownChan)
+	closeEventSinks(sinks)
 
-	// Give some time for the listener to close gracefully
-	time.Sleep(time.Second)
-
-	// Unsubscribe the listener from all servers
-	log.Println("Unsubscribing from servers...")
-	DeleteSubscriptionsFromAllServers(AppConfig.RedfishServers, subscriptionMap)
-
-	cancel()
-
-	time.Sleep(time.Second)
-
-	// Perform any additional shutdown steps here
+	if runErr != nil {
+		log.Fatalf("Exporter stopped: %v", runErr)
+	}
 	log.Println("Shutdown complete")
 }
@@ -0,0 +1,244 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Warn this long before a maintenance window ends
+	maintenanceExpiryWarning = 15 * time.Minute
+	// Interval of the maintenance window expiry checks
+	maintenanceCheckInterval = time.Minute
+
+	maintenanceStateKey = "maintenance"
+)
+
+// MaintenanceRegistry tracks the servers in planned maintenance, e.g. for a
+// BMC firmware update. Their subscriptions are not reconciled and their
+// events only reach the sinks that aren't alert sinks. The windows are
+// kept in the state store so they survive restarts.
+type MaintenanceRegistry struct {
+	store *StateStore
+
+	mu sync.Mutex
+	// End of the maintenance window by server host
+	windows map[string]time.Time
+	// Servers whose expiry warning was logged
+	warned map[string]bool
+}
+
+// Create the registry with the windows kept in the store
+func NewMaintenanceRegistry(store *StateStore) (*MaintenanceRegistry, error) {
+	m := &MaintenanceRegistry{
+		store:   store,
+		windows: make(map[string]time.Time),
+		warned:  make(map[string]bool),
+	}
+	if _, err := store.Load(maintenanceStateKey, &m.windows); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Servers are tracked by host, so both the configured URL and the address
+// events arrive from can be used
+func maintenanceKey(serverIP string) string {
+	return serverHost(RedfishServer{IP: serverIP})
+}
+
+// Put a server in maintenance until the given time
+func (m *MaintenanceRegistry) EnterMaintenance(serverIP string, until time.Time) error {
+	if !until.After(time.Now()) {
+		return fmt.Errorf("maintenance of server %s must end in the future", serverIP)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := maintenanceKey(serverIP)
+	m.windows[key] = until
+	delete(m.warned, key)
+	log.Printf("Server %s is in maintenance until %s", serverIP, until.Format(time.RFC3339))
+	return m.store.Save(maintenanceStateKey, m.windows)
+}
+
+// End the maintenance of a server
+func (m *MaintenanceRegistry) ExitMaintenance(serverIP string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := maintenanceKey(serverIP)
+	if _, ok := m.windows[key]; !ok {
+		return nil
+	}
+	delete(m.windows, key)
+	delete(m.warned, key)
+	log.Printf("Server %s left maintenance", serverIP)
+	return m.store.Save(maintenanceStateKey, m.windows)
+}
+
+// Whether the server is in a maintenance window that hasn't ended
+func (m *MaintenanceRegistry) InMaintenance(serverIP string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	until, ok := m.windows[maintenanceKey(serverIP)]
+	return ok && time.Now().Before(until)
+}
+
+// Get a copy of the maintenance windows by server host
+func (m *MaintenanceRegistry) Windows() map[string]time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	windows := make(map[string]time.Time, len(m.windows))
+	for key, until := range m.windows {
+		windows[key] = until
+	}
+	return windows
+}
+
+// Warn about windows ending soon and drop the ones that ended, until the
+// context is done
+func (m *MaintenanceRegistry) Run(ctx context.Context) {
+	ticker := time.NewTicker(maintenanceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkExpiry(time.Now())
+		}
+	}
+}
+
+func (m *MaintenanceRegistry) checkExpiry(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expired := false
+	for key, until := range m.windows {
+		switch {
+		case !now.Before(until):
+			log.Printf("Maintenance of server %s ended, resuming reconciliation and alerts", key)
+			delete(m.windows, key)
+			delete(m.warned, key)
+			expired = true
+		case until.Sub(now) <= maintenanceExpiryWarning && !m.warned[key]:
+			log.Printf("Warning: maintenance of server %s ends in %v", key, until.Sub(now).Round(time.Second))
+			m.warned[key] = true
+		}
+	}
+	if expired {
+		if err := m.store.Save(maintenanceStateKey, m.windows); err != nil {
+			log.Printf("Failed to save maintenance windows: %v", err)
+		}
+	}
+}
+
+// maintenanceSink drops the events of servers in maintenance before they
+// reach an alert sink
+type maintenanceSink struct {
+	sink        EventSink
+	maintenance *MaintenanceRegistry
+}
+
+func (s *maintenanceSink) Send(event *EnrichedEvent) error {
+	if s.maintenance.InMaintenance(event.ServerIP) {
+		return nil
+	}
+	return s.sink.Send(event)
+}
+
+func (s *maintenanceSink) Close() error {
+	return s.sink.Close()
+}
+
+// Whether the request carries the admin token, never when it is empty
+func hasAdminToken(r *http.Request, adminToken string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// Whether a sink pages people, as opposed to recording events for audit
+func isAlertSink(sink EventSink) bool {
+	switch sink.(type) {
+	case *EmailAlertSink, *SlackAlertSink, *PagerDutyAlertSink:
+		return true
+	default:
+		return false
+	}
+}
+
+// Serve the maintenance windows. GET lists them, POST with a JSON body
+// {"server": "...", "until": "<RFC 3339 time>"} starts one and DELETE
+// with ?server=... ends one. POST and DELETE need the admin token as
+// bearer token and are refused when it is empty.
+func maintenanceHandler(maintenance *MaintenanceRegistry, adminToken string) http.HandlerFunc {
+	type maintenanceRequest struct {
+		Server string    `json:"server"`
+		Until  time.Time `json:"until"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && !hasAdminToken(r, adminToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "admin token required", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var request maintenanceRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Server == "" {
+				http.Error(w, "expected {\"server\": \"...\", \"until\": \"<RFC 3339 time>\"}", http.StatusBadRequest)
+				return
+			}
+			if err := maintenance.EnterMaintenance(request.Server, request.Until); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			server := r.URL.Query().Get("server")
+			if server == "" {
+				http.Error(w, "missing server parameter", http.StatusBadRequest)
+				return
+			}
+			if err := maintenance.ExitMaintenance(server); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(maintenance.Windows()); err != nil {
+			log.Printf("Failed to write maintenance response: %v", err)
+		}
+	}
+}
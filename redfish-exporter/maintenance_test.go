@@ -0,0 +1,79 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceHandlerRequiresAdminToken(t *testing.T) {
+	until := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body := `{"server": "https://10.0.0.1", "until": "` + until + `"}`
+	tests := []struct {
+		name            string
+		adminToken      string
+		method          string
+		target          string
+		authorization   string
+		wantStatus      int
+		wantMaintenance bool
+	}{
+		{name: "list without token", adminToken: "secret", method: http.MethodGet, target: "/maintenance", wantStatus: http.StatusOK},
+		{name: "start with token", adminToken: "secret", method: http.MethodPost, target: "/maintenance", authorization: "Bearer secret", wantStatus: http.StatusOK, wantMaintenance: true},
+		{name: "start without token", adminToken: "secret", method: http.MethodPost, target: "/maintenance", wantStatus: http.StatusUnauthorized},
+		{name: "start with wrong token", adminToken: "secret", method: http.MethodPost, target: "/maintenance", authorization: "Bearer guess", wantStatus: http.StatusUnauthorized},
+		{name: "start with basic auth", adminToken: "secret", method: http.MethodPost, target: "/maintenance", authorization: "Basic secret", wantStatus: http.StatusUnauthorized},
+		{name: "start without configured token", method: http.MethodPost, target: "/maintenance", authorization: "Bearer ", wantStatus: http.StatusUnauthorized},
+		{name: "end without token", adminToken: "secret", method: http.MethodDelete, target: "/maintenance?server=https://10.0.0.1", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStateStore("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			maintenance, err := NewMaintenanceRegistry(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.method == http.MethodDelete {
+				if err := maintenance.EnterMaintenance("https://10.0.0.1", time.Now().Add(time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(body))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			maintenanceHandler(maintenance, tt.adminToken).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			wantMaintenance := tt.wantMaintenance || (tt.method == http.MethodDelete && tt.wantStatus != http.StatusOK)
+			if got := maintenance.InMaintenance("https://10.0.0.1"); got != wantMaintenance {
+				t.Errorf("InMaintenance() = %v, want %v", got, wantMaintenance)
+			}
+		})
+	}
+}
@@ -20,25 +20,302 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var eventCountMetric = prometheus.NewCounterVec(
-	prometheus.CounterOpts{
-		Name: "RedFishEvents_recieved",
-		Help: "Total number of events processed",
-	},
-	[]string{"SourceIP", "EventType"}, // Define the labels you want to use
-)
+// Metrics are the prometheus metrics of one exporter, registered with the
+// exporter's registerer so two exporters in one process don't share them
+type Metrics struct {
+	eventCount            *prometheus.CounterVec
+	eventProcessingTime   *prometheus.GaugeVec
+	redfishEvents         *prometheus.CounterVec
+	connectErrors         *prometheus.CounterVec
+	reconcileCreated      prometheus.Counter
+	reconcileRecreated    prometheus.Counter
+	reconcileNoop         prometheus.Counter
+	reconcileUpdated      prometheus.Counter
+	sseReconnects         *prometheus.CounterVec
+	ntpEnabled            *prometheus.GaugeVec
+	ntpServers            *prometheus.GaugeVec
+	amdGPUHBMErrors       *prometheus.GaugeVec
+	amdGPUXGMILinkStatus  *prometheus.GaugeVec
+	amdGPUInfo            *prometheus.GaugeVec
+	amdXGMILinkHealth     *prometheus.GaugeVec
+	amdXGMILinkSpeed      *prometheus.GaugeVec
+	memoryErrorTrend      *prometheus.GaugeVec
+	systemHealth          *prometheus.GaugeVec
+	managerHealth         *prometheus.GaugeVec
+	amdXGMILinkWidth      *prometheus.GaugeVec
+	eventsRateLimited     *prometheus.CounterVec
+	sinkEventsDropped     *prometheus.CounterVec
+	serverCircuitOpen     *prometheus.GaugeVec
+	eventsMalformed       *prometheus.CounterVec
+	eventSchemaViolations *prometheus.CounterVec
+	serverDegraded        *prometheus.GaugeVec
+	eventDeliveryLatency  *prometheus.HistogramVec
+	eventClockSkew        *prometheus.CounterVec
+	incidents             *prometheus.CounterVec
+	subscriptionRollback  *prometheus.CounterVec
+	jobPreemptions        *prometheus.CounterVec
+}
 
-var eventProcessingTimeMetric = prometheus.NewGaugeVec(
-	prometheus.GaugeOpts{
-		Name: "RedFishEvents_processing_time",
-		Help: "Time taken to process events",
-	},
-	[]string{"SourceIP", "EventType"}, // Define the labels you want to use
-)
+// Create the metrics, they are only exported once registered
+func NewMetrics() *Metrics {
+	return &Metrics{
+		eventCount: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "RedFishEvents_recieved",
+				Help: "Total number of events processed",
+			},
+			[]string{"SourceIP", "EventType"}, // Define the labels you want to use
+		),
+		eventProcessingTime: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "RedFishEvents_processing_time",
+				Help: "Time taken to process events",
+			},
+			[]string{"SourceIP", "EventType"}, // Define the labels you want to use
+		),
+		redfishEvents: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_events_total",
+				Help: "Total number of redfish events received by message ID and severity",
+			},
+			[]string{"server", "message_id", "severity"},
+		),
+		connectErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_connect_errors_total",
+				Help: "Total number of failed redfish server connections by error category",
+			},
+			[]string{"category"},
+		),
+		reconcileCreated: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "redfish_reconcile_created_total",
+				Help: "Total number of subscriptions created by reconcile for servers without one",
+			},
+		),
+		reconcileRecreated: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "redfish_reconcile_recreated_total",
+				Help: "Total number of subscriptions recreated by reconcile after disappearing from the server",
+			},
+		),
+		reconcileNoop: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "redfish_reconcile_noop_total",
+				Help: "Total number of subscriptions found in place by reconcile",
+			},
+		),
+		reconcileUpdated: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "redfish_reconcile_updated_total",
+				Help: "Total number of subscriptions patched by reconcile after drifting from the payload",
+			},
+		),
+		sseReconnects: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_sse_reconnects_total",
+				Help: "Total number of reconnections of dropped server-sent event streams",
+			},
+			[]string{"server"},
+		),
+		ntpEnabled: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_ntp_enabled",
+				Help: "Whether NTP is enabled on the BMC (1) or not (0)",
+			},
+			[]string{"server_ip"},
+		),
+		ntpServers: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_ntp_servers",
+				Help: "Number of NTP servers configured on the BMC",
+			},
+			[]string{"server_ip"},
+		),
+		amdGPUHBMErrors: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_amd_gpu_hbm_errors",
+				Help: "HBM errors reported by an AMD GPU, by type corrected or uncorrected",
+			},
+			[]string{"type", "server_ip", "processor_id"},
+		),
+		amdGPUXGMILinkStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_amd_gpu_xgmi_link_status",
+				Help: "Whether the XGMI links of an AMD GPU are up (1) or not (0)",
+			},
+			[]string{"server_ip", "processor_id"},
+		),
+		amdGPUInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_amd_gpu_info",
+				Help: "ROCm version reported by an AMD GPU, always 1",
+			},
+			[]string{"server_ip", "processor_id", "rocm_version"},
+		),
+		amdXGMILinkHealth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_amd_xgmi_link_health",
+				Help: "Health of the XGMI link between two AMD GPUs: OK (0), Warning (1) or Critical (2)",
+			},
+			[]string{"server_ip", "source_gpu", "dest_gpu"},
+		),
+		amdXGMILinkSpeed: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_amd_xgmi_link_speed_gbps",
+				Help: "Current speed of the XGMI link between two AMD GPUs in Gbit/s",
+			},
+			[]string{"server_ip", "source_gpu", "dest_gpu"},
+		),
+		memoryErrorTrend: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_memory_correctable_error_trend",
+				Help: "Fitted growth of the correctable ECC errors of a DIMM in errors per hour",
+			},
+			[]string{"server_ip", "dimm"},
+		),
+		systemHealth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_system_health",
+				Help: "Whether the health of a system is the state (1) or not (0)",
+			},
+			[]string{"server_ip", "system", "state"},
+		),
+		managerHealth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_manager_health",
+				Help: "Whether the health of a manager (BMC) is the state (1) or not (0)",
+			},
+			[]string{"server_ip", "manager", "state"},
+		),
+		amdXGMILinkWidth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_amd_xgmi_link_width",
+				Help: "Number of lanes of the XGMI link between two AMD GPUs",
+			},
+			[]string{"server_ip", "source_gpu", "dest_gpu"},
+		),
+		eventsRateLimited: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_events_rate_limited_total",
+				Help: "Total number of events dropped by the event rate limiter",
+			},
+			[]string{"server", "registry_prefix"},
+		),
+		sinkEventsDropped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_sink_events_dropped_total",
+				Help: "Total number of events dropped because the queue of a sink was full",
+			},
+			[]string{"sink"},
+		),
+		serverCircuitOpen: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_server_circuit_open",
+				Help: "Whether the server is skipped after repeated failures (1) or not (0)",
+			},
+			[]string{"server"},
+		),
+		eventsMalformed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_events_malformed_total",
+				Help: "Total number of event payloads rejected as malformed",
+			},
+			[]string{"server"},
+		),
+		eventSchemaViolations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_event_schema_violations_total",
+				Help: "Total number of event payloads not matching the Redfish Event schema",
+			},
+			[]string{"server"},
+		),
+		serverDegraded: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "redfish_server_degraded",
+				Help: "Whether the server's subscription checks keep failing (1) or not (0)",
+			},
+			[]string{"server"},
+		),
+		eventDeliveryLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "redfish_event_delivery_latency_seconds",
+				Help:    "Time between an event's EventTimestamp and its receipt",
+				Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 120, 300},
+			},
+			[]string{"server"},
+		),
+		eventClockSkew: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_event_clock_skew_total",
+				Help: "Total number of events received with an EventTimestamp in the future",
+			},
+			[]string{"server"},
+		),
+		incidents: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_incidents_total",
+				Help: "Total number of incidents correlated from related events",
+			},
+			[]string{"server", "type"},
+		),
+		subscriptionRollback: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_subscription_rollback_total",
+				Help: "Total number of subscriptions rolled back after a failed create, by whether the deletion succeeded",
+			},
+			[]string{"result"},
+		),
+		jobPreemptions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "redfish_job_preemptions_total",
+				Help: "Total number of slurm jobs preempted for hardware faults of the server",
+			},
+			[]string{"server"},
+		),
+	}
+}
 
-func init() {
-	// Register the counter with Prometheus's default registry
-	prometheus.MustRegister(eventCountMetric)
-	// Register the gauge with Prometheus's default registry
-	prometheus.MustRegister(eventProcessingTimeMetric)
+// Register the metrics with the registerer, failing when some are already
+// registered
+func (m *Metrics) Register(registerer prometheus.Registerer) error {
+	collectors := []prometheus.Collector{
+		m.eventCount,
+		m.eventProcessingTime,
+		m.redfishEvents,
+		m.connectErrors,
+		m.reconcileCreated,
+		m.reconcileRecreated,
+		m.reconcileNoop,
+		m.reconcileUpdated,
+		m.sseReconnects,
+		m.ntpEnabled,
+		m.ntpServers,
+		m.amdGPUHBMErrors,
+		m.amdGPUXGMILinkStatus,
+		m.amdGPUInfo,
+		m.amdXGMILinkHealth,
+		m.amdXGMILinkSpeed,
+		m.memoryErrorTrend,
+		m.systemHealth,
+		m.managerHealth,
+		m.amdXGMILinkWidth,
+		m.eventsRateLimited,
+		m.sinkEventsDropped,
+		m.serverCircuitOpen,
+		m.eventsMalformed,
+		m.eventSchemaViolations,
+		m.serverDegraded,
+		m.eventDeliveryLatency,
+		m.eventClockSkew,
+		m.incidents,
+		m.subscriptionRollback,
+		m.jobPreemptions,
+	}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
 }
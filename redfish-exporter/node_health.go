@@ -0,0 +1,343 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+// HealthThresholds sets when a subsystem fails the node health check. A
+// component reporting Critical health always fails it.
+type HealthThresholds struct {
+	// Uncorrected HBM errors a GPU may have
+	MaxHBMUncorrectedErrors int64 `json:"maxHbmUncorrectedErrors"`
+	// Highest reading of any temperature sensor, the sensors' own critical
+	// thresholds when zero
+	MaxTemperatureCelsius float64 `json:"maxTemperatureCelsius"`
+	// Highest power draw of a chassis, unlimited when zero
+	MaxPowerWatts float64 `json:"maxPowerWatts"`
+	// Power supplies of a chassis that must be healthy, not checked when zero
+	MinPowerSupplies int `json:"minPowerSupplies"`
+	// Also fail on drives predicted to fail, not only on failed ones
+	FailOnPredictedDriveFailure bool `json:"failOnPredictedDriveFailure"`
+}
+
+func (t HealthThresholds) validate() error {
+	if t.MaxHBMUncorrectedErrors < 0 || t.MaxTemperatureCelsius < 0 || t.MaxPowerWatts < 0 || t.MinPowerSupplies < 0 {
+		return errors.New("healthThresholds can't be negative")
+	}
+	return nil
+}
+
+// HealthChecker reads one subsystem of a server and returns why it fails
+// the thresholds, nothing when it is healthy
+type HealthChecker interface {
+	Name() string
+	CheckHealth(ctx context.Context, server RedfishServer, thresholds HealthThresholds) ([]string, error)
+}
+
+func (g *GPUHealthCollector) Name() string {
+	return "gpu"
+}
+
+// Check the health, HBM errors and XGMI links of the server's GPUs
+func (g *GPUHealthCollector) CheckHealth(ctx context.Context, server RedfishServer, thresholds HealthThresholds) ([]string, error) {
+	gpus, err := readGPUHealth(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	var reasons []string
+	for _, gpu := range gpus {
+		if gpu.health == common.CriticalHealth {
+			reasons = append(reasons, fmt.Sprintf("GPU %s health is Critical", gpu.id))
+		}
+		if gpu.amd == nil {
+			continue
+		}
+		if gpu.amd.HBMUncorrectedErrors > thresholds.MaxHBMUncorrectedErrors {
+			reasons = append(reasons, fmt.Sprintf("GPU %s has %d uncorrected HBM errors", gpu.id, gpu.amd.HBMUncorrectedErrors))
+		}
+		if gpu.amd.XGMILinkStatus != "" && !xgmiLinkUp(gpu.amd.XGMILinkStatus) {
+			reasons = append(reasons, fmt.Sprintf("GPU %s XGMI links are %s", gpu.id, gpu.amd.XGMILinkStatus))
+		}
+	}
+	return reasons, nil
+}
+
+// ThermalCollector reads the temperature sensors of the servers' chassis
+type ThermalCollector struct{}
+
+func NewThermalCollector() *ThermalCollector {
+	return &ThermalCollector{}
+}
+
+func (t *ThermalCollector) Name() string {
+	return "thermal"
+}
+
+// Check the temperatures against the sensors' critical thresholds, or
+// MaxTemperatureCelsius when set
+func (t *ThermalCollector) CheckHealth(ctx context.Context, server RedfishServer, thresholds HealthThresholds) ([]string, error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	chassis, err := c.Service.Chassis()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chassis on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	var reasons []string
+	for _, ch := range chassis {
+		thermal, err := ch.Thermal()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get thermal of %s on server %s: %w", ch.ODataID, server.IP, normalizeRedfishError(err))
+		}
+		if thermal == nil {
+			continue
+		}
+		for _, sensor := range thermal.Temperatures {
+			limit := float64(sensor.UpperThresholdCritical)
+			if thresholds.MaxTemperatureCelsius > 0 {
+				limit = thresholds.MaxTemperatureCelsius
+			}
+			switch {
+			case sensor.Status.Health == common.CriticalHealth:
+				reasons = append(reasons, fmt.Sprintf("temperature sensor %s health is Critical", sensor.Name))
+			case limit > 0 && float64(sensor.ReadingCelsius) >= limit:
+				reasons = append(reasons, fmt.Sprintf("temperature sensor %s reads %.1f°C, limit %.1f°C", sensor.Name, sensor.ReadingCelsius, limit))
+			}
+		}
+	}
+	return reasons, nil
+}
+
+// PowerCollector reads the power draw and power supplies of the servers'
+// chassis
+type PowerCollector struct{}
+
+func NewPowerCollector() *PowerCollector {
+	return &PowerCollector{}
+}
+
+func (p *PowerCollector) Name() string {
+	return "power"
+}
+
+// Check the power supplies and the power draw of every chassis
+func (p *PowerCollector) CheckHealth(ctx context.Context, server RedfishServer, thresholds HealthThresholds) ([]string, error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	chassis, err := c.Service.Chassis()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chassis on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	var reasons []string
+	for _, ch := range chassis {
+		power, err := ch.Power()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get power of %s on server %s: %w", ch.ODataID, server.IP, normalizeRedfishError(err))
+		}
+		if power == nil {
+			continue
+		}
+		healthy := 0
+		for _, supply := range power.PowerSupplies {
+			switch {
+			case supply.Status.State == common.AbsentState:
+			case supply.Status.Health == common.CriticalHealth:
+				reasons = append(reasons, fmt.Sprintf("power supply %s health is Critical", supply.Name))
+			default:
+				healthy++
+			}
+		}
+		if thresholds.MinPowerSupplies > 0 && len(power.PowerSupplies) > 0 && healthy < thresholds.MinPowerSupplies {
+			reasons = append(reasons, fmt.Sprintf("chassis %s has %d healthy power supplies, %d required", ch.ID, healthy, thresholds.MinPowerSupplies))
+		}
+		for _, control := range power.PowerControl {
+			if thresholds.MaxPowerWatts > 0 && float64(control.PowerConsumedWatts) > thresholds.MaxPowerWatts {
+				reasons = append(reasons, fmt.Sprintf("chassis %s draws %.0fW, limit %.0fW", ch.ID, control.PowerConsumedWatts, thresholds.MaxPowerWatts))
+			}
+		}
+	}
+	return reasons, nil
+}
+
+// StorageCollector reads the drives of the servers' systems
+type StorageCollector struct{}
+
+func NewStorageCollector() *StorageCollector {
+	return &StorageCollector{}
+}
+
+func (s *StorageCollector) Name() string {
+	return "storage"
+}
+
+// Check the health of the drives, and their failure prediction when
+// FailOnPredictedDriveFailure is set
+func (s *StorageCollector) CheckHealth(ctx context.Context, server RedfishServer, thresholds HealthThresholds) ([]string, error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	systems, err := c.Service.Systems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systems on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	var reasons []string
+	for _, system := range systems {
+		storage, err := system.Storage()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get storage of %s on server %s: %w", system.ODataID, server.IP, normalizeRedfishError(err))
+		}
+		for _, controller := range storage {
+			drives, err := controller.Drives()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get drives of %s on server %s: %w", controller.ODataID, server.IP, normalizeRedfishError(err))
+			}
+			for _, drive := range drives {
+				switch {
+				case drive.Status.Health == common.CriticalHealth:
+					reasons = append(reasons, fmt.Sprintf("drive %s health is Critical", drive.ID))
+				case drive.FailurePredicted && thresholds.FailOnPredictedDriveFailure:
+					reasons = append(reasons, fmt.Sprintf("drive %s is predicted to fail", drive.ID))
+				}
+			}
+		}
+	}
+	return reasons, nil
+}
+
+// NodeHealth is the health of a Slurm node's server
+type NodeHealth struct {
+	Healthy bool `json:"healthy"`
+	// Why the node is unhealthy, empty when it is healthy
+	Reasons []string `json:"reasons"`
+	// Subsystems that couldn't be checked, they don't make the node unhealthy
+	Errors      []string  `json:"errors,omitempty"`
+	LastChecked time.Time `json:"last_checked"`
+}
+
+// NodeHealthAPI serves the health of Slurm nodes from their servers' GPUs,
+// temperatures, power and storage, for the Slurm prolog to refuse jobs on
+// faulty nodes. A subsystem that can't be read doesn't fail the check, so
+// an unreachable BMC doesn't drain the node.
+type NodeHealthAPI struct {
+	servers    []RedfishServer
+	thresholds HealthThresholds
+	checkers   []HealthChecker
+}
+
+func NewNodeHealthAPI(servers []RedfishServer, thresholds HealthThresholds) *NodeHealthAPI {
+	return &NodeHealthAPI{
+		servers:    servers,
+		thresholds: thresholds,
+		checkers: []HealthChecker{
+			NewGPUHealthCollector(servers),
+			NewThermalCollector(),
+			NewPowerCollector(),
+			NewStorageCollector(),
+		},
+	}
+}
+
+// Check all subsystems of the server of a Slurm node in parallel
+func (n *NodeHealthAPI) Check(ctx context.Context, slurmNode string) (*NodeHealth, error) {
+	var server RedfishServer
+	for _, candidate := range n.servers {
+		if candidate.SlurmNode == slurmNode {
+			server = candidate
+			break
+		}
+	}
+	if server.IP == "" {
+		return nil, fmt.Errorf("%w: no server for slurm node %s", ErrServerNotFound, slurmNode)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	health := &NodeHealth{Reasons: []string{}}
+	for _, checker := range n.checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reasons, err := checker.CheckHealth(ctx, server, n.thresholds)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("Failed to check %s health of slurm node %s: %v", checker.Name(), slurmNode, err)
+				health.Errors = append(health.Errors, fmt.Sprintf("%s: %v", checker.Name(), err))
+				return
+			}
+			for _, reason := range reasons {
+				health.Reasons = append(health.Reasons, checker.Name()+": "+reason)
+			}
+		}()
+	}
+	wg.Wait()
+	health.Healthy = len(health.Reasons) == 0
+	health.LastChecked = time.Now()
+	return health, nil
+}
+
+// Serve GET /node-health/{slurmNode}
+func (n *NodeHealthAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	slurmNode := strings.TrimPrefix(r.URL.Path, "/node-health/")
+	if slurmNode == "" || strings.Contains(slurmNode, "/") {
+		http.Error(w, "expected /node-health/{slurmNode}", http.StatusNotFound)
+		return
+	}
+
+	health, err := n.Check(r.Context(), slurmNode)
+	if errors.Is(err, ErrServerNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !health.Healthy {
+		log.Printf("Slurm node %s is unhealthy: %s", slurmNode, strings.Join(health.Reasons, "; "))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		log.Printf("Failed to write node health response: %v", err)
+	}
+}
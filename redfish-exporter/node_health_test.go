@@ -0,0 +1,293 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func link(uri string) map[string]string {
+	return map[string]string{"@odata.id": uri}
+}
+
+// Serve a chassis with the Thermal and Power resources, nil ones left out
+func addChassis(bmc *fakeBMC, thermal, power map[string]interface{}) {
+	bmc.root["Chassis"] = link("/redfish/v1/Chassis")
+	chassis := map[string]interface{}{"Id": "1"}
+	if thermal != nil {
+		chassis["Thermal"] = link("/redfish/v1/Chassis/1/Thermal")
+		bmc.resources["/redfish/v1/Chassis/1/Thermal"] = thermal
+	}
+	if power != nil {
+		chassis["Power"] = link("/redfish/v1/Chassis/1/Power")
+		bmc.resources["/redfish/v1/Chassis/1/Power"] = power
+	}
+	bmc.addCollection("/redfish/v1/Chassis", chassis)
+}
+
+// Serve a storage controller with the drives in the fake's system
+func addDrives(bmc *fakeBMC, drives ...map[string]interface{}) {
+	bmc.addSystem(map[string]interface{}{"Storage": link("/redfish/v1/Systems/1/Storage")})
+	var links []map[string]string
+	for _, drive := range drives {
+		uri := "/redfish/v1/Systems/1/Storage/1/Drives/" + drive["Id"].(string)
+		bmc.resources[uri] = drive
+		links = append(links, link(uri))
+	}
+	bmc.addCollection("/redfish/v1/Systems/1/Storage", map[string]interface{}{"Id": "1", "Drives": links})
+}
+
+func temperature(name string, reading, critical float64, health string) map[string]interface{} {
+	return map[string]interface{}{
+		"Name":                   name,
+		"ReadingCelsius":         reading,
+		"UpperThresholdCritical": critical,
+		"Status":                 map[string]string{"State": "Enabled", "Health": health},
+	}
+}
+
+func powerSupply(name, state, health string) map[string]interface{} {
+	return map[string]interface{}{"Name": name, "Status": map[string]string{"State": state, "Health": health}}
+}
+
+func drive(id, health string, failurePredicted bool) map[string]interface{} {
+	return map[string]interface{}{"Id": id, "FailurePredicted": failurePredicted, "Status": map[string]string{"State": "Enabled", "Health": health}}
+}
+
+func TestHealthThresholdsValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds HealthThresholds
+		wantErr    bool
+	}{
+		{"zero", HealthThresholds{}, false},
+		{"set", HealthThresholds{MaxHBMUncorrectedErrors: 1, MaxTemperatureCelsius: 85, MaxPowerWatts: 3000, MinPowerSupplies: 2}, false},
+		{"negative temperature", HealthThresholds{MaxTemperatureCelsius: -1}, true},
+		{"negative power supplies", HealthThresholds{MinPowerSupplies: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.thresholds.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHealthCheckers(t *testing.T) {
+	tests := []struct {
+		name        string
+		checker     HealthChecker
+		setup       func(bmc *fakeBMC)
+		thresholds  HealthThresholds
+		wantReasons []string
+	}{
+		{
+			name:    "GPUs healthy",
+			checker: NewGPUHealthCollector(nil),
+			setup: func(bmc *fakeBMC) {
+				addProcessors(bmc, amdGPU("GPU0", map[string]interface{}{"HBMUncorrectedErrors": 1, "XGMILinkStatus": "Up"}))
+			},
+			thresholds: HealthThresholds{MaxHBMUncorrectedErrors: 1},
+		},
+		{
+			name:    "GPU errors and links down",
+			checker: NewGPUHealthCollector(nil),
+			setup: func(bmc *fakeBMC) {
+				gpu := amdGPU("GPU0", map[string]interface{}{"HBMUncorrectedErrors": 2, "XGMILinkStatus": "Down"})
+				gpu["Status"] = map[string]string{"State": "Enabled", "Health": "Critical"}
+				addProcessors(bmc, gpu)
+			},
+			thresholds: HealthThresholds{MaxHBMUncorrectedErrors: 1},
+			wantReasons: []string{
+				"GPU GPU0 health is Critical",
+				"GPU GPU0 has 2 uncorrected HBM errors",
+				"GPU GPU0 XGMI links are Down",
+			},
+		},
+		{
+			name:    "temperatures below the sensors' thresholds",
+			checker: NewThermalCollector(),
+			setup: func(bmc *fakeBMC) {
+				addChassis(bmc, map[string]interface{}{"Temperatures": []interface{}{temperature("CPU0", 70, 95, "OK")}}, nil)
+			},
+		},
+		{
+			name:    "temperature over the sensor's threshold",
+			checker: NewThermalCollector(),
+			setup: func(bmc *fakeBMC) {
+				addChassis(bmc, map[string]interface{}{"Temperatures": []interface{}{
+					temperature("CPU0", 96, 95, "OK"),
+					temperature("Inlet", 20, 0, "Critical"),
+				}}, nil)
+			},
+			wantReasons: []string{
+				"temperature sensor CPU0 reads 96.0°C, limit 95.0°C",
+				"temperature sensor Inlet health is Critical",
+			},
+		},
+		{
+			name:    "temperature over the configured limit",
+			checker: NewThermalCollector(),
+			setup: func(bmc *fakeBMC) {
+				addChassis(bmc, map[string]interface{}{"Temperatures": []interface{}{temperature("CPU0", 85, 95, "OK")}}, nil)
+			},
+			thresholds:  HealthThresholds{MaxTemperatureCelsius: 80},
+			wantReasons: []string{"temperature sensor CPU0 reads 85.0°C, limit 80.0°C"},
+		},
+		{
+			name:    "chassis without thermal",
+			checker: NewThermalCollector(),
+			setup:   func(bmc *fakeBMC) { addChassis(bmc, nil, nil) },
+		},
+		{
+			name:    "power supplies healthy",
+			checker: NewPowerCollector(),
+			setup: func(bmc *fakeBMC) {
+				addChassis(bmc, nil, map[string]interface{}{
+					"PowerSupplies": []interface{}{powerSupply("PSU0", "Enabled", "OK"), powerSupply("PSU1", "Enabled", "OK")},
+					"PowerControl":  []interface{}{map[string]interface{}{"PowerConsumedWatts": 2500}},
+				})
+			},
+			thresholds: HealthThresholds{MinPowerSupplies: 2, MaxPowerWatts: 3000},
+		},
+		{
+			name:    "power supply failed and over the power limit",
+			checker: NewPowerCollector(),
+			setup: func(bmc *fakeBMC) {
+				addChassis(bmc, nil, map[string]interface{}{
+					"PowerSupplies": []interface{}{
+						powerSupply("PSU0", "Enabled", "OK"),
+						powerSupply("PSU1", "Enabled", "Critical"),
+						powerSupply("PSU2", "Absent", ""),
+					},
+					"PowerControl": []interface{}{map[string]interface{}{"PowerConsumedWatts": 3200}},
+				})
+			},
+			thresholds: HealthThresholds{MinPowerSupplies: 2, MaxPowerWatts: 3000},
+			wantReasons: []string{
+				"power supply PSU1 health is Critical",
+				"chassis 1 has 1 healthy power supplies, 2 required",
+				"chassis 1 draws 3200W, limit 3000W",
+			},
+		},
+		{
+			name:    "drive predicted to fail, not failing the check",
+			checker: NewStorageCollector(),
+			setup: func(bmc *fakeBMC) {
+				addDrives(bmc, drive("Disk0", "OK", true))
+			},
+		},
+		{
+			name:    "failed drive and drive predicted to fail",
+			checker: NewStorageCollector(),
+			setup: func(bmc *fakeBMC) {
+				addDrives(bmc, drive("Disk0", "Critical", false), drive("Disk1", "OK", true), drive("Disk2", "OK", false))
+			},
+			thresholds: HealthThresholds{FailOnPredictedDriveFailure: true},
+			wantReasons: []string{
+				"drive Disk0 health is Critical",
+				"drive Disk1 is predicted to fail",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			tt.setup(bmc)
+
+			reasons, err := tt.checker.CheckHealth(context.Background(), bmc.server(), tt.thresholds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Collection members are read in parallel
+			slices.Sort(reasons)
+			wantReasons := slices.Sorted(slices.Values(tt.wantReasons))
+			if !slices.Equal(reasons, wantReasons) {
+				t.Errorf("reasons = %q, want %q", reasons, wantReasons)
+			}
+			if bmc.openSessions() != 0 {
+				t.Errorf("%d sessions left open", bmc.openSessions())
+			}
+		})
+	}
+}
+
+func TestNodeHealthAPI(t *testing.T) {
+	healthy := newFakeBMC(t)
+	addProcessors(healthy, amdGPU("GPU0", map[string]interface{}{"XGMILinkStatus": "Up"}))
+	addChassis(healthy, map[string]interface{}{"Temperatures": []interface{}{temperature("CPU0", 60, 95, "OK")}}, nil)
+
+	unhealthy := newFakeBMC(t)
+	addChassis(unhealthy, map[string]interface{}{"Temperatures": []interface{}{temperature("CPU0", 99, 95, "OK")}}, nil)
+
+	unreachable := newFakeBMC(t)
+	unreachable.failures["POST /redfish/v1/SessionService/Sessions"] = 503
+
+	// Only the first server has a system, the GPU and storage checks of the
+	// second fail without making the node unhealthy
+	servers := []RedfishServer{healthy.server(), unhealthy.server(), unreachable.server()}
+	for i, node := range []string{"gpu-001", "gpu-002", "gpu-003"} {
+		servers[i].SlurmNode = node
+	}
+	api := NewNodeHealthAPI(servers, HealthThresholds{})
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantStatus  int
+		wantHealthy bool
+		wantReasons []string
+		wantErrors  int
+	}{
+		{"healthy node", http.MethodGet, "/node-health/gpu-001", http.StatusOK, true, []string{}, 0},
+		{"unhealthy node", http.MethodGet, "/node-health/gpu-002", http.StatusOK, false, []string{"thermal: temperature sensor CPU0 reads 99.0°C, limit 95.0°C"}, 2},
+		{"unreachable BMC stays healthy", http.MethodGet, "/node-health/gpu-003", http.StatusOK, true, []string{}, 4},
+		{"unknown node", http.MethodGet, "/node-health/gpu-009", http.StatusNotFound, false, nil, 0},
+		{"no node", http.MethodGet, "/node-health/", http.StatusNotFound, false, nil, 0},
+		{"wrong method", http.MethodPost, "/node-health/gpu-001", http.StatusMethodNotAllowed, false, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			api.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var health NodeHealth
+			if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+				t.Fatal(err)
+			}
+			if health.Healthy != tt.wantHealthy || !slices.Equal(health.Reasons, tt.wantReasons) || len(health.Errors) != tt.wantErrors {
+				t.Errorf("health = %+v, want healthy %v, reasons %q and %d errors", health, tt.wantHealthy, tt.wantReasons, tt.wantErrors)
+			}
+			if health.LastChecked.IsZero() {
+				t.Error("last_checked not set")
+			}
+		})
+	}
+}
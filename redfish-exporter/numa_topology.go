@@ -0,0 +1,207 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// NUMATopology is the CPU sockets of a server's first system with the
+// memory local to each, for placing jobs next to their memory
+type NUMATopology struct {
+	ServerIP    string       `json:"serverIP"`
+	System      string       `json:"system"`
+	Sockets     []NUMASocket `json:"sockets"`
+	CollectedAt time.Time    `json:"collectedAt"`
+}
+
+// NUMASocket is a CPU socket and the DIMMs installed in it
+type NUMASocket struct {
+	Socket    int      `json:"socket"`
+	CPUs      []string `json:"cpus"`
+	Cores     int      `json:"cores"`
+	Threads   int      `json:"threads"`
+	DIMMs     []string `json:"dimms"`
+	MemoryMiB int      `json:"memoryMiB"`
+}
+
+func (t *NUMATopology) ToJSON() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// Get or add the socket with the number, keeping them sorted
+func (t *NUMATopology) socket(number int) *NUMASocket {
+	i, found := slices.BinarySearchFunc(t.Sockets, number, func(s NUMASocket, n int) int {
+		return s.Socket - n
+	})
+	if !found {
+		t.Sockets = slices.Insert(t.Sockets, i, NUMASocket{Socket: number, CPUs: []string{}, DIMMs: []string{}})
+	}
+	return &t.Sockets[i]
+}
+
+// NUMATopologyCollector reads the NUMA topology of servers on request
+type NUMATopologyCollector struct {
+	servers []RedfishServer
+}
+
+func NewNUMATopologyCollector(servers []RedfishServer) *NUMATopologyCollector {
+	return &NUMATopologyCollector{servers: servers}
+}
+
+// Find a server by its IP, name or host, as the IPs are URLs
+func (n *NUMATopologyCollector) server(serverID string) (RedfishServer, bool) {
+	if server := getServerInfo(n.servers, serverID); server.IP != "" {
+		return server, true
+	}
+	for _, server := range n.servers {
+		if serverHost(server) == serverID {
+			return server, true
+		}
+	}
+	return RedfishServer{}, false
+}
+
+// Read the topology of the server with the IP, name or host
+func (n *NUMATopologyCollector) Collect(ctx context.Context, serverID string) (*NUMATopology, error) {
+	server, ok := n.server(serverID)
+	if !ok {
+		return nil, ErrServerNotFound
+	}
+
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	systems, err := c.Service.Systems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systems on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	if len(systems) == 0 {
+		return nil, fmt.Errorf("no systems found on server %s", server.IP)
+	}
+	system := systems[0]
+
+	processors, err := system.Processors()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get processors on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	memory, err := system.Memory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	return buildNUMATopology(server, system.ID, processors, memory), nil
+}
+
+// Group the CPUs by the number in their Socket label and the DIMMs by the
+// Socket of their MemoryLocation
+func buildNUMATopology(server RedfishServer, systemID string, processors []*redfish.Processor, memory []*redfish.Memory) *NUMATopology {
+	topology := &NUMATopology{ServerIP: server.ID(), System: systemID, Sockets: []NUMASocket{}, CollectedAt: time.Now()}
+	// gofish reads the members of a collection in parallel, they come in
+	// no particular order
+	processors = slices.SortedFunc(slices.Values(processors), func(a, b *redfish.Processor) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	memory = slices.SortedFunc(slices.Values(memory), func(a, b *redfish.Memory) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	cpus := 0
+	for _, processor := range processors {
+		if processor.ProcessorType != redfish.CPUProcessorType {
+			continue
+		}
+		number, ok := socketNumber(processor.Socket)
+		if !ok {
+			// Without a number the CPUs are numbered in the order of their IDs
+			number = cpus
+		}
+		cpus++
+		socket := topology.socket(number)
+		socket.CPUs = append(socket.CPUs, processor.ID)
+		socket.Cores += processor.TotalCores
+		socket.Threads += processor.TotalThreads
+	}
+	for _, dimm := range memory {
+		if dimm.CapacityMiB == 0 {
+			// An empty slot
+			continue
+		}
+		socket := topology.socket(dimm.MemoryLocation.Socket)
+		socket.DIMMs = append(socket.DIMMs, dimm.ID)
+		socket.MemoryMiB += dimm.CapacityMiB
+	}
+	return topology
+}
+
+// The number at the end of a socket label such as "CPU 1" or "P0"
+func socketNumber(label string) (int, bool) {
+	end := len(label)
+	start := strings.LastIndexFunc(label, func(r rune) bool { return r < '0' || r > '9' }) + 1
+	if start == end {
+		return 0, false
+	}
+	number, err := strconv.Atoi(label[start:end])
+	return number, err == nil
+}
+
+// Serves GET /topology/{serverIP}, the server given by its host, e.g.
+// /topology/10.0.0.1 for https://10.0.0.1, or by its name
+func topologyHandler(collector *NUMATopologyCollector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		serverID := strings.TrimPrefix(r.URL.Path, "/topology/")
+		if serverID == "" || strings.Contains(serverID, "/") {
+			http.Error(w, "expected /topology/{serverIP}", http.StatusNotFound)
+			return
+		}
+
+		topology, err := collector.Collect(r.Context(), serverID)
+		if errors.Is(err, ErrServerNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		data, err := topology.ToJSON()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(data); err != nil {
+			log.Printf("Failed to write topology response: %v", err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// Serve a system with the processors and DIMMs
+func addNUMASystem(bmc *fakeBMC, processors, memory []map[string]interface{}) {
+	bmc.addSystem(map[string]interface{}{
+		"Processors": map[string]string{"@odata.id": "/redfish/v1/Systems/1/Processors"},
+		"Memory":     map[string]string{"@odata.id": "/redfish/v1/Systems/1/Memory"},
+	})
+	bmc.addCollection("/redfish/v1/Systems/1/Processors", processors...)
+	bmc.addCollection("/redfish/v1/Systems/1/Memory", memory...)
+}
+
+func dimm(id string, socket, capacityMiB int) map[string]interface{} {
+	return map[string]interface{}{
+		"Id":             id,
+		"CapacityMiB":    capacityMiB,
+		"MemoryLocation": map[string]int{"Socket": socket},
+	}
+}
+
+func TestSocketNumber(t *testing.T) {
+	tests := []struct {
+		label  string
+		want   int
+		wantOK bool
+	}{
+		{"CPU 1", 1, true},
+		{"P0", 0, true},
+		{"Socket12", 12, true},
+		{"3", 3, true},
+		{"CPU", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := socketNumber(tt.label)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("socketNumber(%q) = %d, %v, want %d, %v", tt.label, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestTopologyHandler(t *testing.T) {
+	bmc := newFakeBMC(t)
+	addNUMASystem(bmc,
+		[]map[string]interface{}{
+			{"Id": "CPU0", "ProcessorType": "CPU", "Socket": "P0", "TotalCores": 64, "TotalThreads": 128},
+			{"Id": "CPU1", "ProcessorType": "CPU", "Socket": "P1", "TotalCores": 64, "TotalThreads": 128},
+			{"Id": "GPU0", "ProcessorType": "GPU", "Socket": "OAM0"},
+		},
+		[]map[string]interface{}{
+			dimm("DIMM0", 0, 65536),
+			dimm("DIMM1", 1, 65536),
+			dimm("DIMM2", 1, 32768),
+			dimm("DIMM3", 0, 0),
+		},
+	)
+	broken := newFakeBMC(t)
+	broken.failures["GET /redfish/v1/Systems"] = 500
+
+	server := bmc.server()
+	server.Name = "node1"
+	brokenServer := broken.server()
+	brokenServer.Name = "node2"
+	handler := topologyHandler(NewNUMATopologyCollector([]RedfishServer{server, brokenServer}))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"by name", http.MethodGet, "/topology/node1", http.StatusOK},
+		{"unknown server", http.MethodGet, "/topology/node9", http.StatusNotFound},
+		{"no server", http.MethodGet, "/topology/", http.StatusNotFound},
+		{"nested path", http.MethodGet, "/topology/node1/sockets", http.StatusNotFound},
+		{"BMC failure", http.MethodGet, "/topology/node2", http.StatusBadGateway},
+		{"wrong method", http.MethodPost, "/topology/node1", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var topology NUMATopology
+			if err := json.Unmarshal(rec.Body.Bytes(), &topology); err != nil {
+				t.Fatal(err)
+			}
+			want := []NUMASocket{
+				{Socket: 0, CPUs: []string{"CPU0"}, Cores: 64, Threads: 128, DIMMs: []string{"DIMM0"}, MemoryMiB: 65536},
+				{Socket: 1, CPUs: []string{"CPU1"}, Cores: 64, Threads: 128, DIMMs: []string{"DIMM1", "DIMM2"}, MemoryMiB: 98304},
+			}
+			if topology.ServerIP != "node1" || topology.System != "1" || !slices.EqualFunc(topology.Sockets, want, numaSocketsEqual) {
+				t.Errorf("topology = %+v, want sockets %+v", topology, want)
+			}
+		})
+	}
+}
+
+func TestBuildNUMATopologyUnlabeledSockets(t *testing.T) {
+	bmc := newFakeBMC(t)
+	addNUMASystem(bmc,
+		[]map[string]interface{}{
+			{"Id": "CPU0", "ProcessorType": "CPU", "TotalCores": 32},
+			{"Id": "CPU1", "ProcessorType": "CPU", "TotalCores": 32},
+		},
+		[]map[string]interface{}{dimm("DIMM0", 1, 16384)},
+	)
+
+	topology, err := NewNUMATopologyCollector([]RedfishServer{bmc.server()}).Collect(context.Background(), bmc.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topology.Sockets) != 2 || topology.Sockets[1].CPUs[0] != "CPU1" || topology.Sockets[1].DIMMs[0] != "DIMM0" {
+		t.Errorf("sockets = %+v, want the CPUs numbered in order", topology.Sockets)
+	}
+}
+
+func numaSocketsEqual(a, b NUMASocket) bool {
+	return a.Socket == b.Socket && a.Cores == b.Cores && a.Threads == b.Threads && a.MemoryMiB == b.MemoryMiB &&
+		slices.Equal(a.CPUs, b.CPUs) && slices.Equal(a.DIMMs, b.DIMMs)
+}
@@ -0,0 +1,208 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginType of the servers authenticated with an OAuth2 bearer token
+// instead of a username and password
+const LoginTypeOAuth = "oauth"
+
+// Tokens are refreshed this long before they expire
+const tokenExpiryMargin = 30 * time.Second
+
+// Returned when a server logs in with OAuth but no token source is set
+var ErrNoTokenSource = errors.New("no OAuth token source configured")
+
+// Whether the server authenticates with a bearer token
+func isOAuthLogin(server RedfishServer) bool {
+	return strings.EqualFold(server.LoginType, LoginTypeOAuth)
+}
+
+// BearerToken is an access token and when it expires, zero for never
+type BearerToken struct {
+	AccessToken string
+	Expiry      time.Time
+}
+
+func (t *BearerToken) valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(tokenExpiryMargin).Before(t.Expiry))
+}
+
+// TokenSource hands out the bearer tokens of the OAuth servers, e.g. from
+// an identity provider
+type TokenSource interface {
+	Token(ctx context.Context) (*BearerToken, error)
+}
+
+// OAuthConfig is an OAuth2 client credentials grant against the identity
+// provider's token endpoint
+type OAuthConfig struct {
+	TokenURL     string   `json:"tokenURL"`
+	ClientID     string   `json:"clientID"`
+	ClientSecret string   `json:"clientSecret"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// ClientCredentialsTokenSource gets tokens with the OAuth2 client
+// credentials grant
+type ClientCredentialsTokenSource struct {
+	Config OAuthConfig
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+func (s *ClientCredentialsTokenSource) Token(ctx context.Context) (*BearerToken, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.Config.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.Config.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(s.Config.ClientID), url.QueryEscape(s.Config.ClientSecret))
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OAuth token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to get OAuth token: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode OAuth token: %v", err)
+	}
+	if tokenResponse.AccessToken == "" {
+		return nil, errors.New("failed to get OAuth token: empty access_token")
+	}
+	if tokenResponse.TokenType != "" && !strings.EqualFold(tokenResponse.TokenType, "bearer") {
+		return nil, fmt.Errorf("failed to get OAuth token: unsupported token_type %q", tokenResponse.TokenType)
+	}
+
+	token := &BearerToken{AccessToken: tokenResponse.AccessToken}
+	if tokenResponse.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// cachedTokenSource keeps the token of a source until it is about to
+// expire or a server rejects it
+type cachedTokenSource struct {
+	source TokenSource
+	mu     sync.Mutex
+	token  *BearerToken
+}
+
+func newCachedTokenSource(source TokenSource) *cachedTokenSource {
+	return &cachedTokenSource{source: source}
+}
+
+func (s *cachedTokenSource) Token(ctx context.Context) (*BearerToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.valid(time.Now()) {
+		return s.token, nil
+	}
+	token, err := s.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+// Drop the token if it is still the cached one, the next request gets a
+// new one
+func (s *cachedTokenSource) invalidate(token *BearerToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = nil
+	}
+}
+
+// bearerTransport sends a bearer token with every request. A request
+// rejected with 401 is sent once more with a new token.
+type bearerTransport struct {
+	base   http.RoundTripper
+	tokens *cachedTokenSource
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.tokens == nil {
+		return nil, ErrNoTokenSource
+	}
+	token, err := t.tokens.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.send(req, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// The body was used up and can't be sent again
+		return resp, nil
+	}
+
+	// The token expired early or was revoked
+	t.tokens.invalidate(token)
+	token, err = t.tokens.Token(req.Context())
+	if err != nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.send(retry, token)
+}
+
+func (t *bearerTransport) send(req *http.Request, token *BearerToken) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,156 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientCredentialsTokenSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		response   string
+		wantToken  string
+		wantExpiry bool
+		wantErr    bool
+	}{
+		{"token with expiry", http.StatusOK, `{"access_token": "abc", "token_type": "Bearer", "expires_in": 3600}`, "abc", true, false},
+		{"token without expiry", http.StatusOK, `{"access_token": "abc"}`, "abc", false, false},
+		{"rejected credentials", http.StatusUnauthorized, `{"error": "invalid_client"}`, "", false, true},
+		{"empty token", http.StatusOK, `{"access_token": ""}`, "", false, true},
+		{"unsupported token type", http.StatusOK, `{"access_token": "abc", "token_type": "mac"}`, "", false, true},
+		{"malformed response", http.StatusOK, `{"access_token":`, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, password, ok := r.BasicAuth()
+				if r.Method != http.MethodPost || !ok || user != "exporter" || password != "s%3Acret" ||
+					r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "redfish.read redfish.write" {
+					http.Error(w, "unexpected token request", http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.response)
+			}))
+			defer provider.Close()
+
+			source := &ClientCredentialsTokenSource{Config: OAuthConfig{
+				TokenURL:     provider.URL,
+				ClientID:     "exporter",
+				ClientSecret: "s:cret",
+				Scopes:       []string{"redfish.read", "redfish.write"},
+			}}
+			token, err := source.Token(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Token() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if token.AccessToken != tt.wantToken || token.Expiry.IsZero() == tt.wantExpiry {
+				t.Errorf("Token() = %+v, want %s with expiry %v", token, tt.wantToken, tt.wantExpiry)
+			}
+		})
+	}
+}
+
+// Hands out token-1, token-2, ... counting the tokens issued
+type sequenceTokenSource struct {
+	mu     sync.Mutex
+	issued int
+	expiry time.Duration
+}
+
+func (s *sequenceTokenSource) Token(ctx context.Context) (*BearerToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issued++
+	token := &BearerToken{AccessToken: fmt.Sprintf("token-%d", s.issued)}
+	if s.expiry != 0 {
+		token.Expiry = time.Now().Add(s.expiry)
+	}
+	return token, nil
+}
+
+func TestBearerTransport(t *testing.T) {
+	tests := []struct {
+		name string
+		// Prefix of the tokens the BMC accepts
+		accepted   string
+		expiry     time.Duration
+		requests   int
+		wantStatus int
+		wantIssued int
+	}{
+		{"token reused", "token-1", 0, 3, http.StatusOK, 1},
+		{"token about to expire renewed", "token-", 10 * time.Second, 3, http.StatusOK, 3},
+		{"revoked token replaced", "token-2", 0, 2, http.StatusOK, 2},
+		{"new token rejected too", "other", 0, 1, http.StatusUnauthorized, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "+tt.accepted) {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+			}))
+			defer bmc.Close()
+
+			source := &sequenceTokenSource{expiry: tt.expiry}
+			client := &http.Client{Transport: &bearerTransport{base: http.DefaultTransport, tokens: newCachedTokenSource(source)}}
+			var status int
+			for i := 0; i < tt.requests; i++ {
+				resp, err := client.Post(bmc.URL, "application/json", strings.NewReader("{}"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				status = resp.StatusCode
+			}
+			if status != tt.wantStatus || source.issued != tt.wantIssued {
+				t.Errorf("last status %d after %d tokens, want %d after %d", status, source.issued, tt.wantStatus, tt.wantIssued)
+			}
+			for _, body := range bodies {
+				if body != "{}" {
+					t.Errorf("body %q, want the request body replayed", body)
+				}
+			}
+		})
+	}
+}
+
+func TestBearerTransportWithoutTokenSource(t *testing.T) {
+	client := &http.Client{Transport: &bearerTransport{base: http.DefaultTransport}}
+	if _, err := client.Get("http://10.0.0.1/redfish/v1"); !errors.Is(err, ErrNoTokenSource) {
+		t.Errorf("Get() error = %v, want %v", err, ErrNoTokenSource)
+	}
+}
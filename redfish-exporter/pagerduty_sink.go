@@ -0,0 +1,182 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	pagerDutyRequestTimeout   = 10 * time.Second
+)
+
+// PagerDutyAlertSink triggers a PagerDuty incident for critical events and
+// resolves it once the server reports an OK event for the same condition.
+// Test events never trigger or resolve incidents.
+type PagerDutyAlertSink struct {
+	routingKey string
+	eventsURL  string
+	client     *http.Client
+
+	mu sync.Mutex
+	// Open incident dedup keys by server IP and condition
+	dedupKeys map[pagerDutyIncidentKey]string
+}
+
+type pagerDutyIncidentKey struct {
+	serverIP string
+	// The OriginOfCondition of the events, their MessageId without one
+	condition string
+}
+
+// The incident of an event, critical and OK events of the same resource
+// share it while their MessageIds differ
+func pagerDutyIncident(event *EnrichedEvent) pagerDutyIncidentKey {
+	condition := event.OriginOfCondition.OdataId
+	if condition == "" {
+		condition = event.MessageId
+	}
+	return pagerDutyIncidentKey{serverIP: event.ServerIP, condition: condition}
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func NewPagerDutyAlertSink(routingKey, eventsURL string) (*PagerDutyAlertSink, error) {
+	if routingKey == "" {
+		return nil, fmt.Errorf("pagerduty routing key is required")
+	}
+	if eventsURL == "" {
+		eventsURL = DefaultPagerDutyEventsURL
+	}
+	return &PagerDutyAlertSink{
+		routingKey: routingKey,
+		eventsURL:  eventsURL,
+		client:     &http.Client{Timeout: pagerDutyRequestTimeout},
+		dedupKeys:  make(map[pagerDutyIncidentKey]string),
+	}, nil
+}
+
+func (s *PagerDutyAlertSink) Send(event *EnrichedEvent) error {
+	if isTestEvent(event.Event) {
+		return nil
+	}
+	switch event.Severity {
+	case "Critical":
+		return s.trigger(event)
+	case "OK":
+		return s.resolve(pagerDutyIncident(event))
+	default:
+		return nil
+	}
+}
+
+func (s *PagerDutyAlertSink) Close() error {
+	return nil
+}
+
+func (s *PagerDutyAlertSink) trigger(event *EnrichedEvent) error {
+	key := pagerDutyIncident(event)
+	dedupKey := fmt.Sprintf("redfish/%s/%s", key.serverIP, key.condition)
+
+	err := s.post(pagerDutyEvent{
+		RoutingKey:  s.routingKey,
+		EventAction: "trigger",
+		DedupKey:    dedupKey,
+		Payload: &pagerDutyPayload{
+			Summary:   event.Message,
+			Source:    event.ServerIP,
+			Severity:  "critical",
+			Timestamp: event.EventTimestamp,
+			CustomDetails: map[string]string{
+				"slurm_node": event.SlurmNode,
+				"message_id": event.MessageId,
+				"event_id":   event.EventId,
+				"datacenter": event.Location.Datacenter,
+				"row":        event.Location.Row,
+				"rack":       event.Location.Rack,
+				"asset_tag":  event.Location.AssetTag,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.dedupKeys[key] = dedupKey
+	s.mu.Unlock()
+	return nil
+}
+
+// Resolve the open incident of the condition, if any
+func (s *PagerDutyAlertSink) resolve(key pagerDutyIncidentKey) error {
+	s.mu.Lock()
+	dedupKey, open := s.dedupKeys[key]
+	s.mu.Unlock()
+	if !open {
+		return nil
+	}
+
+	err := s.post(pagerDutyEvent{
+		RoutingKey:  s.routingKey,
+		EventAction: "resolve",
+		DedupKey:    dedupKey,
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.dedupKeys, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *PagerDutyAlertSink) post(event pagerDutyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pagerduty event: %w", err)
+	}
+
+	resp, err := s.client.Post(s.eventsURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post pagerduty %s event: %w", event.EventAction, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("pagerduty returned status %d for %s event", resp.StatusCode, event.EventAction)
+	}
+	return nil
+}
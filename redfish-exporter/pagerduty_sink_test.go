@@ -0,0 +1,138 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Records the "action dedup_key" of the events posted to PagerDuty
+func newFakePagerDuty(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil || event.RoutingKey != "routing-key" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		actions = append(actions, event.EventAction+" "+event.DedupKey)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), actions...)
+	}
+}
+
+func pagerDutyTestEvent(severity, messageID, origin, eventID string) *EnrichedEvent {
+	return &EnrichedEvent{
+		Event: Event{
+			EventId:           eventID,
+			Severity:          severity,
+			MessageId:         messageID,
+			OriginOfCondition: OriginOfCondition{OdataId: origin},
+		},
+		ServerIP: "10.0.0.1",
+	}
+}
+
+func TestPagerDutyAlertSink(t *testing.T) {
+	const psu = "/redfish/v1/Chassis/1/PowerSubsystem/PowerSupplies/1"
+	const fan = "/redfish/v1/Chassis/1/ThermalSubsystem/Fans/1"
+
+	tests := []struct {
+		name   string
+		events []*EnrichedEvent
+		want   []string
+	}{
+		{
+			name: "OK of the same resource resolves",
+			events: []*EnrichedEvent{
+				pagerDutyTestEvent("Critical", "Power.1.0.PowerSupplyFailed", psu, "1"),
+				pagerDutyTestEvent("OK", "Power.1.0.PowerSupplyOK", psu, "2"),
+			},
+			want: []string{"trigger redfish/10.0.0.1/" + psu, "resolve redfish/10.0.0.1/" + psu},
+		},
+		{
+			name: "OK of another resource leaves the incident open",
+			events: []*EnrichedEvent{
+				pagerDutyTestEvent("Critical", "Power.1.0.PowerSupplyFailed", psu, "1"),
+				pagerDutyTestEvent("OK", "Fan.1.0.FanRestored", fan, "2"),
+			},
+			want: []string{"trigger redfish/10.0.0.1/" + psu},
+		},
+		{
+			name: "test event leaves the incident open",
+			events: []*EnrichedEvent{
+				pagerDutyTestEvent("Critical", "Power.1.0.PowerSupplyFailed", psu, "1"),
+				pagerDutyTestEvent("OK", "Base.1.0.Success", "/redfish/v1/EventService", testEventIDPrefix+"1"),
+				{Event: Event{EventId: "renamed", Severity: "OK", MessageId: "Base.1.0.Success", Message: "Delivery check " + testEventIDPrefix + "2", OriginOfCondition: OriginOfCondition{OdataId: psu}}, ServerIP: "10.0.0.1"},
+			},
+			want: []string{"trigger redfish/10.0.0.1/" + psu},
+		},
+		{
+			name: "critical test event pages no one",
+			events: []*EnrichedEvent{
+				pagerDutyTestEvent("Critical", "Base.1.0.Success", "", testEventIDPrefix+"1"),
+			},
+		},
+		{
+			name: "without OriginOfCondition the MessageId is the condition",
+			events: []*EnrichedEvent{
+				pagerDutyTestEvent("Critical", "Base.1.0.Fault", "", "1"),
+				pagerDutyTestEvent("OK", "Base.1.0.Other", "", "2"),
+				pagerDutyTestEvent("OK", "Base.1.0.Fault", "", "3"),
+			},
+			want: []string{"trigger redfish/10.0.0.1/Base.1.0.Fault", "resolve redfish/10.0.0.1/Base.1.0.Fault"},
+		},
+		{
+			name: "warnings are ignored",
+			events: []*EnrichedEvent{
+				pagerDutyTestEvent("Warning", "Fan.1.0.FanDegraded", fan, "1"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, actions := newFakePagerDuty(t)
+			sink, err := NewPagerDutyAlertSink("routing-key", server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, event := range tt.events {
+				if err := sink.Send(event); err != nil {
+					t.Fatalf("Send(%s) error = %v", event.EventId, err)
+				}
+			}
+			if got := actions(); !reflect.DeepEqual(got, tt.want) && (len(got) != 0 || len(tt.want) != 0) {
+				t.Errorf("PagerDuty received %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// The prefix with a leading slash and without a trailing one, "" for the root
+func normalizePathPrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+// Whether a request path is the prefix or below it
+func matchesPathPrefix(path, prefix string) bool {
+	prefix = normalizePathPrefix(prefix)
+	if prefix == "" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// Check that the subscriptions deliver to the receiver's path prefix, as
+// rendered for every server. Only the http destinations are checked, SNMP
+// and Syslog destinations don't reach the receiver.
+func checkDestinationPrefix(payload SubscriptionPayload, servers []RedfishServer, prefix string) error {
+	if !isRedfishProtocol(payload.Protocol) {
+		return nil
+	}
+	for _, server := range servers {
+		serverPayload, err := RenderPayload(payload, server)
+		if err != nil {
+			return err
+		}
+		for _, destinationPayload := range splitDestinations(serverPayload) {
+			destinationURL, err := url.Parse(destinationPayload.Destination)
+			if err != nil {
+				return fmt.Errorf("invalid subscription Destination %q: %v", destinationPayload.Destination, err)
+			}
+			if !matchesPathPrefix(destinationURL.Path, prefix) {
+				return fmt.Errorf("subscription Destination %q is outside of the receiver's path prefix %s", destinationPayload.Destination, normalizePathPrefix(prefix))
+			}
+		}
+	}
+	return nil
+}
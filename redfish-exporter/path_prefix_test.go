@@ -0,0 +1,150 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func TestMatchesPathPrefix(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		want   bool
+	}{
+		{"/", "", true},
+		{"/events", "/", true},
+		{"/redfish-events", "redfish-events", true},
+		{"/redfish-events/node-1", "/redfish-events/", true},
+		{"/redfish-events", " /redfish-events/ ", true},
+		{"/redfish-eventsx", "/redfish-events", false},
+		{"/other", "/redfish-events", false},
+		{"/", "/redfish-events", false},
+	}
+	for _, tt := range tests {
+		if got := matchesPathPrefix(tt.path, tt.prefix); got != tt.want {
+			t.Errorf("matchesPathPrefix(%q, %q) = %v, want %v", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDestinationPrefix(t *testing.T) {
+	servers := []RedfishServer{{IP: "https://10.0.0.1", SlurmNode: "node-1"}, {IP: "https://10.0.0.2", SlurmNode: "node-2"}}
+	tests := []struct {
+		name    string
+		payload SubscriptionPayload
+		prefix  string
+		wantErr bool
+	}{
+		{"no prefix", SubscriptionPayload{Destination: "https://10.0.0.100:8080"}, "", false},
+		{"under the prefix", SubscriptionPayload{Destination: "https://10.0.0.100:8080/events/all"}, "/events", false},
+		{"outside the prefix", SubscriptionPayload{Destination: "https://10.0.0.100:8080/other"}, "/events", true},
+		{"all destinations checked", SubscriptionPayload{Destinations: []string{"https://10.0.0.100:8080/events", "https://10.0.0.101:8080"}}, "/events", true},
+		{"template rendered per server", SubscriptionPayload{DestinationTemplate: "https://10.0.0.100:8080/events/{{.SlurmNode}}"}, "/events", false},
+		{"template outside the prefix", SubscriptionPayload{DestinationTemplate: "https://10.0.0.100:8080/{{.SlurmNode}}"}, "/events", true},
+		{"snmp not checked", SubscriptionPayload{Destination: "snmp://10.0.0.100:162", Protocol: redfish.SNMPv2cEventDestinationProtocol}, "/events", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDestinationPrefix(tt.payload, servers, tt.prefix)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkDestinationPrefix() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Start a listener on a free port, returning its address
+func startTestListener(t *testing.T, s *Server) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s.listener = listener
+	go s.acceptLoop(Config{})
+	t.Cleanup(func() { listener.Close() })
+	return listener.Addr().String()
+}
+
+func TestRejectedRequestKeepsConnection(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"without body", ""},
+		{"with body", `{"Events": [{"MessageId": "Base.1.0.Rejected"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer("127.0.0.1", "0", nil, nil)
+			s.SetPathPrefix("/events")
+			conn, err := net.Dial("tcp", startTestListener(t, s))
+			if err != nil {
+				t.Fatalf("failed to connect: %v", err)
+			}
+			defer conn.Close()
+
+			valid := `{"Events": [{"MessageId": "Base.1.0.Accepted"}]}`
+			go fmt.Fprintf(conn, "POST /other HTTP/1.1\r\nHost: exporter\r\nContent-Length: %d\r\n\r\n%s"+
+				"POST /events HTTP/1.1\r\nHost: exporter\r\nContent-Length: %d\r\n\r\n%s",
+				len(tt.body), tt.body, len(valid), valid)
+
+			reader := bufio.NewReader(conn)
+			for i, want := range []int{http.StatusNotFound, http.StatusOK} {
+				resp, err := http.ReadResponse(reader, nil)
+				if err != nil {
+					t.Fatalf("failed to read response %d: %v", i+1, err)
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != want {
+					t.Errorf("response %d status = %d, want %d", i+1, resp.StatusCode, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiscardBody(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		want bool
+	}{
+		{"empty", 0, true},
+		{"small", 512, true},
+		{"largest discarded", maxDiscardedBodySize, true},
+		{"too large", maxDiscardedBodySize + 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/other", strings.NewReader(strings.Repeat("x", tt.size)))
+			if got := discardBody(req); got != tt.want {
+				t.Errorf("discardBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
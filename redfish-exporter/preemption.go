@@ -0,0 +1,288 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	preemptionBufferSize = 64
+	// Time allowed for finding and preempting the jobs of a node
+	preemptionTimeout = 30 * time.Second
+	// Default time before the same job is preempted again
+	DefaultPreemptionCooldown = 10 * time.Minute
+)
+
+// PreemptionRule selects the hardware faults the jobs of a node are
+// preempted for
+type PreemptionRule struct {
+	// Lowest severity of the events, "Warning" or "Critical"
+	Severity string `json:"severity"`
+	// Regular expression matching the event's OriginOfCondition, e.g.
+	// "/Processors/GPU" for GPU faults. Every resource when empty.
+	ResourcePattern string `json:"resourcePattern"`
+}
+
+type PreemptionConfig struct {
+	Rules []PreemptionRule `json:"rules"`
+	// Time before the same job is preempted again, DefaultPreemptionCooldown
+	// when zero
+	CooldownPeriod Duration `json:"cooldownPeriod"`
+}
+
+// SlurmJobLookup finds the jobs running on a slurm node
+type SlurmJobLookup interface {
+	RunningJobs(ctx context.Context, node string) ([]string, error)
+}
+
+// JobPreemptor asks a job to give up its node
+type JobPreemptor interface {
+	Preempt(ctx context.Context, jobID string, reason string) error
+}
+
+// PreemptionEvent reports a job preempted for a hardware fault
+type PreemptionEvent struct {
+	JobID       string
+	Node        string
+	ServerIP    string
+	Reason      string
+	Event       *EnrichedEvent
+	PreemptedAt time.Time
+}
+
+type preemptionRule struct {
+	minRank  int
+	resource *regexp.Regexp
+}
+
+// PreemptionTrigger is an event sink preempting the slurm jobs running on
+// the node of a server that reports a fault matching its rules. A job is
+// preempted at most once per cooldown period, however many events follow.
+type PreemptionTrigger struct {
+	rules     []preemptionRule
+	cooldown  time.Duration
+	lookup    SlurmJobLookup
+	preemptor JobPreemptor
+	events    chan *PreemptionEvent
+	metrics   *Metrics
+
+	mu sync.Mutex
+	// When each job was last preempted
+	preempted map[string]time.Time
+	closed    bool
+	pending   sync.WaitGroup
+}
+
+func NewPreemptionTrigger(cfg PreemptionConfig, lookup SlurmJobLookup, preemptor JobPreemptor, metrics *Metrics) (*PreemptionTrigger, error) {
+	t := &PreemptionTrigger{
+		cooldown:  cfg.CooldownPeriod.Duration,
+		lookup:    lookup,
+		preemptor: preemptor,
+		events:    make(chan *PreemptionEvent, preemptionBufferSize),
+		metrics:   metrics,
+		preempted: make(map[string]time.Time),
+	}
+	if t.cooldown <= 0 {
+		t.cooldown = DefaultPreemptionCooldown
+	}
+	for i, rule := range cfg.Rules {
+		switch rule.Severity {
+		case "Warning", "Critical":
+		default:
+			return nil, fmt.Errorf("preemption rule %d: severity must be Warning or Critical, got %q", i, rule.Severity)
+		}
+		resource, err := regexp.Compile(rule.ResourcePattern)
+		if err != nil {
+			return nil, fmt.Errorf("preemption rule %d: invalid resource pattern: %v", i, err)
+		}
+		t.rules = append(t.rules, preemptionRule{minRank: severityRank(rule.Severity), resource: resource})
+	}
+	return t, nil
+}
+
+// The preempted jobs, closed by Close
+func (t *PreemptionTrigger) Events() <-chan *PreemptionEvent {
+	return t.events
+}
+
+// Preempt the jobs of the event's node in the background if the event
+// matches a rule. Test events never preempt jobs.
+func (t *PreemptionTrigger) Send(event *EnrichedEvent) error {
+	if event.SlurmNode == "" || isTestEvent(event.Event) || !t.matches(event) {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.pending.Add(1)
+	go func() {
+		defer t.pending.Done()
+		t.preemptNode(event)
+	}()
+	return nil
+}
+
+func (t *PreemptionTrigger) matches(event *EnrichedEvent) bool {
+	for _, rule := range t.rules {
+		if severityRank(event.Severity) >= rule.minRank && rule.resource.MatchString(event.OriginOfCondition.OdataId) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *PreemptionTrigger) preemptNode(event *EnrichedEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), preemptionTimeout)
+	defer cancel()
+
+	jobs, err := t.lookup.RunningJobs(ctx, event.SlurmNode)
+	if err != nil {
+		log.Printf("Failed to find the jobs running on node %s: %v", event.SlurmNode, err)
+		return
+	}
+	reason := fmt.Sprintf("%s hardware fault on %s: %s", event.Severity, event.OriginOfCondition.OdataId, event.Message)
+	for _, jobID := range jobs {
+		if !t.reserve(jobID, time.Now()) {
+			log.Printf("Job %s on node %s was preempted less than %v ago, skipping", jobID, event.SlurmNode, t.cooldown)
+			continue
+		}
+		if err := t.preemptor.Preempt(ctx, jobID, reason); err != nil {
+			log.Printf("Failed to preempt job %s on node %s: %v", jobID, event.SlurmNode, err)
+			t.release(jobID)
+			continue
+		}
+		log.Printf("Preempted job %s on node %s: %s", jobID, event.SlurmNode, reason)
+		t.metrics.jobPreemptions.WithLabelValues(event.ServerIP).Inc()
+		t.emit(&PreemptionEvent{
+			JobID:       jobID,
+			Node:        event.SlurmNode,
+			ServerIP:    event.ServerIP,
+			Reason:      reason,
+			Event:       event,
+			PreemptedAt: time.Now(),
+		})
+	}
+}
+
+// Claim the job for preemption unless it was preempted within the cooldown
+func (t *PreemptionTrigger) reserve(jobID string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if last, ok := t.preempted[jobID]; ok && now.Sub(last) < t.cooldown {
+		return false
+	}
+	t.preempted[jobID] = now
+	// Forget the jobs whose cooldown has passed
+	for id, last := range t.preempted {
+		if now.Sub(last) >= t.cooldown {
+			delete(t.preempted, id)
+		}
+	}
+	return true
+}
+
+// Undo the claim of a job that couldn't be preempted
+func (t *PreemptionTrigger) release(jobID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.preempted, jobID)
+}
+
+// The channel is only closed once no preemption is in progress
+func (t *PreemptionTrigger) emit(event *PreemptionEvent) {
+	select {
+	case t.events <- event:
+	default:
+		log.Printf("Preemption buffer full, dropping preemption event of job %s", event.JobID)
+	}
+}
+
+// Wait for the preemptions in progress and close the event channel
+func (t *PreemptionTrigger) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	t.pending.Wait()
+	close(t.events)
+	return nil
+}
+
+// The event a preemption is reported to the alert sinks as
+func preemptionEvent(preemption *PreemptionEvent) *EnrichedEvent {
+	return &EnrichedEvent{
+		Event: Event{
+			EventType:         "Alert",
+			EventId:           "JobPreempted-" + preemption.JobID,
+			EventTimestamp:    preemption.PreemptedAt.Format(time.RFC3339),
+			Severity:          "Warning",
+			Message:           fmt.Sprintf("Preempted job %s on node %s: %s", preemption.JobID, preemption.Node, preemption.Reason),
+			MessageId:         "JobPreempted",
+			OriginOfCondition: preemption.Event.OriginOfCondition,
+		},
+		ServerIP:   preemption.ServerIP,
+		SlurmNode:  preemption.Node,
+		ReceivedAt: preemption.PreemptedAt,
+		Location:   preemption.Event.Location,
+	}
+}
+
+// squeueJobLookup lists the running jobs of a node with squeue
+type squeueJobLookup struct{}
+
+func (squeueJobLookup) RunningJobs(ctx context.Context, node string) ([]string, error) {
+	out, err := exec.CommandContext(ctx, "squeue", "--noheader", "--states=RUNNING", "--format=%i", "--nodelist="+node).Output()
+	if err != nil {
+		return nil, fmt.Errorf("squeue failed: %w", commandError(err))
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// scancelPreemptor sends SIGUSR1 to a job with scancel so it can checkpoint
+// and exit. scancel has no reason argument, the reason is only logged.
+type scancelPreemptor struct{}
+
+func (scancelPreemptor) Preempt(ctx context.Context, jobID string, reason string) error {
+	if _, err := exec.CommandContext(ctx, "scancel", "--signal=SIGUSR1", jobID).Output(); err != nil {
+		return fmt.Errorf("scancel failed: %w", commandError(err))
+	}
+	return nil
+}
+
+// Add the stderr of a failed command to its error
+func commandError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
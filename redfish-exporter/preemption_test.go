@@ -0,0 +1,166 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeJobLookup map[string][]string
+
+func (l fakeJobLookup) RunningJobs(ctx context.Context, node string) ([]string, error) {
+	return l[node], nil
+}
+
+// Records the preempted jobs
+type fakePreemptor struct {
+	mu   sync.Mutex
+	jobs []string
+}
+
+func (p *fakePreemptor) Preempt(ctx context.Context, jobID string, reason string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.jobs = append(p.jobs, jobID)
+	return nil
+}
+
+func (p *fakePreemptor) preempted() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	jobs := append([]string(nil), p.jobs...)
+	sort.Strings(jobs)
+	return jobs
+}
+
+func TestPreemptionTrigger(t *testing.T) {
+	gpuFault := func(serverIP, node, eventID string) *EnrichedEvent {
+		return &EnrichedEvent{
+			Event: Event{
+				EventId:           eventID,
+				Severity:          "Critical",
+				MessageId:         "GPU.1.0.Fault",
+				OriginOfCondition: OriginOfCondition{OdataId: "/redfish/v1/Systems/1/Processors/GPU0"},
+			},
+			ServerIP:  serverIP,
+			SlurmNode: node,
+		}
+	}
+	tests := []struct {
+		name        string
+		events      []*EnrichedEvent
+		maintenance string
+		want        []string
+	}{
+		{
+			name:   "matching fault preempts the node's jobs",
+			events: []*EnrichedEvent{gpuFault("10.0.0.1", "node1", "1")},
+			want:   []string{"100", "101"},
+		},
+		{
+			name:   "repeated fault preempts once per cooldown",
+			events: []*EnrichedEvent{gpuFault("10.0.0.1", "node1", "1"), gpuFault("10.0.0.1", "node1", "2")},
+			want:   []string{"100", "101"},
+		},
+		{
+			name: "warning below the rule's severity",
+			events: []*EnrichedEvent{func() *EnrichedEvent {
+				event := gpuFault("10.0.0.1", "node1", "1")
+				event.Severity = "Warning"
+				return event
+			}()},
+		},
+		{
+			name: "fault of another resource",
+			events: []*EnrichedEvent{func() *EnrichedEvent {
+				event := gpuFault("10.0.0.1", "node1", "1")
+				event.OriginOfCondition.OdataId = "/redfish/v1/Chassis/1/Fans/0"
+				return event
+			}()},
+		},
+		{
+			name:   "event without slurm node",
+			events: []*EnrichedEvent{gpuFault("10.0.0.1", "", "1")},
+		},
+		{
+			name:   "test event",
+			events: []*EnrichedEvent{gpuFault("10.0.0.1", "node1", testEventIDPrefix+"1")},
+		},
+		{
+			name:        "server in maintenance",
+			events:      []*EnrichedEvent{gpuFault("10.0.0.1", "node1", "1")},
+			maintenance: "10.0.0.1",
+		},
+		{
+			name:        "other server in maintenance",
+			events:      []*EnrichedEvent{gpuFault("10.0.0.2", "node2", "1")},
+			maintenance: "10.0.0.1",
+			want:        []string{"200"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preemptor := &fakePreemptor{}
+			lookup := fakeJobLookup{"node1": {"100", "101"}, "node2": {"200"}}
+			cfg := PreemptionConfig{Rules: []PreemptionRule{{Severity: "Critical", ResourcePattern: "/Processors/GPU"}}}
+			trigger, err := NewPreemptionTrigger(cfg, lookup, preemptor, NewMetrics())
+			if err != nil {
+				t.Fatal(err)
+			}
+			store, err := NewStateStore("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			maintenance, err := NewMaintenanceRegistry(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.maintenance != "" {
+				if err := maintenance.EnterMaintenance(tt.maintenance, time.Now().Add(time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			sink := &maintenanceSink{sink: trigger, maintenance: maintenance}
+
+			for _, event := range tt.events {
+				if err := sink.Send(event); err != nil {
+					t.Fatalf("Send() error = %v", err)
+				}
+				// Let the preemption finish before the next event
+				trigger.pending.Wait()
+			}
+			if err := sink.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if got := preemptor.preempted(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("preempted jobs = %v, want %v", got, tt.want)
+			}
+			var emitted int
+			for range trigger.Events() {
+				emitted++
+			}
+			if emitted != len(tt.want) {
+				t.Errorf("emitted %d preemption events, want %d", emitted, len(tt.want))
+			}
+		})
+	}
+}
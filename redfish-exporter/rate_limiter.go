@@ -0,0 +1,212 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Registry prefixes getting buckets of their own when RateLimitConfig
+// lists none
+var DefaultRateLimitPrefixes = []string{"Base", "EventLog", "Platform", "ResourceEvent", "TaskEvent"}
+
+const (
+	// Bucket shared by the events of servers that aren't configured
+	unknownServerKey = "unknown"
+	// Bucket shared by the registry prefixes that aren't in the allow-list
+	otherPrefixKey = "other"
+	// Interval of the sweeps dropping the idle buckets
+	rateLimitSweepInterval = time.Minute
+)
+
+// RateLimitConfig limits the events forwarded to the sinks. Each configured
+// server and listed message registry prefix gets its own token bucket, the
+// global bucket is shared by all servers
+type RateLimitConfig struct {
+	// Events per second refilled per server and registry prefix, disabled when zero
+	Rate float64 `json:"rate"`
+	// Events allowed in a burst per server and registry prefix
+	Burst int `json:"burst"`
+	// Events per second refilled across all servers, disabled when zero
+	GlobalRate  float64 `json:"globalRate"`
+	GlobalBurst int     `json:"globalBurst"`
+	// Registry prefixes with buckets of their own, the others share one.
+	// DefaultRateLimitPrefixes when empty.
+	Prefixes []string `json:"prefixes"`
+}
+
+// Whether any limit is set
+func (c RateLimitConfig) Enabled() bool {
+	return c.Rate > 0 || c.GlobalRate > 0
+}
+
+type rateLimitKey struct {
+	server string
+	prefix string
+}
+
+// RateLimiter is an EventSink dropping the events that exceed the configured
+// rates, so an event storm from a faulty BMC doesn't overwhelm the sinks.
+// Buckets are only kept for the configured servers and the listed prefixes,
+// so a BMC can't make up keys, and full buckets are dropped while idle.
+type RateLimiter struct {
+	sink    EventSink
+	cfg     RateLimitConfig
+	metrics *Metrics
+	// Server ID by host
+	servers  map[string]string
+	prefixes map[string]bool
+
+	mu        sync.Mutex
+	buckets   map[rateLimitKey]*rate.Limiter
+	global    *rate.Limiter
+	lastSweep time.Time
+}
+
+// Wrap a sink with a rate limiter for the events of the servers
+func NewRateLimiter(sink EventSink, cfg RateLimitConfig, servers []RedfishServer, metrics *Metrics) *RateLimiter {
+	l := &RateLimiter{
+		sink:      sink,
+		cfg:       cfg,
+		metrics:   metrics,
+		servers:   make(map[string]string, len(servers)),
+		prefixes:  make(map[string]bool),
+		buckets:   make(map[rateLimitKey]*rate.Limiter),
+		lastSweep: time.Now(),
+	}
+	for _, server := range servers {
+		l.servers[serverHost(server)] = server.ID()
+	}
+	prefixes := cfg.Prefixes
+	if len(prefixes) == 0 {
+		prefixes = DefaultRateLimitPrefixes
+	}
+	for _, prefix := range prefixes {
+		l.prefixes[prefix] = true
+	}
+	if cfg.GlobalRate > 0 {
+		l.global = newLimiter(cfg.GlobalRate, cfg.GlobalBurst)
+	}
+	return l
+}
+
+func newLimiter(eventsPerSecond float64, burst int) *rate.Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(eventsPerSecond), burst)
+}
+
+// Forward the event to the wrapped sink unless it exceeds the rate
+func (l *RateLimiter) Send(event *EnrichedEvent) error {
+	key := l.key(event)
+	if !l.allow(key, time.Now()) {
+		l.metrics.eventsRateLimited.WithLabelValues(key.server, key.prefix).Inc()
+		return nil
+	}
+	return l.sink.Send(event)
+}
+
+// Close the wrapped sink
+func (l *RateLimiter) Close() error {
+	return l.sink.Close()
+}
+
+// The bucket of an event, from a bounded set of servers and prefixes
+func (l *RateLimiter) key(event *EnrichedEvent) rateLimitKey {
+	key := rateLimitKey{server: unknownServerKey, prefix: otherPrefixKey}
+	if id, ok := l.servers[serverHost(RedfishServer{IP: event.ServerIP})]; ok {
+		key.server = id
+	}
+	if prefix := registryPrefix(event.MessageId); l.prefixes[prefix] {
+		key.prefix = prefix
+	}
+	return key
+}
+
+// Take a token from the key's and the global bucket, only when both have one
+func (l *RateLimiter) allow(key rateLimitKey, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	var bucket *rate.Limiter
+	if l.cfg.Rate > 0 {
+		bucket = l.buckets[key]
+		if bucket == nil {
+			bucket = newLimiter(l.cfg.Rate, l.cfg.Burst)
+			l.buckets[key] = bucket
+		}
+		if bucket.TokensAt(now) < 1 {
+			return false
+		}
+	}
+	if l.global != nil && !l.global.AllowN(now, 1) {
+		return false
+	}
+	if bucket != nil {
+		bucket.AllowN(now, 1)
+	}
+	return true
+}
+
+// Drop the buckets that refilled completely, a new bucket starts full too
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.TokensAt(now) >= float64(bucket.Burst()) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// The registry prefix of a MessageId like Base.1.8.PropertyValueError
+func registryPrefix(messageId string) string {
+	prefix, _, _ := strings.Cut(messageId, ".")
+	return prefix
+}
+
+// fanoutSink forwards every event to several sinks
+type fanoutSink []EventSink
+
+func (f fanoutSink) Send(event *EnrichedEvent) error {
+	var errs []error
+	for _, sink := range f {
+		if err := sink.Send(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanoutSink) Close() error {
+	var errs []error
+	for _, sink := range f {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
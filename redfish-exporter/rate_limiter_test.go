@@ -0,0 +1,185 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Counts the events it receives
+type countingSink struct {
+	events []*EnrichedEvent
+}
+
+func (s *countingSink) Send(event *EnrichedEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *countingSink) Close() error {
+	return nil
+}
+
+func rateLimitedEvent(serverIP, messageID string) *EnrichedEvent {
+	return &EnrichedEvent{Event: Event{MessageId: messageID}, ServerIP: serverIP}
+}
+
+func TestRateLimiter(t *testing.T) {
+	servers := []RedfishServer{{IP: "https://10.0.0.1", Name: "node1"}, {IP: "https://10.0.0.2"}}
+	tests := []struct {
+		name          string
+		cfg           RateLimitConfig
+		events        []*EnrichedEvent
+		wantForwarded int
+	}{
+		{
+			name: "burst per server and prefix",
+			cfg:  RateLimitConfig{Rate: 0.001, Burst: 2},
+			events: []*EnrichedEvent{
+				rateLimitedEvent("10.0.0.1", "Base.1.0.A"),
+				rateLimitedEvent("10.0.0.1", "Base.1.0.B"),
+				rateLimitedEvent("10.0.0.1", "Base.1.0.C"),
+			},
+			wantForwarded: 2,
+		},
+		{
+			name: "servers have their own buckets",
+			cfg:  RateLimitConfig{Rate: 0.001, Burst: 1},
+			events: []*EnrichedEvent{
+				rateLimitedEvent("10.0.0.1", "Base.1.0.A"),
+				rateLimitedEvent("10.0.0.2", "Base.1.0.A"),
+			},
+			wantForwarded: 2,
+		},
+		{
+			name: "listed prefixes have their own buckets",
+			cfg:  RateLimitConfig{Rate: 0.001, Burst: 1},
+			events: []*EnrichedEvent{
+				rateLimitedEvent("10.0.0.1", "Base.1.0.A"),
+				rateLimitedEvent("10.0.0.1", "ResourceEvent.1.0.A"),
+			},
+			wantForwarded: 2,
+		},
+		{
+			name: "unlisted prefixes share a bucket",
+			cfg:  RateLimitConfig{Rate: 0.001, Burst: 1},
+			events: []*EnrichedEvent{
+				rateLimitedEvent("10.0.0.1", "Vendor1.1.0.A"),
+				rateLimitedEvent("10.0.0.1", "Vendor2.1.0.A"),
+			},
+			wantForwarded: 1,
+		},
+		{
+			name: "configured allow-list",
+			cfg:  RateLimitConfig{Rate: 0.001, Burst: 1, Prefixes: []string{"Vendor1", "Vendor2"}},
+			events: []*EnrichedEvent{
+				rateLimitedEvent("10.0.0.1", "Vendor1.1.0.A"),
+				rateLimitedEvent("10.0.0.1", "Vendor2.1.0.A"),
+			},
+			wantForwarded: 2,
+		},
+		{
+			name: "unknown servers share a bucket",
+			cfg:  RateLimitConfig{Rate: 0.001, Burst: 1},
+			events: []*EnrichedEvent{
+				rateLimitedEvent("10.0.0.8", "Base.1.0.A"),
+				rateLimitedEvent("10.0.0.9", "Base.1.0.A"),
+			},
+			wantForwarded: 1,
+		},
+		{
+			name: "global limit across servers",
+			cfg:  RateLimitConfig{GlobalRate: 0.001, GlobalBurst: 2},
+			events: []*EnrichedEvent{
+				rateLimitedEvent("10.0.0.1", "Base.1.0.A"),
+				rateLimitedEvent("10.0.0.2", "Base.1.0.A"),
+				rateLimitedEvent("10.0.0.2", "TaskEvent.1.0.A"),
+			},
+			wantForwarded: 2,
+		},
+		{
+			name: "event dropped by the global limit keeps its server's token",
+			cfg:  RateLimitConfig{Rate: 0.001, Burst: 1, GlobalRate: 0.001, GlobalBurst: 1},
+			events: []*EnrichedEvent{
+				rateLimitedEvent("10.0.0.1", "Base.1.0.A"),
+				rateLimitedEvent("10.0.0.2", "Base.1.0.A"),
+			},
+			wantForwarded: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &countingSink{}
+			limiter := NewRateLimiter(sink, tt.cfg, servers, NewMetrics())
+			for _, event := range tt.events {
+				if err := limiter.Send(event); err != nil {
+					t.Fatalf("Send() error = %v", err)
+				}
+			}
+			if len(sink.events) != tt.wantForwarded {
+				t.Errorf("forwarded %d events, want %d", len(sink.events), tt.wantForwarded)
+			}
+		})
+	}
+}
+
+func TestRateLimiterKeysAreBounded(t *testing.T) {
+	servers := []RedfishServer{{IP: "https://10.0.0.1", Name: "node1"}}
+	limiter := NewRateLimiter(&countingSink{}, RateLimitConfig{Rate: 1, Burst: 1}, servers, NewMetrics())
+	for i := 0; i < 1000; i++ {
+		limiter.Send(rateLimitedEvent(fmt.Sprintf("10.1.%d.%d", i/256, i%256), fmt.Sprintf("Made%d.1.0.Up", i)))
+		limiter.Send(rateLimitedEvent("10.0.0.1", fmt.Sprintf("Made%d.1.0.Up", i)))
+	}
+	want := map[rateLimitKey]bool{
+		{server: unknownServerKey, prefix: otherPrefixKey}: true,
+		{server: "node1", prefix: otherPrefixKey}:          true,
+	}
+	if len(limiter.buckets) != len(want) {
+		t.Fatalf("%d buckets, want %d", len(limiter.buckets), len(want))
+	}
+	for key := range limiter.buckets {
+		if !want[key] {
+			t.Errorf("unexpected bucket %+v", key)
+		}
+	}
+}
+
+func TestRateLimiterEvictsIdleBuckets(t *testing.T) {
+	servers := []RedfishServer{{IP: "https://10.0.0.1"}, {IP: "https://10.0.0.2"}}
+	limiter := NewRateLimiter(&countingSink{}, RateLimitConfig{Rate: 1, Burst: 5}, servers, NewMetrics())
+	start := time.Now()
+	first := limiter.key(rateLimitedEvent("10.0.0.1", "Base.1.0.A"))
+	second := limiter.key(rateLimitedEvent("10.0.0.2", "Base.1.0.A"))
+	limiter.allow(first, start)
+	limiter.allow(second, start)
+
+	// The first server stays busy, the second one's bucket refills
+	now := start
+	for now.Sub(start) <= rateLimitSweepInterval {
+		now = now.Add(time.Second)
+		limiter.allow(first, now)
+		limiter.allow(first, now)
+	}
+	if _, ok := limiter.buckets[second]; ok {
+		t.Error("idle bucket was kept")
+	}
+	if _, ok := limiter.buckets[first]; !ok {
+		t.Error("busy bucket was dropped")
+	}
+}
@@ -0,0 +1,414 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// ReconcileReport lists the servers by what Reconcile did for them
+type ReconcileReport struct {
+	// Servers without a subscription that got one
+	Created []string
+	// Servers with a subscription that had disappeared from the BMC and was recreated
+	Recreated []string
+	// Servers with a subscription that differed from the payload and was patched
+	Updated []string
+	// Servers whose subscriptions are still in place
+	Unchanged []string
+	// Servers that could not be checked or subscribed
+	Errors map[string]error
+	// Servers skipped because they failed too often, see ServerBreakers
+	Skipped []string
+	// Servers skipped because they are in maintenance
+	InMaintenance []string
+}
+
+// Make sure every server has the subscriptions recorded in subscriptionMap,
+// creating missing subscriptions and updating the map with their URIs.
+// Servers whose circuit breaker is open are skipped.
+func Reconcile(servers []RedfishServer, payload SubscriptionPayload, subscriptionMap map[string]ServerSubscriptions) ReconcileReport {
+	report := ReconcileReport{Errors: make(map[string]error)}
+
+	for _, server := range servers {
+		if !server.settings().breakers.Allow(server.ID()) {
+			report.Skipped = append(report.Skipped, server.ID())
+			continue
+		}
+
+		serverPayload, err := RenderPayload(payload, server)
+		if err != nil {
+			report.Errors[server.ID()] = err
+			continue
+		}
+		// Compared with the server's policy, not the payload's
+		serverPayload = applyDeliveryPolicy(server, serverPayload)
+
+		unlock := server.settings().locks.Lock(server.IP)
+		subscriptions, tracked := subscriptionMap[server.ID()]
+		if !tracked {
+			subscriptions = make(ServerSubscriptions)
+		}
+		changed := false
+		updated := false
+		for _, destinationPayload := range splitDestinations(serverPayload) {
+			destination := destinationPayload.Destination
+			subscription, exists := subscriptions[destination]
+			// Left alone until resumed with SetSubscriptionEnabled
+			if exists && subscription.Paused {
+				continue
+			}
+			if exists {
+				subscriptionURI := subscription.URI
+				found, diffs, err := checkSubscription(server, subscriptionURI, destinationPayload)
+				if err != nil {
+					report.Errors[server.ID()] = err
+					break
+				}
+				if found && len(diffs) == 0 {
+					server.settings().metrics.reconcileNoop.Inc()
+					continue
+				}
+				// Patch a changed subscription where possible, recreating it
+				// would miss the events sent in between
+				if found && patchable(diffs) && subscriptionPatchSupported(context.Background(), server) {
+					err := updateSubscription(context.Background(), server, subscriptionURI, destinationPayload)
+					if err == nil {
+						server.settings().metrics.reconcileUpdated.Inc()
+						updated = true
+						continue
+					}
+					log.Printf("Failed to update subscription %s on server %s, recreating it: %v", subscriptionURI, server.IP, err)
+				} else if found {
+					log.Printf("Subscription %s on server %s differs from the payload, recreating it: %v", subscriptionURI, server.IP, diffs)
+				} else {
+					log.Printf("Subscription %s is missing on server %s, recreating it", subscriptionURI, server.IP)
+				}
+			}
+
+			created, err := createSubscription(server, destinationPayload)
+			if err != nil {
+				report.Errors[server.ID()] = err
+				break
+			}
+			subscriptions[destination] = created[destination]
+			changed = true
+			if exists {
+				server.settings().metrics.reconcileRecreated.Inc()
+			} else {
+				server.settings().metrics.reconcileCreated.Inc()
+			}
+			// Kept in the map either way, the next reconcile checks it again
+			if err := verifyCreatedSubscription(server, created[destination].URI, destinationPayload); err != nil {
+				report.Errors[server.ID()] = err
+				break
+			}
+		}
+		unlock()
+		if len(subscriptions) > 0 {
+			subscriptionMap[server.ID()] = subscriptions
+		}
+		server.settings().breakers.Record(server.ID(), report.Errors[server.ID()])
+
+		switch {
+		case report.Errors[server.ID()] != nil:
+		case !tracked:
+			report.Created = append(report.Created, server.ID())
+		case changed:
+			report.Recreated = append(report.Recreated, server.ID())
+		case updated:
+			report.Updated = append(report.Updated, server.ID())
+		default:
+			report.Unchanged = append(report.Unchanged, server.ID())
+		}
+	}
+
+	return report
+}
+
+// Reconciler keeps the subscriptions of a set of servers in place. It owns
+// the subscription map while running, reconciling either all servers
+// periodically or a single server on request.
+type Reconciler struct {
+	// Held while the subscription map is read or changed, including the
+	// redfish calls of a reconcile or delete, so they never overlap
+	mu              sync.Mutex
+	servers         []RedfishServer
+	payload         SubscriptionPayload
+	subscriptionMap map[string]ServerSubscriptions
+	// Copy of the subscription map taken after every change, so the
+	// listener can read it without waiting for the redfish calls under mu
+	publishedMu sync.RWMutex
+	published   map[string]ServerSubscriptions
+	// Set once the subscriptions are deleted, nothing is created after that
+	closed bool
+	// Servers in maintenance are left alone, optional
+	maintenance *MaintenanceRegistry
+	// Timing of the checks, and when each server is checked next
+	watchdog WatchdogConfig
+	schedule map[string]*watchdogState
+}
+
+// Returned when reconciling after the subscriptions were deleted on shutdown
+var ErrReconcilerClosed = errors.New("reconciler closed")
+
+func NewReconciler(servers []RedfishServer, payload SubscriptionPayload, subscriptionMap map[string]ServerSubscriptions) *Reconciler {
+	return &Reconciler{
+		servers:         servers,
+		payload:         payload,
+		subscriptionMap: subscriptionMap,
+		watchdog:        WatchdogConfig{MaxConsecutiveFailures: DefaultMaxConsecutiveFailures},
+		schedule:        make(map[string]*watchdogState),
+	}
+}
+
+// Replace the subscription map, e.g. with the subscriptions created on startup
+func (r *Reconciler) SetSubscriptions(subscriptionMap map[string]ServerSubscriptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish()
+	r.subscriptionMap = subscriptionMap
+}
+
+// Publish a copy of the subscription map for SubscriptionsOf, must be
+// called with mu held after changing the map
+func (r *Reconciler) publish() {
+	published := copySubscriptionMap(r.subscriptionMap)
+	r.publishedMu.Lock()
+	r.published = published
+	r.publishedMu.Unlock()
+}
+
+// Get the current subscriptions of a server by destination, without
+// waiting for a reconcile in progress. The result must not be modified.
+func (r *Reconciler) SubscriptionsOf(serverID string) ServerSubscriptions {
+	r.publishedMu.RLock()
+	defer r.publishedMu.RUnlock()
+	return r.published[serverID]
+}
+
+// Set the timing of the checks, zero fields keep their defaults
+func (r *Reconciler) SetWatchdog(cfg WatchdogConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.watchdog = r.watchdog.merge(&cfg)
+}
+
+// Skip the servers in maintenance, so subscriptions deleted on purpose
+// during e.g. a firmware update are not recreated
+func (r *Reconciler) SetMaintenance(maintenance *MaintenanceRegistry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maintenance = maintenance
+}
+
+// Split the servers into those to reconcile and those in maintenance
+func (r *Reconciler) activeServers(servers []RedfishServer) ([]RedfishServer, []string) {
+	if r.maintenance == nil {
+		return servers, nil
+	}
+	var active []RedfishServer
+	var inMaintenance []string
+	for _, server := range servers {
+		if r.maintenance.InMaintenance(server.IP) {
+			inMaintenance = append(inMaintenance, server.ID())
+		} else {
+			active = append(active, server)
+		}
+	}
+	return active, inMaintenance
+}
+
+// Get a copy of the subscription map
+func (r *Reconciler) Subscriptions() map[string]ServerSubscriptions {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return copySubscriptionMap(r.subscriptionMap)
+}
+
+// Delete all subscriptions in the map and stop reconciling. The lock is
+// held for the whole deletion, so a reconcile running concurrently either
+// finishes first and its subscriptions get deleted, or finds the
+// reconciler closed and creates nothing.
+func (r *Reconciler) DeleteAll(redfishServers []RedfishServer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish()
+
+	r.closed = true
+	subscriptionMap := copySubscriptionMap(r.subscriptionMap)
+	// Subscriptions paused by deleting them are gone already
+	for _, subscriptions := range subscriptionMap {
+		for destination, subscription := range subscriptions {
+			if subscription.Deleted {
+				delete(subscriptions, destination)
+			}
+		}
+	}
+	err := DeleteSubscriptionsFromAllServers(redfishServers, subscriptionMap)
+	r.subscriptionMap = make(map[string]ServerSubscriptions)
+	return err
+}
+
+// Copy a subscription map, so it can be iterated without holding its lock
+func copySubscriptionMap(subscriptionMap map[string]ServerSubscriptions) map[string]ServerSubscriptions {
+	subscriptionMapCopy := make(map[string]ServerSubscriptions, len(subscriptionMap))
+	for serverIP, subscriptions := range subscriptionMap {
+		subscriptionMapCopy[serverIP] = make(ServerSubscriptions, len(subscriptions))
+		for destination, subscription := range subscriptions {
+			subscriptionMapCopy[serverIP][destination] = subscription
+		}
+	}
+	return subscriptionMapCopy
+}
+
+// Check each server's subscriptions every poll interval until the context
+// is done, see WatchdogConfig
+func (r *Reconciler) Run(ctx context.Context) {
+	r.mu.Lock()
+	interval := r.watchdog.PollInterval.Duration
+	tick := r.tickInterval()
+	r.mu.Unlock()
+	if tick <= 0 {
+		log.Println("No reconcile interval set, not starting reconcile loop")
+		return
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	log.Printf("Starting subscription reconcile loop every %v", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Context done, stopping reconcile loop")
+			return
+		case now := <-ticker.C:
+			r.mu.Lock()
+			if r.closed {
+				r.mu.Unlock()
+				return
+			}
+			due := r.dueServers(now)
+			if len(due) == 0 {
+				r.mu.Unlock()
+				continue
+			}
+			servers, inMaintenance := r.activeServers(due)
+			report := Reconcile(servers, r.payload, r.subscriptionMap)
+			report.InMaintenance = inMaintenance
+			r.scheduleNext(now, due, report)
+			r.publish()
+			r.mu.Unlock()
+			log.Printf("Reconciled subscriptions: %d created, %d recreated, %d updated, %d unchanged, %d failed, %d skipped, %d in maintenance",
+				len(report.Created), len(report.Recreated), len(report.Updated), len(report.Unchanged), len(report.Errors), len(report.Skipped), len(report.InMaintenance))
+			for serverID, err := range report.Errors {
+				log.Printf("Failed to reconcile subscription on server %s: %v", serverID, err)
+			}
+		}
+	}
+}
+
+// Reconcile the subscriptions of a single server, e.g. right after its BMC
+// came back from a reboot, instead of waiting for the next full reconcile
+func (r *Reconciler) ReconcileServer(server RedfishServer, payload SubscriptionPayload) (ReconcileReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ReconcileReport{}, ErrReconcilerClosed
+	}
+	if _, inMaintenance := r.activeServers([]RedfishServer{server}); len(inMaintenance) > 0 {
+		return ReconcileReport{InMaintenance: inMaintenance}, nil
+	}
+
+	report := Reconcile([]RedfishServer{server}, payload, r.subscriptionMap)
+	r.publish()
+	if err := report.Errors[server.ID()]; err != nil {
+		return report, fmt.Errorf("failed to reconcile subscription on server %s: %w", server.IP, err)
+	}
+	return report, nil
+}
+
+// Find the servers lacking a subscription that matches the payload, keyed by
+// server ID, without changing anything. Servers whose subscriptions could
+// not be read are left out of the map and reported in the error.
+func FindMissingSubscriptions(servers []RedfishServer, payload SubscriptionPayload) (map[string]bool, error) {
+	serverSubscriptions, errs := GetAllSubscriptionsAcrossServers(servers)
+
+	missing := make(map[string]bool)
+	var checkErrs []error
+	for _, server := range servers {
+		if err, failed := errs[server.ID()]; failed {
+			checkErrs = append(checkErrs, err)
+			continue
+		}
+		serverPayload, err := RenderPayload(payload, server)
+		if err != nil {
+			checkErrs = append(checkErrs, err)
+			continue
+		}
+		serverPayload = applyDeliveryPolicy(server, serverPayload)
+
+		missing[server.ID()] = false
+		for _, destinationPayload := range splitDestinations(serverPayload) {
+			found := slices.ContainsFunc(serverSubscriptions[server.ID()], func(subscription *redfish.EventDestination) bool {
+				return subscriptionMatches(subscription, destinationPayload)
+			})
+			if !found {
+				missing[server.ID()] = true
+				break
+			}
+		}
+	}
+	return missing, errors.Join(checkErrs...)
+}
+
+// Read back a subscription just created. A subscription the BMC did not
+// store is an error, one it stored differently is only logged since many
+// BMCs leave out optional properties.
+func verifyCreatedSubscription(server RedfishServer, subscriptionURI string, payload SubscriptionPayload) error {
+	subscription, err := GetSubscriptionByURI(context.Background(), server, subscriptionURI)
+	if err != nil {
+		return fmt.Errorf("failed to verify created subscription: %w", err)
+	}
+	if diffs := DiffSubscriptionPayload(payload, subscription); len(diffs) > 0 {
+		log.Printf("Subscription %s on server %s was stored differently than requested: %v", subscriptionURI, server.IP, diffs)
+	}
+	return nil
+}
+
+// Check whether the subscription with the given URI still exists on the
+// server and how it differs from the payload
+func checkSubscription(server RedfishServer, subscriptionURI string, payload SubscriptionPayload) (bool, []PayloadDiff, error) {
+	subscriptions, err := getServerSubscriptions(server)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to check subscription on server %s: %w", server.IP, err)
+	}
+	for _, subscription := range subscriptions {
+		if subscription.ODataID == subscriptionURI {
+			return true, DiffSubscriptionPayload(payload, subscription), nil
+		}
+	}
+	return false, nil, nil
+}
@@ -0,0 +1,155 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func TestListenerFollowsReconciledSubscriptions(t *testing.T) {
+	b := newFakeBMC(t)
+	server := b.server()
+	payload := SubscriptionPayload{Destination: "https://10.0.0.100:8080", Protocol: "Redfish", EventTypes: []redfish.EventType{redfish.AlertEventType}, Context: "scrapefish"}
+	reconciler := NewReconciler([]RedfishServer{server}, payload, make(map[string]ServerSubscriptions))
+	listener := NewServer("127.0.0.1", "8080", nil, nil)
+	listener.SetReconciler(reconciler)
+
+	if uri := listener.subscriptionURI(server.ID()); uri != "" {
+		t.Fatalf("subscriptionURI() before subscribing = %q, want none", uri)
+	}
+
+	report, err := reconciler.ReconcileServer(server, payload)
+	if err != nil || len(report.Created) != 1 {
+		t.Fatalf("ReconcileServer() = %+v, %v, want the subscription created", report, err)
+	}
+	created := listener.subscriptionURI(server.ID())
+	if created == "" || b.subscription(created) == nil {
+		t.Fatalf("subscriptionURI() = %q, want the subscription on the BMC", created)
+	}
+
+	// The BMC loses the subscription and the reconciler recreates it
+	b.mu.Lock()
+	delete(b.subscriptions, created)
+	b.mu.Unlock()
+	report, err = reconciler.ReconcileServer(server, payload)
+	if err != nil || len(report.Recreated) != 1 {
+		t.Fatalf("ReconcileServer() = %+v, %v, want the subscription recreated", report, err)
+	}
+	recreated := listener.subscriptionURI(server.ID())
+	if recreated == created || b.subscription(recreated) == nil {
+		t.Errorf("subscriptionURI() after recreating = %q, want the new subscription instead of %q", recreated, created)
+	}
+
+	if err := reconciler.DeleteAll([]RedfishServer{server}); err != nil {
+		t.Fatalf("DeleteAll() error = %v", err)
+	}
+	if uri := listener.subscriptionURI(server.ID()); uri != "" {
+		t.Errorf("subscriptionURI() after DeleteAll = %q, want none", uri)
+	}
+}
+
+func TestSubscriptionsKeyedByServerID(t *testing.T) {
+	payload := SubscriptionPayload{Destination: "https://10.0.0.100:8080", Protocol: "Redfish", EventTypes: []redfish.EventType{redfish.AlertEventType}, Context: "scrapefish"}
+	subscribed, unsubscribed, failing := newFakeBMC(t), newFakeBMC(t), newFakeBMC(t)
+	subscribed.addSubscription(map[string]interface{}{"Destination": payload.Destination, "Protocol": "Redfish", "Context": "scrapefish", "EventTypes": []string{"Alert"}})
+	failing.failures["GET /redfish/v1/EventService/Subscriptions"] = http.StatusInternalServerError
+
+	// Named servers are keyed by their name, the others by their IP
+	servers := []RedfishServer{subscribed.server(), unsubscribed.server(), failing.server()}
+	servers[0].Name = "node-1"
+	servers[2].Name = "node-3"
+
+	subscriptions, errs := GetAllSubscriptionsAcrossServers(servers)
+	if len(subscriptions["node-1"]) != 1 || len(subscriptions[unsubscribed.URL]) != 0 {
+		t.Errorf("GetAllSubscriptionsAcrossServers() = %v, want one subscription on node-1", subscriptions)
+	}
+	if _, ok := subscriptions[servers[0].IP]; ok {
+		t.Error("GetAllSubscriptionsAcrossServers() keyed a named server by its IP")
+	}
+	if len(errs) != 1 || errs["node-3"] == nil {
+		t.Errorf("GetAllSubscriptionsAcrossServers() errors = %v, want node-3 only", errs)
+	}
+
+	missing, err := FindMissingSubscriptions(servers, payload)
+	if err == nil {
+		t.Error("FindMissingSubscriptions() error = nil, want node-3 reported")
+	}
+	want := map[string]bool{"node-1": false, unsubscribed.URL: true}
+	if len(missing) != len(want) {
+		t.Fatalf("FindMissingSubscriptions() = %v, want %v", missing, want)
+	}
+	for serverID, wantMissing := range want {
+		if got, ok := missing[serverID]; !ok || got != wantMissing {
+			t.Errorf("FindMissingSubscriptions()[%s] = %v, %v, want %v", serverID, got, ok, wantMissing)
+		}
+	}
+}
+
+// Run with -race: creating and deleting the subscriptions concurrently
+// leaves none behind on the BMCs
+func TestReconcileConcurrentWithDeleteAll(t *testing.T) {
+	payload := SubscriptionPayload{Destination: "https://10.0.0.100:8080", Protocol: "Redfish", EventTypes: []redfish.EventType{redfish.AlertEventType}, Context: "scrapefish"}
+	var bmcs []*fakeBMC
+	var servers []RedfishServer
+	for i := 0; i < 3; i++ {
+		bmc := newFakeBMC(t)
+		bmcs = append(bmcs, bmc)
+		servers = append(servers, bmc.server())
+	}
+	reconciler := NewReconciler(servers, payload, make(map[string]ServerSubscriptions))
+
+	var wg sync.WaitGroup
+	created := make(chan struct{})
+	var createdOnce sync.Once
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server RedfishServer) {
+			defer wg.Done()
+			for {
+				report, err := reconciler.ReconcileServer(server, payload)
+				if errors.Is(err, ErrReconcilerClosed) {
+					return
+				}
+				if len(report.Created) > 0 {
+					createdOnce.Do(func() { close(created) })
+				}
+				// Read the map while it is changed
+				reconciler.Subscriptions()
+			}
+		}(server)
+	}
+
+	<-created
+	if err := reconciler.DeleteAll(servers); err != nil {
+		t.Errorf("DeleteAll() error = %v", err)
+	}
+	wg.Wait()
+
+	for i, bmc := range bmcs {
+		if n := bmc.subscriptionCount(); n != 0 {
+			t.Errorf("BMC %d has %d subscriptions left", i, n)
+		}
+	}
+	if subscriptions := reconciler.Subscriptions(); len(subscriptions) != 0 {
+		t.Errorf("Subscriptions() = %v, want none", subscriptions)
+	}
+}
@@ -0,0 +1,330 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Set at build time with -ldflags "-X main.version=<version>"
+var version = "dev"
+
+const (
+	redfishTLSHandshakeTimeout = 10 * time.Second
+	// Idle connections to a BMC are closed after this long
+	redfishIdleConnTimeout = 90 * time.Second
+)
+
+// RedfishClientConfig holds the settings of the HTTP client used for all
+// redfish connections
+type RedfishClientConfig struct {
+	// User-Agent sent with every request, defaults to ADA-redfish-exporter/<version>
+	UserAgent string
+	// Optional extra header identifying this exporter in BMC audit logs
+	IDHeaderName  string
+	IDHeaderValue string
+	// Timeout of a single request, no timeout when zero
+	Timeout time.Duration
+	// Minimum TLS version like "1.2", defaults to TLS 1.2
+	TLSMinVersion string
+	// Names of the allowed TLS 1.0-1.2 cipher suites like
+	// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, Go's defaults when empty.
+	// TLS 1.3 suites are not configurable.
+	TLSCipherSuites []string
+	// How the servers without an AuthMode of their own are authenticated,
+	// AuthModeSession when empty
+	DefaultAuthMode AuthMode
+	// Retries of the requests failing with a network error or a 429, 502,
+	// 503 or 504 status, none when MaxAttempts is below 2. Only idempotent
+	// requests are retried unless RetryNonIdempotent is set.
+	Retry              RetryConfig
+	RetryNonIdempotent bool
+}
+
+// AuthMode selects how gofish authenticates with a server
+type AuthMode string
+
+const (
+	// Log in to a session and send its token, logging out when done
+	AuthModeSession AuthMode = "session"
+	// Send the username and password with every request
+	AuthModeBasic AuthMode = "basic"
+)
+
+func (m AuthMode) validate() error {
+	switch m {
+	case "", AuthModeSession, AuthModeBasic:
+		return nil
+	}
+	return fmt.Errorf("unknown auth mode %q, expected %q or %q", m, AuthModeSession, AuthModeBasic)
+}
+
+// The auth mode of a server: its own AuthMode wins over the default, and
+// without either sessions are used
+func effectiveAuthMode(server RedfishServer, defaultMode AuthMode) AuthMode {
+	if server.AuthMode != "" {
+		return server.AuthMode
+	}
+	if defaultMode != "" {
+		return defaultMode
+	}
+	return AuthModeSession
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Build the TLS settings of the redfish connections, failing on unknown
+// versions and cipher suite names
+func (config RedfishClientConfig) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true, // TODO Set Based on login type
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if config.TLSMinVersion != "" {
+		minVersion, ok := tlsVersions[strings.TrimPrefix(config.TLSMinVersion, "TLS")]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS version %q, expected one of 1.0, 1.1, 1.2 or 1.3", config.TLSMinVersion)
+		}
+		tlsConfig.MinVersion = minVersion
+	}
+
+	if len(config.TLSCipherSuites) > 0 {
+		suites := make(map[string]uint16)
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			suites[suite.Name] = suite.ID
+		}
+		for _, name := range config.TLSCipherSuites {
+			id, ok := suites[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown TLS cipher suite %q", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// TLSPolicy overrides the TLS settings of the connections to one server.
+// Fields left at their zero value keep the shared settings.
+type TLSPolicy struct {
+	// Minimum TLS version like tls.VersionTLS12 (0x0303)
+	MinVersion uint16 `json:"minVersion,omitempty"`
+	// IDs of the allowed TLS 1.0-1.2 cipher suites
+	CipherSuites []uint16 `json:"cipherSuites,omitempty"`
+	// Passed on to tls.Config, which ignores it since Go 1.18
+	PreferServerCipherSuites bool `json:"preferServerCipherSuites,omitempty"`
+}
+
+// Read the TLS policy from REDFISH_TLS_MIN_VERSION and
+// REDFISH_TLS_CIPHER_SUITES, falling back to the defaults when they are
+// invalid
+func TLSPolicyFromEnv() TLSPolicy {
+	config := RedfishClientConfig{
+		TLSMinVersion:   os.Getenv("REDFISH_TLS_MIN_VERSION"),
+		TLSCipherSuites: splitList(os.Getenv("REDFISH_TLS_CIPHER_SUITES")),
+	}
+	tlsConfig, err := config.TLSConfig()
+	if err != nil {
+		log.Printf("Invalid redfish TLS settings, using the defaults: %v", err)
+		tlsConfig, _ = RedfishClientConfig{}.TLSConfig()
+	}
+	return TLSPolicy{MinVersion: tlsConfig.MinVersion, CipherSuites: tlsConfig.CipherSuites}
+}
+
+// Check that the version and cipher suites are known
+func (p TLSPolicy) validate() error {
+	if p.MinVersion != 0 && !slices.Contains(slices.Collect(maps.Values(tlsVersions)), p.MinVersion) {
+		return fmt.Errorf("unknown TLS version 0x%04x", p.MinVersion)
+	}
+	suites := make(map[uint16]bool)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites[suite.ID] = true
+	}
+	for _, id := range p.CipherSuites {
+		if !suites[id] {
+			return fmt.Errorf("unknown TLS cipher suite 0x%04x", id)
+		}
+	}
+	return nil
+}
+
+// Apply the policy over the shared TLS settings
+func (p TLSPolicy) apply(tlsConfig *tls.Config) {
+	if p.MinVersion != 0 {
+		tlsConfig.MinVersion = p.MinVersion
+	}
+	if len(p.CipherSuites) > 0 {
+		tlsConfig.CipherSuites = p.CipherSuites
+	}
+	tlsConfig.PreferServerCipherSuites = p.PreferServerCipherSuites
+}
+
+func defaultUserAgent() string {
+	return "ADA-redfish-exporter/" + version
+}
+
+// Build the HTTP client for a server, with its TLS policy and, for OAuth
+// servers, sending the bearer token. The clients of a server share their
+// connections.
+func newServerHTTPClient(server RedfishServer) *http.Client {
+	settings := server.settings()
+	client := newRedfishHTTPClientWithTransport(settings.client, settings.transports.get(server))
+	if isOAuthLogin(server) {
+		client.Transport = &bearerTransport{base: client.Transport, tokens: settings.tokens}
+	}
+	if settings.sessions != nil {
+		client.Transport = &sessionTrackingTransport{base: client.Transport, serverIP: server.IP, tracker: settings.sessions}
+	}
+	return client
+}
+
+func newRedfishHTTPClientWithPolicy(config RedfishClientConfig, policy *TLSPolicy) *http.Client {
+	return newRedfishHTTPClientWithTransport(config, newRedfishTransport(config, policy))
+}
+
+// Build the transport of the redfish connections. BMCs announcing h2 in
+// ALPN are talked to over HTTP/2, which multiplexes the requests over a
+// single connection and uses up fewer of their few concurrent sessions.
+func newRedfishTransport(config RedfishClientConfig, policy *TLSPolicy) *http.Transport {
+	tlsConfig, err := config.TLSConfig()
+	if err != nil {
+		// The config is validated on startup, this only happens for embedders
+		// skipping validation
+		log.Printf("Invalid redfish TLS settings, using the defaults: %v", err)
+		tlsConfig, _ = RedfishClientConfig{}.TLSConfig()
+	}
+	if policy != nil {
+		policy.apply(tlsConfig)
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSHandshakeTimeout: redfishTLSHandshakeTimeout,
+		TLSClientConfig:     tlsConfig,
+		IdleConnTimeout:     redfishIdleConnTimeout,
+		ForceAttemptHTTP2:   true,
+	}
+	if err := http2.ConfigureTransport(transport); err != nil {
+		log.Printf("Failed to enable HTTP/2 for redfish connections: %v", err)
+	}
+	return transport
+}
+
+func newRedfishHTTPClientWithTransport(config RedfishClientConfig, transport http.RoundTripper) *http.Client {
+	headers := make(http.Header)
+	userAgent := config.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent()
+	}
+	headers.Set("User-Agent", userAgent)
+	if config.IDHeaderName != "" {
+		headers.Set(config.IDHeaderName, config.IDHeaderValue)
+	}
+
+	base := transport
+	if config.Retry.MaxAttempts > 1 {
+		base = &retryingTransport{base: transport, retry: config.Retry, retryNonIdempotent: config.RetryNonIdempotent}
+	}
+	return &http.Client{Transport: &headerTransport{base: base, headers: headers}, Timeout: config.Timeout}
+}
+
+// serverTransports keeps one transport per server and TLS policy, so the
+// clients built for a server reuse its connections
+type serverTransports struct {
+	mu         sync.Mutex
+	transports map[serverTransportKey]*http.Transport
+}
+
+type serverTransportKey struct {
+	ip     string
+	policy *TLSPolicy
+}
+
+func newServerTransports() *serverTransports {
+	return &serverTransports{transports: make(map[serverTransportKey]*http.Transport)}
+}
+
+// The transport of the server, built with its TLS policy on first use
+func (t *serverTransports) get(server RedfishServer) *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := serverTransportKey{ip: server.IP, policy: server.TLSConfig}
+	transport, ok := t.transports[key]
+	if !ok {
+		transport = newRedfishTransport(server.settings().client, server.TLSConfig)
+		t.transports[key] = transport
+	}
+	return transport
+}
+
+// Get the HTTP protocol negotiated with the server, "h2" or "http/1.1".
+// Only the unauthenticated service root is requested.
+func ProtocolVersion(ctx context.Context, server RedfishServer) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(server.IP, "/")+"/redfish/v1/", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := newServerHTTPClient(server).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach server %s: %w", server.IP, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.ProtoMajor == 2 {
+		return "h2", nil
+	}
+	return "http/1.1", nil
+}
+
+// headerTransport sets fixed headers on every request, overriding the ones
+// set by gofish
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	// gofish closes the connection after each request when it didn't build
+	// the HTTP client itself, the connections are kept for reuse instead
+	req.Close = false
+	for key, values := range t.headers {
+		req.Header[key] = values
+	}
+	return t.base.RoundTrip(req)
+}
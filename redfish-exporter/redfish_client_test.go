@@ -0,0 +1,331 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRedfishClientHeaders(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        RedfishClientConfig
+		wantUserAgent string
+		wantIDHeader  string
+	}{
+		{"defaults", RedfishClientConfig{}, "ADA-redfish-exporter/dev", ""},
+		{"configured", RedfishClientConfig{UserAgent: "fleet-monitor/2.0", IDHeaderName: "X-Client-Id", IDHeaderValue: "exporter-1"}, "fleet-monitor/2.0", "exporter-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header http.Header
+			bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+			}))
+			defer bmc.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, bmc.URL+"/redfish/v1", nil)
+			req.Header.Set("User-Agent", "gofish/1.0")
+			resp, err := newRedfishHTTPClientWithPolicy(tt.config, nil).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := header.Get("User-Agent"); got != tt.wantUserAgent {
+				t.Errorf("User-Agent = %q, want %q", got, tt.wantUserAgent)
+			}
+			if got := header.Get("X-Client-Id"); got != tt.wantIDHeader {
+				t.Errorf("X-Client-Id = %q, want %q", got, tt.wantIDHeader)
+			}
+		})
+	}
+}
+
+func TestRedfishClientConfigTLSConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      RedfishClientConfig
+		wantVersion uint16
+		wantSuites  []uint16
+		wantErr     bool
+	}{
+		{"defaults", RedfishClientConfig{}, tls.VersionTLS12, nil, false},
+		{"TLS 1.3", RedfishClientConfig{TLSMinVersion: "1.3"}, tls.VersionTLS13, nil, false},
+		{"prefixed version", RedfishClientConfig{TLSMinVersion: "TLS1.1"}, tls.VersionTLS11, nil, false},
+		{"cipher suites", RedfishClientConfig{TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}, tls.VersionTLS12, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, false},
+		{"unknown version", RedfishClientConfig{TLSMinVersion: "2.0"}, 0, nil, true},
+		{"unknown cipher suite", RedfishClientConfig{TLSCipherSuites: []string{"TLS_NULL"}}, 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := tt.config.TLSConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tlsConfig.MinVersion != tt.wantVersion || !slices.Equal(tlsConfig.CipherSuites, tt.wantSuites) {
+				t.Errorf("TLSConfig() = version 0x%04x, suites %v, want 0x%04x, %v", tlsConfig.MinVersion, tlsConfig.CipherSuites, tt.wantVersion, tt.wantSuites)
+			}
+		})
+	}
+}
+
+func TestRedfishClientConfigTLSHandshake(t *testing.T) {
+	// A BMC only accepting TLS 1.3
+	bmc := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	bmc.TLS = &tls.Config{MinVersion: tls.VersionTLS13}
+	bmc.StartTLS()
+	t.Cleanup(bmc.Close)
+
+	tests := []struct {
+		name   string
+		config RedfishClientConfig
+		// Pins the client to an older version, the latest supported when 0
+		maxVersion uint16
+		wantErr    bool
+	}{
+		{name: "defaults", config: RedfishClientConfig{}},
+		{name: "TLS 1.3 required", config: RedfishClientConfig{TLSMinVersion: "1.3"}},
+		{name: "pinned to TLS 1.2", config: RedfishClientConfig{TLSMinVersion: "1.2"}, maxVersion: tls.VersionTLS12, wantErr: true},
+		{name: "pinned to TLS 1.2 with cipher suites", config: RedfishClientConfig{TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}, maxVersion: tls.VersionTLS12, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := tt.config.TLSConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tlsConfig.MaxVersion = tt.maxVersion
+			conn, err := tls.Dial("tcp", bmc.Listener.Addr().String(), tlsConfig)
+			if tt.wantErr {
+				if err == nil {
+					conn.Close()
+					t.Fatal("handshake succeeded with a TLS 1.3 only server")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("handshake failed: %v", err)
+			}
+			defer conn.Close()
+			if version := conn.ConnectionState().Version; version != tls.VersionTLS13 {
+				t.Errorf("negotiated TLS version 0x%04x, want 0x%04x", version, tls.VersionTLS13)
+			}
+		})
+	}
+}
+
+func TestTLSPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      TLSPolicy
+		wantVersion uint16
+		wantErr     bool
+	}{
+		{"shared settings kept", TLSPolicy{}, tls.VersionTLS12, false},
+		{"older version for legacy BMCs", TLSPolicy{MinVersion: tls.VersionTLS10, CipherSuites: []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA}}, tls.VersionTLS10, false},
+		{"unknown version", TLSPolicy{MinVersion: 0x0200}, 0, true},
+		{"unknown cipher suite", TLSPolicy{CipherSuites: []uint16{0xffff}}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			tlsConfig, _ := RedfishClientConfig{}.TLSConfig()
+			tt.policy.apply(tlsConfig)
+			if tlsConfig.MinVersion != tt.wantVersion {
+				t.Errorf("MinVersion = 0x%04x, want 0x%04x", tlsConfig.MinVersion, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestServiceRootReachableUsesServerTLSPolicy(t *testing.T) {
+	// A BMC only speaking TLS 1.2
+	bmc := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	bmc.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	bmc.StartTLS()
+	t.Cleanup(bmc.Close)
+
+	tests := []struct {
+		name   string
+		policy *TLSPolicy
+		want   bool
+	}{
+		{name: "default policy", want: true},
+		{name: "policy allowing TLS 1.2", policy: &TLSPolicy{MinVersion: tls.VersionTLS12}, want: true},
+		{name: "policy requiring TLS 1.3", policy: &TLSPolicy{MinVersion: tls.VersionTLS13}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := RedfishServer{IP: bmc.URL, TLSConfig: tt.policy}
+			if got := serviceRootReachable(context.Background(), newServerHTTPClient(server), server); got != tt.want {
+				t.Errorf("serviceRootReachable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLSPolicyFromEnvRejectsOldServers(t *testing.T) {
+	// A BMC only speaking TLS 1.1
+	bmc := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	bmc.TLS = &tls.Config{MinVersion: tls.VersionTLS11, MaxVersion: tls.VersionTLS11}
+	bmc.StartTLS()
+	t.Cleanup(bmc.Close)
+
+	tests := []struct {
+		name        string
+		minVersion  string
+		wantVersion uint16
+		wantErr     bool
+	}{
+		{name: "TLS 1.2 by default", wantVersion: tls.VersionTLS12, wantErr: true},
+		{name: "TLS 1.2 required", minVersion: "1.2", wantVersion: tls.VersionTLS12, wantErr: true},
+		{name: "TLS 1.1 allowed", minVersion: "1.1", wantVersion: tls.VersionTLS11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REDFISH_TLS_MIN_VERSION", tt.minVersion)
+			policy := TLSPolicyFromEnv()
+			if policy.MinVersion != tt.wantVersion {
+				t.Fatalf("MinVersion = 0x%04x, want 0x%04x", policy.MinVersion, tt.wantVersion)
+			}
+
+			server := RedfishServer{IP: bmc.URL, TLSConfig: &policy}
+			resp, err := newServerHTTPClient(server).Get(bmc.URL)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("connected to a TLS 1.1 server")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.TLS.Version != tls.VersionTLS11 {
+				t.Errorf("negotiated TLS version 0x%04x, want 0x%04x", resp.TLS.Version, tls.VersionTLS11)
+			}
+		})
+	}
+}
+
+func TestEffectiveAuthMode(t *testing.T) {
+	tests := []struct {
+		serverMode  AuthMode
+		defaultMode AuthMode
+		want        AuthMode
+	}{
+		{"", "", AuthModeSession},
+		{"", AuthModeBasic, AuthModeBasic},
+		{AuthModeSession, AuthModeBasic, AuthModeSession},
+		{AuthModeBasic, "", AuthModeBasic},
+	}
+	for _, tt := range tests {
+		if got := effectiveAuthMode(RedfishServer{AuthMode: tt.serverMode}, tt.defaultMode); got != tt.want {
+			t.Errorf("effectiveAuthMode(%q, %q) = %q, want %q", tt.serverMode, tt.defaultMode, got, tt.want)
+		}
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		http2 bool
+		want  string
+	}{
+		{"HTTP/2", true, "h2"},
+		{"HTTP/1.1", false, "http/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			bmc.EnableHTTP2 = tt.http2
+			bmc.StartTLS()
+			defer bmc.Close()
+
+			got, err := ProtocolVersion(context.Background(), RedfishServer{IP: bmc.URL})
+			if err != nil || got != tt.want {
+				t.Errorf("ProtocolVersion() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerHTTPClientReusesConnections(t *testing.T) {
+	tests := []struct {
+		name      string
+		http2     bool
+		wantProto string
+	}{
+		{"HTTP/2", true, "HTTP/2.0"},
+		{"HTTP/1.1", false, "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var connections atomic.Int32
+			bmc := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			bmc.EnableHTTP2 = tt.http2
+			bmc.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					connections.Add(1)
+				}
+			}
+			bmc.StartTLS()
+			defer bmc.Close()
+			server := withConnectionSettings([]RedfishServer{{IP: bmc.URL}}, newConnectionSettings(RedfishClientConfig{}))[0]
+
+			// Separate clients, each request asking to close the connection
+			// like gofish does
+			for i := 0; i < 3; i++ {
+				req, _ := http.NewRequest(http.MethodGet, bmc.URL+"/redfish/v1/", nil)
+				req.Close = true
+				resp, err := newServerHTTPClient(server).Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				if resp.Proto != tt.wantProto {
+					t.Errorf("protocol = %s, want %s", resp.Proto, tt.wantProto)
+				}
+			}
+			if got := connections.Load(); got != 1 {
+				t.Errorf("%d connections opened, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+// ConnectErrorCategory groups redfish connection failures so that logs and
+// metrics can tell a network outage apart from bad credentials.
+type ConnectErrorCategory string
+
+const (
+	ConnectErrorDNS               ConnectErrorCategory = "DNSError"
+	ConnectErrorConnectionRefused ConnectErrorCategory = "ConnectionRefused"
+	ConnectErrorTimeout           ConnectErrorCategory = "Timeout"
+	ConnectErrorAuthFailed        ConnectErrorCategory = "AuthFailed"
+	ConnectErrorTLS               ConnectErrorCategory = "TLSError"
+	ConnectErrorUnknown           ConnectErrorCategory = "Unknown"
+)
+
+// RedfishConnectError is returned by getRedfishClient when the connection
+// to a redfish server could not be established
+type RedfishConnectError struct {
+	Server   string
+	Category ConnectErrorCategory
+	Err      error
+}
+
+func (e *RedfishConnectError) Error() string {
+	return fmt.Sprintf("%s connecting to redfish server %s: %v", e.Category, e.Server, e.Err)
+}
+
+func (e *RedfishConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Classify a gofish connect error into a ConnectErrorCategory
+func classifyConnectError(err error) ConnectErrorCategory {
+	if err == nil {
+		return ""
+	}
+
+	var redfishErr *common.Error
+	if errors.As(err, &redfishErr) {
+		switch redfishErr.HTTPReturnedStatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ConnectErrorAuthFailed
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ConnectErrorDNS
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ConnectErrorConnectionRefused
+	}
+
+	if isTLSError(err) {
+		return ConnectErrorTLS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ConnectErrorTimeout
+	}
+
+	return ConnectErrorUnknown
+}
+
+func isTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	return errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &verifyErr) ||
+		errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr)
+}
+
+// NonStandardBMCError replaces redfish errors whose body was not a redfish
+// JSON error, such as the HTML or empty bodies some BMCs return
+type NonStandardBMCError struct {
+	StatusCode int
+}
+
+func (e *NonStandardBMCError) Error() string {
+	return fmt.Sprintf("BMC returned non-standard error (status %d)", e.StatusCode)
+}
+
+// Replace redfish errors with a non-JSON body by a concise NonStandardBMCError
+// so vendor error pages don't end up in the logs. Other errors are returned
+// unchanged.
+func normalizeRedfishError(err error) error {
+	var redfishErr *common.Error
+	if !errors.As(err, &redfishErr) || redfishErr.HTTPReturnedStatusCode == 0 || redfishErr.Code != "" {
+		return err
+	}
+	body := strings.TrimSpace(redfishErr.Message)
+	if body != "" && json.Valid([]byte(body)) {
+		return err
+	}
+	return &NonStandardBMCError{StatusCode: redfishErr.HTTPReturnedStatusCode}
+}
+
+// Return the HTTP status code of a redfish error, or 0 if there is none
+func redfishStatusCode(err error) int {
+	var redfishErr *common.Error
+	if errors.As(err, &redfishErr) {
+		return redfishErr.HTTPReturnedStatusCode
+	}
+	var nonStandardErr *NonStandardBMCError
+	if errors.As(err, &nonStandardErr) {
+		return nonStandardErr.StatusCode
+	}
+	return 0
+}
+
+// Returned when subscriptions are created for an empty server list, see
+// BatchCreateOptions.AllowEmpty
+var ErrNoServers = errors.New("no servers to subscribe to")
+
+// Returned by ValidateSubscriptionPayload for a payload with nothing set
+var ErrEmptyPayload = errors.New("subscription payload is empty")
+
+// Returned when two payloads of a batch subscribe the same Destination on a
+// server, creating the second would delete the first as a conflict
+var ErrDuplicateDestinationInBatch = errors.New("duplicate destination in batch")
+
+// Returned when a server answers without a usable event service
+var ErrNoEventService = errors.New("no event service")
+
+// Returned by VerifyCredentials when the BMC rejects the credentials
+var ErrAuthFailed = errors.New("authentication failed")
+
+// Returned by PingServer when the BMC no longer accepts the session
+var ErrSessionExpired = errors.New("session expired")
+
+// Returned by PingServer when the BMC doesn't answer the service root
+var ErrUnhealthyServer = errors.New("server unhealthy")
+
+// Returned for a server IP or name that isn't configured
+var ErrServerNotFound = errors.New("server not found")
+
+// Returned by BIOSManager.CommitSettings when asked to reboot a node that
+// still runs slurm jobs
+var ErrNodeNotDrained = errors.New("node not drained")
@@ -0,0 +1,200 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+// Connection failures must reach the callers as RedfishConnectError
+func TestConnectErrorIsWrapped(t *testing.T) {
+	bmc := newFakeBMC(t)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	wrongPassword := bmc.server()
+	wrongPassword.Password = "wrong"
+
+	servers := []struct {
+		name         string
+		server       RedfishServer
+		wantCategory ConnectErrorCategory
+	}{
+		{"connection refused", RedfishServer{IP: closed.URL, Username: "admin", Password: "password"}, ConnectErrorConnectionRefused},
+		{"wrong password", wrongPassword, ConnectErrorAuthFailed},
+	}
+	calls := []struct {
+		name string
+		call func(RedfishServer) error
+	}{
+		{"deleteSubscriptionFromServer", func(server RedfishServer) error {
+			return deleteSubscriptionFromServer(server, "/redfish/v1/EventService/Subscriptions/1")
+		}},
+		{"GetSubscriptionByURI", func(server RedfishServer) error {
+			_, err := GetSubscriptionByURI(context.Background(), server, "/redfish/v1/EventService/Subscriptions/1")
+			return err
+		}},
+		{"getServerSubscriptions", func(server RedfishServer) error {
+			_, err := getServerSubscriptions(server)
+			return err
+		}},
+		{"checkSubscription", func(server RedfishServer) error {
+			_, _, err := checkSubscription(server, "/redfish/v1/EventService/Subscriptions/1", SubscriptionPayload{})
+			return err
+		}},
+		{"createSubscription", func(server RedfishServer) error {
+			_, err := createSubscription(server, SubscriptionPayload{Destination: "https://10.0.0.9:8080"})
+			return err
+		}},
+	}
+
+	for _, server := range servers {
+		for _, call := range calls {
+			t.Run(fmt.Sprintf("%s/%s", server.name, call.name), func(t *testing.T) {
+				err := call.call(server.server)
+				var connectErr *RedfishConnectError
+				if !errors.As(err, &connectErr) {
+					t.Fatalf("error %v is not a RedfishConnectError", err)
+				}
+				if connectErr.Category != server.wantCategory {
+					t.Errorf("category = %s, want %s", connectErr.Category, server.wantCategory)
+				}
+			})
+		}
+	}
+}
+
+func TestNormalizeRedfishError(t *testing.T) {
+	redfishErr := common.ConstructError(http.StatusBadRequest, []byte(`{"error": {"code": "Base.1.0.PropertyMissing", "message": "Destination is required"}}`))
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		// Whether the error is replaced by a NonStandardBMCError
+		wantNormalized bool
+	}{
+		{"HTML body", common.ConstructError(http.StatusInternalServerError, []byte("<html><body><h1>500 Internal Server Error</h1></body></html>")), http.StatusInternalServerError, true},
+		{"empty body", common.ConstructError(http.StatusServiceUnavailable, nil), http.StatusServiceUnavailable, true},
+		{"whitespace body", common.ConstructError(http.StatusBadGateway, []byte("\n  \n")), http.StatusBadGateway, true},
+		{"redfish error", redfishErr, http.StatusBadRequest, false},
+		{"JSON without a redfish error", common.ConstructError(http.StatusNotFound, []byte(`{"status": "not found"}`)), http.StatusNotFound, false},
+		{"wrapped HTML body", fmt.Errorf("failed: %w", common.ConstructError(http.StatusForbidden, []byte("<h1>Forbidden</h1>"))), http.StatusForbidden, true},
+		{"not a redfish error", errors.New("connection reset"), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := normalizeRedfishError(tt.err)
+			var nonStandardErr *NonStandardBMCError
+			if got := errors.As(err, &nonStandardErr); got != tt.wantNormalized {
+				t.Fatalf("normalizeRedfishError() = %v, normalized %v, want %v", err, got, tt.wantNormalized)
+			}
+			if !tt.wantNormalized && err != tt.err {
+				t.Errorf("normalizeRedfishError() = %v, want the error unchanged", err)
+			}
+			if tt.wantNormalized {
+				want := fmt.Sprintf("BMC returned non-standard error (status %d)", tt.wantStatus)
+				if err.Error() != want {
+					t.Errorf("Error() = %q, want %q", err.Error(), want)
+				}
+			}
+			if got := redfishStatusCode(err); got != tt.wantStatus {
+				t.Errorf("redfishStatusCode() = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// An error page of the BMC is replaced in the deletion error
+func TestDeleteSubscriptionHTMLError(t *testing.T) {
+	bmc := newFakeBMC(t)
+	uri := "/redfish/v1/EventService/Subscriptions/9"
+	bmc.handlers[uri] = func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, "<html><body><h1>500 Internal Server Error</h1></body></html>")
+	}
+
+	err := deleteSubscriptionFromServer(bmc.server(), uri)
+	var nonStandardErr *NonStandardBMCError
+	if !errors.As(err, &nonStandardErr) {
+		t.Fatalf("deleteSubscriptionFromServer() error = %v, want a NonStandardBMCError", err)
+	}
+	if nonStandardErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("StatusCode = %d, want %d", nonStandardErr.StatusCode, http.StatusInternalServerError)
+	}
+	if strings.Contains(err.Error(), "<html>") {
+		t.Errorf("error %q contains the error page", err)
+	}
+}
+
+func TestMissingEventService(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(bmc *fakeBMC)
+	}{
+		{"empty event service", func(bmc *fakeBMC) {
+			bmc.handlers["/redfish/v1/EventService"] = func(w http.ResponseWriter, r *http.Request) {
+				writeFakeJSON(w, http.StatusOK, map[string]interface{}{})
+			}
+		}},
+		{"null event service", func(bmc *fakeBMC) {
+			bmc.handlers["/redfish/v1/EventService"] = func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, "null")
+			}
+		}},
+	}
+	calls := []struct {
+		name string
+		call func(server RedfishServer) error
+	}{
+		{"createSubscription", func(server RedfishServer) error {
+			_, err := createSubscription(server, SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish"})
+			return err
+		}},
+		{"deleteSubscriptionFromServer", func(server RedfishServer) error {
+			return deleteSubscriptionFromServer(server, "/redfish/v1/EventService/Subscriptions/1")
+		}},
+		{"getServerSubscriptions", func(server RedfishServer) error {
+			_, err := getServerSubscriptions(server)
+			return err
+		}},
+		{"SendTestEvent", func(server RedfishServer) error {
+			_, err := SendTestEvent(server, "test")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		for _, call := range calls {
+			t.Run(tt.name+"/"+call.name, func(t *testing.T) {
+				bmc := newFakeBMC(t)
+				tt.setup(bmc)
+				if err := call.call(bmc.server()); !errors.Is(err, ErrNoEventService) {
+					t.Errorf("%s() error = %v, want ErrNoEventService", call.name, err)
+				}
+			})
+		}
+	}
+}
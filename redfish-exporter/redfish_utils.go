@@ -17,19 +17,89 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/stmcginnis/gofish"
+	"github.com/stmcginnis/gofish/common"
 	"github.com/stmcginnis/gofish/redfish"
 )
 
 type RedfishServer struct {
 	IP        string `json:"ip"`
+	Name      string `json:"name,omitempty"` // Stable identity, see ID
 	Username  string `json:"username"`
 	Password  string `json:"password"`
 	LoginType string `json:"loginType"`
 	SlurmNode string `json:"slurmNode"`
+	// Poll the server's log services instead of subscribing, for servers that can't reach the listener
+	PollEvents bool `json:"pollEvents"`
+	// Read events from the server's server-sent event stream instead of subscribing
+	UseSSE bool `json:"useSSE"`
+	// Free-form labels used to select a subset of the servers, e.g. rack or env
+	Labels map[string]string `json:"labels,omitempty"`
+	// Physical location, passed on with the server's events
+	ServerLocation
+	// Timing of the server's subscription checks, the exporter's when not set
+	Watchdog *WatchdogConfig `json:"watchdog,omitempty"`
+	// TLS settings of the connections to the server, the exporter's when not set
+	TLSConfig *TLSPolicy `json:"tlsConfig,omitempty"`
+	// "session" or "basic", overrides the exporter's DefaultAuthMode
+	AuthMode AuthMode `json:"authMode,omitempty"`
+	// Settings of the exporter the server is configured in, the defaults
+	// when nil
+	connection *connectionSettings
+}
+
+// The key of the server in the subscription maps and its metrics label,
+// its Name or its IP without one. A name stays the same when a BMC behind
+// NAT or DHCP changes its address.
+func (s RedfishServer) ID() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.IP
+}
+
+// ServerLocation is where a server is installed
+type ServerLocation struct {
+	Datacenter string `json:"datacenter,omitempty"`
+	Row        string `json:"row,omitempty"`
+	Rack       string `json:"rack,omitempty"`
+	AssetTag   string `json:"assetTag,omitempty"`
+}
+
+// Format the location like dc1/row2/rack3, leaving out the parts not set
+func (l ServerLocation) String() string {
+	var parts []string
+	for _, part := range []string{l.Datacenter, l.Row, l.Rack} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Get the servers installed in a rack
+func ServersInRack(servers []RedfishServer, rack string) []RedfishServer {
+	var rackServers []RedfishServer
+	for _, server := range servers {
+		if server.Rack == rack {
+			rackServers = append(rackServers, server)
+		}
+	}
+	return rackServers
 }
 
 type SubscriptionPayload struct {
@@ -42,50 +112,396 @@ type SubscriptionPayload struct {
 	Oem                 interface{}                      `json:"Oem,omitempty"`
 	Protocol            redfish.EventDestinationProtocol `json:"Protocol,omitempty"`
 	Context             string                           `json:"Context,omitempty"`
+	// Only deliver events from these resources, given as URIs like /redfish/v1/Chassis/1
+	OriginResources []string `json:"OriginResources,omitempty"`
+	// Also deliver events from the resources below the ones subscribed to, sent only when set
+	SubordinateResources *bool `json:"SubordinateResources,omitempty"`
+	// Per server Destination, see RenderPayload
+	DestinationTemplate string `json:"DestinationTemplate,omitempty"`
+	// Have the BMC verify the receiver's certificate, sent as VerifyCertificate when set
+	VerifyDestinationCert *bool `json:"VerifyDestinationCert,omitempty"`
+	// PEM file with the receiver's certificate, installed on BMCs that support it
+	DestinationCertificateFile string `json:"DestinationCertificateFile,omitempty"`
+	// Create one subscription per destination instead of a single one for Destination
+	Destinations []string `json:"Destinations,omitempty"`
+	// Defaults to the type matching Protocol, e.g. SNMPTrap for SNMP protocols
+	SubscriptionType redfish.SubscriptionType `json:"SubscriptionType,omitempty"`
+	// Required for the SNMP protocols
+	SNMP *SNMPDestinationSettings `json:"SNMP,omitempty"`
+	// Messages forwarded by the Syslog protocols
+	SyslogFilters []SyslogDestinationFilter `json:"SyslogFilters,omitempty"`
+	// Id asked for the subscription, so it keeps the same URI when recreated.
+	// BMCs that don't take client-specified Ids pick their own.
+	PreferredID string `json:"PreferredID,omitempty"`
+	// Only deliver events of these severities: OK, Warning or Critical.
+	// Sent as Severities to BMCs whose event service lists them.
+	SeverityFilter []string `json:"SeverityFilter,omitempty"`
+}
+
+// SubscriptionRecord is a subscription created on a server. ID is the last
+// segment of the URI, like "1" in /redfish/v1/EventService/Subscriptions/1.
+type SubscriptionRecord struct {
+	URI       string    `json:"uri"`
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	ServerIP  string    `json:"serverIP"`
+	// Existing subscriptions to the destination deleted to make room for it
+	ConflictsCleared int `json:"conflictsCleared,omitempty"`
+	// Delivery paused with SetSubscriptionEnabled
+	Paused bool `json:"paused,omitempty"`
+	// Paused by deleting it on a BMC that can't disable subscriptions, it's
+	// recreated on resume
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Record a subscription just created on the server
+func newSubscriptionRecord(server RedfishServer, uri string) *SubscriptionRecord {
+	return &SubscriptionRecord{
+		URI:       uri,
+		ID:        subscriptionIDFromURI(uri),
+		CreatedAt: time.Now(),
+		ServerIP:  server.IP,
+	}
+}
+
+// Get the subscription Id from its URI, ignoring a trailing slash
+func subscriptionIDFromURI(uri string) string {
+	uri = strings.TrimRight(uri, "/")
+	if uri == "" {
+		return ""
+	}
+	return path.Base(uri)
+}
+
+// Subscriptions by destination, for the subscriptions created on one server
+type ServerSubscriptions map[string]*SubscriptionRecord
+
+// Split the payload into one payload per destination
+func splitDestinations(payload SubscriptionPayload) []SubscriptionPayload {
+	if len(payload.Destinations) == 0 {
+		return []SubscriptionPayload{payload}
+	}
+	payloads := make([]SubscriptionPayload, 0, len(payload.Destinations))
+	for i, destination := range payload.Destinations {
+		destinationPayload := payload
+		destinationPayload.Destination = destination
+		destinationPayload.Destinations = nil
+		if payload.PreferredID != "" {
+			// Subscription Ids are unique, number them per destination
+			destinationPayload.PreferredID = fmt.Sprintf("%s-%d", payload.PreferredID, i+1)
+		}
+		payloads = append(payloads, destinationPayload)
+	}
+	return payloads
+}
+
+// Check that no two payloads of a batch have the same Destination. Each
+// subscription deletes the existing ones with its Destination, so the later
+// payload would delete the subscription of the earlier one.
+func checkDestinationCollisions(payloads []SubscriptionPayload) error {
+	seen := make(map[string]bool)
+	for _, payload := range payloads {
+		if payload.Destination == "" {
+			// Rendered per server from DestinationTemplate
+			continue
+		}
+		if seen[payload.Destination] {
+			return fmt.Errorf("%w: %s", ErrDuplicateDestinationInBatch, payload.Destination)
+		}
+		seen[payload.Destination] = true
+	}
+	return nil
+}
+
+// The severities of Redfish events, the values of SeverityFilter
+var eventSeverities = []string{"OK", "Warning", "Critical"}
+
+// Check that the subscription payload can be sent to the servers, and log
+// a warning for settings that are valid but probably not intended
+func ValidateSubscriptionPayload(payload SubscriptionPayload) error {
+	if reflect.ValueOf(payload).IsZero() {
+		return ErrEmptyPayload
+	}
+	if len(payload.Destinations) > 0 {
+		destinationPayloads := splitDestinations(payload)
+		if err := checkDestinationCollisions(destinationPayloads); err != nil {
+			return err
+		}
+		for _, destinationPayload := range destinationPayloads {
+			if err := ValidateSubscriptionPayload(destinationPayload); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if payload.DestinationTemplate != "" && payload.Destination == "" {
+		// Destination is rendered and validated per server
+		return nil
+	}
+	if strings.TrimSpace(payload.Destination) == "" {
+		return errors.New("subscription Destination is empty")
+	}
+	destinationURL, err := url.Parse(payload.Destination)
+	if err != nil {
+		return fmt.Errorf("invalid subscription Destination %q: %v", payload.Destination, err)
+	}
+	schemes := destinationSchemes(payload.Protocol)
+	if !slices.Contains(schemes, destinationURL.Scheme) || destinationURL.Host == "" {
+		return fmt.Errorf("invalid subscription Destination %q: must be an absolute %s URL", payload.Destination, strings.Join(schemes, " or "))
+	}
+	if err := validateProtocolSettings(payload); err != nil {
+		return fmt.Errorf("invalid subscription payload: %w", err)
+	}
+
+	for key := range payload.HTTPHeaders {
+		if isReservedHeader(key) {
+			return fmt.Errorf("%w: %s", ErrReservedHeader, key)
+		}
+	}
+
+	if payload.PreferredID != "" && payload.PreferredID != url.PathEscape(payload.PreferredID) {
+		return fmt.Errorf("invalid subscription PreferredID %q: must be a single URI path segment", payload.PreferredID)
+	}
+
+	for _, resource := range payload.OriginResources {
+		if !isODataID(resource) {
+			return fmt.Errorf("invalid subscription OriginResource %q: must be a resource URI like /redfish/v1/Chassis/1", resource)
+		}
+	}
+
+	for _, severity := range payload.SeverityFilter {
+		if !slices.Contains(eventSeverities, severity) {
+			return fmt.Errorf("invalid subscription SeverityFilter %q: must be one of %s", severity, strings.Join(eventSeverities, ", "))
+		}
+	}
+
+	if payload.SubordinateResources != nil && *payload.SubordinateResources && len(payload.ResourceTypes) == 0 && len(payload.OriginResources) == 0 {
+		log.Println("Warning: SubordinateResources is set without ResourceTypes or OriginResources, events from all subordinate resources will be delivered")
+	}
+	return nil
+}
+
+// Whether a string is a resource URI as used in @odata.id
+func isODataID(id string) bool {
+	if !strings.HasPrefix(id, "/redfish/v1/") || strings.ContainsAny(id, " \t\n?#") {
+		return false
+	}
+	parsed, err := url.Parse(id)
+	return err == nil && parsed.EscapedPath() == id
+}
+
+// odataIDRef is a link to a resource as sent in request bodies
+type odataIDRef struct {
+	ODataID string `json:"@odata.id"`
 }
 
 // Create a new connection to a redfish server
 func getRedfishClient(server RedfishServer) (*gofish.APIClient, error) {
+	return getRedfishClientContext(context.Background(), server)
+}
+
+// Create a new connection to a redfish server, the requests made with it
+// are canceled when the context is done
+func getRedfishClientContext(ctx context.Context, server RedfishServer) (*gofish.APIClient, error) {
 	clientConfig := gofish.ClientConfig{
-		Endpoint: server.IP,
-		Username: server.Username,
-		Password: server.Password,
-		Insecure: true, // TODO Set Based on login type
+		Endpoint:   server.IP,
+		Username:   server.Username,
+		Password:   server.Password,
+		Insecure:   true, // TODO Set Based on login type
+		HTTPClient: newServerHTTPClient(server),
+		BasicAuth:  effectiveAuthMode(server, server.settings().client.DefaultAuthMode) == AuthModeBasic,
+	}
+	if isOAuthLogin(server) {
+		// Authenticated by the bearer token of the HTTP client instead of
+		// a session
+		clientConfig.Username = ""
+		clientConfig.Password = ""
 	}
 
-	c, err := gofish.Connect(clientConfig)
+	c, err := gofish.ConnectContext(ctx, clientConfig)
 	if err != nil {
-		log.Printf("Error connecting to redfish server %s: %v", server.IP, err)
-		return nil, err
+		connectErr := &RedfishConnectError{Server: server.IP, Category: classifyConnectError(err), Err: err}
+		server.settings().metrics.connectErrors.WithLabelValues(string(connectErr.Category)).Inc()
+		log.Printf("Error connecting to redfish server %s (%s): %v", server.IP, connectErr.Category, err)
+		return nil, connectErr
 	}
 
 	log.Printf("Successfully connected to redfish server %s", server.IP)
 	return c, nil
 }
 
-// Create a subscription
-func createSubscription(server RedfishServer, SubscriptionPayload SubscriptionPayload) (string, error) {
+// Time allowed for VerifyCredentials to log in, check and log out
+const verifyCredentialsTimeout = 30 * time.Second
+
+// Check that the server's username and password are accepted, logging in,
+// reading the session service and logging out again, so no session is
+// left behind. Rejected credentials return ErrAuthFailed.
+func VerifyCredentials(server RedfishServer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), verifyCredentialsTimeout)
+	defer cancel()
+
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		if classifyConnectError(err) == ConnectErrorAuthFailed {
+			return fmt.Errorf("%w on server %s", ErrAuthFailed, server.IP)
+		}
+		return fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	// The service root is readable without credentials, the session
+	// service is not
+	resp, err := c.Get("/redfish/v1/SessionService")
+	if err != nil {
+		switch redfishStatusCode(err) {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("%w on server %s", ErrAuthFailed, server.IP)
+		}
+		return fmt.Errorf("failed to verify credentials on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Create a subscription for every destination of the payload. If one of
+// them fails the ones already created are deleted.
+func createSubscription(server RedfishServer, SubscriptionPayload SubscriptionPayload) (ServerSubscriptions, error) {
+	return createSubscriptionWithContext(context.Background(), server, SubscriptionPayload)
+}
+
+// Create the subscriptions, connecting to the server with the context
+func createSubscriptionWithContext(ctx context.Context, server RedfishServer, SubscriptionPayload SubscriptionPayload) (ServerSubscriptions, error) {
+	subscriptions, err := createServerSubscriptions(ctx, server, SubscriptionPayload)
+	if err != nil {
+		rollbackServerSubscriptions(server, subscriptions)
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Create a subscription for every destination of the payload, stopping at
+// the first failure. The subscriptions created until then are returned
+// along with the error.
+func createServerSubscriptions(ctx context.Context, server RedfishServer, SubscriptionPayload SubscriptionPayload) (ServerSubscriptions, error) {
+	SubscriptionPayload = applyDeliveryPolicy(server, SubscriptionPayload)
+	subscriptions := make(ServerSubscriptions)
+	for _, destinationPayload := range splitDestinations(SubscriptionPayload) {
+		subscriptionURI, conflicts, err := createDestinationSubscription(ctx, server, destinationPayload)
+		if err != nil {
+			subscriptionStats.RecordFailedCreate(server.ID())
+			return subscriptions, err
+		}
+		subscriptionStats.RecordCreated(server.ID(), subscriptionURI)
+		if destinationPayload.PreferredID != "" {
+			honored := subscriptionIDFromURI(subscriptionURI) == destinationPayload.PreferredID
+			if !honored {
+				log.Printf("Server %s did not use the preferred subscription Id %s, subscription is %s", server.IP, destinationPayload.PreferredID, subscriptionURI)
+			}
+			subscriptionStats.RecordPreferredID(server.ID(), honored)
+		}
+		subscription := newSubscriptionRecord(server, subscriptionURI)
+		subscription.ConflictsCleared = conflicts
+		subscriptions[destinationPayload.Destination] = subscription
+	}
+	return subscriptions, nil
+}
+
+// Delete the subscriptions created on a server by a failed create
+func rollbackServerSubscriptions(server RedfishServer, subscriptions ServerSubscriptions) {
+	if len(subscriptions) == 0 {
+		return
+	}
+	failed := 0
+	for _, created := range subscriptions {
+		if err := deleteSubscriptionFromServer(server, created.URI); err != nil {
+			log.Printf("Failed to delete event subscription on server %s: %v", server.IP, err)
+			failed++
+		}
+	}
+	server.settings().metrics.recordRollback(len(subscriptions)-failed, failed)
+	log.Printf("Rolled back %d subscriptions on server %s, %d deletions failed", len(subscriptions)-failed, server.IP, failed)
+}
+
+// Delete the subscriptions in the map after a failed create, logging how
+// many were rolled back
+func rollbackSubscriptions(ctx context.Context, redfishServers []RedfishServer, subscriptionMap map[string]ServerSubscriptions) {
+	total := 0
+	for _, subscriptions := range subscriptionMap {
+		total += len(subscriptions)
+	}
+	if total == 0 {
+		return
+	}
+	deleted, _ := deleteSubscriptionsCounted(ctx, redfishServers, subscriptionMap)
+	serversMetrics(redfishServers).recordRollback(deleted, total-deleted)
+	log.Printf("Rolled back %d subscriptions on %d servers, %d deletions failed", deleted, len(subscriptionMap), total-deleted)
+}
+
+func (m *Metrics) recordRollback(deleted, failed int) {
+	m.subscriptionRollback.WithLabelValues("deleted").Add(float64(deleted))
+	m.subscriptionRollback.WithLabelValues("failed").Add(float64(failed))
+}
+
+// Create the subscription for a single destination, returning its URI and
+// the number of existing subscriptions to the destination it replaced
+func createDestinationSubscription(ctx context.Context, server RedfishServer, SubscriptionPayload SubscriptionPayload) (string, int, error) {
 
 	// Establish a connection to the server
-	c, err := getRedfishClient(server)
+	c, err := getRedfishClientContext(ctx, server)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server %s: %v", server.IP, err)
+		return "", 0, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
 	}
 	defer c.Logout()
 
 	// Get the event service
-	eventService, err := c.Service.EventService()
+	eventService, err := getEventService(c)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get event service on server %s: %w", server.IP, err)
+	}
+
+	if err := checkProtocolSupported(eventService, SubscriptionPayload.Protocol); err != nil {
+		return "", 0, fmt.Errorf("can't subscribe on server %s: %w", server.IP, err)
+	}
+
+	// Deleting the conflicts first frees their slots for the quota check
+	conflicts, err := deleteConflictingSubscriptions(ctx, server, SubscriptionPayload)
 	if err != nil {
-		return "", fmt.Errorf("failed to get event service on server %s: %v", server.IP, err)
+		return "", conflicts, fmt.Errorf("failed to delete conflicting subscriptions on server %s: %w", server.IP, err)
+	}
+	if err := subscriptionQuotas.check(server, eventService, SubscriptionPayload.Destination); err != nil {
+		return "", conflicts, err
+	}
+
+	// Firmware expecting other property names gets a request built here
+	var fieldNames map[string]string
+	if workaround := findFirmwareWorkaround(c, server); workaround != nil {
+		fieldNames = workaround.SubscriptionFieldNames
 	}
 
-	deleteConflictingSubscriptions(server, SubscriptionPayload)
-	// Create the subscription based on the Redfish version
-	if isV1_5() {
-		return createV1_5Subscription(eventService, SubscriptionPayload)
+	// Create the subscription based on the Redfish version, SNMP and Syslog
+	// subscriptions never existed before v1.5. gofish can't send
+	// OriginResources, SubordinateResources or an Id, so those subscriptions
+	// are built here as well.
+	var subscriptionURI string
+	if isV1_5() || !isRedfishProtocol(SubscriptionPayload.Protocol) || len(SubscriptionPayload.OriginResources) > 0 || SubscriptionPayload.SubordinateResources != nil || SubscriptionPayload.PreferredID != "" || len(fieldNames) > 0 {
+		subscriptionURI, err = createV1_5Subscription(eventService, SubscriptionPayload, fieldNames)
 	} else {
-		return createLegacySubscription(eventService, SubscriptionPayload)
+		subscriptionURI, err = createLegacySubscription(eventService, SubscriptionPayload)
+		if err == nil && SubscriptionPayload.VerifyDestinationCert != nil {
+			// Legacy subscriptions can't carry VerifyCertificate on creation
+			err = setVerifyCertificate(c, subscriptionURI, *SubscriptionPayload.VerifyDestinationCert)
+		}
+	}
+	if err == nil && SubscriptionPayload.DestinationCertificateFile != "" {
+		err = installDestinationCertificate(c, subscriptionURI, SubscriptionPayload.DestinationCertificateFile)
 	}
+	if err != nil {
+		if subscriptionURI != "" {
+			eventService.DeleteEventSubscription(subscriptionURI)
+		}
+		return "", 0, err
+	}
+	return subscriptionURI, conflicts, nil
 }
 
 func isV1_5() bool {
@@ -94,21 +510,114 @@ func isV1_5() bool {
 	return false
 }
 
-// Create V1.5 subscription
-func createV1_5Subscription(eventService *redfish.EventService, SubscriptionPayload SubscriptionPayload) (string, error) {
-	subscriptionURI, err := eventService.CreateEventSubscriptionInstance(
-		SubscriptionPayload.Destination,
-		SubscriptionPayload.RegistryPrefixes,
-		SubscriptionPayload.ResourceTypes,
-		SubscriptionPayload.HTTPHeaders,
-		SubscriptionPayload.Protocol,
-		SubscriptionPayload.Context,
-		SubscriptionPayload.DeliveryRetryPolicy,
-		SubscriptionPayload.Oem,
-	)
+// Body of a v1.5 subscription request, gofish has no way to send
+// OriginResources, SubordinateResources, VerifyCertificate or the Id so the
+// request is built here
+type v1_5SubscriptionRequest struct {
+	Id                   string                           `json:"Id,omitempty"`
+	Destination          string                           `json:"Destination"`
+	RegistryPrefixes     []string                         `json:"RegistryPrefixes,omitempty"`
+	ResourceTypes        []string                         `json:"ResourceTypes,omitempty"`
+	DeliveryRetryPolicy  redfish.DeliveryRetryPolicy      `json:"DeliveryRetryPolicy,omitempty"`
+	HTTPHeaders          map[string]string                `json:"HttpHeaders,omitempty"`
+	Oem                  interface{}                      `json:"Oem,omitempty"`
+	Protocol             redfish.EventDestinationProtocol `json:"Protocol"`
+	Context              string                           `json:"Context"`
+	OriginResources      []odataIDRef                     `json:"OriginResources,omitempty"`
+	SubordinateResources *bool                            `json:"SubordinateResources,omitempty"`
+	VerifyCertificate    *bool                            `json:"VerifyCertificate,omitempty"`
+	SubscriptionType     redfish.SubscriptionType         `json:"SubscriptionType,omitempty"`
+	SNMP                 *SNMPDestinationSettings         `json:"SNMP,omitempty"`
+	SyslogFilters        []SyslogDestinationFilter        `json:"SyslogFilters,omitempty"`
+	Severities           []string                         `json:"Severities,omitempty"`
+}
+
+// The severities of the filter the event service can filter on, nil when it
+// can't, logging a warning for those left out
+func supportedSeverities(eventService *redfish.EventService, filter []string) []string {
+	if len(filter) == 0 {
+		return nil
+	}
+	if len(eventService.Severities) == 0 {
+		log.Println("Warning: the event service doesn't support severity filters, subscribing to all severities")
+		return nil
+	}
+	var severities []string
+	for _, severity := range filter {
+		if slices.Contains(eventService.Severities, common.Health(severity)) {
+			severities = append(severities, severity)
+		} else {
+			log.Printf("Warning: the event service can't filter on severity %s, leaving it out", severity)
+		}
+	}
+	if len(severities) == 0 {
+		log.Println("Warning: the event service supports none of the severity filters, subscribing to all severities")
+	}
+	return severities
+}
+
+// Create V1.5 subscription, sending the request properties in fieldNames
+// under the names they map to
+func createV1_5Subscription(eventService *redfish.EventService, SubscriptionPayload SubscriptionPayload, fieldNames map[string]string) (string, error) {
+	if strings.TrimSpace(eventService.Subscriptions) == "" {
+		return "", errors.New("failed to create v1.5 subscription: empty subscription link in the event service")
+	}
+
+	request := v1_5SubscriptionRequest{
+		Destination:          SubscriptionPayload.Destination,
+		RegistryPrefixes:     SubscriptionPayload.RegistryPrefixes,
+		ResourceTypes:        SubscriptionPayload.ResourceTypes,
+		DeliveryRetryPolicy:  SubscriptionPayload.DeliveryRetryPolicy,
+		HTTPHeaders:          SubscriptionPayload.HTTPHeaders,
+		Oem:                  SubscriptionPayload.Oem,
+		Protocol:             SubscriptionPayload.Protocol,
+		Context:              SubscriptionPayload.Context,
+		SubordinateResources: SubscriptionPayload.SubordinateResources,
+		VerifyCertificate:    SubscriptionPayload.VerifyDestinationCert,
+		SubscriptionType:     subscriptionTypeFor(SubscriptionPayload),
+		SNMP:                 SubscriptionPayload.SNMP,
+		SyslogFilters:        SubscriptionPayload.SyslogFilters,
+		Id:                   SubscriptionPayload.PreferredID,
+		Severities:           supportedSeverities(eventService, SubscriptionPayload.SeverityFilter),
+	}
+	for _, resource := range SubscriptionPayload.OriginResources {
+		request.OriginResources = append(request.OriginResources, odataIDRef{ODataID: resource})
+	}
+	if request.SubordinateResources != nil && !eventService.SubordinateResourcesSupported {
+		log.Println("SubordinateResources is not supported by the event service, subscribing without it")
+		request.SubordinateResources = nil
+	}
+
+	post := func() (*http.Response, error) {
+		if len(fieldNames) == 0 {
+			return eventService.GetClient().Post(eventService.Subscriptions, request)
+		}
+		body, err := renameJSONFields(request, fieldNames)
+		if err != nil {
+			return nil, err
+		}
+		return eventService.GetClient().Post(eventService.Subscriptions, body)
+	}
 
+	resp, err := post()
+	if err != nil && request.Id != "" && redfishStatusCode(err) == http.StatusBadRequest {
+		// Most BMCs treat Id as read-only, some reject the request for it
+		log.Printf("Event service rejected the subscription Id %s, subscribing without it", request.Id)
+		request.Id = ""
+		resp, err = post()
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to create v1.5 subscription: %w", err)
+		return "", fmt.Errorf("failed to create v1.5 subscription: %w", normalizeRedfishError(err))
+	}
+	defer resp.Body.Close()
+
+	// The new subscription is returned in the Location header
+	subscriptionURI := resp.Header.Get("Location")
+	if subscriptionURI == "" {
+		return "", errors.New("failed to create v1.5 subscription: no Location header in subscription response")
+	}
+	if locationURL, err := url.ParseRequestURI(subscriptionURI); err == nil {
+		subscriptionURI = locationURL.RequestURI()
 	}
 
 	return subscriptionURI, nil
@@ -116,6 +625,9 @@ func createV1_5Subscription(eventService *redfish.EventService, SubscriptionPayl
 
 // Create legacy subscription
 func createLegacySubscription(eventService *redfish.EventService, SubscriptionPayload SubscriptionPayload) (string, error) {
+	if len(SubscriptionPayload.SeverityFilter) > 0 {
+		log.Println("Warning: severity filters need Redfish 1.5 subscriptions, subscribing to all severities")
+	}
 	subscriptionURI, err := eventService.CreateEventSubscription(
 		SubscriptionPayload.Destination,
 		SubscriptionPayload.EventTypes,
@@ -126,102 +638,382 @@ func createLegacySubscription(eventService *redfish.EventService, SubscriptionPa
 	)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to create legacy subscription: %w", err)
+		return "", fmt.Errorf("failed to create legacy subscription: %w", normalizeRedfishError(err))
 	}
 
 	return subscriptionURI, nil
 }
 
+// Time allowed for deleting the subscriptions of a failed or canceled
+// CreateSubscriptionsForAllServersContext, independent of its context
+const subscriptionRollbackTimeout = 60 * time.Second
+
+// CreateOption sets an optional BatchCreateOptions field of
+// CreateSubscriptionsForAllServers
+type CreateOption func(*BatchCreateOptions)
+
+// WithServerDone reports each server as it completes, see
+// BatchCreateOptions.OnServerDone
+func WithServerDone(onServerDone func(ip string, uri string, err error)) CreateOption {
+	return func(o *BatchCreateOptions) {
+		o.OnServerDone = onServerDone
+	}
+}
+
+// WithSmokeTest checks that each server delivers a test event through its
+// new subscription, see BatchCreateOptions.SmokeTest
+func WithSmokeTest() CreateOption {
+	return func(o *BatchCreateOptions) {
+		o.SmokeTest = true
+	}
+}
+
 // Create subscriptions for all servers and return their URIs
 // Rollback if any subscription attempt fails
-func CreateSubscriptionsForAllServers(redfishServers []RedfishServer, subscriptionPayload SubscriptionPayload) (map[string]string, error) {
-	subscriptionMap := make(map[string]string)
+func CreateSubscriptionsForAllServers(redfishServers []RedfishServer, subscriptionPayload SubscriptionPayload, opts ...CreateOption) (map[string]ServerSubscriptions, error) {
+	return CreateSubscriptionsForAllServersContext(context.Background(), redfishServers, subscriptionPayload, opts...)
+}
 
-	for _, server := range redfishServers {
+// Create subscriptions for all servers, no server is contacted once the
+// context is done. The subscriptions created until then are rolled back.
+// An empty server list is ErrNoServers, see BatchCreateSubscriptions to
+// allow it.
+func CreateSubscriptionsForAllServersContext(ctx context.Context, redfishServers []RedfishServer, subscriptionPayload SubscriptionPayload, opts ...CreateOption) (map[string]ServerSubscriptions, error) {
+	options := BatchCreateOptions{AtomicityLevel: AllOrNothing}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	result, err := batchCreate(ctx, redfishServers, subscriptionPayload, options)
+	if err != nil {
+		return nil, err
+	}
+	return result.SubscriptionMap(), nil
+}
 
-		// Establish a connection to the server
-		subscriptionURI, err := createSubscription(server, subscriptionPayload)
-		if err != nil {
-			DeleteSubscriptionsFromAllServers(redfishServers, subscriptionMap)
-			return nil, fmt.Errorf("subscription failed on server %s: %v, rolling back previous subscriptions", server.IP, err)
+// Create subscriptions for all servers within a total deadline, rolling
+// back on failures like CreateSubscriptionsForAllServers. Once the deadline
+// passes the servers in progress are canceled, and the subscriptions of
+// the servers completed by then are returned along with a
+// BatchDeadlineError naming the unprocessed servers.
+func CreateSubscriptionsForAllServersWithDeadline(redfishServers []RedfishServer, subscriptionPayload SubscriptionPayload, deadline time.Duration) (map[string]ServerSubscriptions, error) {
+	result, err := batchCreate(context.Background(), redfishServers, subscriptionPayload, BatchCreateOptions{AtomicityLevel: AllOrNothing, Deadline: deadline})
+	var deadlineErr *BatchDeadlineError
+	if err != nil && !errors.As(err, &deadlineErr) {
+		return nil, err
+	}
+	return result.SubscriptionMap(), err
+}
+
+// Delete all event subscriptions stored in the map, returning the
+// failures along with logging them. The map is only read before the
+// deletions start, but callers sharing it with other goroutines must pass
+// a copy, see Reconciler.DeleteAll.
+func DeleteSubscriptionsFromAllServers(redfishServers []RedfishServer, subscriptionMap map[string]ServerSubscriptions) error {
+	return deleteSubscriptions(context.Background(), redfishServers, subscriptionMap)
+}
+
+// Delete the subscriptions in the map until the context is done
+func deleteSubscriptions(ctx context.Context, redfishServers []RedfishServer, subscriptionMap map[string]ServerSubscriptions) error {
+	_, err := deleteSubscriptionsCounted(ctx, redfishServers, subscriptionMap)
+	return err
+}
+
+// Delete the subscriptions in the map, returning how many were deleted.
+// Those not attempted before the context was done count as not deleted.
+func deleteSubscriptionsCounted(ctx context.Context, redfishServers []RedfishServer, subscriptionMap map[string]ServerSubscriptions) (int, error) {
+	var deleted atomic.Int64
+	pool := NewWorkerPool[error](serversConcurrency(redfishServers))
+	for serverIP, subscriptions := range subscriptionMap {
+		server := getServerInfo(redfishServers, serverIP)
+		for _, subscription := range subscriptions {
+			subscriptionURI := subscription.URI
+			pool.Add(func() error {
+				unlock := server.settings().locks.Lock(server.IP)
+				defer unlock()
+				err := deleteSubscriptionFromServerContext(ctx, server, subscriptionURI)
+				if err != nil {
+					log.Printf("Failed to delete event subscription on server %s: %v", server.IP, err)
+				} else {
+					deleted.Add(1)
+					log.Printf("Successfully deleted event subscription from server %s: %s", server.IP, subscriptionURI)
+				}
+				return err
+			})
 		}
+	}
+	if err := errors.Join(pool.Run(ctx)...); err != nil {
+		return int(deleted.Load()), err
+	}
+	return int(deleted.Load()), ctx.Err()
+}
+
+// Delete the subscriptions owned by this exporter from all servers, whether
+// or not they are in the subscription map. A subscription is owned when its
+// Context starts with ownerContext, subscriptions of other tools are kept.
+func DeleteOwnedSubscriptions(redfishServers []RedfishServer, ownerContext string) {
+	if strings.TrimSpace(ownerContext) == "" {
+		log.Println("No subscription owner context set, skipping owned subscription cleanup")
+		return
+	}
 
-		log.Printf("Successfully created subscription on redfish server %s: %s", server.IP, subscriptionURI)
-		subscriptionMap[server.IP] = subscriptionURI
+	serverSubscriptions, errs := GetAllSubscriptionsAcrossServers(redfishServers)
+	for serverID, err := range errs {
+		log.Printf("Failed to get event subscriptions on server %s: %v", serverID, err)
 	}
 
-	return subscriptionMap, nil
+	pool := NewWorkerPool[struct{}](serversConcurrency(redfishServers))
+	for _, server := range redfishServers {
+		for _, subscription := range serverSubscriptions[server.ID()] {
+			if !strings.HasPrefix(subscription.Context, ownerContext) {
+				continue
+			}
+			subscriptionURI := subscription.ODataID
+			pool.Add(func() struct{} {
+				unlock := server.settings().locks.Lock(server.IP)
+				defer unlock()
+				err := deleteSubscriptionFromServer(server, subscriptionURI)
+				if err != nil {
+					log.Printf("Failed to delete owned event subscription %s on server %s: %v", subscriptionURI, server.IP, err)
+				} else {
+					log.Printf("Successfully deleted owned event subscription from server %s: %s", server.IP, subscriptionURI)
+				}
+				return struct{}{}
+			})
+		}
+	}
+	pool.Run(context.Background())
 }
 
-// Delete all event subscriptions stored in the map
-func DeleteSubscriptionsFromAllServers(redfishServers []RedfishServer, subscriptionMap map[string]string) {
-	for serverIP, subscriptionURI := range subscriptionMap {
-		server := getServerInfo(redfishServers, serverIP)
-		err := deleteSubscriptionFromServer(server, subscriptionURI)
+// Delete the subscriptions of all servers whose Destination starts with
+// prefix, e.g. every collector of a retired subnet, whoever created them.
+// Returns the number deleted and the errors of the servers that couldn't be
+// listed or the subscriptions that couldn't be deleted.
+func DeleteSubscriptionsByDestinationPrefix(redfishServers []RedfishServer, prefix string) (int, error) {
+	if prefix == "" {
+		return 0, errors.New("destination prefix is required")
+	}
+
+	serverSubscriptions, listErrs := GetAllSubscriptionsAcrossServers(redfishServers)
+	var errs []error
+	for serverID, err := range listErrs {
+		errs = append(errs, fmt.Errorf("failed to get event subscriptions on server %s: %w", serverID, err))
+	}
+
+	pool := NewWorkerPool[error](serversConcurrency(redfishServers))
+	for _, server := range redfishServers {
+		for _, subscription := range serverSubscriptions[server.ID()] {
+			if !strings.HasPrefix(subscription.Destination, prefix) {
+				continue
+			}
+			subscriptionURI := subscription.ODataID
+			destination := subscription.Destination
+			pool.Add(func() error {
+				unlock := server.settings().locks.Lock(server.IP)
+				defer unlock()
+				err := deleteSubscriptionFromServer(server, subscriptionURI)
+				if err == nil {
+					log.Printf("Deleted event subscription %s to %s from server %s", subscriptionURI, destination, server.IP)
+				}
+				return err
+			})
+		}
+	}
+	deleted := 0
+	for _, err := range pool.Run(context.Background()) {
 		if err != nil {
-			log.Printf("Failed to delete event subscription on server %s: %v", server.IP, err)
+			errs = append(errs, err)
 		} else {
-			log.Printf("Successfully deleted event subscription from server %s: %s", server.IP, subscriptionURI)
+			deleted++
 		}
 	}
+	return deleted, errors.Join(errs...)
+}
+
+// Get the subscriptions of the servers in parallel and call fn from the
+// calling goroutine as each server completes, with the server's ID. Stops
+// once fn returns false or the context is done, the servers not contacted
+// by then are skipped. Unlike GetAllSubscriptionsAcrossServers only the
+// servers fn has not seen yet are held in memory.
+func RangeSubscriptions(ctx context.Context, redfishServers []RedfishServer, fn func(serverID string, subscriptions []*redfish.EventDestination, err error) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type subscriptionsResult struct {
+		serverID      string
+		subscriptions []*redfish.EventDestination
+		err           error
+	}
+	pool := NewWorkerPool[subscriptionsResult](serversConcurrency(redfishServers))
+	results := pool.Results()
+	go func() {
+		for _, server := range redfishServers {
+			err := pool.Submit(ctx, func() subscriptionsResult {
+				subscriptions, err := getServerSubscriptionsContext(ctx, server)
+				return subscriptionsResult{serverID: server.ID(), subscriptions: subscriptions, err: err}
+			})
+			if err != nil {
+				break
+			}
+		}
+		pool.Wait()
+	}()
+
+	for result := range results {
+		if !fn(result.Value.serverID, result.Value.subscriptions, result.Value.err) {
+			// Release the workers still running, the context is canceled
+			// so they finish quickly
+			go func() {
+				for range results {
+				}
+			}()
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// Get the subscriptions of all servers in parallel, keyed by server ID.
+// Servers whose subscriptions could not be read are returned in the error map.
+func GetAllSubscriptionsAcrossServers(redfishServers []RedfishServer) (map[string][]*redfish.EventDestination, map[string]error) {
+	type subscriptionsResult struct {
+		subscriptions []*redfish.EventDestination
+		err           error
+	}
+
+	pool := NewWorkerPool[subscriptionsResult](serversConcurrency(redfishServers))
+	for _, server := range redfishServers {
+		pool.Add(func() subscriptionsResult {
+			subscriptions, err := getServerSubscriptions(server)
+			return subscriptionsResult{subscriptions: subscriptions, err: err}
+		})
+	}
+	results := pool.Run(context.Background())
+
+	serverSubscriptions := make(map[string][]*redfish.EventDestination)
+	errs := make(map[string]error)
+	for i, result := range results {
+		serverID := redfishServers[i].ID()
+		if result.err != nil {
+			errs[serverID] = result.err
+			continue
+		}
+		serverSubscriptions[serverID] = result.subscriptions
+	}
+	return serverSubscriptions, errs
 }
 
 // Delete a subscription from a redfish server
 func deleteSubscriptionFromServer(server RedfishServer, subscriptionURI string) error {
+	return deleteSubscriptionFromServerContext(context.Background(), server, subscriptionURI)
+}
 
-	c, err := getRedfishClient(server)
+// Delete a subscription, connecting to the server with the context
+func deleteSubscriptionFromServerContext(ctx context.Context, server RedfishServer, subscriptionURI string) error {
+
+	c, err := getRedfishClientContext(ctx, server)
 	if err != nil {
-		return fmt.Errorf("failed to connect to server %s: %v", server.IP, err)
+		return fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
 	}
 	defer c.Logout()
 
 	// Get the event service
-	eventService, err := c.Service.EventService()
+	eventService, err := getEventService(c)
 	if err != nil {
-		return fmt.Errorf("failed to get event service on server %s: %v", server.IP, err)
+		return fmt.Errorf("failed to get event service on server %s: %w", server.IP, err)
 	}
 
 	// Attempt to delete the subscription
 	err = eventService.DeleteEventSubscription(subscriptionURI)
 	if err != nil {
-		return fmt.Errorf("failed to delete event subscription on server %s: %v", server.IP, err)
+		return fmt.Errorf("failed to delete event subscription on server %s: %w", server.IP, normalizeRedfishError(err))
 	}
+	subscriptionStats.RecordDeleted(server.ID(), subscriptionURI)
 
 	return nil
 }
 
 // Unsubscribes/deletes conflicting subscriptions from the server
-func deleteConflictingSubscriptions(server RedfishServer, subscriptionPayload SubscriptionPayload) error {
-	subscriptions, err := getServerSubscriptions(server)
+func deleteConflictingSubscriptions(ctx context.Context, server RedfishServer, subscriptionPayload SubscriptionPayload) (int, error) {
+	subscriptions, err := getServerSubscriptionsContext(ctx, server)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	deleted := 0
 	for _, subscription := range subscriptions {
 		if subscription.Destination == subscriptionPayload.Destination {
-			err := deleteSubscriptionFromServer(server, subscription.ODataID)
+			err := deleteSubscriptionFromServerContext(ctx, server, subscription.ODataID)
 			if err != nil {
-				return fmt.Errorf("failed to delete event subscription %s, on server %s: %v", subscription.ID, server.IP, err)
+				return deleted, fmt.Errorf("failed to delete event subscription %s, on server %s: %w", subscription.ID, server.IP, err)
 			} else {
 				log.Printf("successfully deleted overlapping event subscription %s from server %s", subscription.ID, server.IP)
 			}
+			deleted++
 		}
 	}
-	return nil
+	return deleted, nil
+}
+
+// Get the server's event service. Some firmware answers without an error
+// but without a usable event service, that is returned as ErrNoEventService.
+func getEventService(c *gofish.APIClient) (eventService *redfish.EventService, err error) {
+	if c.Service == nil {
+		return nil, ErrNoEventService
+	}
+	// gofish dereferences the event service it decoded, which is nil when
+	// the BMC answers null
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("Failed to decode the event service: %v", recovered)
+			eventService, err = nil, ErrNoEventService
+		}
+	}()
+	eventService, err = c.Service.EventService()
+	if err != nil {
+		return nil, err
+	}
+	if eventService == nil || eventService.ODataID == "" {
+		return nil, ErrNoEventService
+	}
+	return eventService, nil
+}
+
+// Returned by GetSubscriptionByURI when the server has no subscription at the URI
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+// Get a single subscription of a server by its URI, as returned when it was created
+func GetSubscriptionByURI(ctx context.Context, server RedfishServer, uri string) (*redfish.EventDestination, error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	subscription, err := redfish.GetEventDestination(c, uri)
+	if err != nil {
+		if redfishStatusCode(err) == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s on server %s", ErrSubscriptionNotFound, uri, server.IP)
+		}
+		return nil, fmt.Errorf("failed to get event subscription %s on server %s: %w", uri, server.IP, normalizeRedfishError(err))
+	}
+	return subscription, nil
 }
 
 // Gets all subscriptions currently active on the given server
 func getServerSubscriptions(server RedfishServer) ([]*redfish.EventDestination, error) {
+	return getServerSubscriptionsContext(context.Background(), server)
+}
 
-	c, err := getRedfishClient(server)
+func getServerSubscriptionsContext(ctx context.Context, server RedfishServer) ([]*redfish.EventDestination, error) {
+	c, err := getRedfishClientContext(ctx, server)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to server %s: %v", server.IP, err)
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
 	}
 	defer c.Logout()
 
 	// Get the event service
-	eventService, err := c.Service.EventService()
+	eventService, err := getEventService(c)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get event service on server %s: %v", server.IP, err)
+		return nil, fmt.Errorf("failed to get event service on server %s: %w", server.IP, err)
 	}
 
 	subscriptions, err := eventService.GetEventSubscriptions()
@@ -231,12 +1023,124 @@ func getServerSubscriptions(server RedfishServer) ([]*redfish.EventDestination,
 	return subscriptions, nil
 }
 
-// Retrieve the server's credentials from the config based on IP
+// Return the host part of the server's IP, which is the address events are received from
+func serverHost(server RedfishServer) string {
+	u, err := url.Parse(server.IP)
+	if err != nil || u.Hostname() == "" {
+		return server.IP
+	}
+	return u.Hostname()
+}
+
+// TestEventResult describes how a redfish server responded to SendTestEvent
+type TestEventResult struct {
+	// EventId of the submitted test event, to correlate with the received event
+	EventId    string
+	Accepted   bool
+	StatusCode int
+	Message    string
+}
+
+// Ask the redfish server to send a test event to its subscribers. A server
+// rejecting the request is reported in the result, the error is only set when
+// the request could not be made.
+func SendTestEvent(server RedfishServer, message string) (TestEventResult, error) {
+	return sendTestEvent(server, message, newTestEventID())
+}
+
+// Prefix of the EventIds of the test events sent by this exporter
+const testEventIDPrefix = "TestEvent-"
+
+// EventId of a new test event
+func newTestEventID() string {
+	return fmt.Sprintf("%s%d", testEventIDPrefix, time.Now().UnixNano())
+}
+
+// Whether the event is a test event sent by a smoke test or delivery check.
+// Some BMCs replace the EventId of test events, their Message carries it too.
+func isTestEvent(event Event) bool {
+	return strings.HasPrefix(event.EventId, testEventIDPrefix) || strings.Contains(event.Message, testEventIDPrefix)
+}
+
+// Ask the server to send a test event with the given EventId
+func sendTestEvent(server RedfishServer, message string, eventID string) (TestEventResult, error) {
+	result := TestEventResult{EventId: eventID}
+
+	c, err := getRedfishClient(server)
+	if err != nil {
+		return result, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	eventService, err := getEventService(c)
+	if err != nil {
+		return result, fmt.Errorf("failed to get event service on server %s: %w", server.IP, err)
+	}
+	if eventService.SubmitTestEventTarget == "" {
+		result.Message = "SubmitTestEvent is not supported by the event service"
+		return result, nil
+	}
+
+	testEvent := map[string]interface{}{
+		"EventGroupId":      "0",
+		"EventId":           result.EventId,
+		"EventTimestamp":    time.Now().UTC().Format(time.RFC3339),
+		"EventType":         "Alert",
+		"Message":           message,
+		"MessageArgs":       []string{},
+		"MessageId":         "Base.1.0.Success",
+		"OriginOfCondition": eventService.ODataID,
+		"Severity":          "OK",
+	}
+
+	resp, err := c.Post(eventService.SubmitTestEventTarget, testEvent)
+	if err != nil {
+		var redfishErr *common.Error
+		if errors.As(err, &redfishErr) && redfishErr.HTTPReturnedStatusCode != 0 {
+			result.StatusCode = redfishErr.HTTPReturnedStatusCode
+			result.Message = redfishErr.Message
+			return result, nil
+		}
+		return result, fmt.Errorf("failed to submit test event on server %s: %v", server.IP, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	result.Accepted = true
+	result.StatusCode = resp.StatusCode
+	result.Message = strings.TrimSpace(string(body))
+	return result, nil
+}
+
+// Retrieve the server's credentials from the config based on IP or Name
 func getServerInfo(redfishServers []RedfishServer, serverIP string) RedfishServer {
 	for _, redfishServer := range redfishServers {
-		if redfishServer.IP == serverIP {
+		if redfishServer.IP == serverIP || (redfishServer.Name != "" && redfishServer.Name == serverIP) {
 			return redfishServer
 		}
 	}
 	return RedfishServer{}
 }
+
+// ServerIndex finds servers by IP or Name without scanning the server list
+type ServerIndex map[string]RedfishServer
+
+// Index the servers by IP and by Name. Like getServerInfo the first server
+// of the list matching a key wins, whether by IP or by Name.
+func BuildServerIndex(redfishServers []RedfishServer) ServerIndex {
+	index := make(ServerIndex, 2*len(redfishServers))
+	for _, redfishServer := range redfishServers {
+		if _, taken := index[redfishServer.IP]; !taken {
+			index[redfishServer.IP] = redfishServer
+		}
+		if _, taken := index[redfishServer.Name]; redfishServer.Name != "" && !taken {
+			index[redfishServer.Name] = redfishServer
+		}
+	}
+	return index
+}
+
+// Get a server by IP or Name like getServerInfo, the zero server when unknown
+func (index ServerIndex) Lookup(serverIP string) RedfishServer {
+	return index[serverIP]
+}
@@ -0,0 +1,828 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func benchmarkServers(n int) []RedfishServer {
+	servers := make([]RedfishServer, n)
+	for i := range servers {
+		servers[i] = RedfishServer{IP: fmt.Sprintf("https://10.0.%d.%d", i/256, i%256), Name: fmt.Sprintf("node%04d", i)}
+	}
+	return servers
+}
+
+func TestServerIndexLookup(t *testing.T) {
+	servers := []RedfishServer{
+		{IP: "https://10.0.0.1", Name: "node01"},
+		{IP: "https://10.0.0.2"},
+		// Named like the first server's IP, which keeps resolving to it
+		{IP: "https://10.0.0.3", Name: "https://10.0.0.1"},
+	}
+	index := BuildServerIndex(servers)
+
+	tests := []struct {
+		key    string
+		wantIP string
+	}{
+		{"https://10.0.0.1", "https://10.0.0.1"},
+		{"node01", "https://10.0.0.1"},
+		{"https://10.0.0.2", "https://10.0.0.2"},
+		{"https://10.0.0.3", "https://10.0.0.3"},
+		{"node02", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := index.Lookup(tt.key).IP; got != tt.wantIP {
+				t.Errorf("Lookup(%q) = %q, want %q", tt.key, got, tt.wantIP)
+			}
+			if got := getServerInfo(servers, tt.key).IP; got != tt.wantIP {
+				t.Errorf("getServerInfo(%q) = %q, want %q", tt.key, got, tt.wantIP)
+			}
+		})
+	}
+}
+
+func TestServerIndexMatchesGetServerInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []RedfishServer
+	}{
+		{
+			name: "duplicate IPs",
+			servers: []RedfishServer{
+				{IP: "https://10.0.0.1", Name: "node01", Username: "first"},
+				{IP: "https://10.0.0.1", Name: "node02", Username: "second"},
+			},
+		},
+		{
+			name: "Name of an earlier server is a later server's IP",
+			servers: []RedfishServer{
+				{IP: "https://10.0.0.1", Name: "https://10.0.0.2"},
+				{IP: "https://10.0.0.2", Name: "node02"},
+			},
+		},
+		{
+			name: "Name of a later server is an earlier server's IP",
+			servers: []RedfishServer{
+				{IP: "https://10.0.0.1", Name: "node01"},
+				{IP: "https://10.0.0.2", Name: "https://10.0.0.1"},
+			},
+		},
+		{
+			name: "duplicate Names",
+			servers: []RedfishServer{
+				{IP: "https://10.0.0.1", Name: "node01"},
+				{IP: "https://10.0.0.2", Name: "node01"},
+			},
+		},
+		{
+			name: "IP equal to its own Name",
+			servers: []RedfishServer{
+				{IP: "https://10.0.0.1", Name: "https://10.0.0.1", Username: "first"},
+				{IP: "https://10.0.0.2", Name: "https://10.0.0.1", Username: "second"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := BuildServerIndex(tt.servers)
+			keys := []string{"", "unknown"}
+			for _, server := range tt.servers {
+				keys = append(keys, server.IP, server.Name)
+			}
+			for _, key := range keys {
+				want := getServerInfo(tt.servers, key)
+				if got := index.Lookup(key); got.IP != want.IP || got.Name != want.Name || got.Username != want.Username {
+					t.Errorf("Lookup(%q) = %s (%s), getServerInfo = %s (%s)", key, got.IP, got.Name, want.IP, want.Name)
+				}
+			}
+		})
+	}
+}
+
+// The linear scan of getServerInfo against the index, looking up the last
+// server for the scan's worst case. Compared by bench_check.sh.
+func BenchmarkGetServerInfo_Linear(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		servers := benchmarkServers(n)
+		key := servers[n-1].Name
+		b.Run(fmt.Sprintf("servers=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				getServerInfo(servers, key)
+			}
+		})
+	}
+}
+
+func BenchmarkGetServerInfo_Map(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		servers := benchmarkServers(n)
+		key := servers[n-1].Name
+		index := BuildServerIndex(servers)
+		b.Run(fmt.Sprintf("servers=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				index.Lookup(key)
+			}
+		})
+	}
+}
+
+func TestCreateSubscriptionProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload SubscriptionPayload
+		// Property the BMC must have received, nil when it must be absent
+		property string
+		want     interface{}
+	}{
+		{
+			name:     "SubordinateResources on the default path",
+			payload:  SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", SubordinateResources: boolPtr(true)},
+			property: "SubordinateResources",
+			want:     true,
+		},
+		{
+			name:     "SubordinateResources false",
+			payload:  SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", SubordinateResources: boolPtr(false)},
+			property: "SubordinateResources",
+			want:     false,
+		},
+		{
+			name:     "legacy path",
+			payload:  SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", Context: "ctx", EventTypes: []redfish.EventType{redfish.AlertEventType}},
+			property: "SubordinateResources",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.eventService["SubordinateResourcesSupported"] = true
+
+			created, err := createSubscription(bmc.server(), tt.payload)
+			if err != nil {
+				t.Fatalf("createSubscription() error = %v", err)
+			}
+			subscription := bmc.subscription(created[tt.payload.Destination].URI)
+			if subscription == nil {
+				t.Fatalf("subscription %s not created on the BMC", created[tt.payload.Destination].URI)
+			}
+			if got := subscription[tt.property]; got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.property, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateSubscriptionWithoutLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload SubscriptionPayload
+	}{
+		{"v1.5 request", SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", OriginResources: []string{"/redfish/v1/Systems/1"}}},
+		{"legacy request", SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", Context: "ctx", EventTypes: []redfish.EventType{redfish.AlertEventType}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.omitLocation = true
+
+			created, err := createSubscription(bmc.server(), tt.payload)
+			if err == nil {
+				t.Fatalf("createSubscription() = %v, want an error", created)
+			}
+		})
+	}
+}
+
+func TestSubscriptionIDFromURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"/redfish/v1/EventService/Subscriptions/42", "42"},
+		{"/redfish/v1/EventService/Subscriptions/42/", "42"},
+		{"https://10.0.0.1/redfish/v1/EventService/Subscriptions/abc-1", "abc-1"},
+		{"7", "7"},
+		{"", ""},
+		{"/", ""},
+	}
+
+	for _, tt := range tests {
+		if got := subscriptionIDFromURI(tt.uri); got != tt.want {
+			t.Errorf("subscriptionIDFromURI(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestCreateSubscriptionRecord(t *testing.T) {
+	bmc := newFakeBMC(t)
+	server := bmc.server()
+	payload := SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", OriginResources: []string{"/redfish/v1/Systems/1"}}
+
+	before := time.Now()
+	created, err := createSubscription(server, payload)
+	if err != nil {
+		t.Fatalf("createSubscription() error = %v", err)
+	}
+	record := created[payload.Destination]
+	if record == nil {
+		t.Fatalf("createSubscription() = %v, want a record for %s", created, payload.Destination)
+	}
+	if bmc.subscription(record.URI) == nil {
+		t.Errorf("URI = %s, not a subscription of the BMC", record.URI)
+	}
+	if want := subscriptionIDFromURI(record.URI); record.ID != want || record.ID == "" {
+		t.Errorf("ID = %q, want %q", record.ID, want)
+	}
+	if record.ServerIP != server.IP {
+		t.Errorf("ServerIP = %s, want %s", record.ServerIP, server.IP)
+	}
+	if record.CreatedAt.Before(before) {
+		t.Errorf("CreatedAt = %v, want after %v", record.CreatedAt, before)
+	}
+}
+
+func TestDeleteOwnedSubscriptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		ownerContext string
+		// Owned subscription whose deletion fails
+		failDelete bool
+		wantOwned  []bool
+	}{
+		{name: "only owned deleted", ownerContext: "scrapefish", wantOwned: []bool{false, false}},
+		{name: "no owner context", wantOwned: []bool{true, true}},
+		{name: "failed deletion skipped", ownerContext: "scrapefish", failDelete: true, wantOwned: []bool{true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			owned := []string{
+				bmc.addSubscription(map[string]interface{}{"Destination": "https://10.0.0.100:8080", "Context": "scrapefish-node01"}),
+				bmc.addSubscription(map[string]interface{}{"Destination": "https://10.0.0.100:8081", "Context": "scrapefish"}),
+			}
+			foreign := bmc.addSubscription(map[string]interface{}{"Destination": "https://10.0.0.200:443", "Context": "other-tool"})
+			if tt.failDelete {
+				bmc.failures["DELETE "+owned[0]] = http.StatusInternalServerError
+			}
+
+			DeleteOwnedSubscriptions([]RedfishServer{bmc.server()}, tt.ownerContext)
+
+			for i, uri := range owned {
+				if got := bmc.subscription(uri) != nil; got != tt.wantOwned[i] {
+					t.Errorf("owned subscription %s kept = %v, want %v", uri, got, tt.wantOwned[i])
+				}
+			}
+			if bmc.subscription(foreign) == nil {
+				t.Error("foreign subscription deleted")
+			}
+			if bmc.count("DELETE "+foreign) != 0 {
+				t.Error("deletion of the foreign subscription requested")
+			}
+		})
+	}
+}
+
+func TestSendTestEventResult(t *testing.T) {
+	const action = "/redfish/v1/EventService/Actions/EventService.SubmitTestEvent"
+	tests := []struct {
+		name string
+		// Status code of the action, accepted with 204 when zero
+		status int
+		// Event service without the SubmitTestEvent action
+		unsupported  bool
+		wantAccepted bool
+		wantStatus   int
+		wantMessage  string
+	}{
+		{name: "accepted", wantAccepted: true, wantStatus: http.StatusNoContent},
+		{name: "method not allowed", status: http.StatusMethodNotAllowed, wantStatus: http.StatusMethodNotAllowed, wantMessage: "Method Not Allowed"},
+		{name: "action missing", unsupported: true, wantMessage: "SubmitTestEvent is not supported by the event service"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			if tt.status != 0 {
+				bmc.failures["POST "+action] = tt.status
+			}
+			if tt.unsupported {
+				bmc.eventService["Actions"] = map[string]interface{}{}
+			}
+
+			result, err := SendTestEvent(bmc.server(), "delivery check")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Accepted != tt.wantAccepted || result.StatusCode != tt.wantStatus {
+				t.Errorf("result = accepted %v, status %d, want %v, %d", result.Accepted, result.StatusCode, tt.wantAccepted, tt.wantStatus)
+			}
+			if !strings.Contains(result.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", result.Message, tt.wantMessage)
+			}
+			if !strings.HasPrefix(result.EventId, testEventIDPrefix) {
+				t.Errorf("EventId = %q, want the %s prefix", result.EventId, testEventIDPrefix)
+			}
+			wantPosted := 1
+			if tt.unsupported {
+				wantPosted = 0
+			}
+			if got := bmc.count("POST " + action); got != wantPosted {
+				t.Errorf("test event posted %d times, want %d", got, wantPosted)
+			}
+		})
+	}
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	bmc := newFakeBMC(t)
+	bmc.resources["/redfish/v1/SessionService"] = map[string]interface{}{"@odata.id": "/redfish/v1/SessionService", "Id": "SessionService"}
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		name   string
+		server func() RedfishServer
+		// Status code of the session service instead of reading it
+		sessionServiceStatus int
+		wantErr              bool
+		wantAuthFailed       bool
+	}{
+		{name: "valid credentials", server: bmc.server},
+		{name: "wrong password", server: func() RedfishServer {
+			server := bmc.server()
+			server.Password = "wrong"
+			return server
+		}, wantErr: true, wantAuthFailed: true},
+		{name: "session refused", server: bmc.server, sessionServiceStatus: http.StatusForbidden, wantErr: true, wantAuthFailed: true},
+		{name: "session service failing", server: bmc.server, sessionServiceStatus: http.StatusInternalServerError, wantErr: true},
+		{name: "unreachable host", server: func() RedfishServer {
+			return RedfishServer{IP: unreachable.URL, Username: "admin", Password: "password"}
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc.mu.Lock()
+			delete(bmc.failures, "GET /redfish/v1/SessionService")
+			if tt.sessionServiceStatus != 0 {
+				bmc.failures["GET /redfish/v1/SessionService"] = tt.sessionServiceStatus
+			}
+			bmc.mu.Unlock()
+
+			err := VerifyCredentials(tt.server())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrAuthFailed); got != tt.wantAuthFailed {
+				t.Errorf("VerifyCredentials() error = %v, ErrAuthFailed %v, want %v", err, got, tt.wantAuthFailed)
+			}
+			if n := bmc.openSessions(); n != 0 {
+				t.Errorf("%d sessions left open", n)
+			}
+		})
+	}
+}
+
+func TestCreateSubscriptionPreferredID(t *testing.T) {
+	tests := []struct {
+		name      string
+		honorIDs  bool
+		rejectIDs bool
+		wantPosts int
+		wantID    bool
+	}{
+		{name: "BMC honoring the Id", honorIDs: true, wantPosts: 1, wantID: true},
+		{name: "BMC ignoring the Id", wantPosts: 1},
+		{name: "BMC rejecting the Id", rejectIDs: true, wantPosts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.honorIDs, bmc.rejectIDs = tt.honorIDs, tt.rejectIDs
+			server := bmc.server()
+			payload := SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", PreferredID: "scrapefish"}
+
+			created, err := createSubscription(server, payload)
+			if err != nil {
+				t.Fatalf("createSubscription() error = %v", err)
+			}
+			uri := created[payload.Destination].URI
+			if bmc.subscription(uri) == nil {
+				t.Fatalf("subscription %s not created on the BMC", uri)
+			}
+			if got := subscriptionIDFromURI(uri) == payload.PreferredID; got != tt.wantID {
+				t.Errorf("subscription %s has the preferred Id = %v, want %v", uri, got, tt.wantID)
+			}
+			if got := bmc.count("POST /redfish/v1/EventService/Subscriptions"); got != tt.wantPosts {
+				t.Errorf("subscription requested %d times, want %d", got, tt.wantPosts)
+			}
+			stats := GetStats(server.ID())
+			if stats == nil || stats.PreferredIDHonored == nil {
+				t.Fatal("whether the preferred Id was honored isn't recorded")
+			}
+			if *stats.PreferredIDHonored != tt.wantID {
+				t.Errorf("PreferredIDHonored = %v, want %v", *stats.PreferredIDHonored, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestValidatePreferredID(t *testing.T) {
+	tests := []struct {
+		preferredID string
+		wantErr     bool
+	}{
+		{"scrapefish", false},
+		{"scrapefish-node01", false},
+		{"a/b", true},
+		{"../Subscriptions", true},
+		{"with space", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preferredID, func(t *testing.T) {
+			payload := SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", PreferredID: tt.preferredID}
+			if err := ValidateSubscriptionPayload(payload); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSubscriptionPayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRangeSubscriptions(t *testing.T) {
+	var servers []RedfishServer
+	wantCounts := map[string]int{}
+	for i := 0; i < 3; i++ {
+		bmc := newFakeBMC(t)
+		for j := 0; j < i; j++ {
+			bmc.addSubscription(map[string]interface{}{"Destination": fmt.Sprintf("https://10.0.0.%d:8080", j+1)})
+		}
+		servers = append(servers, bmc.server())
+		wantCounts[bmc.server().ID()] = i
+	}
+	failing := newFakeBMC(t)
+	failing.failures["GET /redfish/v1/EventService/Subscriptions"] = http.StatusInternalServerError
+	servers = append(servers, failing.server())
+
+	seen := map[string]int{}
+	err := RangeSubscriptions(context.Background(), servers, func(serverID string, subscriptions []*redfish.EventDestination, err error) bool {
+		seen[serverID]++
+		if serverID == failing.server().ID() {
+			if err == nil {
+				t.Errorf("server %s: no error for failing subscriptions", serverID)
+			}
+			return true
+		}
+		if err != nil {
+			t.Errorf("server %s: error = %v", serverID, err)
+		}
+		if len(subscriptions) != wantCounts[serverID] {
+			t.Errorf("server %s: %d subscriptions, want %d", serverID, len(subscriptions), wantCounts[serverID])
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("RangeSubscriptions() error = %v", err)
+	}
+	if len(seen) != len(servers) {
+		t.Errorf("callback called for %d servers, want %d", len(seen), len(servers))
+	}
+	for serverID, n := range seen {
+		if n != 1 {
+			t.Errorf("server %s: callback called %d times, want once", serverID, n)
+		}
+	}
+}
+
+func TestRangeSubscriptionsStops(t *testing.T) {
+	var servers []RedfishServer
+	for i := 0; i < 4; i++ {
+		servers = append(servers, newFakeBMC(t).server())
+	}
+	settings := newConnectionSettings(RedfishClientConfig{})
+	settings.maxConcurrency = 1
+	servers = withConnectionSettings(servers, settings)
+
+	calls := 0
+	err := RangeSubscriptions(context.Background(), servers, func(string, []*redfish.EventDestination, error) bool {
+		calls++
+		return false
+	})
+	if err != nil {
+		t.Errorf("RangeSubscriptions() error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("callback called %d times after returning false, want once", calls)
+	}
+
+	// Nothing is listed once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	err = RangeSubscriptions(ctx, servers, func(string, []*redfish.EventDestination, error) bool {
+		calls++
+		return true
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("RangeSubscriptions() with a canceled context error = %v, want context.Canceled", err)
+	}
+	if calls != 0 {
+		t.Errorf("callback called %d times with a canceled context", calls)
+	}
+}
+
+func TestDuplicateDestinationInBatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		destinations []string
+		wantErr      bool
+	}{
+		{name: "distinct destinations", destinations: []string{"https://10.0.0.100:8080", "https://10.0.0.101:8080"}},
+		{name: "colliding destinations", destinations: []string{"https://10.0.0.100:8080", "https://10.0.0.101:8080", "https://10.0.0.100:8080"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			payload := SubscriptionPayload{Destinations: tt.destinations, Protocol: "Redfish", EventTypes: []redfish.EventType{redfish.AlertEventType}, Context: "scrapefish"}
+
+			batch, err := BatchCreateSubscriptions(context.Background(), []RedfishServer{bmc.server()}, payload, BatchCreateOptions{})
+			if got := errors.Is(err, ErrDuplicateDestinationInBatch); got != tt.wantErr {
+				t.Fatalf("BatchCreateSubscriptions() error = %v, ErrDuplicateDestinationInBatch %v, want %v", err, got, tt.wantErr)
+			}
+			if tt.wantErr {
+				if requests := bmc.requestLog(); len(requests) != 0 {
+					t.Errorf("BMC was contacted before the collision was found: %v", requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := bmc.subscriptionCount(); n != len(tt.destinations) {
+				t.Errorf("BMC has %d subscriptions, want %d", n, len(tt.destinations))
+			}
+			if len(batch.Servers) != 1 {
+				t.Errorf("batch has %d servers, want 1", len(batch.Servers))
+			}
+		})
+	}
+}
+
+func TestDeleteSubscriptionsByDestinationPrefix(t *testing.T) {
+	bmc := newFakeBMC(t)
+	oldA := bmc.addSubscription(map[string]interface{}{"Destination": "http://old-a/events"})
+	oldB := bmc.addSubscription(map[string]interface{}{"Destination": "http://old-b/events"})
+	newURI := bmc.addSubscription(map[string]interface{}{"Destination": "http://new/events"})
+	other := newFakeBMC(t)
+	undeletable := other.addSubscription(map[string]interface{}{"Destination": "http://old-c/events"})
+	other.failures["DELETE "+undeletable] = http.StatusInternalServerError
+
+	deleted, err := DeleteSubscriptionsByDestinationPrefix([]RedfishServer{bmc.server(), other.server()}, "http://old-")
+	if deleted != 2 {
+		t.Errorf("DeleteSubscriptionsByDestinationPrefix() = %d, want 2", deleted)
+	}
+	if err == nil {
+		t.Error("DeleteSubscriptionsByDestinationPrefix() error = nil, want the failed deletion")
+	}
+	if bmc.subscription(oldA) != nil || bmc.subscription(oldB) != nil {
+		t.Error("old- subscriptions not deleted")
+	}
+	if bmc.subscription(newURI) == nil {
+		t.Error("subscription to http://new deleted")
+	}
+
+	if _, err := DeleteSubscriptionsByDestinationPrefix([]RedfishServer{bmc.server()}, ""); err == nil {
+		t.Error("DeleteSubscriptionsByDestinationPrefix() with an empty prefix error = nil")
+	}
+	if bmc.subscription(newURI) == nil {
+		t.Error("subscription deleted with an empty prefix")
+	}
+}
+
+func TestCreateSubscriptionSeverityFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		// Severities of the event service, none when nil
+		supported []string
+		// Severities the BMC must have received, nil when absent
+		want interface{}
+	}{
+		{"supported", []string{"OK", "Warning", "Critical"}, "[Critical Warning]"},
+		{"partly supported", []string{"OK", "Critical"}, "[Critical]"},
+		{"none supported", []string{"OK"}, nil},
+		{"no severity filtering", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			if tt.supported != nil {
+				bmc.eventService["Severities"] = tt.supported
+			}
+			payload := SubscriptionPayload{
+				Destination:     "https://10.0.0.9:8080",
+				Protocol:        "Redfish",
+				OriginResources: []string{"/redfish/v1/Systems/1"},
+				SeverityFilter:  []string{"Critical", "Warning"},
+			}
+
+			created, err := createSubscription(bmc.server(), payload)
+			if err != nil {
+				t.Fatalf("createSubscription() error = %v", err)
+			}
+			subscription := bmc.subscription(created[payload.Destination].URI)
+			got, ok := subscription["Severities"]
+			if tt.want == nil {
+				if ok {
+					t.Errorf("Severities = %v, want none", got)
+				}
+				return
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("Severities = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSeverityFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  []string
+		wantErr bool
+	}{
+		{"known severities", []string{"OK", "Warning", "Critical"}, false},
+		{"unknown severity", []string{"Critical", "Fatal"}, true},
+		{"wrong case", []string{"critical"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", SeverityFilter: tt.filter}
+			if err := ValidateSubscriptionPayload(payload); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSubscriptionPayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsODataID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"chassis", "/redfish/v1/Chassis/1", true},
+		{"nested", "/redfish/v1/Systems/1/Processors/GPU0", true},
+		{"escaped", "/redfish/v1/Chassis/Rack%201", true},
+		{"relative", "Chassis/1", false},
+		{"relative to the root", "redfish/v1/Chassis/1", false},
+		{"absolute URL", "https://10.0.0.1/redfish/v1/Chassis/1", false},
+		{"outside the service", "/api/v1/Chassis/1", false},
+		{"service root", "/redfish/v1", false},
+		{"empty", "", false},
+		{"space", "/redfish/v1/Chassis/Rack 1", false},
+		{"query", "/redfish/v1/Chassis?$expand=*", false},
+		{"fragment", "/redfish/v1/Chassis/1#/Status", false},
+		{"invalid escape", "/redfish/v1/Chassis/%zz", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isODataID(tt.id); got != tt.want {
+				t.Errorf("isODataID(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOriginResourcesRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []string
+	}{
+		{"relative", []string{"Chassis/1"}},
+		{"malformed", []string{"/redfish/v1/Chassis/%zz"}},
+		{"one of several", []string{"/redfish/v1/Chassis/1", "https://10.0.0.1/redfish/v1/Chassis/2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := SubscriptionPayload{Destination: "https://10.0.0.9:8080", Protocol: "Redfish", OriginResources: tt.resources}
+			if err := ValidateSubscriptionPayload(payload); err == nil {
+				t.Error("ValidateSubscriptionPayload() error = nil, want the OriginResource rejected")
+			}
+
+			bmc := newFakeBMC(t)
+			if _, err := CreateSubscriptionsForAllServers([]RedfishServer{bmc.server()}, payload); err == nil {
+				t.Error("CreateSubscriptionsForAllServers() error = nil, want the OriginResource rejected")
+			}
+			if requests := bmc.requestLog(); len(requests) != 0 {
+				t.Errorf("requests = %v, want none for a rejected payload", requests)
+			}
+		})
+	}
+}
+
+func TestCreateSubscriptionsExpiredContext(t *testing.T) {
+	var requests atomic.Int32
+	bmc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer bmc.Close()
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	created, err := CreateSubscriptionsForAllServersContext(ctx, []RedfishServer{{IP: bmc.URL}}, batchPayload())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CreateSubscriptionsForAllServersContext() = %v, %v, want context.DeadlineExceeded", created, err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("%d requests made with an expired context, want none", n)
+	}
+}
+
+func TestCreateSubscriptionsCanceledRollsBack(t *testing.T) {
+	healthy := newFakeBMC(t)
+	hanging := newFakeBMC(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hanging.handlers["/redfish/v1/EventService/Subscriptions"] = func(w http.ResponseWriter, r *http.Request) {
+		// Cancel once the other server has its subscription
+		for deadline := time.Now().Add(5 * time.Second); healthy.subscriptionCount() == 0 && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		<-r.Context().Done()
+	}
+
+	_, err := CreateSubscriptionsForAllServersContext(ctx, []RedfishServer{healthy.server(), hanging.server()}, batchPayload())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CreateSubscriptionsForAllServersContext() error = %v, want context.Canceled", err)
+	}
+	if got := healthy.subscriptionCount(); got != 0 {
+		t.Errorf("%d subscriptions left after the cancel, want them rolled back", got)
+	}
+}
+
+func TestServerLocation(t *testing.T) {
+	var servers []RedfishServer
+	config := `[
+		{"ip": "https://10.0.0.1", "datacenter": "dc1", "row": "r2", "rack": "3", "assetTag": "SRV-0001"},
+		{"ip": "https://10.0.0.2", "rack": "4"},
+		{"ip": "https://10.0.0.3", "datacenter": "dc1", "rack": "3"}]`
+	if err := json.Unmarshal([]byte(config), &servers); err != nil {
+		t.Fatal(err)
+	}
+
+	want := ServerLocation{Datacenter: "dc1", Row: "r2", Rack: "3", AssetTag: "SRV-0001"}
+	if servers[0].ServerLocation != want {
+		t.Errorf("location = %+v, want %+v", servers[0].ServerLocation, want)
+	}
+	for i, want := range []string{"dc1/r2/3", "4", "dc1/3"} {
+		if got := servers[i].ServerLocation.String(); got != want {
+			t.Errorf("server %d location = %q, want %q", i, got, want)
+		}
+	}
+	rack := ServersInRack(servers, "3")
+	if len(rack) != 2 || rack[0].IP != "https://10.0.0.1" || rack[1].IP != "https://10.0.0.3" {
+		t.Errorf("ServersInRack() = %v, want the servers of rack 3", rack)
+	}
+}
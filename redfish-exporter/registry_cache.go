@@ -0,0 +1,191 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// Message registries used when the BMC's registries can't be fetched
+//
+//go:embed registries/*.json
+var bundledRegistries embed.FS
+
+const (
+	// Time before loading the registries of a server again after a failure
+	registryRetryInterval = 5 * time.Minute
+	// Maximum number of servers whose registries are loaded at once
+	maxConcurrentRegistryLoads = 4
+)
+
+// RegistryCache resolves MessageIds to their messages using the message
+// registries of the BMCs, falling back to the bundled registries
+type RegistryCache struct {
+	mu sync.RWMutex
+	// Bundled registries by prefix, e.g. Base
+	bundled map[string]*redfish.MessageRegistry
+	// Registries loaded from each server by server ID, then prefix
+	servers map[string]map[string]*redfish.MessageRegistry
+	// Servers whose registries are being loaded
+	loading map[string]bool
+	// When loading the registries of each server last failed
+	failedAt map[string]time.Time
+	// Time before retrying a server whose registries failed to load
+	retryInterval time.Duration
+	// Semaphore bounding the loads running at once
+	loads chan struct{}
+}
+
+// Create a registry cache holding the bundled registries
+func NewRegistryCache() *RegistryCache {
+	cache := &RegistryCache{
+		bundled:       make(map[string]*redfish.MessageRegistry),
+		servers:       make(map[string]map[string]*redfish.MessageRegistry),
+		loading:       make(map[string]bool),
+		failedAt:      make(map[string]time.Time),
+		retryInterval: registryRetryInterval,
+		loads:         make(chan struct{}, maxConcurrentRegistryLoads),
+	}
+
+	files, err := bundledRegistries.ReadDir("registries")
+	if err != nil {
+		log.Printf("Failed to read bundled message registries: %v", err)
+		return cache
+	}
+	for _, file := range files {
+		data, err := bundledRegistries.ReadFile(path.Join("registries", file.Name()))
+		if err != nil {
+			log.Printf("Failed to read bundled message registry %s: %v", file.Name(), err)
+			continue
+		}
+		var registry redfish.MessageRegistry
+		if err := json.Unmarshal(data, &registry); err != nil {
+			log.Printf("Failed to parse bundled message registry %s: %v", file.Name(), err)
+			continue
+		}
+		cache.bundled[registry.RegistryPrefix] = &registry
+	}
+	return cache
+}
+
+// Start loading the message registries of a server in the background
+// unless they are loaded, being loaded, or failed to load less than the
+// retry interval ago. It never waits for the server.
+func (r *RegistryCache) EnsureLoaded(server RedfishServer) {
+	id := server.ID()
+	r.mu.Lock()
+	_, loaded := r.servers[id]
+	failedAt, failed := r.failedAt[id]
+	if loaded || r.loading[id] || (failed && time.Since(failedAt) < r.retryInterval) {
+		r.mu.Unlock()
+		return
+	}
+	r.loading[id] = true
+	r.mu.Unlock()
+
+	go r.load(server)
+}
+
+// Load the registries of a server once a load slot is free, recording a
+// failure so the load is retried later
+func (r *RegistryCache) load(server RedfishServer) {
+	r.loads <- struct{}{}
+	defer func() { <-r.loads }()
+
+	err := r.LoadFromServer(server)
+	if err != nil {
+		log.Printf("Failed to load message registries from server %s, using bundled registries: %v", server.IP, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.loading, server.ID())
+	if err != nil {
+		r.failedAt[server.ID()] = time.Now()
+	}
+}
+
+// Fetch the English message registries of a server into the cache
+func (r *RegistryCache) LoadFromServer(server RedfishServer) error {
+	c, err := getRedfishClient(server)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	registries, err := c.Service.MessageRegistriesByLanguage("en")
+	if err != nil {
+		return fmt.Errorf("failed to get message registries on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+
+	byPrefix := make(map[string]*redfish.MessageRegistry, len(registries))
+	for _, registry := range registries {
+		byPrefix[registry.RegistryPrefix] = registry
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.servers[server.ID()] = byPrefix
+	delete(r.failedAt, server.ID())
+	log.Printf("Loaded %d message registries from server %s", len(registries), server.IP)
+	return nil
+}
+
+// Resolve a MessageId like Base.1.0.GeneralError sent by a server to its
+// message, with %1, %2... replaced by the args, and its severity. The
+// server's own registries take precedence over the bundled ones.
+func (r *RegistryCache) Resolve(serverID, messageId string, args []string) (message, severity string, err error) {
+	parts := strings.Split(messageId, ".")
+	if len(parts) < 2 {
+		return "", "", fmt.Errorf("invalid MessageId %q", messageId)
+	}
+	prefix, key := parts[0], parts[len(parts)-1]
+
+	r.mu.RLock()
+	registry, ok := r.servers[serverID][prefix]
+	if !ok {
+		registry, ok = r.bundled[prefix]
+	}
+	r.mu.RUnlock()
+	if !ok {
+		return "", "", fmt.Errorf("no message registry for %s", prefix)
+	}
+	registryMessage, ok := registry.Messages[key]
+	if !ok {
+		return "", "", fmt.Errorf("message %s not found in registry %s", key, registry.ID)
+	}
+
+	message = registryMessage.Message
+	// Replace from the highest argument so %1 doesn't match the start of %10
+	for i := len(args); i >= 1; i-- {
+		message = strings.ReplaceAll(message, "%"+strconv.Itoa(i), args[i-1])
+	}
+	severity = registryMessage.MessageSeverity
+	if severity == "" {
+		severity = registryMessage.Severity
+	}
+	return message, severity, nil
+}
@@ -0,0 +1,138 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Serve a Custom message registry whose Hello message is text
+func serveRegistry(b *fakeBMC, text string) {
+	b.root["Registries"] = map[string]string{"@odata.id": "/redfish/v1/Registries"}
+	b.resources["/redfish/v1/Registries"] = map[string]interface{}{
+		"@odata.id": "/redfish/v1/Registries",
+		"Members":   []map[string]string{{"@odata.id": "/redfish/v1/Registries/Custom"}},
+	}
+	b.resources["/redfish/v1/Registries/Custom"] = map[string]interface{}{
+		"@odata.id": "/redfish/v1/Registries/Custom",
+		"Id":        "Custom",
+		"Languages": []string{"en"},
+		"Location":  []map[string]string{{"Language": "en", "Uri": "/registries/Custom.json"}},
+	}
+	b.resources["/registries/Custom.json"] = map[string]interface{}{
+		"@odata.id":       "/registries/Custom.json",
+		"Id":              "Custom.1.0.0",
+		"RegistryPrefix":  "Custom",
+		"RegistryVersion": "1.0.0",
+		"Messages": map[string]interface{}{
+			"Hello": map[string]interface{}{"Message": text, "MessageSeverity": "OK"},
+		},
+	}
+}
+
+// Wait until no registry load is running for the server
+func waitForRegistryLoad(t *testing.T, r *RegistryCache, server RedfishServer) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.RLock()
+		loading := r.loading[server.ID()]
+		r.mu.RUnlock()
+		if !loading {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("registries of %s still loading", server.IP)
+}
+
+func TestRegistryCacheResolvesPerServer(t *testing.T) {
+	first, second := newFakeBMC(t), newFakeBMC(t)
+	serveRegistry(first, "Hello %1 from the first BMC")
+	serveRegistry(second, "Hello %1 from the second BMC")
+
+	r := NewRegistryCache()
+	for _, b := range []*fakeBMC{first, second} {
+		r.EnsureLoaded(b.server())
+		waitForRegistryLoad(t, r, b.server())
+	}
+
+	tests := []struct {
+		name      string
+		serverID  string
+		messageID string
+		want      string
+		wantErr   bool
+	}{
+		{"first server", first.server().ID(), "Custom.1.0.Hello", "Hello node from the first BMC", false},
+		{"second server", second.server().ID(), "Custom.1.0.Hello", "Hello node from the second BMC", false},
+		{"unknown server has no custom registry", "10.0.0.1", "Custom.1.0.Hello", "", true},
+		{"bundled fallback", first.server().ID(), "Base.1.0.GeneralError", "", false},
+		{"invalid MessageId", first.server().ID(), "Hello", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, _, err := r.Resolve(tt.serverID, tt.messageID, []string{"node"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != "" && message != tt.want {
+				t.Errorf("Resolve() = %q, want %q", message, tt.want)
+			}
+			if !tt.wantErr && message == "" {
+				t.Error("Resolve() returned an empty message")
+			}
+		})
+	}
+}
+
+func TestRegistryCacheRetriesFailedLoads(t *testing.T) {
+	b := newFakeBMC(t)
+	serveRegistry(b, "Hello %1")
+	b.failures["GET /redfish/v1/Registries"] = http.StatusInternalServerError
+	server := b.server()
+
+	r := NewRegistryCache()
+	r.EnsureLoaded(server)
+	waitForRegistryLoad(t, r, server)
+	if _, _, err := r.Resolve(server.ID(), "Custom.1.0.Hello", nil); err == nil {
+		t.Fatal("Resolve() succeeded after a failed load")
+	}
+
+	// Within the retry interval the failed server isn't contacted again
+	r.EnsureLoaded(server)
+	waitForRegistryLoad(t, r, server)
+	if got := b.count("GET /redfish/v1/Registries"); got != 1 {
+		t.Fatalf("registry collection fetched %d times within the retry interval, want 1", got)
+	}
+
+	b.mu.Lock()
+	delete(b.failures, "GET /redfish/v1/Registries")
+	b.mu.Unlock()
+	r.mu.Lock()
+	r.retryInterval = 0
+	r.mu.Unlock()
+
+	r.EnsureLoaded(server)
+	waitForRegistryLoad(t, r, server)
+	message, _, err := r.Resolve(server.ID(), "Custom.1.0.Hello", []string{"again"})
+	if err != nil || message != "Hello again" {
+		t.Fatalf("Resolve() after retry = %q, %v, want %q", message, err, "Hello again")
+	}
+}
@@ -0,0 +1,92 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const restartDetectTimeout = 10 * time.Second
+
+// Poll the service root of the servers every interval and call onRestart
+// for a server that answers again after being unreachable. A BMC coming
+// back from a reboot has lost its subscriptions.
+func RunRestartDetector(ctx context.Context, servers []RedfishServer, interval time.Duration, onRestart func(RedfishServer)) {
+	// Each server is checked with its own TLS policy and credentials
+	clients := make(map[string]*http.Client, len(servers))
+	for _, server := range servers {
+		client := newServerHTTPClient(server)
+		client.Timeout = restartDetectTimeout
+		clients[server.IP] = client
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Servers that did not answer the last check
+	unreachable := make(map[string]bool)
+
+	log.Printf("Starting BMC restart detector every %v", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Context done, stopping restart detector")
+			return
+		case <-ticker.C:
+			pool := NewWorkerPool[bool](serversConcurrency(servers))
+			for _, server := range servers {
+				pool.Add(func() bool {
+					return serviceRootReachable(ctx, clients[server.IP], server)
+				})
+			}
+			for i, reachable := range pool.Run(ctx) {
+				server := servers[i]
+				if !reachable {
+					if !unreachable[server.IP] && ctx.Err() == nil {
+						log.Printf("Server %s is unreachable", server.IP)
+					}
+					unreachable[server.IP] = true
+					continue
+				}
+				if unreachable[server.IP] {
+					delete(unreachable, server.IP)
+					log.Printf("Server %s is reachable again, checking its subscriptions", server.IP)
+					onRestart(server)
+				}
+			}
+		}
+	}
+}
+
+// Whether the server's redfish service root answers. The service root
+// does not require authentication, which keeps the check cheap.
+func serviceRootReachable(ctx context.Context, client *http.Client, server RedfishServer) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(server.IP, "/")+"/redfish/v1/", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
@@ -0,0 +1,98 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryConfig controls how failed operations are retried
+type RetryConfig struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Fraction of the backoff added or removed at random, between 0 and 1
+	Jitter float64
+}
+
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Jitter:         0.2,
+}
+
+// Backoff returns the delay before the given retry, starting at 1, doubling
+// the initial backoff for every attempt up to the maximum
+func (r RetryConfig) Backoff(retry int) time.Duration {
+	backoff := r.InitialBackoff
+	for i := 1; i < retry && backoff < r.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+		backoff = r.MaxBackoff
+	}
+	if r.Jitter > 0 {
+		backoff += time.Duration((rand.Float64()*2 - 1) * r.Jitter * float64(backoff))
+	}
+	return backoff
+}
+
+// circuitBreaker stops calls to a failing destination. After threshold
+// consecutive failures it opens and rejects calls until the cooldown has
+// passed, then lets a call through to probe the destination again.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may be made
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures < b.threshold || time.Since(b.openedAt) >= b.cooldown
+}
+
+// IsOpen reports whether calls are currently rejected
+func (b *circuitBreaker) IsOpen() bool {
+	return !b.Allow()
+}
+
+func (b *circuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+func (b *circuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,96 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryConfigBackoff(t *testing.T) {
+	config := RetryConfig{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	}
+	for _, tt := range tests {
+		if got := config.Backoff(tt.retry); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.retry, got, tt.want)
+		}
+	}
+}
+
+func TestRetryConfigBackoffJitter(t *testing.T) {
+	config := RetryConfig{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if got := config.Backoff(3); got < 320*time.Millisecond || got > 480*time.Millisecond {
+			t.Fatalf("Backoff(3) = %v, want 400ms ± 20%%", got)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	tests := []struct {
+		name      string
+		failures  int
+		success   bool
+		wait      time.Duration
+		wantAllow bool
+	}{
+		{"closed below the threshold", 2, false, 0, true},
+		{"open at the threshold", 3, false, 0, false},
+		{"half open after the cooldown", 3, false, cooldown, true},
+		{"success closes it", 3, true, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := newCircuitBreaker(3, cooldown)
+			for i := 0; i < tt.failures; i++ {
+				breaker.RecordFailure()
+			}
+			if tt.success {
+				breaker.RecordSuccess()
+			}
+			time.Sleep(tt.wait)
+			if got := breaker.Allow(); got != tt.wantAllow {
+				t.Errorf("Allow() = %v, want %v", got, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerReopensAfterFailedProbe(t *testing.T) {
+	breaker := newCircuitBreaker(2, 30*time.Millisecond)
+	breaker.RecordFailure()
+	breaker.RecordFailure()
+	time.Sleep(30 * time.Millisecond)
+	if !breaker.Allow() {
+		t.Fatal("Allow() = false after the cooldown, want a probe let through")
+	}
+	breaker.RecordFailure()
+	if breaker.Allow() {
+		t.Error("Allow() = true after the probe failed, want the breaker open again")
+	}
+}
@@ -0,0 +1,126 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryingTransport(t *testing.T) {
+	tests := []struct {
+		name               string
+		method             string
+		body               string
+		retryNonIdempotent bool
+		statuses           []int
+		retryAfter         string
+		wantStatus         int
+		wantRequests       int
+	}{
+		{"succeeds first time", http.MethodGet, "", false, []int{http.StatusOK}, "", http.StatusOK, 1},
+		{"unavailable retried", http.MethodGet, "", false, []int{http.StatusServiceUnavailable, http.StatusOK}, "", http.StatusOK, 2},
+		{"gives up after the attempts", http.MethodGet, "", false, []int{http.StatusBadGateway}, "", http.StatusBadGateway, 3},
+		{"rejection not retried", http.MethodGet, "", false, []int{http.StatusNotFound, http.StatusOK}, "", http.StatusNotFound, 1},
+		{"internal error not retried", http.MethodDelete, "", false, []int{http.StatusInternalServerError, http.StatusOK}, "", http.StatusInternalServerError, 1},
+		{"put body replayed", http.MethodPut, "{}", false, []int{http.StatusTooManyRequests, http.StatusOK}, "0", http.StatusOK, 2},
+		{"post not retried", http.MethodPost, "{}", false, []int{http.StatusServiceUnavailable, http.StatusCreated}, "", http.StatusServiceUnavailable, 1},
+		{"post retried when allowed", http.MethodPost, "{}", true, []int{http.StatusServiceUnavailable, http.StatusCreated}, "", http.StatusCreated, 2},
+		{"long retry-after returned", http.MethodGet, "", false, []int{http.StatusServiceUnavailable, http.StatusOK}, "3600", http.StatusServiceUnavailable, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var bodies []string
+			statuses := tt.statuses
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				bodies = append(bodies, string(body))
+				status := statuses[0]
+				if len(statuses) > 1 {
+					statuses = statuses[1:]
+				}
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &retryingTransport{
+				base:               http.DefaultTransport,
+				retry:              RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+				retryNonIdempotent: tt.retryNonIdempotent,
+			}}
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequest(tt.method, server.URL+"/redfish/v1", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if len(bodies) != tt.wantRequests {
+				t.Errorf("%d requests made, want %d", len(bodies), tt.wantRequests)
+			}
+			for i, got := range bodies {
+				if got != tt.body {
+					t.Errorf("request %d body = %q, want %q", i, got, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
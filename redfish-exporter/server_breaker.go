@@ -0,0 +1,86 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultServerBreakerThreshold = 5
+	DefaultServerBreakerCooldown  = 10 * time.Minute
+)
+
+// ServerBreakers holds a circuit breaker per server, so the reconcile loop
+// skips the BMCs that keep failing instead of waiting on them every round
+type ServerBreakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+	metrics  *Metrics
+}
+
+// Create the breakers, a server is skipped for the cooldown after threshold
+// consecutive failures
+func NewServerBreakers(threshold int, cooldown time.Duration, metrics *Metrics) *ServerBreakers {
+	return &ServerBreakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		breakers:  make(map[string]*circuitBreaker),
+		metrics:   metrics,
+	}
+}
+
+func (s *ServerBreakers) breaker(serverIP string) *circuitBreaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	breaker, ok := s.breakers[serverIP]
+	if !ok {
+		breaker = newCircuitBreaker(s.threshold, s.cooldown)
+		s.breakers[serverIP] = breaker
+	}
+	return breaker
+}
+
+// Allow reports whether the server may be contacted
+func (s *ServerBreakers) Allow(serverIP string) bool {
+	allowed := s.breaker(serverIP).Allow()
+	s.setCircuitOpenMetric(serverIP, !allowed)
+	return allowed
+}
+
+// Record the result of contacting the server, a success closes its breaker
+func (s *ServerBreakers) Record(serverIP string, err error) {
+	breaker := s.breaker(serverIP)
+	if err != nil {
+		breaker.RecordFailure()
+	} else {
+		breaker.RecordSuccess()
+	}
+	s.setCircuitOpenMetric(serverIP, breaker.IsOpen())
+}
+
+func (s *ServerBreakers) setCircuitOpenMetric(serverIP string, open bool) {
+	value := 0.0
+	if open {
+		value = 1
+	}
+	s.metrics.serverCircuitOpen.WithLabelValues(serverIP).Set(value)
+}
@@ -0,0 +1,84 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func circuitOpenValue(t *testing.T, breakers *ServerBreakers, serverIP string) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := breakers.metrics.serverCircuitOpen.WithLabelValues(serverIP).Write(&metric); err != nil {
+		t.Fatalf("failed to read gauge: %v", err)
+	}
+	return metric.GetGauge().GetValue()
+}
+
+func TestServerBreakers(t *testing.T) {
+	errUnreachable := errors.New("connection refused")
+	tests := []struct {
+		name      string
+		results   []error
+		wantAllow bool
+	}{
+		{"new server", nil, true},
+		{"below the threshold", []error{errUnreachable, errUnreachable}, true},
+		{"at the threshold", []error{errUnreachable, errUnreachable, errUnreachable}, false},
+		{"success resets the count", []error{errUnreachable, errUnreachable, nil, errUnreachable, errUnreachable}, true},
+		{"success after opening", []error{errUnreachable, errUnreachable, errUnreachable, nil}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakers := NewServerBreakers(3, time.Hour, NewMetrics())
+			serverIP := "10.0.0.1"
+			for _, err := range tt.results {
+				breakers.Record(serverIP, err)
+			}
+			if got := breakers.Allow(serverIP); got != tt.wantAllow {
+				t.Errorf("Allow() = %v, want %v", got, tt.wantAllow)
+			}
+			wantMetric := 1.0
+			if tt.wantAllow {
+				wantMetric = 0
+			}
+			if got := circuitOpenValue(t, breakers, serverIP); got != wantMetric {
+				t.Errorf("redfish_server_circuit_open = %v, want %v", got, wantMetric)
+			}
+			// Other servers keep their own breaker
+			if !breakers.Allow("10.0.0.2") {
+				t.Error("Allow() = false for a server that never failed")
+			}
+		})
+	}
+}
+
+func TestServerBreakersCooldown(t *testing.T) {
+	breakers := NewServerBreakers(1, 30*time.Millisecond, NewMetrics())
+	breakers.Record("10.0.0.1", errors.New("timeout"))
+	if breakers.Allow("10.0.0.1") {
+		t.Fatal("Allow() = true right after the failure")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !breakers.Allow("10.0.0.1") {
+		t.Error("Allow() = false after the cooldown")
+	}
+}
@@ -0,0 +1,95 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A single requirement of a label selector
+type labelRequirement struct {
+	key   string
+	value string
+	// Match servers without the label value instead of with it
+	negate bool
+	// Only check whether the label is set
+	exists bool
+}
+
+func (r labelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.key]
+	if r.exists {
+		return ok != r.negate
+	}
+	return (ok && value == r.value) != r.negate
+}
+
+// Parse a comma separated label selector. Each requirement is one of
+// key=value, key!=value, key (label set) or !key (label not set).
+func parseSelector(selector string) ([]labelRequirement, error) {
+	var requirements []labelRequirement
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var requirement labelRequirement
+		switch {
+		case strings.Contains(part, "!="):
+			key, value, _ := strings.Cut(part, "!=")
+			requirement = labelRequirement{key: strings.TrimSpace(key), value: strings.TrimSpace(value), negate: true}
+		case strings.Contains(part, "="):
+			key, value, _ := strings.Cut(part, "=")
+			requirement = labelRequirement{key: strings.TrimSpace(key), value: strings.TrimSpace(value)}
+		case strings.HasPrefix(part, "!"):
+			requirement = labelRequirement{key: strings.TrimSpace(part[1:]), exists: true, negate: true}
+		default:
+			requirement = labelRequirement{key: part, exists: true}
+		}
+		if requirement.key == "" {
+			return nil, fmt.Errorf("invalid label selector %q: empty label name in %q", selector, part)
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements, nil
+}
+
+// Return the servers whose labels match the selector, e.g. "rack=3,env!=dev".
+// An empty selector matches all servers.
+func Filter(servers []RedfishServer, selector string) ([]RedfishServer, error) {
+	requirements, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []RedfishServer
+	for _, server := range servers {
+		matches := true
+		for _, requirement := range requirements {
+			if !requirement.matches(server.Labels) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, server)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,62 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	servers := []RedfishServer{
+		{IP: "https://10.0.0.1", Labels: map[string]string{"rack": "3", "env": "prod"}},
+		{IP: "https://10.0.0.2", Labels: map[string]string{"rack": "3", "env": "dev"}},
+		{IP: "https://10.0.0.3", Labels: map[string]string{"rack": "4", "gpu": ""}},
+		{IP: "https://10.0.0.4"},
+	}
+	tests := []struct {
+		selector string
+		want     []string
+		wantErr  bool
+	}{
+		{"", []string{"https://10.0.0.1", "https://10.0.0.2", "https://10.0.0.3", "https://10.0.0.4"}, false},
+		{"rack=3", []string{"https://10.0.0.1", "https://10.0.0.2"}, false},
+		{"rack=3,env!=dev", []string{"https://10.0.0.1"}, false},
+		{" rack = 3 , env = prod ", []string{"https://10.0.0.1"}, false},
+		{"env!=dev", []string{"https://10.0.0.1", "https://10.0.0.3", "https://10.0.0.4"}, false},
+		{"gpu", []string{"https://10.0.0.3"}, false},
+		{"!env", []string{"https://10.0.0.3", "https://10.0.0.4"}, false},
+		{"rack=5", nil, false},
+		{"=3", nil, true},
+		{"rack=3,!", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			filtered, err := Filter(servers, tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Filter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, server := range filtered {
+				got = append(got, server.IP)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	var locks KeyedMutex
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	holders := make(map[string]int)
+	maxHolders := make(map[string]int)
+
+	for i := 0; i < 20; i++ {
+		key := []string{"https://10.0.0.1", "https://10.0.0.2"}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.Lock(key)
+			mu.Lock()
+			holders[key]++
+			maxHolders[key] = max(maxHolders[key], holders[key])
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			holders[key]--
+			mu.Unlock()
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	for key, n := range maxHolders {
+		if n != 1 {
+			t.Errorf("%d goroutines held the lock of %s at once, want 1", n, key)
+		}
+	}
+	if len(locks.locks) != 0 {
+		t.Errorf("%d locks left after unlocking, want them dropped", len(locks.locks))
+	}
+}
+
+func TestKeyedMutexKeysIndependent(t *testing.T) {
+	var locks KeyedMutex
+	unlock := locks.Lock("https://10.0.0.1")
+	defer unlock()
+
+	locked := make(chan struct{})
+	go func() {
+		locks.Lock("https://10.0.0.2")()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("locking another key waited for the held one")
+	}
+}
@@ -0,0 +1,125 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+package main
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// The server's IP as a set key: lower case, without a default port or a
+// trailing slash, so "https://BMC1:443/" and "https://bmc1" are the same
+func normalizeServerIP(ip string) string {
+	ip = strings.TrimRight(strings.TrimSpace(ip), "/")
+	u, err := url.Parse(ip)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(ip)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 address without a port
+		host = "[" + host + "]"
+	}
+	return scheme + "://" + host + u.EscapedPath()
+}
+
+// Whether two servers are the same BMC accessed the same way. The password
+// is left out, a changed password updates a server rather than replacing it.
+func (s RedfishServer) Equal(other RedfishServer) bool {
+	return normalizeServerIP(s.IP) == normalizeServerIP(other.IP) &&
+		s.Username == other.Username &&
+		s.LoginType == other.LoginType &&
+		s.AuthMode == other.AuthMode
+}
+
+// ServerSet is a set of servers keyed by normalized IP, e.g. to compare the
+// desired servers with the current ones
+type ServerSet map[string]RedfishServer
+
+func FromSlice(servers []RedfishServer) ServerSet {
+	set := make(ServerSet, len(servers))
+	for _, server := range servers {
+		set.Add(server)
+	}
+	return set
+}
+
+// Add a server, replacing the one with the same IP
+func (s ServerSet) Add(server RedfishServer) {
+	s[normalizeServerIP(server.IP)] = server
+}
+
+func (s ServerSet) Remove(server RedfishServer) {
+	delete(s, normalizeServerIP(server.IP))
+}
+
+// Whether the set has a server equal to this one, see RedfishServer.Equal
+func (s ServerSet) Contains(server RedfishServer) bool {
+	member, ok := s[normalizeServerIP(server.IP)]
+	return ok && member.Equal(server)
+}
+
+// The servers of other that s lacks, and the servers of s that other lacks.
+// A server whose username or login type changed is in both.
+func (s ServerSet) Diff(other ServerSet) (added, removed ServerSet) {
+	added = make(ServerSet)
+	removed = make(ServerSet)
+	for key, server := range other {
+		if !s.Contains(server) {
+			added[key] = server
+		}
+	}
+	for key, server := range s {
+		if !other.Contains(server) {
+			removed[key] = server
+		}
+	}
+	return added, removed
+}
+
+// The servers of s that other has too
+func (s ServerSet) Intersect(other ServerSet) ServerSet {
+	common := make(ServerSet)
+	for key, server := range s {
+		if other.Contains(server) {
+			common[key] = server
+		}
+	}
+	return common
+}
+
+// The servers sorted by normalized IP
+func (s ServerSet) ToSlice() []RedfishServer {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	servers := make([]RedfishServer, 0, len(s))
+	for _, key := range keys {
+		servers = append(servers, s[key])
+	}
+	return servers
+}
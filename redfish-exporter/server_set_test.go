@@ -0,0 +1,86 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeServerIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"https://10.0.0.1", "https://10.0.0.1"},
+		{" https://BMC1:443/ ", "https://bmc1"},
+		{"http://bmc1:80", "http://bmc1"},
+		{"https://bmc1:8443", "https://bmc1:8443"},
+		{"https://[fd00::1]:443", "https://[fd00::1]"},
+		{"https://[FD00::1]:8443", "https://[fd00::1]:8443"},
+		{"https://bmc1/redfish/", "https://bmc1/redfish"},
+		{"BMC1", "bmc1"},
+	}
+	for _, tt := range tests {
+		if got := normalizeServerIP(tt.ip); got != tt.want {
+			t.Errorf("normalizeServerIP(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func serverIPs(set ServerSet) []string {
+	var ips []string
+	for _, server := range set.ToSlice() {
+		ips = append(ips, server.IP)
+	}
+	return ips
+}
+
+func TestServerSet(t *testing.T) {
+	current := FromSlice([]RedfishServer{
+		{IP: "https://10.0.0.1", Username: "admin", Password: "old"},
+		{IP: "https://10.0.0.2", Username: "admin"},
+		{IP: "https://10.0.0.3", Username: "admin"},
+	})
+	desired := FromSlice([]RedfishServer{
+		// Same server, only the password changed
+		{IP: "https://10.0.0.1:443/", Username: "admin", Password: "new"},
+		{IP: "https://10.0.0.2", Username: "operator"},
+		{IP: "https://10.0.0.4", Username: "admin"},
+	})
+
+	added, removed := current.Diff(desired)
+	tests := []struct {
+		name string
+		got  ServerSet
+		want []string
+	}{
+		{"added", added, []string{"https://10.0.0.2", "https://10.0.0.4"}},
+		{"removed", removed, []string{"https://10.0.0.2", "https://10.0.0.3"}},
+		{"intersection", current.Intersect(desired), []string{"https://10.0.0.1"}},
+	}
+	for _, tt := range tests {
+		if got := serverIPs(tt.got); !slices.Equal(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	current.Remove(RedfishServer{IP: "https://10.0.0.3/"})
+	if current.Contains(RedfishServer{IP: "https://10.0.0.3", Username: "admin"}) || len(current) != 2 {
+		t.Errorf("servers after Remove() = %v", serverIPs(current))
+	}
+}
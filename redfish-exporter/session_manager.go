@@ -0,0 +1,154 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/stmcginnis/gofish"
+)
+
+// Time allowed for PingServer to read the service root
+const pingTimeout = 5 * time.Second
+
+// Check that a connection is still usable by reading the service root.
+// A rejected session returns ErrSessionExpired, any other failure or
+// status ErrUnhealthyServer.
+func PingServer(ctx context.Context, c *gofish.APIClient) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	// The client's requests are bound to the context it was connected
+	// with, so the timeout is enforced here
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := c.Get("/redfish/v1")
+		done <- result{resp, err}
+	}()
+
+	var res result
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrUnhealthyServer, ctx.Err())
+	case res = <-done:
+	}
+
+	if res.err != nil {
+		if redfishStatusCode(res.err) == http.StatusUnauthorized {
+			return ErrSessionExpired
+		}
+		return fmt.Errorf("%w: %v", ErrUnhealthyServer, normalizeRedfishError(res.err))
+	}
+	res.resp.Body.Close()
+
+	switch res.resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrSessionExpired
+	}
+	return fmt.Errorf("%w: status %d", ErrUnhealthyServer, res.resp.StatusCode)
+}
+
+// SessionManager keeps one connection per server open between uses, so
+// periodic tasks don't log in and out on every run
+type SessionManager struct {
+	mu      sync.Mutex
+	clients map[string]*gofish.APIClient
+}
+
+func NewSessionManager() *SessionManager {
+	return &SessionManager{clients: make(map[string]*gofish.APIClient)}
+}
+
+// Return the connection to the server, connecting if there is none. A
+// cached connection is pinged first and replaced if its session expired.
+func (m *SessionManager) Get(ctx context.Context, server RedfishServer) (*gofish.APIClient, error) {
+	m.mu.Lock()
+	c, ok := m.clients[server.ID()]
+	m.mu.Unlock()
+
+	if ok {
+		err := PingServer(ctx, c)
+		if err == nil {
+			return c, nil
+		}
+		if !errors.Is(err, ErrSessionExpired) {
+			return nil, fmt.Errorf("failed to ping server %s: %w", server.IP, err)
+		}
+		log.Printf("Session on server %s expired, reconnecting", server.IP)
+		m.evict(server.ID(), c)
+	}
+
+	// The connection outlives ctx, it is logged out of by Close
+	c, err := getRedfishClientContext(context.WithoutCancel(ctx), server)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cached, ok := m.clients[server.ID()]; ok {
+		// Another caller connected in the meantime
+		c.Logout()
+		return cached, nil
+	}
+	m.clients[server.ID()] = c
+	return c, nil
+}
+
+// Log out of the server and drop its connection
+func (m *SessionManager) Evict(server RedfishServer) {
+	m.mu.Lock()
+	c, ok := m.clients[server.ID()]
+	m.mu.Unlock()
+	if ok {
+		m.evict(server.ID(), c)
+	}
+}
+
+// Drop the connection if it is still the cached one and log out of it
+func (m *SessionManager) evict(id string, c *gofish.APIClient) {
+	m.mu.Lock()
+	if m.clients[id] == c {
+		delete(m.clients, id)
+	}
+	m.mu.Unlock()
+	c.Logout()
+}
+
+// Log out of all servers
+func (m *SessionManager) Close() {
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = make(map[string]*gofish.APIClient)
+	m.mu.Unlock()
+
+	for _, c := range clients {
+		c.Logout()
+	}
+}
@@ -0,0 +1,107 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestPingServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(bmc *fakeBMC)
+		wantErr error
+	}{
+		{"healthy", func(bmc *fakeBMC) {}, nil},
+		{"session expired", (*fakeBMC).expireSessions, ErrSessionExpired},
+		{"service unavailable", func(bmc *fakeBMC) {
+			bmc.mu.Lock()
+			bmc.failures["GET /redfish/v1"] = http.StatusServiceUnavailable
+			bmc.mu.Unlock()
+		}, ErrUnhealthyServer},
+		{"unreachable", func(bmc *fakeBMC) { bmc.CloseClientConnections(); bmc.Close() }, ErrUnhealthyServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			c, err := getRedfishClient(bmc.server())
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.prepare(bmc)
+			if err := PingServer(context.Background(), c); !errors.Is(err, tt.wantErr) {
+				t.Errorf("PingServer() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSessionManager(t *testing.T) {
+	const login = "POST /redfish/v1/SessionService/Sessions"
+	bmc := newFakeBMC(t)
+	server := bmc.server()
+	manager := NewSessionManager()
+
+	first, err := manager.Get(context.Background(), server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := manager.Get(context.Background(), server)
+	if err != nil || second != first || bmc.count(login) != 1 {
+		t.Fatalf("Get() again = %p, %v after %d logins, want the cached connection", second, err, bmc.count(login))
+	}
+
+	bmc.expireSessions()
+	renewed, err := manager.Get(context.Background(), server)
+	if err != nil || renewed == first || bmc.count(login) != 2 {
+		t.Fatalf("Get() after the session expired = %p, %v after %d logins, want a new connection", renewed, err, bmc.count(login))
+	}
+
+	manager.Evict(server)
+	if bmc.openSessions() != 0 {
+		t.Errorf("%d sessions open after Evict(), want 0", bmc.openSessions())
+	}
+	// The connection outlives the context it was made with
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := manager.Get(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	manager.Close()
+	if bmc.openSessions() != 0 {
+		t.Errorf("%d sessions open after Close(), want 0", bmc.openSessions())
+	}
+}
+
+func TestSessionManagerUnhealthyServer(t *testing.T) {
+	bmc := newFakeBMC(t)
+	manager := NewSessionManager()
+	defer manager.Close()
+	if _, err := manager.Get(context.Background(), bmc.server()); err != nil {
+		t.Fatal(err)
+	}
+
+	bmc.mu.Lock()
+	bmc.failures["GET /redfish/v1"] = http.StatusInternalServerError
+	bmc.mu.Unlock()
+	if _, err := manager.Get(context.Background(), bmc.server()); !errors.Is(err, ErrUnhealthyServer) {
+		t.Errorf("Get() error = %v, want %v", err, ErrUnhealthyServer)
+	}
+}
@@ -0,0 +1,230 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// A BMC session opened by the exporter
+type trackedSession struct {
+	ServerIP string
+	// Path of the session resource, e.g. /redfish/v1/SessionService/Sessions/12
+	URI   string
+	Token string
+}
+
+// SessionTracker records the BMC sessions the exporter has open in a lock
+// file, one "serverIP sessionURI token" per line, so the sessions left by
+// a crash can be deleted on the next start instead of using up the BMCs'
+// session slots until they expire. The file holds session tokens and is
+// only readable by the owner.
+type SessionTracker struct {
+	path string
+
+	mu       sync.Mutex
+	sessions map[trackedSession]struct{}
+	// Sessions found in the file on start, until Recover deletes them
+	leftover []trackedSession
+}
+
+// Open the lock file, which doesn't need to exist yet. Its sessions are
+// kept as leftovers for Recover.
+func NewSessionTracker(path string) (*SessionTracker, error) {
+	t := &SessionTracker{path: path, sessions: make(map[trackedSession]struct{})}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session lock file: %v", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		t.leftover = append(t.leftover, trackedSession{ServerIP: fields[0], URI: fields[1], Token: fields[2]})
+	}
+	return t, nil
+}
+
+// Record a session opened on a server
+func (t *SessionTracker) Add(serverIP, uri, token string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessions[trackedSession{ServerIP: serverIP, URI: uri, Token: token}] = struct{}{}
+	return t.write()
+}
+
+// Forget a session deleted on a server
+func (t *SessionTracker) Remove(serverIP, uri string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	removed := false
+	for session := range t.sessions {
+		if session.ServerIP == serverIP && session.URI == uri {
+			delete(t.sessions, session)
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
+	}
+	return t.write()
+}
+
+// Write the open sessions and the leftovers not recovered yet
+func (t *SessionTracker) write() error {
+	var b strings.Builder
+	for _, session := range t.leftover {
+		fmt.Fprintf(&b, "%s %s %s\n", session.ServerIP, session.URI, session.Token)
+	}
+	for session := range t.sessions {
+		fmt.Fprintf(&b, "%s %s %s\n", session.ServerIP, session.URI, session.Token)
+	}
+
+	// Write to a temporary file first, so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), filepath.Base(t.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write session lock file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write session lock file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write session lock file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), t.path); err != nil {
+		return fmt.Errorf("failed to write session lock file: %v", err)
+	}
+	return nil
+}
+
+// Delete the sessions a previous run of the exporter left in the lock file
+// and clear them from it. A session the BMC no longer knows or accepts
+// is gone already. Sessions that couldn't be deleted are dropped too,
+// they expire on the BMC eventually.
+func (t *SessionTracker) Recover(ctx context.Context, servers []RedfishServer) error {
+	t.mu.Lock()
+	leftover := t.leftover
+	t.mu.Unlock()
+	if len(leftover) == 0 {
+		return nil
+	}
+
+	log.Printf("Deleting %d BMC sessions left by a previous run", len(leftover))
+	var errs []error
+	for _, session := range leftover {
+		server := getServerInfo(servers, session.ServerIP)
+		if server.IP == "" {
+			server = RedfishServer{IP: session.ServerIP}
+		}
+		if err := deleteSession(ctx, server, session.URI, session.Token); err != nil {
+			log.Printf("Failed to delete leftover session %s on server %s: %v", session.URI, session.ServerIP, err)
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("Deleted leftover session %s on server %s", session.URI, session.ServerIP)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.leftover = nil
+	if err := t.write(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Delete a session with its own token
+func deleteSession(ctx context.Context, server RedfishServer, uri, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, strings.TrimSuffix(server.IP, "/")+uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", token)
+	// Not through the tracking transport, the session isn't in its map
+	resp, err := newRedfishHTTPClientWithPolicy(server.settings().client, server.TLSConfig).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach server %s: %w", server.IP, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode < 300, resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusNotFound:
+		return nil
+	}
+	return fmt.Errorf("deleting session %s on server %s returned status %d", uri, server.IP, resp.StatusCode)
+}
+
+// sessionTrackingTransport records the sessions gofish opens and deletes
+// on a server in the session tracker
+type sessionTrackingTransport struct {
+	base     http.RoundTripper
+	serverIP string
+	tracker  *SessionTracker
+}
+
+func (t *sessionTrackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	switch {
+	case req.Method == http.MethodPost && strings.HasSuffix(path, "/SessionService/Sessions") && resp.StatusCode < 300:
+		token := resp.Header.Get("X-Auth-Token")
+		uri := sessionPath(resp.Header.Get("Location"))
+		if token != "" && uri != "" {
+			if err := t.tracker.Add(t.serverIP, uri, token); err != nil {
+				log.Printf("Failed to track session on server %s: %v", t.serverIP, err)
+			}
+		}
+	case req.Method == http.MethodDelete && strings.Contains(path, "/SessionService/Sessions/"):
+		if err := t.tracker.Remove(t.serverIP, path); err != nil {
+			log.Printf("Failed to untrack session on server %s: %v", t.serverIP, err)
+		}
+	}
+	return resp, nil
+}
+
+// The path of a session's Location, which BMCs send as a path or a full URL
+func sessionPath(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
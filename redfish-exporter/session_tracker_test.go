@@ -0,0 +1,118 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Connect to the BMC through a session tracker writing to path
+func trackedServer(t *testing.T, bmc *fakeBMC, path string) (RedfishServer, *SessionTracker) {
+	t.Helper()
+	tracker, err := NewSessionTracker(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := newConnectionSettings(RedfishClientConfig{})
+	settings.sessions = tracker
+	return withConnectionSettings([]RedfishServer{bmc.server()}, settings)[0], tracker
+}
+
+func TestSessionTrackerRecoversCrashedSessions(t *testing.T) {
+	bmc := newFakeBMC(t)
+	path := filepath.Join(t.TempDir(), "sessions.lock")
+
+	// The first run opens two sessions and crashes without logging out
+	server, _ := trackedServer(t, bmc, path)
+	for i := 0; i < 2; i++ {
+		if _, err := getRedfishClient(server); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if bmc.openSessions() != 2 {
+		t.Fatalf("%d sessions open, want 2", bmc.openSessions())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || strings.Count(string(data), "\n") != 2 {
+		t.Fatalf("lock file %q, %v, want both sessions", data, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("lock file mode %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	// The next run deletes them
+	server, tracker := trackedServer(t, bmc, path)
+	if err := tracker.Recover(context.Background(), []RedfishServer{server}); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	if bmc.openSessions() != 0 {
+		t.Errorf("%d sessions open after Recover(), want 0", bmc.openSessions())
+	}
+	if data, err := os.ReadFile(path); err != nil || len(data) != 0 {
+		t.Errorf("lock file %q, %v after Recover(), want it empty", data, err)
+	}
+}
+
+func TestSessionTrackerForgetsLoggedOutSessions(t *testing.T) {
+	bmc := newFakeBMC(t)
+	path := filepath.Join(t.TempDir(), "sessions.lock")
+	server, _ := trackedServer(t, bmc, path)
+
+	c, err := getRedfishClient(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Logout()
+
+	if data, err := os.ReadFile(path); err != nil || len(data) != 0 {
+		t.Errorf("lock file %q, %v after logging out, want it empty", data, err)
+	}
+}
+
+func TestDeleteSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"deleted", 0, false},
+		{"already gone", http.StatusNotFound, false},
+		{"token no longer accepted", http.StatusUnauthorized, false},
+		{"server error", http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.mu.Lock()
+			bmc.sessions["7"] = true
+			if tt.status != 0 {
+				bmc.failures["DELETE /redfish/v1/SessionService/Sessions/7"] = tt.status
+			}
+			bmc.mu.Unlock()
+
+			err := deleteSession(context.Background(), bmc.server(), "/redfish/v1/SessionService/Sessions/7", "token-7")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("deleteSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
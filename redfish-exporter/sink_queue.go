@@ -0,0 +1,118 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Events buffered for each sink before new ones are dropped
+const DefaultSinkQueueSize = 1024
+
+var errSinkQueueClosed = errors.New("sink queue closed")
+
+// queuedSink hands the events to a sink from its own goroutine, so a slow
+// sink doesn't hold up the listener or the other sinks. When the queue is
+// full the event is dropped and counted. The wrapped sink isn't closed, it
+// belongs to whoever created it.
+type queuedSink struct {
+	sink    EventSink
+	name    string
+	events  chan *EnrichedEvent
+	done    chan struct{}
+	metrics *Metrics
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newQueuedSink(sink EventSink, name string, size int, metrics *Metrics) *queuedSink {
+	if size <= 0 {
+		size = DefaultSinkQueueSize
+	}
+	q := &queuedSink{
+		sink:    sink,
+		name:    name,
+		events:  make(chan *EnrichedEvent, size),
+		done:    make(chan struct{}),
+		metrics: metrics,
+	}
+	go q.run()
+	return q
+}
+
+func (q *queuedSink) run() {
+	defer close(q.done)
+	for event := range q.events {
+		if err := q.sink.Send(event); err != nil {
+			log.Printf("Error sending event %s to %s sink: %v", event.EventId, q.name, err)
+		}
+	}
+}
+
+// Queue the event without waiting for the sink
+func (q *queuedSink) Send(event *EnrichedEvent) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return errSinkQueueClosed
+	}
+	select {
+	case q.events <- event:
+		return nil
+	default:
+		q.metrics.sinkEventsDropped.WithLabelValues(q.name).Inc()
+		return fmt.Errorf("%s sink queue full, dropping event %s", q.name, event.EventId)
+	}
+}
+
+// Stop accepting events and wait until the queued ones were sent
+func (q *queuedSink) Close() error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+	q.mu.Unlock()
+	<-q.done
+	return nil
+}
+
+// Name of a sink in logs and metrics
+func sinkName(sink EventSink) string {
+	switch sink.(type) {
+	case *SyslogEventSink:
+		return "syslog"
+	case *EmailAlertSink:
+		return "email"
+	case *SlackAlertSink:
+		return "slack"
+	case *PagerDutyAlertSink:
+		return "pagerduty"
+	case *HTTPEventSink:
+		return "http"
+	case *AMQPEventSink:
+		return "amqp"
+	case *SNMPTrapSink:
+		return "snmp"
+	default:
+		return fmt.Sprintf("%T", sink)
+	}
+}
@@ -0,0 +1,120 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Records the events it receives, blocking until released
+type blockingSink struct {
+	// Receives each event once the sink is blocked on it
+	started chan string
+	release chan struct{}
+	mu      sync.Mutex
+	sent    []string
+	closed  bool
+}
+
+func (s *blockingSink) Send(event *EnrichedEvent) error {
+	s.started <- event.EventId
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, event.EventId)
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestQueuedSink(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		// Events sent while the sink is blocked on the first one
+		events      int
+		wantDropped int
+	}{
+		{name: "fits in the queue", size: 4, events: 3},
+		{name: "fills the queue", size: 4, events: 4},
+		{name: "overflows the queue", size: 2, events: 6, wantDropped: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &blockingSink{started: make(chan string, 1), release: make(chan struct{})}
+			name := "test-" + tt.name
+			metrics := NewMetrics()
+			queue := newQueuedSink(sink, name, tt.size, metrics)
+			if err := queue.Send(&EnrichedEvent{Event: Event{EventId: "first"}}); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			<-sink.started
+			go func() {
+				for range sink.started {
+				}
+			}()
+
+			var dropped int
+			for i := 0; i < tt.events; i++ {
+				// Returns right away although the sink is blocked
+				if err := queue.Send(&EnrichedEvent{Event: Event{EventId: strconv.Itoa(i)}}); err != nil {
+					dropped++
+				}
+			}
+			close(sink.release)
+			if err := queue.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			close(sink.started)
+
+			if dropped != tt.wantDropped {
+				t.Errorf("dropped %d events, want %d", dropped, tt.wantDropped)
+			}
+			if got := counterValue(t, metrics.sinkEventsDropped.WithLabelValues(name)); int(got) != tt.wantDropped {
+				t.Errorf("dropped events metric = %v, want %d", got, tt.wantDropped)
+			}
+			if want := 1 + tt.events - tt.wantDropped; len(sink.sent) != want {
+				t.Errorf("sink received %d events, want %d", len(sink.sent), want)
+			}
+			if sink.closed {
+				t.Error("queue closed the wrapped sink")
+			}
+			if err := queue.Send(&EnrichedEvent{}); !errors.Is(err, errSinkQueueClosed) {
+				t.Errorf("Send() after Close() error = %v, want %v", err, errSinkQueueClosed)
+			}
+		})
+	}
+}
+
+// Current value of a counter
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := counter.Write(&metric); err != nil {
+		t.Fatalf("failed to read counter: %v", err)
+	}
+	return metric.GetCounter().GetValue()
+}
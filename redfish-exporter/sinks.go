@@ -0,0 +1,137 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// EnrichedEvent is a redfish event together with details about the server it
+// was received from
+type EnrichedEvent struct {
+	Event
+	ServerIP   string
+	SlurmNode  string
+	Context    string
+	ReceivedAt time.Time
+	// Full URL of the subscription the event was delivered for, if known
+	SubscriptionURI string
+	// Message from the message registry with the MessageArgs filled in, if resolved
+	ResolvedMessage string
+	// Where the server is installed, if configured
+	Location ServerLocation
+	// The payload didn't match the Redfish Event schema
+	SchemaViolation bool
+}
+
+// EventSink is implemented by every destination events are forwarded to
+type EventSink interface {
+	Send(event *EnrichedEvent) error
+	Close() error
+}
+
+// Create the event sinks enabled in the config
+func setupEventSinks(AppConfig Config) []EventSink {
+	var sinks []EventSink
+
+	if AppConfig.Syslog.Enabled {
+		sink, err := NewSyslogEventSink(AppConfig.Syslog.Network, AppConfig.Syslog.Address, AppConfig.Syslog.Tag)
+		if err != nil {
+			log.Fatalf("Failed to create syslog event sink: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if AppConfig.Email.SMTPHost != "" {
+		sink, err := NewEmailAlertSink(AppConfig.Email)
+		if err != nil {
+			log.Fatalf("Failed to create email alert sink: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if AppConfig.Slack.WebhookURL != "" {
+		sink, err := NewSlackAlertSink(AppConfig.Slack.WebhookURL, AppConfig.Slack.MinSeverity)
+		if err != nil {
+			log.Fatalf("Failed to create slack alert sink: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if AppConfig.PagerDuty.RoutingKey != "" {
+		sink, err := NewPagerDutyAlertSink(AppConfig.PagerDuty.RoutingKey, AppConfig.PagerDuty.EventsURL)
+		if err != nil {
+			log.Fatalf("Failed to create pagerduty alert sink: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if AppConfig.HTTPSink.URL != "" {
+		opts := []HTTPSinkOption{}
+		if AppConfig.HTTPSink.ContentType != "" {
+			opts = append(opts, WithContentType(AppConfig.HTTPSink.ContentType))
+		}
+		if AppConfig.HTTPSink.SigningSecret != "" {
+			opts = append(opts, WithRequestSigner([]byte(AppConfig.HTTPSink.SigningSecret)))
+		}
+		sink, err := NewHTTPEventSink(AppConfig.HTTPSink.URL, opts...)
+		if err != nil {
+			log.Fatalf("Failed to create HTTP event sink: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if AppConfig.AMQP.URL != "" {
+		sink, err := NewAMQPEventSink(AppConfig.AMQP)
+		if err != nil {
+			log.Fatalf("Failed to create AMQP event sink: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if AppConfig.SNMPTrap.Target != "" {
+		sink, err := NewSNMPTrapSink(AppConfig.SNMPTrap)
+		if err != nil {
+			log.Fatalf("Failed to create SNMP trap sink: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return sinks
+}
+
+// Rank redfish severities so they can be compared against a threshold
+func severityRank(severity string) int {
+	switch severity {
+	case "Critical":
+		return 2
+	case "Warning":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Close all event sinks, logging any errors
+func closeEventSinks(sinks []EventSink) {
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil {
+			log.Printf("Failed to close event sink: %v", err)
+		}
+	}
+}
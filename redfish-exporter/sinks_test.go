@@ -0,0 +1,126 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"testing"
+)
+
+// closingSink records that it was closed and fails to close with err
+type closingSink struct {
+	countingSink
+	closed bool
+	err    error
+}
+
+func (s *closingSink) Close() error {
+	s.closed = true
+	return s.err
+}
+
+func TestSetupEventSinks(t *testing.T) {
+	receiver, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+	address := receiver.LocalAddr().String()
+
+	tests := []struct {
+		name      string
+		configure func(config *Config)
+		want      []string
+	}{
+		{"none enabled", func(config *Config) {}, nil},
+		{
+			"syslog",
+			func(config *Config) {
+				config.Syslog.Enabled = true
+				config.Syslog.Network = "udp"
+				config.Syslog.Address = address
+			},
+			[]string{"*main.SyslogEventSink"},
+		},
+		{
+			"alerting sinks",
+			func(config *Config) {
+				config.Email = EmailConfig{SMTPHost: "smtp.example.com", FromAddress: "exporter@example.com", ToAddresses: []string{"ops@example.com"}}
+				config.Slack.WebhookURL = "https://hooks.slack.com/services/T0/B0/X"
+				config.PagerDuty.RoutingKey = "routing-key"
+			},
+			[]string{"*main.EmailAlertSink", "*main.SlackAlertSink", "*main.PagerDutyAlertSink"},
+		},
+		{
+			"HTTP and SNMP",
+			func(config *Config) {
+				config.HTTPSink.URL = "https://collector.example.com/events"
+				config.HTTPSink.SigningSecret = "secret"
+				config.SNMPTrap.Target = address
+			},
+			[]string{"*main.HTTPEventSink", "*main.SNMPTrapSink"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config Config
+			tt.configure(&config)
+
+			sinks := setupEventSinks(config)
+			defer closeEventSinks(sinks)
+			var got []string
+			for _, sink := range sinks {
+				got = append(got, fmt.Sprintf("%T", sink))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("sinks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeverityRank(t *testing.T) {
+	tests := []struct {
+		severity string
+		want     int
+	}{
+		{"Critical", 2},
+		{"Warning", 1},
+		{"OK", 0},
+		{"", 0},
+		{"critical", 0},
+	}
+	for _, tt := range tests {
+		if got := severityRank(tt.severity); got != tt.want {
+			t.Errorf("severityRank(%q) = %d, want %d", tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestCloseEventSinks(t *testing.T) {
+	sinks := []*closingSink{{}, {err: errors.New("connection reset")}, {}}
+
+	closeEventSinks([]EventSink{sinks[0], sinks[1], sinks[2]})
+	for i, sink := range sinks {
+		if !sink.closed {
+			t.Errorf("sink %d not closed after an earlier sink failed to close", i)
+		}
+	}
+}
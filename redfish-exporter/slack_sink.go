@@ -0,0 +1,163 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	slackRateLimitInterval = time.Minute
+	slackRequestTimeout    = 10 * time.Second
+)
+
+var slackSeverityColors = map[string]string{
+	"Critical": "#d00000",
+	"Warning":  "#ffc000",
+	"OK":       "#2eb886",
+}
+
+// SlackAlertSink posts events at or above a severity threshold to a Slack
+// incoming webhook. The same message ID from the same server is posted at
+// most once per minute to avoid flooding the channel during error storms.
+type SlackAlertSink struct {
+	webhookURL  string
+	minSeverity string
+	client      *http.Client
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+func NewSlackAlertSink(webhookURL, minSeverity string) (*SlackAlertSink, error) {
+	if webhookURL == "" {
+		return nil, fmt.Errorf("slack webhook URL is required")
+	}
+	if minSeverity == "" {
+		minSeverity = "Warning"
+	}
+	return &SlackAlertSink{
+		webhookURL:  webhookURL,
+		minSeverity: minSeverity,
+		client:      &http.Client{Timeout: slackRequestTimeout},
+		lastSent:    make(map[string]time.Time),
+	}, nil
+}
+
+func (s *SlackAlertSink) Send(event *EnrichedEvent) error {
+	if severityRank(event.Severity) < severityRank(s.minSeverity) {
+		return nil
+	}
+	if !s.allow(event.ServerIP+"|"+event.MessageId, time.Now()) {
+		return nil
+	}
+
+	body, err := json.Marshal(buildSlackMessage(event))
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack message: %w", err)
+	}
+
+	resp, err := s.client.Post(s.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post slack message: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *SlackAlertSink) Close() error {
+	return nil
+}
+
+// Check and record the rate limit for the key
+func (s *SlackAlertSink) allow(key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.lastSent[key]; ok && now.Sub(last) < slackRateLimitInterval {
+		return false
+	}
+	s.lastSent[key] = now
+
+	// Forget keys outside the rate limit window so the map doesn't grow unbounded
+	for k, last := range s.lastSent {
+		if now.Sub(last) >= slackRateLimitInterval {
+			delete(s.lastSent, k)
+		}
+	}
+	return true
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type   string      `json:"type"`
+	Text   *slackText  `json:"text,omitempty"`
+	Fields []slackText `json:"fields,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func buildSlackMessage(event *EnrichedEvent) slackMessage {
+	title := fmt.Sprintf("%s redfish event on %s", event.Severity, event.ServerIP)
+	fields := []slackText{
+		{Type: "mrkdwn", Text: fmt.Sprintf("*Server:*\n%s", event.ServerIP)},
+		{Type: "mrkdwn", Text: fmt.Sprintf("*Slurm Node:*\n%s", event.SlurmNode)},
+		{Type: "mrkdwn", Text: fmt.Sprintf("*Message ID:*\n%s", event.MessageId)},
+		{Type: "mrkdwn", Text: fmt.Sprintf("*Timestamp:*\n%s", event.EventTimestamp)},
+	}
+	if location := event.Location.String(); location != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Location:*\n%s", location)})
+	}
+	if event.Location.AssetTag != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Asset Tag:*\n%s", event.Location.AssetTag)})
+	}
+	if event.SubscriptionURI != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Subscription:*\n<%s>", event.SubscriptionURI)})
+	}
+
+	return slackMessage{
+		Text: title,
+		Attachments: []slackAttachment{{
+			Color: slackSeverityColors[event.Severity],
+			Blocks: []slackBlock{
+				{Type: "header", Text: &slackText{Type: "plain_text", Text: title}},
+				{Type: "section", Text: &slackText{Type: "mrkdwn", Text: event.Message}},
+				{Type: "section", Fields: fields},
+			},
+		}},
+	}
+}
@@ -0,0 +1,191 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Records the titles of the messages posted to the webhook, answering
+// status to every post
+func newFakeSlack(t *testing.T, status int) (*httptest.Server, func() []slackMessage) {
+	t.Helper()
+	var mu sync.Mutex
+	var messages []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message slackMessage
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&message) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		messages = append(messages, message)
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []slackMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]slackMessage(nil), messages...)
+	}
+}
+
+func slackTestEvent(serverIP, severity, messageID string) *EnrichedEvent {
+	return &EnrichedEvent{
+		Event:     Event{Severity: severity, MessageId: messageID, Message: "Fan 1 failed"},
+		ServerIP:  serverIP,
+		SlurmNode: "node-1",
+	}
+}
+
+func TestSlackAlertSink(t *testing.T) {
+	tests := []struct {
+		name        string
+		minSeverity string
+		events      []*EnrichedEvent
+		want        []string
+	}{
+		{
+			name: "warning threshold by default",
+			events: []*EnrichedEvent{
+				slackTestEvent("10.0.0.1", "OK", "Fan.1.0.FanRestored"),
+				slackTestEvent("10.0.0.1", "Warning", "Fan.1.0.FanDegraded"),
+				slackTestEvent("10.0.0.1", "Critical", "Fan.1.0.FanFailed"),
+			},
+			want: []string{"Warning redfish event on 10.0.0.1", "Critical redfish event on 10.0.0.1"},
+		},
+		{
+			name:        "critical threshold",
+			minSeverity: "Critical",
+			events: []*EnrichedEvent{
+				slackTestEvent("10.0.0.1", "Warning", "Fan.1.0.FanDegraded"),
+				slackTestEvent("10.0.0.1", "Critical", "Fan.1.0.FanFailed"),
+			},
+			want: []string{"Critical redfish event on 10.0.0.1"},
+		},
+		{
+			name: "repeated message from a server is posted once",
+			events: []*EnrichedEvent{
+				slackTestEvent("10.0.0.1", "Critical", "Fan.1.0.FanFailed"),
+				slackTestEvent("10.0.0.1", "Critical", "Fan.1.0.FanFailed"),
+				slackTestEvent("10.0.0.2", "Critical", "Fan.1.0.FanFailed"),
+				slackTestEvent("10.0.0.1", "Critical", "Fan.1.0.FanRemoved"),
+			},
+			want: []string{"Critical redfish event on 10.0.0.1", "Critical redfish event on 10.0.0.2", "Critical redfish event on 10.0.0.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, messages := newFakeSlack(t, http.StatusOK)
+			sink, err := NewSlackAlertSink(server.URL, tt.minSeverity)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, event := range tt.events {
+				if err := sink.Send(event); err != nil {
+					t.Fatalf("Send(%s) error = %v", event.MessageId, err)
+				}
+			}
+			var got []string
+			for _, message := range messages() {
+				got = append(got, message.Text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("posted %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlackAlertSinkMessage(t *testing.T) {
+	server, messages := newFakeSlack(t, http.StatusOK)
+	sink, err := NewSlackAlertSink(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := slackTestEvent("10.0.0.1", "Critical", "Fan.1.0.FanFailed")
+	event.Location = ServerLocation{Datacenter: "dc1", Rack: "r12", AssetTag: "A-1"}
+	if err := sink.Send(event); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	posted := messages()
+	if len(posted) != 1 || len(posted[0].Attachments) != 1 {
+		t.Fatalf("posted %+v, want one message with one attachment", posted)
+	}
+	attachment := posted[0].Attachments[0]
+	if attachment.Color != slackSeverityColors["Critical"] {
+		t.Errorf("attachment color = %s, want %s", attachment.Color, slackSeverityColors["Critical"])
+	}
+	if len(attachment.Blocks) != 3 || attachment.Blocks[1].Text.Text != "Fan 1 failed" {
+		t.Fatalf("attachment blocks = %+v, want the header, the message and the fields", attachment.Blocks)
+	}
+	fields := make(map[string]bool)
+	for _, field := range attachment.Blocks[2].Fields {
+		fields[field.Text] = true
+	}
+	for _, want := range []string{"*Slurm Node:*\nnode-1", "*Message ID:*\nFan.1.0.FanFailed", "*Asset Tag:*\nA-1"} {
+		if !fields[want] {
+			t.Errorf("fields %v lack %q", attachment.Blocks[2].Fields, want)
+		}
+	}
+}
+
+func TestSlackAlertSinkErrors(t *testing.T) {
+	if _, err := NewSlackAlertSink("", ""); err == nil {
+		t.Error("NewSlackAlertSink() without a webhook URL error = nil")
+	}
+
+	server, _ := newFakeSlack(t, http.StatusTooManyRequests)
+	sink, err := NewSlackAlertSink(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(slackTestEvent("10.0.0.1", "Critical", "Fan.1.0.FanFailed")); err == nil {
+		t.Error("Send() error = nil, want the webhook status reported")
+	}
+}
+
+func TestSlackRateLimitWindow(t *testing.T) {
+	sink, err := NewSlackAlertSink("https://hooks.slack.invalid/services/1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	tests := []struct {
+		at   time.Duration
+		want bool
+	}{
+		{0, true},
+		{30 * time.Second, false},
+		{slackRateLimitInterval - time.Millisecond, false},
+		{slackRateLimitInterval, true},
+		{slackRateLimitInterval + time.Second, false},
+	}
+	for _, tt := range tests {
+		if got := sink.allow("10.0.0.1|Fan.1.0.FanFailed", start.Add(tt.at)); got != tt.want {
+			t.Errorf("allow() after %v = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	DefaultSNMPCommunity = "public"
+	// DMTF's private enterprise number, used when the events' MessageIds
+	// match no mapping and no enterprise OID is configured
+	DefaultSNMPEnterpriseOID = "1.3.6.1.4.1.412"
+)
+
+// OID of the snmpTrapOID varbind of SNMPv2 traps, RFC 3416
+const oidSNMPTrapOID = ".1.3.6.1.6.3.1.1.4.1.0"
+
+// Specific trap numbers of the events by severity. Every event is an
+// enterpriseSpecific trap of SNMPv1, sent as the SNMPv2 trap
+// {enterprise}.0.{specific} following RFC 3584.
+var snmpSpecificTraps = map[string]int{
+	"OK":       1,
+	"Warning":  2,
+	"Critical": 3,
+}
+
+type SNMPTrapConfig struct {
+	// Address of the trap receiver, port 162 when missing
+	Target string
+	// v2c community, DefaultSNMPCommunity when empty
+	Community string
+	// Enterprise OID of the events no mapping matches,
+	// DefaultSNMPEnterpriseOID when empty
+	EnterpriseOID string
+	// YAML file with a list of SNMPOIDMapping
+	MappingFile string
+}
+
+// SNMPOIDMapping sends the events whose MessageId matches the pattern as
+// traps of the enterprise OID
+type SNMPOIDMapping struct {
+	MessageIDPattern string `json:"messageIdPattern"`
+	EnterpriseOID    string `json:"enterpriseOid"`
+
+	pattern *regexp.Regexp
+	oid     string
+}
+
+// SNMPTrapSink forwards every event as an SNMP v2c trap, for network
+// management systems that can't consume redfish events. The trap OID is
+// {enterprise}.0.{specific} with the enterprise OID of the first mapping
+// matching the MessageId and the specific trap by severity, see
+// snmpSpecificTraps. The event is in the varbinds {enterprise}.1 to .6:
+// message, severity, MessageId, server, origin of condition and timestamp.
+type SNMPTrapSink struct {
+	enterprise string
+	mappings   []SNMPOIDMapping
+	started    time.Time
+
+	mu     sync.Mutex
+	client *gosnmp.GoSNMP
+}
+
+func NewSNMPTrapSink(config SNMPTrapConfig) (*SNMPTrapSink, error) {
+	if config.Target == "" {
+		return nil, fmt.Errorf("SNMP trap target is required")
+	}
+	host, port := config.Target, "162"
+	if splitHost, splitPort, err := net.SplitHostPort(config.Target); err == nil {
+		host, port = splitHost, splitPort
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNMP trap port %q", port)
+	}
+	if config.Community == "" {
+		config.Community = DefaultSNMPCommunity
+	}
+	if config.EnterpriseOID == "" {
+		config.EnterpriseOID = DefaultSNMPEnterpriseOID
+	}
+	enterprise, err := normalizeOID(config.EnterpriseOID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNMP enterprise OID: %w", err)
+	}
+
+	var mappings []SNMPOIDMapping
+	if config.MappingFile != "" {
+		if mappings, err = loadSNMPOIDMappings(config.MappingFile); err != nil {
+			return nil, err
+		}
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:    host,
+		Port:      uint16(portNumber),
+		Transport: "udp",
+		Community: config.Community,
+		Version:   gosnmp.Version2c,
+		Timeout:   5 * time.Second,
+		MaxOids:   gosnmp.MaxOids,
+	}
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to open SNMP trap socket: %w", err)
+	}
+	return &SNMPTrapSink{
+		enterprise: enterprise,
+		mappings:   mappings,
+		started:    time.Now(),
+		client:     client,
+	}, nil
+}
+
+// Read the OID mappings from a YAML file holding a list of
+// messageIdPattern and enterpriseOid pairs
+func loadSNMPOIDMappings(path string) ([]SNMPOIDMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SNMP OID mappings: %v", err)
+	}
+	var mappings []SNMPOIDMapping
+	if err := yaml.UnmarshalStrict(data, &mappings); err != nil {
+		return nil, fmt.Errorf("failed to parse SNMP OID mappings %s: %v", path, err)
+	}
+	for i := range mappings {
+		mappings[i].pattern, err = regexp.Compile(mappings[i].MessageIDPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid messageIdPattern %q: %v", mappings[i].MessageIDPattern, err)
+		}
+		mappings[i].oid, err = normalizeOID(mappings[i].EnterpriseOID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid enterpriseOid of %q: %v", mappings[i].MessageIDPattern, err)
+		}
+	}
+	return mappings, nil
+}
+
+// Send the event as a trap
+func (s *SNMPTrapSink) Send(event *EnrichedEvent) error {
+	trap := s.trap(event)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.client.SendTrap(trap); err != nil {
+		return fmt.Errorf("failed to send SNMP trap: %w", err)
+	}
+	return nil
+}
+
+func (s *SNMPTrapSink) Close() error {
+	return s.client.Conn.Close()
+}
+
+// The enterprise OID of an event's MessageId
+func (s *SNMPTrapSink) enterpriseOID(messageID string) string {
+	for _, mapping := range s.mappings {
+		if mapping.pattern.MatchString(messageID) {
+			return mapping.oid
+		}
+	}
+	return s.enterprise
+}
+
+// The SNMPv2 trap of an event
+func (s *SNMPTrapSink) trap(event *EnrichedEvent) gosnmp.SnmpTrap {
+	enterprise := s.enterpriseOID(event.MessageId)
+	specific, ok := snmpSpecificTraps[event.Severity]
+	if !ok {
+		specific = snmpSpecificTraps["OK"]
+	}
+	child := func(id int) string {
+		return enterprise + "." + strconv.Itoa(id)
+	}
+	text := func(id int, value string) gosnmp.SnmpPDU {
+		return gosnmp.SnmpPDU{Name: child(id), Type: gosnmp.OctetString, Value: value}
+	}
+	message := event.Message
+	if event.ResolvedMessage != "" {
+		message = event.ResolvedMessage
+	}
+
+	return gosnmp.SnmpTrap{Variables: []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(time.Since(s.started) / (10 * time.Millisecond))},
+		{Name: oidSNMPTrapOID, Type: gosnmp.ObjectIdentifier, Value: child(0) + "." + strconv.Itoa(specific)},
+		text(1, message),
+		text(2, event.Severity),
+		text(3, event.MessageId),
+		text(4, event.ServerIP),
+		text(5, event.OriginOfCondition.OdataId),
+		text(6, event.EventTimestamp),
+	}}
+}
+
+// Check a dotted OID such as 1.3.6.1.4.1.412, returning it with the
+// leading dot gosnmp uses
+func normalizeOID(oid string) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return "", fmt.Errorf("OID %q has fewer than two arcs", oid)
+	}
+	arcs := make([]int, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.Atoi(part)
+		if err != nil || arc < 0 {
+			return "", fmt.Errorf("invalid OID %q", oid)
+		}
+		arcs[i] = arc
+	}
+	if arcs[0] > 2 || (arcs[0] < 2 && arcs[1] >= 40) {
+		return "", fmt.Errorf("invalid OID %q", oid)
+	}
+	return "." + strings.Join(parts, "."), nil
+}
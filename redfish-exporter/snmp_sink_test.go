@@ -0,0 +1,174 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Listen on a local UDP port, returning the address to send to and a
+// function reading the next datagram's raw bytes
+func newUDPReceiver(t *testing.T) (string, func() []byte) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen on UDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn.LocalAddr().String(), func() []byte {
+		t.Helper()
+		buf := make([]byte, 65535)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("nothing received on %s: %v", conn.LocalAddr(), err)
+		}
+		return buf[:n]
+	}
+}
+
+func writeSNMPMappings(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mappings.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSNMPTrapSinkSend(t *testing.T) {
+	mappings := writeSNMPMappings(t, `
+- messageIdPattern: ^StorageDevice\.
+  enterpriseOid: 1.3.6.1.4.1.412.1.2
+- messageIdPattern: ^Base\.
+  enterpriseOid: .1.3.6.1.4.1.412.1.1
+`)
+	tests := []struct {
+		name           string
+		event          EnrichedEvent
+		wantEnterprise string
+		wantSpecific   string
+		wantMessage    string
+	}{
+		{
+			name: "mapped critical",
+			event: EnrichedEvent{Event: Event{
+				MessageId: "StorageDevice.1.0.DriveFailure", Severity: "Critical", Message: "Drive 3 failed",
+				EventTimestamp: "2024-10-01T12:00:00Z", OriginOfCondition: OriginOfCondition{OdataId: "/redfish/v1/Chassis/1/Drives/3"},
+			}, ServerIP: "10.0.0.1"},
+			wantEnterprise: ".1.3.6.1.4.1.412.1.2",
+			wantSpecific:   ".1.3.6.1.4.1.412.1.2.0.3",
+			wantMessage:    "Drive 3 failed",
+		},
+		{
+			name: "mapped warning with resolved message",
+			event: EnrichedEvent{Event: Event{MessageId: "Base.1.0.GeneralError", Severity: "Warning", Message: "raw"},
+				ServerIP: "10.0.0.2", ResolvedMessage: "A general error has occurred."},
+			wantEnterprise: ".1.3.6.1.4.1.412.1.1",
+			wantSpecific:   ".1.3.6.1.4.1.412.1.1.0.2",
+			wantMessage:    "A general error has occurred.",
+		},
+		{
+			name:           "unmapped without severity",
+			event:          EnrichedEvent{Event: Event{MessageId: "Custom.1.0.Hello", Message: "Hello"}, ServerIP: "10.0.0.3"},
+			wantEnterprise: ".1.3.6.1.4.1.99",
+			wantSpecific:   ".1.3.6.1.4.1.99.0.1",
+			wantMessage:    "Hello",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, receive := newUDPReceiver(t)
+			sink, err := NewSNMPTrapSink(SNMPTrapConfig{Target: target, Community: "monitoring", EnterpriseOID: "1.3.6.1.4.1.99", MappingFile: mappings})
+			if err != nil {
+				t.Fatalf("NewSNMPTrapSink() error = %v", err)
+			}
+			defer sink.Close()
+
+			if err := sink.Send(&tt.event); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			packet, err := gosnmp.Default.SnmpDecodePacket(receive())
+			if err != nil {
+				t.Fatalf("failed to decode trap: %v", err)
+			}
+			if packet.Version != gosnmp.Version2c || packet.Community != "monitoring" || packet.PDUType != gosnmp.SNMPv2Trap {
+				t.Fatalf("trap is %v %s %v, want a v2c trap with community monitoring", packet.Version, packet.Community, packet.PDUType)
+			}
+
+			want := map[string]interface{}{
+				oidSNMPTrapOID:           tt.wantSpecific,
+				tt.wantEnterprise + ".1": tt.wantMessage,
+				tt.wantEnterprise + ".2": tt.event.Severity,
+				tt.wantEnterprise + ".3": tt.event.MessageId,
+				tt.wantEnterprise + ".4": tt.event.ServerIP,
+				tt.wantEnterprise + ".5": tt.event.OriginOfCondition.OdataId,
+				tt.wantEnterprise + ".6": tt.event.EventTimestamp,
+			}
+			if len(packet.Variables) != len(want)+1 || packet.Variables[0].Type != gosnmp.TimeTicks {
+				t.Fatalf("trap has %d varbinds, want sysUpTime and %d more", len(packet.Variables), len(want))
+			}
+			for _, variable := range packet.Variables[1:] {
+				wantValue, ok := want[variable.Name]
+				if !ok {
+					t.Errorf("unexpected varbind %s", variable.Name)
+					continue
+				}
+				got := variable.Value
+				if bytes, ok := got.([]byte); ok {
+					got = string(bytes)
+				}
+				if got != wantValue {
+					t.Errorf("varbind %s = %v, want %v", variable.Name, got, wantValue)
+				}
+			}
+		})
+	}
+}
+
+func TestNewSNMPTrapSinkValidates(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   SNMPTrapConfig
+		mappings string
+	}{
+		{name: "no target", config: SNMPTrapConfig{}},
+		{name: "invalid port", config: SNMPTrapConfig{Target: "127.0.0.1:trap"}},
+		{name: "invalid enterprise OID", config: SNMPTrapConfig{Target: "127.0.0.1", EnterpriseOID: "1.3.six"}},
+		{name: "missing mapping file", config: SNMPTrapConfig{Target: "127.0.0.1", MappingFile: "/nonexistent/mappings.yaml"}},
+		{name: "invalid pattern", config: SNMPTrapConfig{Target: "127.0.0.1"}, mappings: "- messageIdPattern: \"(\"\n  enterpriseOid: 1.3.6.1\n"},
+		{name: "invalid mapped OID", config: SNMPTrapConfig{Target: "127.0.0.1"}, mappings: "- messageIdPattern: ^Base\n  enterpriseOid: 1.99.1\n"},
+		{name: "unknown field", config: SNMPTrapConfig{Target: "127.0.0.1"}, mappings: "- messageIdPattern: ^Base\n  oid: 1.3.6.1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mappings != "" {
+				tt.config.MappingFile = writeSNMPMappings(t, tt.mappings)
+			}
+			if sink, err := NewSNMPTrapSink(tt.config); err == nil {
+				sink.Close()
+				t.Error("NewSNMPTrapSink() error = nil, want the config rejected")
+			}
+		})
+	}
+}
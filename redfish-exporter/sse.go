@@ -0,0 +1,159 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Backoff between reconnections of a dropped event stream
+var sseRetryConfig = RetryConfig{
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Jitter:         0.2,
+}
+
+// Returned when the BMC does not offer a server-sent event stream
+var ErrSSEUnsupported = errors.New("server-sent events not supported")
+
+// Stream the server's events from its ServerSentEventURI and call handler
+// for every event payload until the context is done. A dropped stream is
+// reopened with exponential backoff, sending the ID of the last event
+// received as Last-Event-ID so BMCs that support it replay the events sent
+// while disconnected.
+func SubscribeSSE(ctx context.Context, server RedfishServer, handler func(Payload)) error {
+	lastEventID := ""
+	retry := 0
+	for {
+		received, err := streamSSE(ctx, server, &lastEventID, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, ErrSSEUnsupported) {
+			return err
+		}
+
+		// Start over with the initial backoff once a stream delivered events
+		if received {
+			retry = 0
+		}
+		retry++
+		backoff := sseRetryConfig.Backoff(retry)
+		log.Printf("Event stream of server %s dropped (%v), reconnecting in %v", server.IP, err, backoff)
+		server.settings().metrics.sseReconnects.WithLabelValues(server.ID()).Inc()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// Read the event stream until it ends, keeping lastEventID up to date.
+// Returns whether any event was received.
+func streamSSE(ctx context.Context, server RedfishServer, lastEventID *string, handler func(Payload)) (bool, error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	eventService, err := getEventService(c)
+	if err != nil {
+		return false, fmt.Errorf("failed to get event service on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	if eventService.ServerSentEventURI == "" {
+		return false, fmt.Errorf("server %s: %w", server.IP, ErrSSEUnsupported)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(server.IP, "/")+eventService.ServerSentEventURI, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if *lastEventID != "" {
+		req.Header.Set("Last-Event-ID", *lastEventID)
+	}
+	// OAuth servers get the bearer token from the client's transport
+	if session, err := c.GetSession(); err == nil && session.Token != "" {
+		req.Header.Set("X-Auth-Token", session.Token)
+	} else if !isOAuthLogin(server) {
+		req.SetBasicAuth(server.Username, server.Password)
+	}
+
+	// The stream stays open, so the client must not time out
+	client := newServerHTTPClient(server)
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("event stream returned status %d", resp.StatusCode)
+	}
+	log.Printf("Connected to event stream of server %s", server.IP)
+
+	received := false
+	var id string
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// A blank line ends the event
+			if data.Len() > 0 {
+				var payload Payload
+				if err := json.Unmarshal([]byte(data.String()), &payload); err != nil {
+					log.Printf("Failed to parse event from server %s: %v", server.IP, err)
+				} else {
+					handler(payload)
+					received = true
+				}
+				if id != "" {
+					*lastEventID = id
+				}
+			}
+			id = ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+			// Comment, used as keep-alive
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return received, err
+	}
+	return received, errors.New("stream closed by server")
+}
@@ -0,0 +1,114 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const sseURI = "/redfish/v1/EventService/SSE"
+
+func TestSubscribeSSE(t *testing.T) {
+	retryConfig := sseRetryConfig
+	sseRetryConfig = RetryConfig{InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+	t.Cleanup(func() { sseRetryConfig = retryConfig })
+
+	bmc := newFakeBMC(t)
+	bmc.eventService["ServerSentEventUri"] = sseURI
+
+	// Each connection gets the next stream, the last one stays open
+	streams := []string{
+		// Refused before the stream starts
+		"",
+		": keep-alive\n\n" +
+			"id: 1\ndata: {\"Id\": \"1\",\ndata: \"Events\": []}\n\n" +
+			"id: 2\ndata: not json\n\n" +
+			"id: 3\ndata: {\"Id\": \"3\"}\n\n",
+		"id: 4\ndata: {\"Id\": \"4\"}\n\n",
+	}
+	var mu sync.Mutex
+	var lastEventIDs []string
+	bmc.handlers[sseURI] = func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connection := len(lastEventIDs)
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		mu.Unlock()
+		if r.Header.Get("Accept") != "text/event-stream" || r.Header.Get("X-Auth-Token") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if connection == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, streams[min(connection, len(streams)-1)])
+		w.(http.Flusher).Flush()
+		if connection >= len(streams)-1 {
+			<-r.Context().Done()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	payloads := make(chan Payload, 10)
+	done := make(chan error)
+	go func() {
+		done <- SubscribeSSE(ctx, bmc.server(), func(payload Payload) { payloads <- payload })
+	}()
+
+	var ids []string
+	for len(ids) < 3 {
+		select {
+		case payload := <-payloads:
+			ids = append(ids, payload.Id)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %v, want 3 events", ids)
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("SubscribeSSE() error = %v after the context was done", err)
+	}
+	if !slices.Equal(ids, []string{"1", "3", "4"}) {
+		t.Errorf("received %v, want the parsable events 1, 3 and 4", ids)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(lastEventIDs, []string{"", "", "3"}) {
+		t.Errorf("Last-Event-ID of the connections = %q, want the last event received resent", lastEventIDs)
+	}
+}
+
+func TestSubscribeSSEUnsupported(t *testing.T) {
+	bmc := newFakeBMC(t)
+
+	err := SubscribeSSE(context.Background(), bmc.server(), func(Payload) {})
+	if !errors.Is(err, ErrSSEUnsupported) {
+		t.Errorf("SubscribeSSE() error = %v, want %v", err, ErrSSEUnsupported)
+	}
+	if bmc.openSessions() != 0 {
+		t.Errorf("%d sessions left open", bmc.openSessions())
+	}
+}
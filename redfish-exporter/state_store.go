@@ -0,0 +1,106 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StateStore keeps the state that has to survive restarts in a JSON file,
+// one entry per key. Without a path the state is only kept in memory.
+type StateStore struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]json.RawMessage
+}
+
+// Open the state file, which doesn't need to exist yet
+func NewStateStore(path string) (*StateStore, error) {
+	s := &StateStore{path: path, entries: make(map[string]json.RawMessage)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %v", err)
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Read the entry stored under key into v, reporting whether it exists
+func (s *StateStore) Load(key string, v any) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.entries[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return true, fmt.Errorf("failed to parse state %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// Store v under key and write the state file
+func (s *StateStore) Save(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state %s: %v", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = data
+	if s.path == "" {
+		return nil
+	}
+
+	fileData, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %v", err)
+	}
+	// Write to a temporary file first, so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(fileData); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	return nil
+}
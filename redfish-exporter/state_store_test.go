@@ -0,0 +1,96 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateStore(t *testing.T) {
+	type windows map[string]string
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+		// Whether a reopened store has the saved state
+		wantPersisted bool
+	}{
+		{"in memory", func(t *testing.T) string { return "" }, false},
+		{"file not created yet", func(t *testing.T) string { return filepath.Join(t.TempDir(), "state.json") }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path(t)
+			store, err := NewStateStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var loaded windows
+			if ok, err := store.Load("maintenance", &loaded); ok || err != nil {
+				t.Fatalf("Load() = %v, %v on a new store", ok, err)
+			}
+			saved := windows{"https://10.0.0.1": "2024-06-01T12:00:00Z"}
+			if err := store.Save("maintenance", saved); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if ok, err := store.Load("maintenance", &loaded); !ok || err != nil || loaded["https://10.0.0.1"] != saved["https://10.0.0.1"] {
+				t.Fatalf("Load() = %v, %v, %v, want the saved state", ok, err, loaded)
+			}
+
+			reopened, err := NewStateStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded = nil
+			ok, err := reopened.Load("maintenance", &loaded)
+			if err != nil || ok != tt.wantPersisted {
+				t.Errorf("Load() after reopening = %v, %v, want %v", ok, err, tt.wantPersisted)
+			}
+		})
+	}
+}
+
+func TestStateStoreCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStateStore(path); err == nil {
+		t.Error("NewStateStore() error = nil for a corrupt file")
+	}
+}
+
+func TestStateStoreLeavesNoTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStateStore(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := store.Save("count", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "state.json" {
+		t.Errorf("files %v, want only state.json", entries)
+	}
+}
@@ -0,0 +1,355 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// What is rolled back when creating subscriptions on a batch of servers fails
+type AtomicityLevel int
+
+const (
+	// Any failure deletes the subscriptions created on all servers
+	AllOrNothing AtomicityLevel = iota
+	// A failing server's subscriptions are deleted, the other servers keep theirs
+	ServerAtomic
+	// Nothing is deleted, a failing server keeps the destinations created before the failure
+	BestEffort
+)
+
+func (l AtomicityLevel) String() string {
+	switch l {
+	case AllOrNothing:
+		return "AllOrNothing"
+	case ServerAtomic:
+		return "ServerAtomic"
+	case BestEffort:
+		return "BestEffort"
+	}
+	return fmt.Sprintf("AtomicityLevel(%d)", int(l))
+}
+
+type BatchCreateOptions struct {
+	AtomicityLevel AtomicityLevel
+	// Treat an empty server list as nothing to do instead of ErrNoServers
+	AllowEmpty bool
+	// Have each server send a test event after subscribing and fail the
+	// server if the listener doesn't receive it within DeliveryTimeout,
+	// defaultDeliveryTimeout when zero. The listener must be running.
+	VerifyDelivery  bool
+	DeliveryTimeout time.Duration
+	// Run a SmokeTest on each server after subscribing, which also checks
+	// that the test event comes with the subscription's Context. Waits
+	// DeliveryTimeout as well. The listener must be running.
+	SmokeTest bool
+	// Retries of a failing server, which is tried once when MaxAttempts is
+	// zero. A failed attempt's subscriptions are deleted before the next.
+	Retry RetryConfig
+	// Total time allowed for the batch, unlimited when zero. Once it passes
+	// the servers still in progress are canceled and the batch returns a
+	// BatchDeadlineError. The servers completed by then keep their
+	// subscriptions, even for AllOrNothing.
+	Deadline time.Duration
+	// Called once per server as it completes, successfully or not, with
+	// the URI of its first subscription. Calls are serialized, so the
+	// function doesn't need to be safe for concurrent use, but a slow one
+	// holds up the workers waiting to report. It sees the server's own
+	// outcome, before an AllOrNothing batch rolls back the servers that
+	// succeeded. Servers never started because the context is done are
+	// reported after the others.
+	OnServerDone func(ip string, uri string, err error)
+}
+
+// BatchDeadlineError is returned when a batch create runs past its
+// Deadline. It wraps context.DeadlineExceeded.
+type BatchDeadlineError struct {
+	Deadline time.Duration
+	// Servers canceled or never started, in the order they were given
+	Unprocessed []string
+}
+
+func (e *BatchDeadlineError) Error() string {
+	return fmt.Sprintf("subscription deadline of %v exceeded, %d servers unprocessed: %s", e.Deadline, len(e.Unprocessed), strings.Join(e.Unprocessed, ", "))
+}
+
+func (e *BatchDeadlineError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// Outcome of a batch create on a single server
+type ServerCreateResult struct {
+	// Subscriptions left on the server, by destination
+	Subscriptions ServerSubscriptions
+	Err           error
+	// Whether subscriptions created on the server were deleted again
+	RolledBack bool
+	// Tries it took, 0 when the server was never tried
+	Attempts int
+	// Time spent on the server, including the retries
+	Duration time.Duration
+}
+
+// URI of the subscription created first on the server, empty without one
+func (r *ServerCreateResult) firstURI() string {
+	var uri string
+	var first time.Time
+	for _, subscription := range r.Subscriptions {
+		if uri == "" || subscription.CreatedAt.Before(first) {
+			uri = subscription.URI
+			first = subscription.CreatedAt
+		}
+	}
+	return uri
+}
+
+// Existing subscriptions deleted to make room for the new ones
+func (r *ServerCreateResult) ConflictsCleared() int {
+	cleared := 0
+	for _, subscription := range r.Subscriptions {
+		cleared += subscription.ConflictsCleared
+	}
+	return cleared
+}
+
+// BatchCreateResult holds the outcome of a batch create by server IP
+type BatchCreateResult struct {
+	Level   AtomicityLevel
+	Servers map[string]*ServerCreateResult
+	// In the order they were given
+	servers []RedfishServer
+}
+
+// ServerResult is the outcome of a batch create on one server, for reports
+type ServerResult struct {
+	IP string
+	// URI of the server's subscription, of the first destination's when
+	// the payload has several
+	URI              string
+	Err              error
+	Attempts         int
+	ConflictsCleared int
+	Duration         time.Duration
+}
+
+// The outcome on every server in the order they were given
+func (r *BatchCreateResult) Results() []ServerResult {
+	results := make([]ServerResult, 0, len(r.servers))
+	for _, server := range r.servers {
+		serverResult := r.Servers[server.ID()]
+		if serverResult == nil {
+			continue
+		}
+		result := ServerResult{
+			IP:               server.IP,
+			Err:              serverResult.Err,
+			Attempts:         serverResult.Attempts,
+			ConflictsCleared: serverResult.ConflictsCleared(),
+			Duration:         serverResult.Duration,
+		}
+		result.URI = serverResult.firstURI()
+		results = append(results, result)
+	}
+	return results
+}
+
+// The subscriptions left on the servers, by server IP
+func (r *BatchCreateResult) SubscriptionMap() map[string]ServerSubscriptions {
+	subscriptionMap := make(map[string]ServerSubscriptions)
+	for serverIP, result := range r.Servers {
+		if len(result.Subscriptions) > 0 {
+			subscriptionMap[serverIP] = result.Subscriptions
+		}
+	}
+	return subscriptionMap
+}
+
+// The errors of the servers that failed, by server IP
+func (r *BatchCreateResult) Errors() map[string]error {
+	errs := make(map[string]error)
+	for serverIP, result := range r.Servers {
+		if result.Err != nil {
+			errs[serverIP] = result.Err
+		}
+	}
+	return errs
+}
+
+// Create subscriptions on all servers, rolling back failures as the
+// options' AtomicityLevel asks. The error is only set when the batch failed
+// as a whole: for AllOrNothing on any failure, for the other levels when
+// the context is done before all servers were handled.
+func BatchCreateSubscriptions(ctx context.Context, redfishServers []RedfishServer, subscriptionPayload SubscriptionPayload, opts BatchCreateOptions) (*BatchCreateResult, error) {
+	return batchCreate(ctx, redfishServers, subscriptionPayload, opts)
+}
+
+func batchCreate(ctx context.Context, redfishServers []RedfishServer, subscriptionPayload SubscriptionPayload, opts BatchCreateOptions) (*BatchCreateResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("subscription canceled: %w", err)
+	}
+	if len(redfishServers) == 0 && !opts.AllowEmpty {
+		return nil, ErrNoServers
+	}
+	if err := ValidateSubscriptionPayload(subscriptionPayload); err != nil {
+		return nil, fmt.Errorf("invalid subscription payload: %w", err)
+	}
+
+	parent := ctx
+	if opts.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Deadline)
+		defer cancel()
+	}
+
+	serverDone := func(server RedfishServer, result *ServerCreateResult) {
+		if opts.OnServerDone != nil {
+			opts.OnServerDone(server.IP, result.firstURI(), result.Err)
+		}
+	}
+
+	pool := NewWorkerPool[*ServerCreateResult](serversConcurrency(redfishServers))
+	// Report each server once, one at a time, as soon as its lock is released
+	results := pool.Results()
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		for result := range results {
+			serverDone(redfishServers[result.Index], result.Value)
+		}
+	}()
+	for _, server := range redfishServers {
+		create := func() *ServerCreateResult {
+			if err := ctx.Err(); err != nil {
+				return &ServerCreateResult{Err: err}
+			}
+			serverPayload, err := RenderPayload(subscriptionPayload, server)
+			if err != nil {
+				return &ServerCreateResult{Err: err}
+			}
+			unlock := server.settings().locks.Lock(server.IP)
+			defer unlock()
+			start := time.Now()
+			var subscriptions ServerSubscriptions
+			attempts := 0
+			for {
+				attempts++
+				subscriptions, err = createServerSubscriptions(ctx, server, serverPayload)
+				if err == nil && opts.VerifyDelivery {
+					err = verifyDelivery(ctx, server, opts.DeliveryTimeout)
+				}
+				if err == nil && opts.SmokeTest {
+					receiver := NewEventReceiver(serverPayload)
+					err = SmokeTest(ctx, server, receiver, opts.DeliveryTimeout)
+					receiver.Close()
+				}
+				if err == nil || attempts >= opts.Retry.MaxAttempts || ctx.Err() != nil {
+					break
+				}
+				rollbackServerSubscriptions(server, subscriptions)
+				subscriptions = nil
+				backoff := opts.Retry.Backoff(attempts)
+				log.Printf("Retrying subscription on server %s in %v: %v", server.IP, backoff, err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(backoff):
+				}
+				if ctx.Err() != nil {
+					break
+				}
+			}
+			if err != nil && ctx.Err() != nil && !errors.Is(err, ctx.Err()) {
+				// Tell a server interrupted by the context apart from one
+				// that failed on its own
+				err = fmt.Errorf("%w: %w", ctx.Err(), err)
+			}
+			result := &ServerCreateResult{Subscriptions: subscriptions, Err: err, Attempts: attempts, Duration: time.Since(start)}
+			if err != nil && opts.AtomicityLevel != BestEffort && len(subscriptions) > 0 {
+				rollbackServerSubscriptions(server, subscriptions)
+				result.Subscriptions = nil
+				result.RolledBack = true
+			}
+			return result
+		}
+		pool.Add(create)
+	}
+	// Servers never started because the context is done are left nil
+	serverResults := pool.Run(ctx)
+	<-reported
+
+	batch := &BatchCreateResult{Level: opts.AtomicityLevel, Servers: make(map[string]*ServerCreateResult), servers: redfishServers}
+	// Only the batch's own deadline passed, not the caller's context
+	deadlineExceeded := opts.Deadline > 0 && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
+	var failed RedfishServer
+	var unprocessed []string
+	for i, result := range serverResults {
+		server := redfishServers[i]
+		if result == nil {
+			result = &ServerCreateResult{Err: ctx.Err()}
+			serverDone(server, result)
+		}
+		batch.Servers[server.ID()] = result
+		if result.Err != nil {
+			if deadlineExceeded && errors.Is(result.Err, context.DeadlineExceeded) {
+				unprocessed = append(unprocessed, server.ID())
+				continue
+			}
+			if failed.IP == "" {
+				failed = server
+			}
+			continue
+		}
+		for _, subscription := range result.Subscriptions {
+			log.Printf("Successfully created subscription on redfish server %s: %s", server.ID(), subscription.URI)
+		}
+	}
+
+	var deadlineErr error
+	if deadlineExceeded {
+		deadlineErr = &BatchDeadlineError{Deadline: opts.Deadline, Unprocessed: unprocessed}
+	}
+
+	if opts.AtomicityLevel == AllOrNothing && (failed.IP != "" || (ctx.Err() != nil && !deadlineExceeded)) {
+		// Roll back even when the context is canceled, within a grace period
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), subscriptionRollbackTimeout)
+		defer cancel()
+		rollbackSubscriptions(rollbackCtx, redfishServers, batch.SubscriptionMap())
+		for _, result := range batch.Servers {
+			if len(result.Subscriptions) > 0 {
+				result.Subscriptions = nil
+				result.RolledBack = true
+			}
+		}
+		if deadlineErr != nil {
+			return batch, fmt.Errorf("subscription failed on server %s: %w, rolling back previous subscriptions: %w", failed.ID(), batch.Servers[failed.ID()].Err, deadlineErr)
+		}
+		if err := ctx.Err(); err != nil {
+			return batch, fmt.Errorf("subscription canceled, rolling back previous subscriptions: %w", err)
+		}
+		return batch, fmt.Errorf("subscription failed on server %s: %w, rolling back previous subscriptions", failed.ID(), batch.Servers[failed.ID()].Err)
+	}
+	if deadlineErr != nil {
+		return batch, deadlineErr
+	}
+	if err := ctx.Err(); err != nil {
+		return batch, fmt.Errorf("subscription canceled: %w", err)
+	}
+	return batch, nil
+}
@@ -0,0 +1,209 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+const postSubscription = "POST /redfish/v1/EventService/Subscriptions"
+
+func batchPayload() SubscriptionPayload {
+	return SubscriptionPayload{Destination: "https://10.0.0.100:8080", Protocol: "Redfish", EventTypes: []redfish.EventType{redfish.AlertEventType}, Context: "scrapefish"}
+}
+
+func TestAtomicityLevelString(t *testing.T) {
+	tests := map[AtomicityLevel]string{
+		AllOrNothing:      "AllOrNothing",
+		ServerAtomic:      "ServerAtomic",
+		BestEffort:        "BestEffort",
+		AtomicityLevel(7): "AtomicityLevel(7)",
+	}
+	for level, want := range tests {
+		if got := level.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestBatchCreateSubscriptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		level AtomicityLevel
+		// Subscriptions left on the healthy server
+		wantHealthy int
+		wantErr     bool
+	}{
+		{"all or nothing", AllOrNothing, 0, true},
+		{"server atomic", ServerAtomic, 1, false},
+		{"best effort", BestEffort, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthy := newFakeBMC(t)
+			failing := newFakeBMC(t)
+			failing.failures[postSubscription] = http.StatusInternalServerError
+			servers := []RedfishServer{healthy.server(), failing.server()}
+			var mu sync.Mutex
+			done := make(map[string]error)
+
+			batch, err := BatchCreateSubscriptions(context.Background(), servers, batchPayload(), BatchCreateOptions{
+				AtomicityLevel: tt.level,
+				OnServerDone: func(ip, uri string, err error) {
+					mu.Lock()
+					defer mu.Unlock()
+					done[ip] = err
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BatchCreateSubscriptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := healthy.subscriptionCount(); got != tt.wantHealthy {
+				t.Errorf("%d subscriptions left on the healthy server, want %d", got, tt.wantHealthy)
+			}
+			if result := batch.Servers[healthy.URL]; result.Err != nil || result.RolledBack != (tt.wantHealthy == 0) {
+				t.Errorf("healthy server result = %+v", result)
+			}
+			if errs := batch.Errors(); len(errs) != 1 || errs[failing.URL] == nil {
+				t.Errorf("Errors() = %v, want the failing server's", errs)
+			}
+			if len(done) != 2 || done[healthy.URL] != nil || done[failing.URL] == nil {
+				t.Errorf("OnServerDone() calls = %v, want both servers with their own outcome", done)
+			}
+			results := batch.Results()
+			if len(results) != 2 || results[0].IP != healthy.URL || (results[0].URI != "") != (tt.wantHealthy > 0) {
+				t.Errorf("Results() = %+v, want the servers in order", results)
+			}
+		})
+	}
+}
+
+func TestBatchCreateSubscriptionsRetry(t *testing.T) {
+	bmc := newFakeBMC(t)
+	bmc.transientFailures[postSubscription] = 1
+
+	batch, err := BatchCreateSubscriptions(context.Background(), []RedfishServer{bmc.server()}, batchPayload(), BatchCreateOptions{
+		Retry: RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := batch.Servers[bmc.URL]; result.Attempts != 2 || len(result.Subscriptions) != 1 {
+		t.Errorf("result = %+v, want one subscription after 2 attempts", result)
+	}
+	if bmc.subscriptionCount() != 1 {
+		t.Errorf("%d subscriptions, want 1", bmc.subscriptionCount())
+	}
+}
+
+func TestBatchCreateSubscriptionsDeadline(t *testing.T) {
+	healthy := newFakeBMC(t)
+	hanging := newFakeBMC(t)
+	hanging.handlers["/redfish/v1/EventService/Subscriptions"] = func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}
+	servers := []RedfishServer{healthy.server(), hanging.server()}
+
+	batch, err := BatchCreateSubscriptions(context.Background(), servers, batchPayload(), BatchCreateOptions{
+		AtomicityLevel: AllOrNothing,
+		Deadline:       500 * time.Millisecond,
+	})
+	var deadlineErr *BatchDeadlineError
+	if !errors.As(err, &deadlineErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("BatchCreateSubscriptions() error = %v, want a BatchDeadlineError", err)
+	}
+	if !slices.Equal(deadlineErr.Unprocessed, []string{hanging.URL}) {
+		t.Errorf("unprocessed = %v, want the hanging server", deadlineErr.Unprocessed)
+	}
+	// Completed servers keep their subscriptions, even for AllOrNothing
+	if healthy.subscriptionCount() != 1 || batch.Servers[healthy.URL].RolledBack {
+		t.Errorf("healthy server has %d subscriptions, want its subscription kept", healthy.subscriptionCount())
+	}
+}
+
+func TestBatchCreateSubscriptionsInvalidInput(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	servers := []RedfishServer{{IP: "https://10.0.0.1"}}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		servers []RedfishServer
+		payload SubscriptionPayload
+		opts    BatchCreateOptions
+		wantErr error
+	}{
+		{"no servers", context.Background(), nil, batchPayload(), BatchCreateOptions{}, ErrNoServers},
+		{"canceled", canceled, servers, batchPayload(), BatchCreateOptions{}, context.Canceled},
+		{"invalid payload", context.Background(), servers, SubscriptionPayload{}, BatchCreateOptions{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch, err := BatchCreateSubscriptions(tt.ctx, tt.servers, tt.payload, tt.opts)
+			if err == nil || batch != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("BatchCreateSubscriptions() = %v, %v, want error %v", batch, err, tt.wantErr)
+			}
+		})
+	}
+
+	batch, err := BatchCreateSubscriptions(context.Background(), nil, batchPayload(), BatchCreateOptions{AllowEmpty: true})
+	if err != nil || len(batch.Servers) != 0 {
+		t.Errorf("BatchCreateSubscriptions() with AllowEmpty = %v, %v, want an empty batch", batch, err)
+	}
+}
+
+func TestCreateSubscriptionsRollbackMetrics(t *testing.T) {
+	healthy := newFakeBMC(t)
+	undeletable := newFakeBMC(t)
+	undeletable.failures["DELETE /redfish/v1/EventService/Subscriptions/1"] = http.StatusInternalServerError
+	failing := newFakeBMC(t)
+	failing.failures[postSubscription] = http.StatusInternalServerError
+	settings := newConnectionSettings(RedfishClientConfig{})
+	servers := withConnectionSettings([]RedfishServer{healthy.server(), undeletable.server(), failing.server()}, settings)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	if _, err := CreateSubscriptionsForAllServers(servers, batchPayload()); err == nil {
+		t.Fatal("CreateSubscriptionsForAllServers() error = nil, want the failing server's")
+	}
+	if healthy.subscriptionCount() != 0 {
+		t.Errorf("%d subscriptions left on the healthy server, want 0", healthy.subscriptionCount())
+	}
+	if got := counterValue(t, settings.metrics.subscriptionRollback.WithLabelValues("deleted")); got != 1 {
+		t.Errorf("deleted rollbacks = %v, want 1", got)
+	}
+	if got := counterValue(t, settings.metrics.subscriptionRollback.WithLabelValues("failed")); got != 1 {
+		t.Errorf("failed rollbacks = %v, want 1", got)
+	}
+	if want := "Rolled back 1 subscriptions on 2 servers, 1 deletions failed"; !strings.Contains(logs.String(), want) {
+		t.Errorf("logs = %q, want %q", logs.String(), want)
+	}
+}
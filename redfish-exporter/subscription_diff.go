@@ -0,0 +1,94 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"slices"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// PayloadDiffKind is the kind of difference between a desired subscription
+// payload and a subscription on a BMC
+type PayloadDiffKind string
+
+const (
+	// A registry prefix of the payload is missing on the BMC
+	AddedRegistryPrefix PayloadDiffKind = "AddedRegistryPrefix"
+	// The BMC has a registry prefix the payload doesn't
+	RemovedRegistryPrefix PayloadDiffKind = "RemovedRegistryPrefix"
+	DestinationChanged    PayloadDiffKind = "DestinationChanged"
+	ContextChanged        PayloadDiffKind = "ContextChanged"
+	ProtocolChanged       PayloadDiffKind = "ProtocolChanged"
+	DeliveryPolicyChanged PayloadDiffKind = "DeliveryPolicyChanged"
+)
+
+// PayloadDiff is one difference, with the desired and the actual value
+type PayloadDiff struct {
+	Kind    PayloadDiffKind `json:"kind"`
+	Desired string          `json:"desired,omitempty"`
+	Actual  string          `json:"actual,omitempty"`
+}
+
+// Compare the desired payload with a subscription on a BMC. Protocol and
+// DeliveryRetryPolicy are only compared when the BMC reports them, since
+// not all BMCs do.
+func DiffSubscriptionPayload(desired SubscriptionPayload, actual *redfish.EventDestination) []PayloadDiff {
+	var diffs []PayloadDiff
+
+	for _, prefix := range desired.RegistryPrefixes {
+		if !slices.Contains(actual.RegistryPrefixes, prefix) {
+			diffs = append(diffs, PayloadDiff{Kind: AddedRegistryPrefix, Desired: prefix})
+		}
+	}
+	for _, prefix := range actual.RegistryPrefixes {
+		if !slices.Contains(desired.RegistryPrefixes, prefix) {
+			diffs = append(diffs, PayloadDiff{Kind: RemovedRegistryPrefix, Actual: prefix})
+		}
+	}
+
+	if desired.Destination != actual.Destination {
+		diffs = append(diffs, PayloadDiff{Kind: DestinationChanged, Desired: desired.Destination, Actual: actual.Destination})
+	}
+	if desired.Context != actual.Context {
+		diffs = append(diffs, PayloadDiff{Kind: ContextChanged, Desired: desired.Context, Actual: actual.Context})
+	}
+
+	desiredProtocol := desired.Protocol
+	if desiredProtocol == "" {
+		desiredProtocol = redfish.RedfishEventDestinationProtocol
+	}
+	if actual.Protocol != "" && desiredProtocol != actual.Protocol {
+		diffs = append(diffs, PayloadDiff{Kind: ProtocolChanged, Desired: string(desiredProtocol), Actual: string(actual.Protocol)})
+	}
+
+	if desired.DeliveryRetryPolicy != "" && actual.DeliveryRetryPolicy != "" && desired.DeliveryRetryPolicy != actual.DeliveryRetryPolicy {
+		diffs = append(diffs, PayloadDiff{Kind: DeliveryPolicyChanged, Desired: string(desired.DeliveryRetryPolicy), Actual: string(actual.DeliveryRetryPolicy)})
+	}
+
+	return diffs
+}
+
+// Whether the subscription on the BMC differs from the desired payload
+func NeedsUpdate(desired SubscriptionPayload, actual *redfish.EventDestination) bool {
+	return len(DiffSubscriptionPayload(desired, actual)) > 0
+}
+
+// Whether an existing subscription is the one the payload asks for
+func subscriptionMatches(subscription *redfish.EventDestination, payload SubscriptionPayload) bool {
+	return subscription.Destination == payload.Destination && len(DiffSubscriptionPayload(payload, subscription)) == 0
+}
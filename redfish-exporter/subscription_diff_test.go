@@ -0,0 +1,90 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func TestDiffSubscriptionPayload(t *testing.T) {
+	desired := SubscriptionPayload{
+		Destination:      "https://10.0.0.100:8080",
+		Context:          "scrapefish",
+		RegistryPrefixes: []string{"Base", "ResourceEvent"},
+	}
+	tests := []struct {
+		name    string
+		desired SubscriptionPayload
+		actual  redfish.EventDestination
+		want    []PayloadDiff
+	}{
+		{
+			name:    "same subscription",
+			desired: desired,
+			actual:  redfish.EventDestination{Destination: "https://10.0.0.100:8080", Context: "scrapefish", RegistryPrefixes: []string{"ResourceEvent", "Base"}, Protocol: redfish.RedfishEventDestinationProtocol},
+		},
+		{
+			name:    "registry prefixes changed",
+			desired: desired,
+			actual:  redfish.EventDestination{Destination: "https://10.0.0.100:8080", Context: "scrapefish", RegistryPrefixes: []string{"Base", "TaskEvent"}},
+			want: []PayloadDiff{
+				{Kind: AddedRegistryPrefix, Desired: "ResourceEvent"},
+				{Kind: RemovedRegistryPrefix, Actual: "TaskEvent"},
+			},
+		},
+		{
+			name:    "destination and context changed",
+			desired: desired,
+			actual:  redfish.EventDestination{Destination: "https://10.0.0.101:8080", Context: "other", RegistryPrefixes: []string{"Base", "ResourceEvent"}},
+			want: []PayloadDiff{
+				{Kind: DestinationChanged, Desired: "https://10.0.0.100:8080", Actual: "https://10.0.0.101:8080"},
+				{Kind: ContextChanged, Desired: "scrapefish", Actual: "other"},
+			},
+		},
+		{
+			name:    "protocol changed",
+			desired: desired,
+			actual:  redfish.EventDestination{Destination: "https://10.0.0.100:8080", Context: "scrapefish", RegistryPrefixes: []string{"Base", "ResourceEvent"}, Protocol: redfish.SNMPv2cEventDestinationProtocol},
+			want:    []PayloadDiff{{Kind: ProtocolChanged, Desired: "Redfish", Actual: "SNMPv2c"}},
+		},
+		{
+			name:    "delivery policy changed",
+			desired: SubscriptionPayload{Destination: "https://10.0.0.100:8080", DeliveryRetryPolicy: redfish.RetryForeverDeliveryRetryPolicy},
+			actual:  redfish.EventDestination{Destination: "https://10.0.0.100:8080", DeliveryRetryPolicy: redfish.SuspendRetriesDeliveryRetryPolicy},
+			want:    []PayloadDiff{{Kind: DeliveryPolicyChanged, Desired: "RetryForever", Actual: "SuspendRetries"}},
+		},
+		{
+			name:    "delivery policy not reported",
+			desired: SubscriptionPayload{Destination: "https://10.0.0.100:8080", DeliveryRetryPolicy: redfish.RetryForeverDeliveryRetryPolicy},
+			actual:  redfish.EventDestination{Destination: "https://10.0.0.100:8080"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := DiffSubscriptionPayload(tt.desired, &tt.actual)
+			if !reflect.DeepEqual(diffs, tt.want) {
+				t.Errorf("DiffSubscriptionPayload() = %+v, want %+v", diffs, tt.want)
+			}
+			if got := NeedsUpdate(tt.desired, &tt.actual); got != (len(tt.want) > 0) {
+				t.Errorf("NeedsUpdate() = %v, want %v", got, len(tt.want) > 0)
+			}
+		})
+	}
+}
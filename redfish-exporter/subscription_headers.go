@@ -0,0 +1,69 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Returned when a subscription header would replace one set by the BMC
+var ErrReservedHeader = errors.New("reserved HTTP header")
+
+// Headers the BMC sets itself when delivering events
+var reservedHeaders = []string{"Content-Type", "Host", "Transfer-Encoding"}
+
+func isReservedHeader(key string) bool {
+	for _, reserved := range reservedHeaders {
+		if strings.EqualFold(key, reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// Set a header the BMC sends with every event, e.g. Authorization for the
+// destination endpoint
+func (p *SubscriptionPayload) SetHTTPHeader(key, value string) error {
+	if isReservedHeader(key) {
+		return fmt.Errorf("%w: %s", ErrReservedHeader, key)
+	}
+	if p.HTTPHeaders == nil {
+		p.HTTPHeaders = make(map[string]string)
+	}
+	p.HTTPHeaders[http.CanonicalHeaderKey(key)] = value
+	return nil
+}
+
+// Get headers from the environment variables starting with prefix, e.g.
+// SUBSCRIPTION_HEADER_X_API_KEY=secret gives "X-Api-Key: secret" for the
+// prefix SUBSCRIPTION_HEADER_
+func HeadersFromEnv(prefix string) map[string]string {
+	headers := make(map[string]string)
+	for _, env := range os.Environ() {
+		name, value, found := strings.Cut(env, "=")
+		if !found || !strings.HasPrefix(name, prefix) || name == prefix {
+			continue
+		}
+		key := strings.ReplaceAll(strings.TrimPrefix(name, prefix), "_", "-")
+		headers[http.CanonicalHeaderKey(key)] = value
+	}
+	return headers
+}
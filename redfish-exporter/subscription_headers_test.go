@@ -0,0 +1,71 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSetHTTPHeader(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantKey string
+		wantErr error
+	}{
+		{"authorization", "Authorization", nil},
+		{"x-api-key", "X-Api-Key", nil},
+		{"content-type", "", ErrReservedHeader},
+		{"HOST", "", ErrReservedHeader},
+		{"Transfer-Encoding", "", ErrReservedHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			var payload SubscriptionPayload
+			err := payload.SetHTTPHeader(tt.key, "secret")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetHTTPHeader() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && payload.HTTPHeaders[tt.wantKey] != "secret" {
+				t.Errorf("HTTPHeaders = %v, want %s set", payload.HTTPHeaders, tt.wantKey)
+			}
+			if tt.wantErr != nil && len(payload.HTTPHeaders) > 0 {
+				t.Errorf("HTTPHeaders = %v, want the reserved header left out", payload.HTTPHeaders)
+			}
+		})
+	}
+}
+
+func TestHeadersFromEnv(t *testing.T) {
+	t.Setenv("TEST_SUBSCRIPTION_HEADER_X_API_KEY", "secret")
+	t.Setenv("TEST_SUBSCRIPTION_HEADER_AUTHORIZATION", "Bearer token")
+	t.Setenv("TEST_SUBSCRIPTION_HEADER_", "ignored")
+	t.Setenv("TEST_OTHER_HEADER", "ignored")
+
+	want := map[string]string{"X-Api-Key": "secret", "Authorization": "Bearer token"}
+	if got := HeadersFromEnv("TEST_SUBSCRIPTION_HEADER_"); !reflect.DeepEqual(got, want) {
+		t.Errorf("HeadersFromEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateSubscriptionPayloadRejectsReservedHeaders(t *testing.T) {
+	payload := SubscriptionPayload{Destination: "https://10.0.0.100:8080", HTTPHeaders: map[string]string{"Content-Type": "text/plain"}}
+	if err := ValidateSubscriptionPayload(payload); !errors.Is(err, ErrReservedHeader) {
+		t.Errorf("ValidateSubscriptionPayload() error = %v, want %v", err, ErrReservedHeader)
+	}
+}
@@ -0,0 +1,253 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// Time allowed for the health-check POST to a new destination
+const migrateVerifyTimeout = 30 * time.Second
+
+type migrateOptions struct {
+	dryRun bool
+	verify func(ctx context.Context, server RedfishServer, destination string) error
+}
+
+type MigrateOption func(*migrateOptions)
+
+// DryRunMigrate only reports the subscriptions that would be migrated
+func DryRunMigrate() MigrateOption {
+	return func(o *migrateOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithMigrateVerifier replaces the health-check POST used to verify that a
+// new destination receives events
+func WithMigrateVerifier(verify func(ctx context.Context, server RedfishServer, destination string) error) MigrateOption {
+	return func(o *migrateOptions) {
+		o.verify = verify
+	}
+}
+
+// A subscription moved, or to be moved in a dry run, to a new destination
+type MigratedSubscription struct {
+	Server         string `json:"server"`
+	OldURI         string `json:"oldUri"`
+	NewURI         string `json:"newUri,omitempty"`
+	OldDestination string `json:"oldDestination"`
+	NewDestination string `json:"newDestination"`
+}
+
+// MigrateResult lists the subscriptions handled by MigrateSubscriptions
+type MigrateResult struct {
+	DryRun   bool                   `json:"dryRun"`
+	Migrated []MigratedSubscription `json:"migrated"`
+	// Servers whose subscriptions could not be read by server ID, they are
+	// left as they are
+	Errors map[string]error `json:"-"`
+}
+
+// Move the subscriptions whose Destination starts with oldDestPrefix to
+// newDestPrefix. The replacements are created first and every new
+// destination is sent a health-check event, the old subscriptions are only
+// deleted once all of them answered. When a create or a verify fails the
+// replacements are deleted again and the old subscriptions kept.
+//
+// HttpHeaders can't be read back from the BMCs and are not carried over.
+// Subscriptions in the exporter's own subscription map are recreated by
+// the reconcile loop, change its payload instead.
+func MigrateSubscriptions(ctx context.Context, servers []RedfishServer, oldDestPrefix, newDestPrefix string, opts ...MigrateOption) (*MigrateResult, error) {
+	options := migrateOptions{verify: verifyDestination}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if oldDestPrefix == "" || newDestPrefix == "" {
+		return nil, errors.New("migration needs both an old and a new destination prefix")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("migration canceled: %w", err)
+	}
+
+	result := &MigrateResult{DryRun: options.dryRun}
+	serverSubscriptions, errs := GetAllSubscriptionsAcrossServers(servers)
+	result.Errors = errs
+
+	type migration struct {
+		server  RedfishServer
+		old     *redfish.EventDestination
+		payload SubscriptionPayload
+	}
+	var migrations []migration
+	for _, server := range servers {
+		for _, subscription := range serverSubscriptions[server.ID()] {
+			if !strings.HasPrefix(subscription.Destination, oldDestPrefix) {
+				continue
+			}
+			payload := payloadFromSubscription(subscription)
+			payload.Destination = newDestPrefix + strings.TrimPrefix(subscription.Destination, oldDestPrefix)
+			migrations = append(migrations, migration{server: server, old: subscription, payload: payload})
+			result.Migrated = append(result.Migrated, MigratedSubscription{
+				Server:         server.IP,
+				OldURI:         subscription.ODataID,
+				OldDestination: subscription.Destination,
+				NewDestination: payload.Destination,
+			})
+		}
+	}
+	if options.dryRun || len(migrations) == 0 {
+		return result, nil
+	}
+
+	// Create the replacements
+	pool := NewWorkerPool[error](serversConcurrency(servers))
+	for i, m := range migrations {
+		pool.Add(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			unlock := m.server.settings().locks.Lock(m.server.IP)
+			defer unlock()
+			subscriptionURI, _, err := createDestinationSubscription(ctx, m.server, m.payload)
+			if err != nil {
+				return fmt.Errorf("failed to create subscription for %s on server %s: %w", m.payload.Destination, m.server.IP, err)
+			}
+			result.Migrated[i].NewURI = subscriptionURI
+			return nil
+		})
+	}
+	createErr := errors.Join(pool.Run(ctx)...)
+	if createErr == nil {
+		createErr = ctx.Err()
+	}
+
+	// Verify every new destination once
+	if createErr == nil {
+		verified := make(map[string]bool)
+		for _, m := range migrations {
+			if verified[m.payload.Destination] {
+				continue
+			}
+			if err := options.verify(ctx, m.server, m.payload.Destination); err != nil {
+				createErr = fmt.Errorf("failed to verify destination %s: %w", m.payload.Destination, err)
+				break
+			}
+			verified[m.payload.Destination] = true
+		}
+	}
+
+	if createErr != nil {
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), subscriptionRollbackTimeout)
+		defer cancel()
+		for i, m := range migrations {
+			if result.Migrated[i].NewURI == "" {
+				continue
+			}
+			if err := deleteSubscriptionFromServerContext(rollbackCtx, m.server, result.Migrated[i].NewURI); err != nil {
+				log.Printf("Failed to delete new event subscription %s on server %s: %v", result.Migrated[i].NewURI, m.server.IP, err)
+			}
+			result.Migrated[i].NewURI = ""
+		}
+		return result, fmt.Errorf("migration aborted, old subscriptions kept: %w", createErr)
+	}
+
+	// Every new destination works, drop the old subscriptions
+	var deleteErrs []error
+	for _, m := range migrations {
+		unlock := m.server.settings().locks.Lock(m.server.IP)
+		err := deleteSubscriptionFromServerContext(ctx, m.server, m.old.ODataID)
+		unlock()
+		if err != nil {
+			deleteErrs = append(deleteErrs, fmt.Errorf("failed to delete old subscription %s on server %s: %w", m.old.ODataID, m.server.IP, err))
+			continue
+		}
+		log.Printf("Migrated subscription on server %s from %s to %s", m.server.IP, m.old.Destination, m.payload.Destination)
+	}
+	return result, errors.Join(deleteErrs...)
+}
+
+// The payload recreating an existing subscription, as far as the BMC
+// reports its settings
+func payloadFromSubscription(subscription *redfish.EventDestination) SubscriptionPayload {
+	payload := SubscriptionPayload{
+		Destination:         subscription.Destination,
+		EventTypes:          subscription.EventTypes,
+		RegistryPrefixes:    subscription.RegistryPrefixes,
+		ResourceTypes:       subscription.ResourceTypes,
+		DeliveryRetryPolicy: subscription.DeliveryRetryPolicy,
+		Protocol:            subscription.Protocol,
+		Context:             subscription.Context,
+		OriginResources:     subscription.OriginResources,
+		SubscriptionType:    subscription.SubscriptionType,
+	}
+	if subscription.SubordinateResources {
+		payload.SubordinateResources = &subscription.SubordinateResources
+	}
+	if subscription.VerifyCertificate {
+		payload.VerifyDestinationCert = &subscription.VerifyCertificate
+	}
+	return payload
+}
+
+// Send a health-check event to a destination with the HTTP client of the
+// server subscribed to it, any 2xx answer counts
+func verifyDestination(ctx context.Context, server RedfishServer, destination string) error {
+	ctx, cancel := context.WithTimeout(ctx, migrateVerifyTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"@odata.type": "#Event.v1_0_0.Event",
+		"Id":          "MigrationHealthCheck",
+		"Name":        "Subscription migration health check",
+		"Events": []map[string]interface{}{{
+			"EventId":        fmt.Sprintf("MigrationHealthCheck-%d", time.Now().UnixNano()),
+			"EventTimestamp": time.Now().UTC().Format(time.RFC3339),
+			"EventType":      "Alert",
+			"MessageId":      "Base.1.0.Success",
+			"Message":        "Subscription migration health check",
+			"Severity":       "OK",
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, destination, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := newServerHTTPClient(server).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("destination answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const oldCollector = "https://10.0.0.100:8080"
+
+// A destination answering health checks with the status, counting them
+func newMigrationDestination(t *testing.T, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var checks atomic.Int32
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks.Add(1)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(destination.Close)
+	return destination, &checks
+}
+
+func TestMigrateSubscriptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		failCreate  bool
+		dryRun      bool
+		wantErr     bool
+		wantChecks  int32
+		wantMigrate bool
+	}{
+		{"migrated", http.StatusNoContent, false, false, false, 1, true},
+		{"dry run", http.StatusNoContent, false, true, false, 0, false},
+		{"destination fails the health check", http.StatusBadGateway, false, false, true, 1, false},
+		{"create fails", http.StatusNoContent, true, false, true, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destination, checks := newMigrationDestination(t, tt.status)
+			bmc := newFakeBMC(t)
+			oldURI := bmc.addSubscription(map[string]interface{}{
+				"Destination": oldCollector + "/events",
+				"Protocol":    "Redfish",
+				"Context":     "scrapefish",
+				"EventTypes":  []string{"Alert"},
+			})
+			otherURI := bmc.addSubscription(map[string]interface{}{"Destination": "https://10.0.0.200/events", "Protocol": "Redfish"})
+			if tt.failCreate {
+				bmc.failures["POST /redfish/v1/EventService/Subscriptions"] = http.StatusInternalServerError
+			}
+			var opts []MigrateOption
+			if tt.dryRun {
+				opts = append(opts, DryRunMigrate())
+			}
+
+			result, err := MigrateSubscriptions(context.Background(), []RedfishServer{bmc.server()}, oldCollector, destination.URL, opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MigrateSubscriptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(result.Migrated) != 1 || result.Migrated[0].OldURI != oldURI || result.Migrated[0].NewDestination != destination.URL+"/events" {
+				t.Fatalf("migrated = %+v, want the subscription to %s", result.Migrated, oldCollector)
+			}
+			if got := checks.Load(); got != tt.wantChecks {
+				t.Errorf("%d health checks, want %d", got, tt.wantChecks)
+			}
+			if bmc.subscription(otherURI) == nil {
+				t.Error("subscription to another destination removed")
+			}
+
+			newURI := result.Migrated[0].NewURI
+			if !tt.wantMigrate {
+				if newURI != "" || bmc.subscriptionCount() != 2 || bmc.subscription(oldURI) == nil {
+					t.Errorf("new URI %q with %d subscriptions, want the old subscription kept alone", newURI, bmc.subscriptionCount())
+				}
+				return
+			}
+			if bmc.subscription(oldURI) != nil {
+				t.Error("old subscription not deleted")
+			}
+			migrated := bmc.subscription(newURI)
+			if migrated["Destination"] != destination.URL+"/events" || migrated["Context"] != "scrapefish" {
+				t.Errorf("new subscription = %v, want the old settings with the new destination", migrated)
+			}
+		})
+	}
+}
+
+func TestMigrateSubscriptionsInvalidPrefixes(t *testing.T) {
+	bmc := newFakeBMC(t)
+	for _, prefixes := range [][2]string{{"", "https://10.0.0.101"}, {oldCollector, ""}} {
+		if _, err := MigrateSubscriptions(context.Background(), []RedfishServer{bmc.server()}, prefixes[0], prefixes[1]); err == nil {
+			t.Errorf("MigrateSubscriptions(%q, %q) succeeded, want an error", prefixes[0], prefixes[1])
+		}
+	}
+	if requests := bmc.requestLog(); len(requests) != 0 {
+		t.Errorf("requests = %v, want none", requests)
+	}
+}
+
+func TestMigrateSubscriptionsVerifier(t *testing.T) {
+	var servers []RedfishServer
+	for i := 0; i < 2; i++ {
+		bmc := newFakeBMC(t)
+		bmc.addSubscription(map[string]interface{}{"Destination": oldCollector + "/a", "Protocol": "Redfish", "Context": "scrapefish", "EventTypes": []string{"Alert"}})
+		servers = append(servers, bmc.server())
+	}
+	var verified []string
+	verifier := WithMigrateVerifier(func(ctx context.Context, server RedfishServer, destination string) error {
+		verified = append(verified, destination)
+		return nil
+	})
+
+	result, err := MigrateSubscriptions(context.Background(), servers, oldCollector, "https://10.0.0.101", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both servers move to the same destination, it is checked once
+	if len(result.Migrated) != 2 || len(verified) != 1 || verified[0] != "https://10.0.0.101/a" {
+		t.Errorf("migrated %d subscriptions verifying %v, want 2 verifying the destination once", len(result.Migrated), verified)
+	}
+}
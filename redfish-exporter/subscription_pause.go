@@ -0,0 +1,158 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+// Returned when a subscription's Status.State can't be changed, because the
+// BMC doesn't report it or rejects the PATCH
+var errNoDisableFlag = errors.New("subscription can't be disabled")
+
+// Pause or resume the delivery of a subscription of the reconciler, e.g.
+// during a maintenance window, without losing its configuration. Where the
+// BMC supports it the subscription's Status.State is patched, otherwise it
+// is deleted on pause and recreated from the payload on resume, with a new
+// URI. Paused subscriptions are neither reconciled nor refreshed.
+func (r *Reconciler) SetSubscriptionEnabled(server, uri string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish()
+	if r.closed {
+		return ErrReconcilerClosed
+	}
+
+	redfishServer := getServerInfo(r.servers, server)
+	if redfishServer.IP == "" {
+		return fmt.Errorf("server %s is not configured", server)
+	}
+	subscriptions := r.subscriptionMap[redfishServer.ID()]
+	var destination string
+	var subscription *SubscriptionRecord
+	for subscriptionDestination, record := range subscriptions {
+		if record.URI == uri {
+			destination, subscription = subscriptionDestination, record
+		}
+	}
+	if subscription == nil {
+		return fmt.Errorf("%w: %s on server %s", ErrSubscriptionNotFound, uri, server)
+	}
+	if subscription.Paused != enabled {
+		return nil
+	}
+
+	unlock := redfishServer.settings().locks.Lock(redfishServer.IP)
+	defer unlock()
+
+	// Replaced rather than changed, copies of the map share the records
+	updated := *subscription
+	switch {
+	case enabled && subscription.Deleted:
+		created, err := recreatePausedSubscription(redfishServer, r.payload, destination)
+		if err != nil {
+			return err
+		}
+		subscriptions[destination] = created
+		log.Printf("Resumed subscription %s on server %s by recreating it, now %s", uri, redfishServer.IP, created.URI)
+		return nil
+	case enabled:
+		if err := setSubscriptionState(redfishServer, uri, common.EnabledState); err != nil {
+			return err
+		}
+		updated.Paused = false
+		log.Printf("Resumed subscription %s on server %s", uri, redfishServer.IP)
+	default:
+		err := setSubscriptionState(redfishServer, uri, common.DisabledState)
+		if errors.Is(err, errNoDisableFlag) {
+			log.Printf("Subscription %s on server %s can't be disabled, deleting it until it's resumed", uri, redfishServer.IP)
+			err = deleteSubscriptionFromServer(redfishServer, uri)
+			updated.Deleted = true
+		}
+		if err != nil {
+			return err
+		}
+		updated.Paused = true
+		log.Printf("Paused subscription %s on server %s", uri, redfishServer.IP)
+	}
+	subscriptions[destination] = &updated
+	return nil
+}
+
+// Patch the Status.State of a subscription, returning errNoDisableFlag when
+// the subscription has no Status or the BMC rejects the change
+func setSubscriptionState(server RedfishServer, uri string, state common.State) error {
+	c, err := getRedfishClient(server)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	resp, err := c.Get(uri)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription %s on server %s: %w", uri, server.IP, normalizeRedfishError(err))
+	}
+	var subscription struct {
+		Status *common.Status
+	}
+	err = json.NewDecoder(resp.Body).Decode(&subscription)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode subscription %s on server %s: %v", uri, server.IP, err)
+	}
+	if subscription.Status == nil {
+		return errNoDisableFlag
+	}
+
+	patchResp, err := c.Patch(uri, map[string]interface{}{
+		"Status": map[string]common.State{"State": state},
+	})
+	if err != nil {
+		if isRejectedStatus(redfishStatusCode(err)) {
+			return errNoDisableFlag
+		}
+		return fmt.Errorf("failed to set the state of subscription %s on server %s: %w", uri, server.IP, normalizeRedfishError(err))
+	}
+	patchResp.Body.Close()
+	return nil
+}
+
+// Recreate a subscription deleted to pause it, from the server's payload
+// for its destination
+func recreatePausedSubscription(server RedfishServer, payload SubscriptionPayload, destination string) (*SubscriptionRecord, error) {
+	serverPayload, err := RenderPayload(payload, server)
+	if err != nil {
+		return nil, err
+	}
+	serverPayload = applyDeliveryPolicy(server, serverPayload)
+	for _, destinationPayload := range splitDestinations(serverPayload) {
+		if destinationPayload.Destination != destination {
+			continue
+		}
+		created, err := createSubscription(server, destinationPayload)
+		if err != nil {
+			return nil, err
+		}
+		return created[destination], nil
+	}
+	return nil, fmt.Errorf("destination %s is no longer in the payload of server %s", destination, server.IP)
+}
@@ -0,0 +1,95 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func TestSetSubscriptionEnabled(t *testing.T) {
+	tests := []struct {
+		name               string
+		subscriptionStatus bool
+		patchStatus        int
+		// Whether the subscription is deleted while paused
+		wantDeleted bool
+	}{
+		{"state patched", true, 0, false},
+		{"no Status on the subscription", false, 0, true},
+		{"state change rejected", true, http.StatusMethodNotAllowed, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.subscriptionStatus = tt.subscriptionStatus
+			bmc.patchStatus = tt.patchStatus
+			server := bmc.server()
+			payload := SubscriptionPayload{Destination: "https://10.0.0.100:8080", Protocol: "Redfish", EventTypes: []redfish.EventType{redfish.AlertEventType}, Context: "scrapefish"}
+			reconciler := NewReconciler([]RedfishServer{server}, payload, make(map[string]ServerSubscriptions))
+			if _, err := reconciler.ReconcileServer(server, payload); err != nil {
+				t.Fatal(err)
+			}
+			uri := reconciler.SubscriptionsOf(server.ID())[payload.Destination].URI
+
+			if err := reconciler.SetSubscriptionEnabled(server.IP, uri, false); err != nil {
+				t.Fatalf("pausing: %v", err)
+			}
+			paused := reconciler.SubscriptionsOf(server.ID())[payload.Destination]
+			if !paused.Paused || paused.Deleted != tt.wantDeleted {
+				t.Errorf("paused subscription %+v, want Deleted %v", paused, tt.wantDeleted)
+			}
+			onBMC := bmc.subscription(uri)
+			if tt.wantDeleted && onBMC != nil {
+				t.Error("subscription still on the BMC, want it deleted while paused")
+			}
+			if !tt.wantDeleted && (onBMC == nil || onBMC["Status"].(map[string]interface{})["State"] != "Disabled") {
+				t.Errorf("subscription on the BMC %v, want it disabled", onBMC)
+			}
+			// Pausing twice changes nothing
+			if err := reconciler.SetSubscriptionEnabled(server.IP, uri, false); err != nil {
+				t.Errorf("pausing again: %v", err)
+			}
+
+			if err := reconciler.SetSubscriptionEnabled(server.IP, uri, true); err != nil {
+				t.Fatalf("resuming: %v", err)
+			}
+			resumed := reconciler.SubscriptionsOf(server.ID())[payload.Destination]
+			if resumed.Paused || resumed.Deleted || bmc.subscription(resumed.URI) == nil {
+				t.Errorf("resumed subscription %+v, want it active on the BMC", resumed)
+			}
+			if (resumed.URI != uri) != tt.wantDeleted {
+				t.Errorf("resumed subscription %s, paused one %s, want a new URI only when recreated", resumed.URI, uri)
+			}
+		})
+	}
+}
+
+func TestSetSubscriptionEnabledUnknownSubscription(t *testing.T) {
+	bmc := newFakeBMC(t)
+	reconciler := NewReconciler([]RedfishServer{bmc.server()}, SubscriptionPayload{}, make(map[string]ServerSubscriptions))
+	err := reconciler.SetSubscriptionEnabled(bmc.URL, "/redfish/v1/EventService/Subscriptions/9", false)
+	if !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("SetSubscriptionEnabled() error = %v, want %v", err, ErrSubscriptionNotFound)
+	}
+	if err := reconciler.SetSubscriptionEnabled("https://10.0.0.99", "/redfish/v1/EventService/Subscriptions/9", false); err == nil {
+		t.Error("SetSubscriptionEnabled() error = nil for an unknown server")
+	}
+}
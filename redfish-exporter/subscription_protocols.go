@@ -0,0 +1,256 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// SNMPDestinationSettings are sent as the SNMP property of SNMP subscriptions
+type SNMPDestinationSettings struct {
+	// Community string of SNMPv1 and SNMPv2c traps
+	TrapCommunity string `json:"TrapCommunity,omitempty"`
+	// SNMPv3 user security
+	AuthenticationProtocol redfish.SNMPAuthenticationProtocols `json:"AuthenticationProtocol,omitempty"`
+	AuthenticationKey      string                              `json:"AuthenticationKey,omitempty"`
+	EncryptionProtocol     redfish.SNMPEncryptionProtocols     `json:"EncryptionProtocol,omitempty"`
+	EncryptionKey          string                              `json:"EncryptionKey,omitempty"`
+}
+
+// SyslogDestinationFilter selects the syslog messages forwarded by Syslog
+// subscriptions, all facilities and severities when empty
+type SyslogDestinationFilter struct {
+	LogFacilities  []redfish.SyslogFacility `json:"LogFacilities,omitempty"`
+	LowestSeverity redfish.SyslogSeverity   `json:"LowestSeverity,omitempty"`
+}
+
+var syslogFacilities = map[redfish.SyslogFacility]bool{
+	"Kern": true, "User": true, "Mail": true, "Daemon": true, "Auth": true, "Syslog": true,
+	"LPR": true, "News": true, "UUCP": true, "Cron": true, "Authpriv": true, "FTP": true,
+	"NTP": true, "Security": true, "Console": true, "SolarisCron": true,
+	"Local0": true, "Local1": true, "Local2": true, "Local3": true,
+	"Local4": true, "Local5": true, "Local6": true, "Local7": true,
+}
+
+var syslogSeverities = map[redfish.SyslogSeverity]bool{
+	"Emergency": true, "Alert": true, "Critical": true, "Error": true, "Warning": true,
+	"Notice": true, "Informational": true, "Debug": true, "All": true,
+}
+
+func isSNMPProtocol(protocol redfish.EventDestinationProtocol) bool {
+	switch protocol {
+	case redfish.SNMPv1EventDestinationProtocol, redfish.SNMPv2cEventDestinationProtocol, redfish.SNMPv3EventDestinationProtocol:
+		return true
+	}
+	return false
+}
+
+func isSyslogProtocol(protocol redfish.EventDestinationProtocol) bool {
+	switch protocol {
+	case redfish.SyslogTLSEventDestinationProtocol, redfish.SyslogTCPEventDestinationProtocol,
+		redfish.SyslogUDPEventDestinationProtocol, redfish.SyslogRELPEventDestinationProtocol:
+		return true
+	}
+	return false
+}
+
+func isRedfishProtocol(protocol redfish.EventDestinationProtocol) bool {
+	return protocol == "" || protocol == redfish.RedfishEventDestinationProtocol
+}
+
+// The Destination URL schemes accepted for a protocol
+func destinationSchemes(protocol redfish.EventDestinationProtocol) []string {
+	switch {
+	case isSNMPProtocol(protocol):
+		return []string{"snmp"}
+	case isSyslogProtocol(protocol):
+		return []string{"syslog"}
+	default:
+		return []string{"http", "https"}
+	}
+}
+
+// Check the settings that go with the payload's Protocol
+func validateProtocolSettings(payload SubscriptionPayload) error {
+	protocol := payload.Protocol
+	switch {
+	case isSNMPProtocol(protocol):
+		if len(payload.SyslogFilters) > 0 {
+			return fmt.Errorf("SyslogFilters can't be set for %s subscriptions", protocol)
+		}
+		return validateSNMPSettings(protocol, payload.SubscriptionType, payload.SNMP)
+
+	case isSyslogProtocol(protocol):
+		if payload.SNMP != nil {
+			return fmt.Errorf("SNMP can't be set for %s subscriptions", protocol)
+		}
+		if payload.SubscriptionType != "" && payload.SubscriptionType != redfish.SyslogSubscriptionType {
+			return fmt.Errorf("SubscriptionType %s can't be used with %s", payload.SubscriptionType, protocol)
+		}
+		for _, filter := range payload.SyslogFilters {
+			for _, facility := range filter.LogFacilities {
+				if !syslogFacilities[facility] {
+					return fmt.Errorf("unknown syslog facility %q", facility)
+				}
+			}
+			if filter.LowestSeverity != "" && !syslogSeverities[filter.LowestSeverity] {
+				return fmt.Errorf("unknown syslog severity %q", filter.LowestSeverity)
+			}
+		}
+		return nil
+
+	case isRedfishProtocol(protocol):
+		if payload.SNMP != nil || len(payload.SyslogFilters) > 0 {
+			return errors.New("SNMP and SyslogFilters can only be set for SNMP and Syslog subscriptions")
+		}
+		if payload.SubscriptionType != "" && payload.SubscriptionType != redfish.RedfishEventSubscriptionType {
+			return fmt.Errorf("SubscriptionType %s can't be used with the Redfish protocol", payload.SubscriptionType)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported subscription Protocol %q", protocol)
+	}
+}
+
+func validateSNMPSettings(protocol redfish.EventDestinationProtocol, subscriptionType redfish.SubscriptionType, snmp *SNMPDestinationSettings) error {
+	switch subscriptionType {
+	case "", redfish.SNMPTrapSubscriptionType:
+	case redfish.SNMPInformSubscriptionType:
+		if protocol == redfish.SNMPv1EventDestinationProtocol {
+			return errors.New("SNMPv1 doesn't support SNMPInform subscriptions")
+		}
+	default:
+		return fmt.Errorf("SubscriptionType %s can't be used with %s", subscriptionType, protocol)
+	}
+
+	if snmp == nil {
+		return fmt.Errorf("%s subscriptions require the SNMP settings", protocol)
+	}
+	if protocol != redfish.SNMPv3EventDestinationProtocol {
+		if snmp.TrapCommunity == "" {
+			return fmt.Errorf("%s subscriptions require an SNMP TrapCommunity", protocol)
+		}
+		return nil
+	}
+
+	if snmp.TrapCommunity != "" {
+		return errors.New("SNMPv3 subscriptions use AuthenticationProtocol instead of TrapCommunity")
+	}
+	switch snmp.AuthenticationProtocol {
+	case "", redfish.CommunityStringSNMPAuthenticationProtocols:
+		return errors.New("SNMPv3 subscriptions require an SNMP AuthenticationProtocol")
+	case redfish.NoneSNMPAuthenticationProtocols:
+	default:
+		if snmp.AuthenticationKey == "" {
+			return fmt.Errorf("SNMP AuthenticationProtocol %s requires an AuthenticationKey", snmp.AuthenticationProtocol)
+		}
+	}
+	if snmp.EncryptionProtocol != "" && snmp.EncryptionProtocol != redfish.NoneSNMPEncryptionProtocols {
+		if snmp.AuthenticationProtocol == redfish.NoneSNMPAuthenticationProtocols {
+			return errors.New("SNMP encryption requires authentication")
+		}
+		if snmp.EncryptionKey == "" {
+			return fmt.Errorf("SNMP EncryptionProtocol %s requires an EncryptionKey", snmp.EncryptionProtocol)
+		}
+	}
+	return nil
+}
+
+// The SubscriptionType sent for the payload, the protocol's default when not set
+func subscriptionTypeFor(payload SubscriptionPayload) redfish.SubscriptionType {
+	if payload.SubscriptionType != "" {
+		return payload.SubscriptionType
+	}
+	switch {
+	case isSNMPProtocol(payload.Protocol):
+		return redfish.SNMPTrapSubscriptionType
+	case isSyslogProtocol(payload.Protocol):
+		return redfish.SyslogSubscriptionType
+	default:
+		return ""
+	}
+}
+
+// The parts of an event service advertising its destination protocols,
+// which gofish doesn't decode
+type advertisedProtocols struct {
+	// Explicit lists of the protocols, authoritative when present
+	SupportedProtocols []redfish.EventDestinationProtocol `json:"SupportedProtocols"`
+	AllowableProtocols []redfish.EventDestinationProtocol `json:"Protocol@Redfish.AllowableValues"`
+	// Present on services sending SNMP traps, versions whose flag is false
+	// are turned off
+	SNMP *struct {
+		EnableSNMPv1Trap  *bool
+		EnableSNMPv2cTrap *bool
+		EnableSNMPv3Trap  *bool
+	} `json:"SNMP"`
+}
+
+// The destination protocols the event service advertises. Redfish is always
+// supported. Without an explicit list, SNMP is supported by services with
+// SNMP settings, and Syslog isn't since it can't be advertised otherwise.
+func supportedProtocols(eventService *redfish.EventService) []redfish.EventDestinationProtocol {
+	protocols := []redfish.EventDestinationProtocol{redfish.RedfishEventDestinationProtocol}
+	var advertised advertisedProtocols
+	if len(eventService.RawData) > 0 {
+		if err := json.Unmarshal(eventService.RawData, &advertised); err != nil {
+			return protocols
+		}
+	}
+
+	listed := slices.Concat(advertised.SupportedProtocols, advertised.AllowableProtocols)
+	if len(listed) > 0 {
+		for _, protocol := range listed {
+			if !slices.Contains(protocols, protocol) {
+				protocols = append(protocols, protocol)
+			}
+		}
+		return protocols
+	}
+
+	if snmp := advertised.SNMP; snmp != nil {
+		enabled := func(flag *bool) bool { return flag == nil || *flag }
+		if enabled(snmp.EnableSNMPv1Trap) {
+			protocols = append(protocols, redfish.SNMPv1EventDestinationProtocol)
+		}
+		if enabled(snmp.EnableSNMPv2cTrap) {
+			protocols = append(protocols, redfish.SNMPv2cEventDestinationProtocol)
+		}
+		if enabled(snmp.EnableSNMPv3Trap) {
+			protocols = append(protocols, redfish.SNMPv3EventDestinationProtocol)
+		}
+	}
+	return protocols
+}
+
+// Check that the event service advertises support for the protocol, see
+// supportedProtocols
+func checkProtocolSupported(eventService *redfish.EventService, protocol redfish.EventDestinationProtocol) error {
+	if isRedfishProtocol(protocol) {
+		return nil
+	}
+	if !slices.Contains(supportedProtocols(eventService), protocol) {
+		return fmt.Errorf("the event service doesn't advertise support for %s subscriptions", protocol)
+	}
+	return nil
+}
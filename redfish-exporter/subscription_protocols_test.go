@@ -0,0 +1,180 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func TestValidateProtocolSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload SubscriptionPayload
+		wantErr string
+	}{
+		{
+			name: "syslog with filters",
+			payload: SubscriptionPayload{
+				Protocol: redfish.SyslogUDPEventDestinationProtocol,
+				SyslogFilters: []SyslogDestinationFilter{
+					{LogFacilities: []redfish.SyslogFacility{"Daemon", "Local0"}, LowestSeverity: "Warning"},
+				},
+			},
+		},
+		{
+			name: "syslog with unknown facility",
+			payload: SubscriptionPayload{
+				Protocol:      redfish.SyslogTCPEventDestinationProtocol,
+				SyslogFilters: []SyslogDestinationFilter{{LogFacilities: []redfish.SyslogFacility{"Kernel"}}},
+			},
+			wantErr: "unknown syslog facility",
+		},
+		{
+			name:    "syslog with SNMP settings",
+			payload: SubscriptionPayload{Protocol: redfish.SyslogUDPEventDestinationProtocol, SNMP: &SNMPDestinationSettings{TrapCommunity: "public"}},
+			wantErr: "SNMP can't be set",
+		},
+		{
+			name:    "SNMPv2c with community",
+			payload: SubscriptionPayload{Protocol: redfish.SNMPv2cEventDestinationProtocol, SNMP: &SNMPDestinationSettings{TrapCommunity: "public"}},
+		},
+		{
+			name:    "SNMPv2c without community",
+			payload: SubscriptionPayload{Protocol: redfish.SNMPv2cEventDestinationProtocol, SNMP: &SNMPDestinationSettings{}},
+			wantErr: "TrapCommunity",
+		},
+		{
+			name:    "SNMP without settings",
+			payload: SubscriptionPayload{Protocol: redfish.SNMPv1EventDestinationProtocol},
+			wantErr: "require the SNMP settings",
+		},
+		{
+			name: "SNMPv3 with authentication and encryption",
+			payload: SubscriptionPayload{Protocol: redfish.SNMPv3EventDestinationProtocol, SNMP: &SNMPDestinationSettings{
+				AuthenticationProtocol: redfish.HMACSHA96SNMPAuthenticationProtocols, AuthenticationKey: "auth-key",
+				EncryptionProtocol: redfish.CBCDESSNMPEncryptionProtocols, EncryptionKey: "priv-key",
+			}},
+		},
+		{
+			name: "SNMPv3 encryption without key",
+			payload: SubscriptionPayload{Protocol: redfish.SNMPv3EventDestinationProtocol, SNMP: &SNMPDestinationSettings{
+				AuthenticationProtocol: redfish.HMACSHA96SNMPAuthenticationProtocols, AuthenticationKey: "auth-key",
+				EncryptionProtocol: redfish.CBCDESSNMPEncryptionProtocols,
+			}},
+			wantErr: "requires an EncryptionKey",
+		},
+		{
+			name:    "SNMPv1 inform",
+			payload: SubscriptionPayload{Protocol: redfish.SNMPv1EventDestinationProtocol, SubscriptionType: redfish.SNMPInformSubscriptionType, SNMP: &SNMPDestinationSettings{TrapCommunity: "public"}},
+			wantErr: "SNMPv1 doesn't support SNMPInform",
+		},
+		{
+			name:    "redfish with syslog filters",
+			payload: SubscriptionPayload{Protocol: redfish.RedfishEventDestinationProtocol, SyslogFilters: []SyslogDestinationFilter{{LowestSeverity: "All"}}},
+			wantErr: "can only be set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateProtocolSettings(tt.payload)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateProtocolSettings() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateProtocolSettings() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateSubscriptionChecksAdvertisedProtocols(t *testing.T) {
+	syslog := SubscriptionPayload{
+		Destination: "syslog://10.0.0.9:514",
+		Protocol:    redfish.SyslogUDPEventDestinationProtocol,
+		Context:     "scrapefish",
+	}
+	snmp := SubscriptionPayload{
+		Destination: "snmp://10.0.0.9:162",
+		Protocol:    redfish.SNMPv2cEventDestinationProtocol,
+		Context:     "scrapefish",
+		SNMP:        &SNMPDestinationSettings{TrapCommunity: "public"},
+	}
+
+	tests := []struct {
+		name         string
+		eventService map[string]interface{}
+		payload      SubscriptionPayload
+		wantCreated  bool
+	}{
+		{"syslog not advertised", nil, syslog, false},
+		{"syslog in SupportedProtocols", map[string]interface{}{"SupportedProtocols": []string{"SyslogUDP"}}, syslog, true},
+		{"syslog in allowable values", map[string]interface{}{"Protocol@Redfish.AllowableValues": []string{"Redfish", "SyslogUDP"}}, syslog, true},
+		{"SNMP not advertised", nil, snmp, false},
+		{"SNMP settings", map[string]interface{}{"SNMP": map[string]interface{}{}}, snmp, true},
+		{"SNMP version turned off", map[string]interface{}{"SNMP": map[string]interface{}{"EnableSNMPv2cTrap": false}}, snmp, false},
+		{"explicit list without SNMP", map[string]interface{}{"SupportedProtocols": []string{"SyslogUDP"}, "SNMP": map[string]interface{}{}}, snmp, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newFakeBMC(t)
+			for key, value := range tt.eventService {
+				b.eventService[key] = value
+			}
+			_, err := createSubscription(b.server(), tt.payload)
+			if tt.wantCreated {
+				if err != nil || b.subscriptionCount() != 1 {
+					t.Errorf("createSubscription() error = %v with %d subscriptions, want one created", err, b.subscriptionCount())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "doesn't advertise support") {
+				t.Errorf("createSubscription() error = %v, want the protocol rejected", err)
+			}
+			if b.subscriptionCount() != 0 {
+				t.Errorf("%d subscriptions created for an unsupported protocol", b.subscriptionCount())
+			}
+		})
+	}
+}
+
+func TestEventServiceCapabilitiesProtocols(t *testing.T) {
+	eventService := &redfish.EventService{
+		ServerSentEventURI: "/redfish/v1/EventService/SSE",
+		SSEFilterPropertiesSupported: redfish.SSEFilterPropertiesSupported{
+			MessageID:      true,
+			RegistryPrefix: true,
+		},
+	}
+	eventService.RawData = []byte(`{"SNMP": {"EnableSNMPv1Trap": false}}`)
+
+	capabilities := eventServiceCapabilities("1.15.0", eventService)
+	if !capabilities.SupportsSNMP || capabilities.SupportsSyslog {
+		t.Errorf("capabilities SNMP %v, Syslog %v, want SNMP only", capabilities.SupportsSNMP, capabilities.SupportsSyslog)
+	}
+	if got := strings.Join(capabilities.Protocols, ","); got != "Redfish,SNMPv2c,SNMPv3" {
+		t.Errorf("capabilities.Protocols = %s, want Redfish,SNMPv2c,SNMPv3", got)
+	}
+	if got := strings.Join(capabilities.SSEFilterProperties, ","); got != "MessageId,RegistryPrefix" {
+		t.Errorf("capabilities.SSEFilterProperties = %s, want MessageId,RegistryPrefix", got)
+	}
+}
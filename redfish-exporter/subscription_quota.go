@@ -0,0 +1,149 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// Returned, wrapped in a SubscriptionQuotaError, when a server has no room
+// for another subscription
+var ErrSubscriptionQuotaExceeded = errors.New("subscription quota exceeded")
+
+// SubscriptionQuotaError is returned instead of creating a subscription the
+// BMC would reject for having too many
+type SubscriptionQuotaError struct {
+	Server  string
+	Max     int
+	Current int
+}
+
+func (e *SubscriptionQuotaError) Error() string {
+	return fmt.Sprintf("%v on server %s: %d of %d subscriptions in use", ErrSubscriptionQuotaExceeded, e.Server, e.Current, e.Max)
+}
+
+func (e *SubscriptionQuotaError) Unwrap() error {
+	return ErrSubscriptionQuotaExceeded
+}
+
+// QuotaManager knows how many subscriptions each server takes, from the
+// MaxNumberOfSubscriptions its event service announces. The limit is read
+// once per server.
+type QuotaManager struct {
+	// Limit by server ID, 0 when the server announces none
+	limits sync.Map
+}
+
+func NewQuotaManager() *QuotaManager {
+	return &QuotaManager{}
+}
+
+// Quotas checked before every subscription is created
+var subscriptionQuotas = NewQuotaManager()
+
+// Get the server's subscription limit, 0 when it announces none
+func (m *QuotaManager) limit(server RedfishServer, eventService *redfish.EventService) (int, error) {
+	if limit, ok := m.limits.Load(server.ID()); ok {
+		return limit.(int), nil
+	}
+	properties, err := getRawProperties(eventService.GetClient(), eventService.ODataID)
+	if err != nil {
+		return 0, err
+	}
+	var limit int
+	if raw, ok := properties["MaxNumberOfSubscriptions"]; ok {
+		if err := json.Unmarshal(raw, &limit); err != nil {
+			return 0, fmt.Errorf("invalid MaxNumberOfSubscriptions: %v", err)
+		}
+	}
+	m.limits.Store(server.ID(), limit)
+	return limit, nil
+}
+
+// Count the subscriptions on the server, leaving out the ones to destination
+// that are replaced when subscribing it again
+func (m *QuotaManager) usage(eventService *redfish.EventService, destination string) (int, error) {
+	subscriptions, err := eventService.GetEventSubscriptions()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, subscription := range subscriptions {
+		if destination == "" || subscription.Destination != destination {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Check that the server has room for a subscription to destination
+func (m *QuotaManager) check(server RedfishServer, eventService *redfish.EventService, destination string) error {
+	limit, err := m.limit(server, eventService)
+	if err != nil {
+		return fmt.Errorf("failed to read the subscription limit of server %s: %w", server.IP, err)
+	}
+	if limit <= 0 {
+		return nil
+	}
+	current, err := m.usage(eventService, destination)
+	if err != nil {
+		return fmt.Errorf("failed to count the subscriptions on server %s: %w", server.IP, err)
+	}
+	if current >= limit {
+		return &SubscriptionQuotaError{Server: server.IP, Max: limit, Current: current}
+	}
+	return nil
+}
+
+// Get the number of subscriptions the server still takes, -1 when it
+// announces no limit
+func (m *QuotaManager) FreeSlots(ctx context.Context, server RedfishServer) (int, error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	eventService, err := getEventService(c)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get event service on server %s: %w", server.IP, err)
+	}
+	limit, err := m.limit(server, eventService)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the subscription limit of server %s: %w", server.IP, err)
+	}
+	if limit <= 0 {
+		return -1, nil
+	}
+	current, err := m.usage(eventService, "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to count the subscriptions on server %s: %w", server.IP, err)
+	}
+	return max(limit-current, 0), nil
+}
+
+// Get the number of subscriptions the server still takes, -1 when it
+// announces no limit
+func FreeSlots(ctx context.Context, server RedfishServer) (int, error) {
+	return subscriptionQuotas.FreeSlots(ctx, server)
+}
@@ -0,0 +1,73 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCreateSubscriptionConflictsAndQuota(t *testing.T) {
+	const destination = "https://10.0.0.9:8080"
+	payload := SubscriptionPayload{Destination: destination, Protocol: "Redfish", OriginResources: []string{"/redfish/v1/Systems/1"}}
+
+	tests := []struct {
+		name string
+		// Destinations of the subscriptions on the BMC before subscribing
+		existing []string
+		limit    int
+		// The DELETE of the first existing subscription fails with this status
+		deleteStatus int
+		wantErr      error
+		wantCount    int
+	}{
+		{name: "no limit", existing: []string{"https://10.0.0.1"}, wantCount: 2},
+		{name: "conflict replaced on a full BMC", existing: []string{destination}, limit: 1, wantCount: 1},
+		{name: "full BMC", existing: []string{"https://10.0.0.1"}, limit: 1, wantErr: ErrSubscriptionQuotaExceeded, wantCount: 1},
+		{name: "conflict not deleted", existing: []string{destination}, deleteStatus: http.StatusInternalServerError, wantErr: errAny, wantCount: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			if tt.limit > 0 {
+				bmc.eventService["MaxNumberOfSubscriptions"] = tt.limit
+			}
+			for i, existing := range tt.existing {
+				uri := bmc.addSubscription(map[string]interface{}{"Destination": existing, "Protocol": "Redfish"})
+				if i == 0 && tt.deleteStatus != 0 {
+					bmc.failures["DELETE "+uri] = tt.deleteStatus
+				}
+			}
+
+			_, err := createSubscription(bmc.server(), payload)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("createSubscription() error = %v", err)
+			case tt.wantErr == errAny && err == nil, tt.wantErr != nil && tt.wantErr != errAny && !errors.Is(err, tt.wantErr):
+				t.Fatalf("createSubscription() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := bmc.subscriptionCount(); got != tt.wantCount {
+				t.Errorf("BMC has %d subscriptions, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+// Stands for any error in the tables
+var errAny = errors.New("any error")
@@ -0,0 +1,139 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Events buffered by an EventReceiver, more are dropped until it is read
+const eventReceiverBuffer = 64
+
+// Returned by SmokeTest when the test event doesn't arrive through the
+// server's subscription within the timeout
+var ErrSmokeTestTimeout = errors.New("smoke test event not received")
+
+// ReceivedEvent is an event received by the listener, with the Context of
+// the payload it came in
+type ReceivedEvent struct {
+	ServerIP string
+	Context  string
+	Event    Event
+}
+
+// EventReceiver receives the events that arrive at the listeners of this
+// process while it is open, for checking the subscriptions created with
+// its payload
+type EventReceiver struct {
+	payload SubscriptionPayload
+	events  chan ReceivedEvent
+}
+
+// Receivers fed by all listeners of this process
+var eventReceivers = struct {
+	mu        sync.Mutex
+	receivers map[*EventReceiver]struct{}
+}{receivers: make(map[*EventReceiver]struct{})}
+
+// Open a receiver for the events of the subscriptions created with the
+// payload. Close it when done.
+func NewEventReceiver(payload SubscriptionPayload) *EventReceiver {
+	r := &EventReceiver{payload: payload, events: make(chan ReceivedEvent, eventReceiverBuffer)}
+	eventReceivers.mu.Lock()
+	eventReceivers.receivers[r] = struct{}{}
+	eventReceivers.mu.Unlock()
+	return r
+}
+
+// The events received since the receiver was opened
+func (r *EventReceiver) Events() <-chan ReceivedEvent {
+	return r.events
+}
+
+// Stop receiving events
+func (r *EventReceiver) Close() {
+	eventReceivers.mu.Lock()
+	delete(eventReceivers.receivers, r)
+	eventReceivers.mu.Unlock()
+}
+
+// Pass an event received by a listener to the open receivers, without
+// blocking the listener on a receiver that isn't read
+func deliverToReceivers(serverIP string, eventContext string, event Event) {
+	eventReceivers.mu.Lock()
+	defer eventReceivers.mu.Unlock()
+	for r := range eventReceivers.receivers {
+		select {
+		case r.events <- ReceivedEvent{ServerIP: serverIP, Context: eventContext, Event: event}:
+		default:
+		}
+	}
+}
+
+// Have the server send a test event and wait for it on the receiver,
+// proving the server delivers events through the subscription created
+// with the receiver's payload. The test event must come with the Context
+// of that subscription, copies sent through other subscriptions of the
+// server are ignored.
+func SmokeTest(ctx context.Context, server RedfishServer, receiver *EventReceiver, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultDeliveryTimeout
+	}
+	payload, err := RenderPayload(receiver.payload, server)
+	if err != nil {
+		return err
+	}
+
+	eventID := newTestEventID()
+	result, err := sendTestEvent(server, "Smoke test "+eventID, eventID)
+	if err != nil {
+		return fmt.Errorf("smoke test on server %s: %v", server.IP, err)
+	}
+	if !result.Accepted {
+		return fmt.Errorf("smoke test on server %s: test event rejected: %s", server.IP, result.Message)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var otherContext string
+	for {
+		select {
+		case received := <-receiver.Events():
+			// Some BMCs replace the EventId of test events, the Message
+			// carries it too
+			if received.Event.EventId != eventID && !strings.Contains(received.Event.Message, eventID) {
+				continue
+			}
+			if payload.Context == "" || received.Context == payload.Context {
+				return nil
+			}
+			otherContext = received.Context
+		case <-timer.C:
+			if otherContext != "" {
+				return fmt.Errorf("%w on server %s within %v, only with context %q instead of %q", ErrSmokeTestTimeout, server.IP, timeout, otherContext, payload.Context)
+			}
+			return fmt.Errorf("%w on server %s within %v", ErrSmokeTestTimeout, server.IP, timeout)
+		case <-ctx.Done():
+			return fmt.Errorf("smoke test on server %s: %w", server.IP, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSmokeTest(t *testing.T) {
+	payload := SubscriptionPayload{Destination: "https://10.0.0.100:8080", Context: "scrapefish-{{.UniqueToken}}"}
+	tests := []struct {
+		name string
+		// Context the test event is delivered with, not delivered when empty
+		deliveredContext func(server RedfishServer) string
+		// Deliver the event under another EventId, mentioning it in the Message
+		replaceEventId bool
+		actionStatus   int
+		wantErr        error
+		wantErrText    string
+	}{
+		{
+			name:             "delivered through the subscription",
+			deliveredContext: func(server RedfishServer) string { return "scrapefish-" + uniqueToken(server) },
+		},
+		{
+			name:             "EventId replaced by the BMC",
+			deliveredContext: func(server RedfishServer) string { return "scrapefish-" + uniqueToken(server) },
+			replaceEventId:   true,
+		},
+		{
+			name:             "delivered through another subscription",
+			deliveredContext: func(server RedfishServer) string { return "other-tool" },
+			wantErr:          ErrSmokeTestTimeout,
+			wantErrText:      "only with context \"other-tool\"",
+		},
+		{
+			name:    "not delivered",
+			wantErr: ErrSmokeTestTimeout,
+		},
+		{
+			name:         "test event rejected",
+			actionStatus: http.StatusBadRequest,
+			wantErrText:  "test event rejected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			server := bmc.server()
+			if tt.actionStatus != 0 {
+				bmc.failures["POST /redfish/v1/EventService/Actions/EventService.SubmitTestEvent"] = tt.actionStatus
+			}
+			if tt.deliveredContext != nil {
+				bmc.onTestEvent = func(event map[string]interface{}) {
+					delivered := Event{EventId: event["EventId"].(string), Message: event["Message"].(string)}
+					if tt.replaceEventId {
+						delivered.EventId = "1"
+					}
+					deliverToReceivers(server.IP, tt.deliveredContext(server), delivered)
+				}
+			}
+
+			receiver := NewEventReceiver(payload)
+			defer receiver.Close()
+			err := SmokeTest(context.Background(), server, receiver, 50*time.Millisecond)
+			if tt.wantErr == nil && tt.wantErrText == "" {
+				if err != nil {
+					t.Errorf("SmokeTest() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("SmokeTest() error = nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("SmokeTest() error = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErrText) {
+				t.Errorf("SmokeTest() error = %v, want it to say %s", err, tt.wantErrText)
+			}
+		})
+	}
+}
+
+func TestSmokeTestCanceled(t *testing.T) {
+	bmc := newFakeBMC(t)
+	receiver := NewEventReceiver(SubscriptionPayload{Destination: "https://10.0.0.100:8080"})
+	defer receiver.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bmc.onTestEvent = func(event map[string]interface{}) { cancel() }
+	if err := SmokeTest(ctx, bmc.server(), receiver, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("SmokeTest() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,179 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SubscriptionStats counts the subscription operations on a server over
+// the lifetime of the exporter
+type SubscriptionStats struct {
+	TotalCreated int `json:"totalCreated"`
+	TotalDeleted int `json:"totalDeleted"`
+	// Subscriptions created by the exporter and not deleted since
+	ActiveCount   int        `json:"activeCount"`
+	FailedCreates int        `json:"failedCreates"`
+	LastCreatedAt *time.Time `json:"lastCreatedAt,omitempty"`
+	LastDeletedAt *time.Time `json:"lastDeletedAt,omitempty"`
+	// Whether the server used the payload's PreferredID for the last
+	// subscription created, unset without a PreferredID
+	PreferredIDHonored *bool `json:"preferredIdHonored,omitempty"`
+}
+
+// StatsRegistry holds the subscription stats of all servers by server ID
+type StatsRegistry struct {
+	mu    sync.Mutex
+	stats map[string]*SubscriptionStats
+	// URIs of the subscriptions created and not deleted yet by server ID,
+	// so deleting other subscriptions leaves ActiveCount alone
+	active map[string]map[string]bool
+}
+
+func NewStatsRegistry() *StatsRegistry {
+	return &StatsRegistry{
+		stats:  make(map[string]*SubscriptionStats),
+		active: make(map[string]map[string]bool),
+	}
+}
+
+// Stats of the subscriptions created and deleted by the exporter
+var subscriptionStats = NewStatsRegistry()
+
+// Update the stats of a server, must be called with mu held
+func (r *StatsRegistry) update(serverID string, update func(*SubscriptionStats)) {
+	stats, ok := r.stats[serverID]
+	if !ok {
+		stats = &SubscriptionStats{}
+		r.stats[serverID] = stats
+	}
+	update(stats)
+}
+
+func (r *StatsRegistry) RecordCreated(serverID, subscriptionURI string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active[serverID] == nil {
+		r.active[serverID] = make(map[string]bool)
+	}
+	r.active[serverID][subscriptionURI] = true
+	r.update(serverID, func(stats *SubscriptionStats) {
+		now := time.Now()
+		stats.TotalCreated++
+		stats.ActiveCount = len(r.active[serverID])
+		stats.LastCreatedAt = &now
+	})
+}
+
+func (r *StatsRegistry) RecordPreferredID(serverID string, honored bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.update(serverID, func(stats *SubscriptionStats) {
+		stats.PreferredIDHonored = &honored
+	})
+}
+
+func (r *StatsRegistry) RecordFailedCreate(serverID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.update(serverID, func(stats *SubscriptionStats) {
+		stats.FailedCreates++
+	})
+}
+
+// Record a deleted subscription. Only subscriptions recorded as created
+// count down ActiveCount, not those of a previous run or of other tools
+// deleted as conflicts.
+func (r *StatsRegistry) RecordDeleted(serverID, subscriptionURI string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.active[serverID], subscriptionURI)
+	r.update(serverID, func(stats *SubscriptionStats) {
+		now := time.Now()
+		stats.TotalDeleted++
+		stats.ActiveCount = len(r.active[serverID])
+		stats.LastDeletedAt = &now
+	})
+}
+
+// Get a copy of the stats of a server, nil if nothing was recorded for it
+func (r *StatsRegistry) GetStats(serverID string) *SubscriptionStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.stats[serverID]
+	if !ok {
+		return nil
+	}
+	statsCopy := *stats
+	return &statsCopy
+}
+
+// Get a copy of the stats of all servers by server ID
+func (r *StatsRegistry) DumpAllStats() map[string]*SubscriptionStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	allStats := make(map[string]*SubscriptionStats, len(r.stats))
+	for serverID, stats := range r.stats {
+		statsCopy := *stats
+		allStats[serverID] = &statsCopy
+	}
+	return allStats
+}
+
+// Get the stats of a server from the exporter's registry
+func GetStats(serverID string) *SubscriptionStats {
+	return subscriptionStats.GetStats(serverID)
+}
+
+// Get the stats of all servers from the exporter's registry
+func DumpAllStats() map[string]*SubscriptionStats {
+	return subscriptionStats.DumpAllStats()
+}
+
+// Serve the current subscriptions of every server along with their stats
+func subscriptionsHandler(reconciler *Reconciler) http.HandlerFunc {
+	type serverSubscriptionsResponse struct {
+		Subscriptions ServerSubscriptions `json:"subscriptions"`
+		Stats         *SubscriptionStats  `json:"stats,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		response := make(map[string]serverSubscriptionsResponse)
+		for serverIP, subscriptions := range reconciler.Subscriptions() {
+			response[serverIP] = serverSubscriptionsResponse{Subscriptions: subscriptions}
+		}
+		for serverIP, stats := range DumpAllStats() {
+			serverResponse := response[serverIP]
+			serverResponse.Stats = stats
+			response[serverIP] = serverResponse
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("Failed to write subscriptions response: %v", err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStatsRegistry(t *testing.T) {
+	const server = "10.0.0.1"
+	tests := []struct {
+		name         string
+		record       func(r *StatsRegistry)
+		wantCreated  int
+		wantDeleted  int
+		wantActive   int
+		wantFailed   int
+		wantLastTime bool
+	}{
+		{
+			name: "created",
+			record: func(r *StatsRegistry) {
+				r.RecordCreated(server, "/redfish/v1/EventService/Subscriptions/1")
+				r.RecordCreated(server, "/redfish/v1/EventService/Subscriptions/2")
+			},
+			wantCreated: 2, wantActive: 2, wantLastTime: true,
+		},
+		{
+			name: "created and deleted",
+			record: func(r *StatsRegistry) {
+				r.RecordCreated(server, "/redfish/v1/EventService/Subscriptions/1")
+				r.RecordCreated(server, "/redfish/v1/EventService/Subscriptions/2")
+				r.RecordDeleted(server, "/redfish/v1/EventService/Subscriptions/1")
+			},
+			wantCreated: 2, wantDeleted: 1, wantActive: 1, wantLastTime: true,
+		},
+		{
+			name: "deleting another tool's subscription keeps the active count",
+			record: func(r *StatsRegistry) {
+				r.RecordCreated(server, "/redfish/v1/EventService/Subscriptions/1")
+				r.RecordDeleted(server, "/redfish/v1/EventService/Subscriptions/7")
+				r.RecordDeleted(server, "/redfish/v1/EventService/Subscriptions/8")
+			},
+			wantCreated: 1, wantDeleted: 2, wantActive: 1, wantLastTime: true,
+		},
+		{
+			name: "deleting twice counts down once",
+			record: func(r *StatsRegistry) {
+				r.RecordCreated(server, "/redfish/v1/EventService/Subscriptions/1")
+				r.RecordDeleted(server, "/redfish/v1/EventService/Subscriptions/1")
+				r.RecordDeleted(server, "/redfish/v1/EventService/Subscriptions/1")
+			},
+			wantCreated: 1, wantDeleted: 2, wantActive: 0, wantLastTime: true,
+		},
+		{
+			name: "failed creates",
+			record: func(r *StatsRegistry) {
+				r.RecordFailedCreate(server)
+				r.RecordFailedCreate(server)
+			},
+			wantFailed: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewStatsRegistry()
+			tt.record(r)
+			stats := r.GetStats(server)
+			if stats == nil {
+				t.Fatal("GetStats() = nil, want stats")
+			}
+			if stats.TotalCreated != tt.wantCreated || stats.TotalDeleted != tt.wantDeleted ||
+				stats.ActiveCount != tt.wantActive || stats.FailedCreates != tt.wantFailed {
+				t.Errorf("GetStats() = %+v, want %d created, %d deleted, %d active, %d failed",
+					stats, tt.wantCreated, tt.wantDeleted, tt.wantActive, tt.wantFailed)
+			}
+			if (stats.LastCreatedAt != nil || stats.LastDeletedAt != nil) != tt.wantLastTime {
+				t.Errorf("GetStats() times = %v, %v, want set %v", stats.LastCreatedAt, stats.LastDeletedAt, tt.wantLastTime)
+			}
+			if all := r.DumpAllStats(); len(all) != 1 || all[server] == nil {
+				t.Errorf("DumpAllStats() = %v, want the stats of %s only", all, server)
+			}
+		})
+	}
+}
+
+func TestSubscriptionStatsOmitUnsetTimes(t *testing.T) {
+	r := NewStatsRegistry()
+	r.RecordFailedCreate("10.0.0.1")
+	data, err := json.Marshal(r.GetStats("10.0.0.1"))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	for _, field := range []string{"lastCreatedAt", "lastDeletedAt"} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("stats JSON %s contains %s, want it omitted when unset", data, field)
+		}
+	}
+	if r.GetStats("10.0.0.2") != nil {
+		t.Error("GetStats() of a server without stats is not nil")
+	}
+}
@@ -0,0 +1,127 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// SubscriptionTTLConfig is for BMCs that delete subscriptions a fixed time
+// after creating them. Subscriptions are recreated RefreshMargin before
+// their TTL runs out.
+type SubscriptionTTLConfig struct {
+	// Lifetime of a subscription on the BMCs, nothing is refreshed when zero
+	TTL Duration `json:"ttl"`
+	// Defaults to a tenth of the TTL
+	RefreshMargin Duration `json:"refreshMargin"`
+}
+
+func (c SubscriptionTTLConfig) validate() error {
+	if c.TTL.Duration < 0 || c.RefreshMargin.Duration < 0 {
+		return errors.New("subscriptionTTL durations can't be negative")
+	}
+	if c.TTL.Duration > 0 && c.RefreshMargin.Duration >= c.TTL.Duration {
+		return errors.New("subscriptionTTL refreshMargin must be shorter than the ttl")
+	}
+	return nil
+}
+
+func (c SubscriptionTTLConfig) refreshMargin() time.Duration {
+	if c.RefreshMargin.Duration > 0 {
+		return c.RefreshMargin.Duration
+	}
+	return c.TTL.Duration / 10
+}
+
+// Time the subscription is to be recreated
+func (c SubscriptionTTLConfig) refreshAt(subscription *SubscriptionRecord) time.Time {
+	return subscription.CreatedAt.Add(c.TTL.Duration - c.refreshMargin())
+}
+
+// Recreate the subscriptions due for a refresh at now, returning the IDs
+// of the servers where subscriptions were recreated. Servers in maintenance
+// are left alone.
+func (r *Reconciler) RefreshExpiring(ttl SubscriptionTTLConfig, now time.Time) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.publish()
+	if r.closed || ttl.TTL.Duration <= 0 {
+		return nil
+	}
+
+	var refreshed []string
+	servers, _ := r.activeServers(r.servers)
+	for _, server := range servers {
+		subscriptions := r.subscriptionMap[server.ID()]
+		if len(subscriptions) == 0 {
+			continue
+		}
+		serverPayload, err := RenderPayload(r.payload, server)
+		if err != nil {
+			log.Printf("Failed to refresh subscriptions on server %s: %v", server.IP, err)
+			continue
+		}
+
+		serverRefreshed := false
+		for _, destinationPayload := range splitDestinations(serverPayload) {
+			subscription, ok := subscriptions[destinationPayload.Destination]
+			if !ok || subscription.Paused || now.Before(ttl.refreshAt(subscription)) {
+				continue
+			}
+			// Creating the subscription deletes the old one with the same
+			// destination
+			unlock := server.settings().locks.Lock(server.IP)
+			created, err := createSubscription(server, destinationPayload)
+			unlock()
+			if err != nil {
+				log.Printf("Failed to refresh subscription %s on server %s: %v", subscription.URI, server.IP, err)
+				continue
+			}
+			subscriptions[destinationPayload.Destination] = created[destinationPayload.Destination]
+			log.Printf("Refreshed subscription %s on server %s before it expires, now %s", subscription.URI, server.IP, created[destinationPayload.Destination].URI)
+			serverRefreshed = true
+		}
+		if serverRefreshed {
+			refreshed = append(refreshed, server.ID())
+		}
+	}
+	return refreshed
+}
+
+// Recreate the subscriptions approaching the end of their TTL until the
+// context is done, checking every half refresh margin
+func (r *Reconciler) RunRefresher(ctx context.Context, ttl SubscriptionTTLConfig) {
+	if ttl.TTL.Duration <= 0 {
+		return
+	}
+	ticker := time.NewTicker(ttl.refreshMargin() / 2)
+	defer ticker.Stop()
+
+	log.Printf("Starting subscription refresher for a TTL of %v", ttl.TTL.Duration)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Context done, stopping subscription refresher")
+			return
+		case now := <-ticker.C:
+			r.RefreshExpiring(ttl, now)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func TestSubscriptionTTLConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SubscriptionTTLConfig
+		wantErr bool
+	}{
+		{"disabled", SubscriptionTTLConfig{}, false},
+		{"default margin", SubscriptionTTLConfig{TTL: Duration{time.Hour}}, false},
+		{"margin shorter than the ttl", SubscriptionTTLConfig{TTL: Duration{time.Hour}, RefreshMargin: Duration{10 * time.Minute}}, false},
+		{"margin as long as the ttl", SubscriptionTTLConfig{TTL: Duration{time.Hour}, RefreshMargin: Duration{time.Hour}}, true},
+		{"negative ttl", SubscriptionTTLConfig{TTL: Duration{-time.Hour}}, true},
+		{"negative margin", SubscriptionTTLConfig{TTL: Duration{time.Hour}, RefreshMargin: Duration{-time.Minute}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.config.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRefreshExpiring(t *testing.T) {
+	ttl := SubscriptionTTLConfig{TTL: Duration{time.Hour}}
+	tests := []struct {
+		name          string
+		after         time.Duration
+		paused        bool
+		wantRefreshed bool
+	}{
+		{"fresh subscription", 30 * time.Minute, false, false},
+		{"within the refresh margin", 55 * time.Minute, false, true},
+		{"past the ttl", 2 * time.Hour, false, true},
+		{"paused subscription", 55 * time.Minute, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			server := bmc.server()
+			payload := SubscriptionPayload{Destination: "https://10.0.0.100:8080", Protocol: "Redfish", EventTypes: []redfish.EventType{redfish.AlertEventType}, Context: "scrapefish"}
+			reconciler := NewReconciler([]RedfishServer{server}, payload, make(map[string]ServerSubscriptions))
+			if _, err := reconciler.ReconcileServer(server, payload); err != nil {
+				t.Fatal(err)
+			}
+			subscription := reconciler.SubscriptionsOf(server.ID())[payload.Destination]
+			if tt.paused {
+				reconciler.subscriptionMap[server.ID()][payload.Destination].Paused = true
+			}
+
+			refreshed := reconciler.RefreshExpiring(ttl, subscription.CreatedAt.Add(tt.after))
+			if got := len(refreshed) == 1; got != tt.wantRefreshed {
+				t.Fatalf("RefreshExpiring() = %v, want refreshed %v", refreshed, tt.wantRefreshed)
+			}
+			current := reconciler.SubscriptionsOf(server.ID())[payload.Destination]
+			if tt.wantRefreshed {
+				if current.URI == subscription.URI || bmc.subscription(subscription.URI) != nil || bmc.subscription(current.URI) == nil {
+					t.Errorf("subscription %s after refreshing %s, want it replaced on the BMC", current.URI, subscription.URI)
+				}
+			} else if current.URI != subscription.URI || bmc.subscriptionCount() != 1 {
+				t.Errorf("subscription %s replaced by %s, want it kept", subscription.URI, current.URI)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/stmcginnis/gofish/common"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// Returned when a subscription differs in properties that are read-only
+// after creation, like Destination and Protocol
+var ErrNotPatchable = errors.New("subscription can't be updated in place")
+
+// Whether PATCH is supported on the subscriptions of each server, by server IP
+var patchSupport sync.Map
+
+// Whether all differences can be fixed by patching the subscription
+func patchable(diffs []PayloadDiff) bool {
+	for _, diff := range diffs {
+		switch diff.Kind {
+		case AddedRegistryPrefix, RemovedRegistryPrefix, ContextChanged, DeliveryPolicyChanged:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Build a PATCH body with only the properties that differ
+func patchBody(desired SubscriptionPayload, diffs []PayloadDiff) map[string]interface{} {
+	body := make(map[string]interface{})
+	for _, diff := range diffs {
+		switch diff.Kind {
+		case AddedRegistryPrefix, RemovedRegistryPrefix:
+			body["RegistryPrefixes"] = desired.RegistryPrefixes
+		case ContextChanged:
+			body["Context"] = desired.Context
+		case DeliveryPolicyChanged:
+			body["DeliveryRetryPolicy"] = desired.DeliveryRetryPolicy
+		}
+	}
+	return body
+}
+
+// Update a subscription in place to match the payload, patching only the
+// properties that changed. Unlike deleting and recreating it, no events
+// are missed in between.
+func updateSubscription(ctx context.Context, server RedfishServer, uri string, patch SubscriptionPayload) error {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	subscription, err := redfish.GetEventDestination(c, uri)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription %s on server %s: %w", uri, server.IP, normalizeRedfishError(err))
+	}
+	diffs := DiffSubscriptionPayload(patch, subscription)
+	if len(diffs) == 0 {
+		return nil
+	}
+	if !patchable(diffs) {
+		return fmt.Errorf("%w: %v", ErrNotPatchable, diffs)
+	}
+
+	resp, err := c.Patch(uri, patchBody(patch, diffs))
+	if err != nil {
+		return fmt.Errorf("failed to update subscription %s on server %s: %w", uri, server.IP, normalizeRedfishError(err))
+	}
+	resp.Body.Close()
+	log.Printf("Updated subscription %s on server %s: %v", uri, server.IP, diffs)
+	return nil
+}
+
+// Check whether the server accepts PATCH on its subscriptions by sending
+// an empty one to its first subscription. 200 and 204 mean supported,
+// anything else, or no subscription to try, unsupported.
+func PatchCapabilityProbe(ctx context.Context, server RedfishServer) bool {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return false
+	}
+	defer c.Logout()
+
+	eventService, err := getEventService(c)
+	if err != nil {
+		return false
+	}
+	subscriptions, err := eventService.GetEventSubscriptions()
+	if err != nil || len(subscriptions) == 0 {
+		return false
+	}
+	return probePatch(c, subscriptions[0].ODataID)
+}
+
+func probePatch(c common.Client, uri string) bool {
+	resp, err := c.Patch(uri, map[string]interface{}{})
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent
+}
+
+// Whether the server supports PATCH on its subscriptions, probed once
+func subscriptionPatchSupported(ctx context.Context, server RedfishServer) bool {
+	if supported, ok := patchSupport.Load(server.IP); ok {
+		return supported.(bool)
+	}
+	supported := PatchCapabilityProbe(ctx, server)
+	patchSupport.Store(server.IP, supported)
+	if !supported {
+		log.Printf("Server %s doesn't support updating subscriptions, changed subscriptions are recreated", server.IP)
+	}
+	return supported
+}
@@ -0,0 +1,103 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateSubscription(t *testing.T) {
+	existing := map[string]interface{}{
+		"Destination":      "https://10.0.0.100:8080",
+		"Protocol":         "Redfish",
+		"Context":          "scrapefish",
+		"RegistryPrefixes": []string{"Base"},
+	}
+	tests := []struct {
+		name      string
+		patch     SubscriptionPayload
+		wantErr   error
+		wantPatch bool
+		// Properties of the subscription afterwards
+		want map[string]interface{}
+	}{
+		{
+			name:  "unchanged",
+			patch: SubscriptionPayload{Destination: "https://10.0.0.100:8080", Context: "scrapefish", RegistryPrefixes: []string{"Base"}},
+		},
+		{
+			name:      "context and prefixes patched",
+			patch:     SubscriptionPayload{Destination: "https://10.0.0.100:8080", Context: "scrapefish-2", RegistryPrefixes: []string{"Base", "ResourceEvent"}},
+			wantPatch: true,
+			want:      map[string]interface{}{"Context": "scrapefish-2", "RegistryPrefixes": []interface{}{"Base", "ResourceEvent"}},
+		},
+		{
+			name:    "destination is read-only",
+			patch:   SubscriptionPayload{Destination: "https://10.0.0.101:8080", Context: "scrapefish", RegistryPrefixes: []string{"Base"}},
+			wantErr: ErrNotPatchable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			uri := bmc.addSubscription(existing)
+
+			err := updateSubscription(context.Background(), bmc.server(), uri, tt.patch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("updateSubscription() error = %v, want %v", err, tt.wantErr)
+			}
+			if patched := bmc.count("PATCH "+uri) > 0; patched != tt.wantPatch {
+				t.Errorf("patched = %v, want %v", patched, tt.wantPatch)
+			}
+			subscription := bmc.subscription(uri)
+			for key, want := range tt.want {
+				if !reflect.DeepEqual(subscription[key], want) {
+					t.Errorf("%s = %v, want %v", key, subscription[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchCapabilityProbe(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription bool
+		patchStatus  int
+		want         bool
+	}{
+		{"patch accepted", true, http.StatusOK, true},
+		{"patch rejected", true, http.StatusMethodNotAllowed, false},
+		{"no subscription to try", false, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t)
+			bmc.patchStatus = tt.patchStatus
+			if tt.subscription {
+				bmc.addSubscription(map[string]interface{}{"Destination": "https://10.0.0.100:8080"})
+			}
+			if got := PatchCapabilityProbe(context.Background(), bmc.server()); got != tt.want {
+				t.Errorf("PatchCapabilityProbe() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
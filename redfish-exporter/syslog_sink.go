@@ -0,0 +1,82 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+const DefaultSyslogTag = "redfish-exporter"
+
+// SyslogEventSink writes redfish events to the local or a remote syslog
+type SyslogEventSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogEventSink connects to syslog. An empty network and raddr use the
+// local syslog daemon, otherwise network is "tcp" or "udp" and raddr is the
+// remote syslog server address.
+func NewSyslogEventSink(network, raddr, tag string) (*SyslogEventSink, error) {
+	if tag == "" {
+		tag = DefaultSyslogTag
+	}
+
+	writer, err := syslog.Dial(network, raddr, syslog.LOG_DAEMON|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+
+	return &SyslogEventSink{writer: writer}, nil
+}
+
+// Send writes the event with a priority mapped from the redfish severity
+func (s *SyslogEventSink) Send(event *EnrichedEvent) error {
+	msg := formatSyslogMessage(event)
+
+	switch event.Severity {
+	case "Critical":
+		return s.writer.Crit(msg)
+	case "Warning":
+		return s.writer.Warning(msg)
+	case "OK":
+		return s.writer.Notice(msg)
+	default:
+		return s.writer.Info(msg)
+	}
+}
+
+func (s *SyslogEventSink) Close() error {
+	return s.writer.Close()
+}
+
+// Format the event as key=value pairs so it can be parsed by log management systems
+func formatSyslogMessage(event *EnrichedEvent) string {
+	return fmt.Sprintf("server=%s slurm_node=%s datacenter=%s row=%s rack=%s asset_tag=%s event_type=%s event_id=%s message_id=%s severity=%s origin=%s message=%q",
+		event.ServerIP,
+		event.SlurmNode,
+		event.Location.Datacenter,
+		event.Location.Row,
+		event.Location.Rack,
+		event.Location.AssetTag,
+		event.EventType,
+		event.EventId,
+		event.MessageId,
+		event.Severity,
+		event.OriginOfCondition.OdataId,
+		event.Message)
+}
@@ -0,0 +1,82 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// <priority>timestamp hostname tag[pid]: message
+var syslogLine = regexp.MustCompile(`^<(\d+)>\S+ \S+ (\S+)\[\d+\]: (.*)\n?$`)
+
+func TestSyslogEventSink(t *testing.T) {
+	tests := []struct {
+		name         string
+		tag          string
+		severity     string
+		wantPriority string
+		wantTag      string
+	}{
+		// LOG_DAEMON is facility 3, so the priorities are 24 + the severity
+		{"critical", "", "Critical", "26", DefaultSyslogTag},
+		{"warning", "", "Warning", "28", DefaultSyslogTag},
+		{"ok", "scrapefish", "OK", "29", "scrapefish"},
+		{"informational", "", "Informational", "30", DefaultSyslogTag},
+		{"no severity", "", "", "30", DefaultSyslogTag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, receive := newUDPReceiver(t)
+			sink, err := NewSyslogEventSink("udp", addr, tt.tag)
+			if err != nil {
+				t.Fatalf("NewSyslogEventSink() error = %v", err)
+			}
+			defer sink.Close()
+
+			event := &EnrichedEvent{
+				Event: Event{
+					EventId: "7", MessageId: "Base.1.0.GeneralError", Severity: tt.severity,
+					Message: "Fan 2 \"failed\"", OriginOfCondition: OriginOfCondition{OdataId: "/redfish/v1/Chassis/1"},
+				},
+				ServerIP:  "10.0.0.1",
+				SlurmNode: "node-1",
+			}
+			if err := sink.Send(event); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			line := string(receive())
+			match := syslogLine.FindStringSubmatch(line)
+			if match == nil {
+				t.Fatalf("received %q, want a syslog line", line)
+			}
+			if match[1] != tt.wantPriority || match[2] != tt.wantTag {
+				t.Errorf("priority %s tag %s, want %s and %s", match[1], match[2], tt.wantPriority, tt.wantTag)
+			}
+			for _, field := range []string{
+				"server=10.0.0.1", "slurm_node=node-1", "event_id=7", "message_id=Base.1.0.GeneralError",
+				"severity=" + tt.severity + " ", "origin=/redfish/v1/Chassis/1", `message="Fan 2 \"failed\""`,
+			} {
+				if !strings.Contains(match[3], field) {
+					t.Errorf("message %q lacks %s", match[3], field)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+// Health values exported by the health gauges, one series per value.
+// Resources without a Status.Health are Unknown.
+var exportedHealthStates = []string{"OK", "Warning", "Critical", "Unknown"}
+
+// ResourceHealth is the status of a system or manager of a server
+type ResourceHealth struct {
+	ID string `json:"id"`
+	// Health of the resource itself and of its subordinate resources
+	Health       common.Health `json:"health"`
+	HealthRollup common.Health `json:"healthRollup,omitempty"`
+	State        common.State  `json:"state"`
+}
+
+// The health value exported for the resource
+func (r ResourceHealth) healthState() string {
+	if r.Health == "" {
+		return "Unknown"
+	}
+	return string(r.Health)
+}
+
+// HealthReport holds the health rollups of the systems and managers of a
+// server
+type HealthReport struct {
+	Systems  []ResourceHealth `json:"systems"`
+	Managers []ResourceHealth `json:"managers"`
+}
+
+// Read the Status of the systems and managers of a server. A server
+// without systems or managers reports none.
+func CollectHealth(server RedfishServer) (HealthReport, error) {
+	return collectHealth(context.Background(), server)
+}
+
+func collectHealth(ctx context.Context, server RedfishServer) (HealthReport, error) {
+	var report HealthReport
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return report, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	systems, err := c.Service.Systems()
+	if err != nil {
+		return report, fmt.Errorf("failed to get systems on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	for _, system := range systems {
+		report.Systems = append(report.Systems, ResourceHealth{
+			ID:           system.ID,
+			Health:       system.Status.Health,
+			HealthRollup: system.Status.HealthRollup,
+			State:        system.Status.State,
+		})
+	}
+
+	managers, err := c.Service.Managers()
+	if err != nil {
+		return report, fmt.Errorf("failed to get managers on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	for _, manager := range managers {
+		report.Managers = append(report.Managers, ResourceHealth{
+			ID:           manager.ID,
+			Health:       manager.Status.Health,
+			HealthRollup: manager.Status.HealthRollup,
+			State:        manager.Status.State,
+		})
+	}
+	return report, nil
+}
+
+// SystemHealthCollector exports the health of the servers' systems and
+// managers
+type SystemHealthCollector struct {
+	servers []RedfishServer
+}
+
+func NewSystemHealthCollector(servers []RedfishServer) *SystemHealthCollector {
+	return &SystemHealthCollector{servers: servers}
+}
+
+// Check all servers every interval until the context is done
+func (h *SystemHealthCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting system health checks every %v", interval)
+	for {
+		h.Collect(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Context done, stopping system health checks")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Read the health of all servers once
+func (h *SystemHealthCollector) Collect(ctx context.Context) {
+	errs := bulkRun(ctx, h.servers, BulkOptions{}, func(server RedfishServer) error {
+		report, err := collectHealth(ctx, server)
+		if err != nil {
+			return err
+		}
+		h.record(server, report)
+		return nil
+	})
+	for serverIP, err := range errs {
+		log.Printf("Failed to check system health on server %s: %v", serverIP, err)
+	}
+}
+
+// Set the series of the current health of every resource to 1, the others to 0
+func (h *SystemHealthCollector) record(server RedfishServer, report HealthReport) {
+	for _, system := range report.Systems {
+		for _, state := range exportedHealthStates {
+			value := 0.0
+			if state == system.healthState() {
+				value = 1
+			}
+			server.settings().metrics.systemHealth.WithLabelValues(server.ID(), system.ID, state).Set(value)
+		}
+	}
+	for _, manager := range report.Managers {
+		for _, state := range exportedHealthStates {
+			value := 0.0
+			if state == manager.healthState() {
+				value = 1
+			}
+			server.settings().metrics.managerHealth.WithLabelValues(server.ID(), manager.ID, state).Set(value)
+		}
+	}
+}
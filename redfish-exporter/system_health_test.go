@@ -0,0 +1,99 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stmcginnis/gofish/common"
+)
+
+func TestResourceHealthState(t *testing.T) {
+	tests := []struct {
+		health common.Health
+		want   string
+	}{
+		{common.OKHealth, "OK"},
+		{common.CriticalHealth, "Critical"},
+		{"", "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := (ResourceHealth{Health: tt.health}).healthState(); got != tt.want {
+			t.Errorf("healthState() of %q = %q, want %q", tt.health, got, tt.want)
+		}
+	}
+}
+
+func TestCollectHealth(t *testing.T) {
+	bmc := newFakeBMC(t)
+	bmc.addSystem(map[string]interface{}{
+		"Status": map[string]string{"State": "Enabled", "Health": "Warning", "HealthRollup": "Critical"},
+	})
+	bmc.addCollection("/redfish/v1/Managers", map[string]interface{}{"Id": "BMC"})
+
+	report, err := CollectHealth(bmc.server())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSystem := ResourceHealth{ID: "1", Health: common.WarningHealth, HealthRollup: common.CriticalHealth, State: common.EnabledState}
+	if len(report.Systems) != 1 || report.Systems[0] != wantSystem {
+		t.Errorf("systems = %+v, want %+v", report.Systems, wantSystem)
+	}
+	if len(report.Managers) != 1 || report.Managers[0] != (ResourceHealth{ID: "BMC"}) {
+		t.Errorf("managers = %+v, want BMC without a status", report.Managers)
+	}
+	if bmc.openSessions() != 0 {
+		t.Errorf("%d sessions left open", bmc.openSessions())
+	}
+
+	bmc.failures["GET /redfish/v1/Managers"] = 500
+	if _, err := CollectHealth(bmc.server()); err == nil {
+		t.Error("expected an error when the managers can't be read")
+	}
+}
+
+func TestSystemHealthCollector(t *testing.T) {
+	bmc := newFakeBMC(t)
+	bmc.addSystem(map[string]interface{}{"Status": map[string]string{"State": "Enabled", "Health": "OK"}})
+	bmc.addCollection("/redfish/v1/Managers", map[string]interface{}{"Id": "BMC"})
+	server := bmc.server()
+	collector := NewSystemHealthCollector([]RedfishServer{server})
+
+	// The manager has no Status, its Unknown series stays set
+	for _, health := range []string{"OK", "Critical", "Warning"} {
+		bmc.setResourceProperty("/redfish/v1/Systems/1", "Status", map[string]string{"State": "Enabled", "Health": health})
+		collector.Collect(context.Background())
+
+		for _, state := range exportedHealthStates {
+			want := 0.0
+			if state == health {
+				want = 1
+			}
+			if got := gaugeValue(t, server.settings().metrics.systemHealth.WithLabelValues(server.ID(), "1", state)); got != want {
+				t.Errorf("health %s: system %s series = %v, want %v", health, state, got, want)
+			}
+			want = 0
+			if state == "Unknown" {
+				want = 1
+			}
+			if got := gaugeValue(t, server.settings().metrics.managerHealth.WithLabelValues(server.ID(), "BMC", state)); got != want {
+				t.Errorf("health %s: manager %s series = %v, want %v", health, state, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// TimeSyncDriftAlert is raised for a BMC with NTP disabled, whose clock and
+// event timestamps are likely to drift
+type TimeSyncDriftAlert struct {
+	Server    RedfishServer
+	Message   string
+	CheckedAt time.Time
+}
+
+// TimeSyncCollector checks the NTP settings of the servers' first manager
+// and exports them as metrics
+type TimeSyncCollector struct {
+	servers []RedfishServer
+	// Called when a server is found with NTP disabled, on the first check
+	// or after it was enabled
+	onAlert func(TimeSyncDriftAlert)
+
+	mu sync.Mutex
+	// Whether NTP was enabled at the last check, by server ID
+	ntpEnabled map[string]bool
+}
+
+func NewTimeSyncCollector(servers []RedfishServer, onAlert func(TimeSyncDriftAlert)) *TimeSyncCollector {
+	return &TimeSyncCollector{servers: servers, onAlert: onAlert, ntpEnabled: make(map[string]bool)}
+}
+
+// Check all servers every interval until the context is done
+func (t *TimeSyncCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting NTP status checks every %v", interval)
+	for {
+		t.Collect(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Context done, stopping NTP status checks")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check the NTP settings of all servers once
+func (t *TimeSyncCollector) Collect(ctx context.Context) {
+	errs := bulkRun(ctx, t.servers, BulkOptions{}, func(server RedfishServer) error {
+		ntp, err := getNTPSettings(ctx, server)
+		if err != nil {
+			return err
+		}
+		t.record(server, ntp)
+		return nil
+	})
+	for serverIP, err := range errs {
+		log.Printf("Failed to check NTP status on server %s: %v", serverIP, err)
+	}
+}
+
+// Update the metrics for a server and raise an alert when NTP was disabled
+// since the last check
+func (t *TimeSyncCollector) record(server RedfishServer, ntp redfish.NTP) {
+	enabled := 0.0
+	if ntp.ProtocolEnabled {
+		enabled = 1
+	}
+	server.settings().metrics.ntpEnabled.WithLabelValues(server.ID()).Set(enabled)
+	server.settings().metrics.ntpServers.WithLabelValues(server.ID()).Set(float64(len(ntp.NTPServers)))
+
+	t.mu.Lock()
+	wasEnabled, checked := t.ntpEnabled[server.ID()]
+	t.ntpEnabled[server.ID()] = ntp.ProtocolEnabled
+	t.mu.Unlock()
+	if checked && !wasEnabled {
+		// Already reported
+		return
+	}
+
+	if !ntp.ProtocolEnabled && t.onAlert != nil {
+		t.onAlert(TimeSyncDriftAlert{
+			Server:    server,
+			Message:   fmt.Sprintf("NTP is disabled on the BMC of server %s, its clock may drift", server.IP),
+			CheckedAt: time.Now(),
+		})
+	}
+}
+
+// Get the network protocol settings of the server's first manager
+func getNetworkProtocol(ctx context.Context, server RedfishServer) (*redfish.NetworkProtocolSettings, func(), error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+
+	managers, err := c.Service.Managers()
+	if err != nil {
+		c.Logout()
+		return nil, nil, fmt.Errorf("failed to get managers on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	if len(managers) == 0 {
+		c.Logout()
+		return nil, nil, fmt.Errorf("no managers found on server %s", server.IP)
+	}
+
+	networkProtocol, err := managers[0].NetworkProtocol()
+	if err != nil {
+		c.Logout()
+		return nil, nil, fmt.Errorf("failed to get network protocol on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	return networkProtocol, c.Logout, nil
+}
+
+func getNTPSettings(ctx context.Context, server RedfishServer) (redfish.NTP, error) {
+	networkProtocol, logout, err := getNetworkProtocol(ctx, server)
+	if err != nil {
+		return redfish.NTP{}, err
+	}
+	defer logout()
+	return networkProtocol.NTP, nil
+}
+
+// Set the NTP servers of the server's first manager
+func SetNTPServers(ctx context.Context, server RedfishServer, ntpServers []string) error {
+	networkProtocol, logout, err := getNetworkProtocol(ctx, server)
+	if err != nil {
+		return err
+	}
+	defer logout()
+
+	payload := map[string]interface{}{
+		"NTP": map[string]interface{}{"NTPServers": ntpServers},
+	}
+	resp, err := networkProtocol.GetClient().Patch(networkProtocol.ODataID, payload)
+	if err != nil {
+		return fmt.Errorf("failed to set NTP servers on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	resp.Body.Close()
+
+	log.Printf("Set NTP servers on server %s to %v", server.IP, ntpServers)
+	return nil
+}
@@ -0,0 +1,67 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func TestTimeSyncAlertsOnTransition(t *testing.T) {
+	tests := []struct {
+		name string
+		// NTP enabled at each check
+		checks     []bool
+		wantAlerts int
+	}{
+		{name: "always enabled", checks: []bool{true, true, true}},
+		{name: "disabled on the first check", checks: []bool{false}, wantAlerts: 1},
+		{name: "stays disabled", checks: []bool{false, false, false}, wantAlerts: 1},
+		{name: "enabled then disabled", checks: []bool{true, false, false}, wantAlerts: 1},
+		{name: "disabled again after being enabled", checks: []bool{false, true, false, false}, wantAlerts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var alerts int
+			server := RedfishServer{IP: "https://10.0.0.1"}
+			collector := NewTimeSyncCollector([]RedfishServer{server}, func(TimeSyncDriftAlert) {
+				alerts++
+			})
+			for _, enabled := range tt.checks {
+				collector.record(server, redfish.NTP{NetworkProtocol: redfish.NetworkProtocol{ProtocolEnabled: enabled}})
+			}
+			if alerts != tt.wantAlerts {
+				t.Errorf("raised %d alerts, want %d", alerts, tt.wantAlerts)
+			}
+		})
+	}
+}
+
+func TestTimeSyncStateIsPerServer(t *testing.T) {
+	var alerted []string
+	servers := []RedfishServer{{IP: "https://10.0.0.1"}, {IP: "https://10.0.0.2"}}
+	collector := NewTimeSyncCollector(servers, func(alert TimeSyncDriftAlert) {
+		alerted = append(alerted, alert.Server.IP)
+	})
+	collector.record(servers[0], redfish.NTP{})
+	collector.record(servers[1], redfish.NTP{})
+	collector.record(servers[0], redfish.NTP{})
+	if len(alerted) != 2 || alerted[0] != servers[0].IP || alerted[1] != servers[1].IP {
+		t.Errorf("alerted servers = %v, want both once", alerted)
+	}
+}
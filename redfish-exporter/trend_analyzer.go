@@ -0,0 +1,339 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Defaults of the correctable error trend analysis
+const (
+	DefaultTrendWindow = 24 * time.Hour
+	// Correctable errors a DIMM is assumed to log before it starts failing
+	// with uncorrectable errors. A heuristic, the actual count depends on
+	// the memory vendor and the BMC's error thresholds.
+	DefaultFailureThreshold = 1000
+	// Fewest samples a trend is fitted to
+	minTrendSamples = 3
+)
+
+// MemoryTrendConfig sets up the prediction of DIMM failures from the growth
+// of their correctable error counts
+type MemoryTrendConfig struct {
+	// Interval of the error count readings, disabled when zero
+	Interval Duration `json:"interval"`
+	// Readings the trend is fitted to, DefaultTrendWindow when zero
+	Window Duration `json:"window"`
+	// Errors per hour above which a failure is predicted
+	SlopeThreshold float64 `json:"slopeThreshold"`
+	// Error count the time to failure is estimated for,
+	// DefaultFailureThreshold when zero
+	FailureThreshold int `json:"failureThreshold"`
+}
+
+func (c MemoryTrendConfig) validate() error {
+	if c.Interval.Duration < 0 || c.Window.Duration < 0 {
+		return fmt.Errorf("memoryTrend interval and window can't be negative")
+	}
+	if c.SlopeThreshold < 0 || c.FailureThreshold < 0 {
+		return fmt.Errorf("memoryTrend thresholds can't be negative")
+	}
+	if c.Interval.Duration > 0 && c.SlopeThreshold == 0 {
+		return fmt.Errorf("memoryTrend slopeThreshold is required with an interval")
+	}
+	return nil
+}
+
+// PredictedFailureAlert is raised when the correctable errors of a DIMM grow
+// fast enough to expect it to fail
+type PredictedFailureAlert struct {
+	Server RedfishServer
+	DIMM   string
+	// Fitted growth of the error count in errors per hour and its 95%
+	// confidence interval
+	SlopePerHour float64
+	SlopeLow     float64
+	SlopeHigh    float64
+	// Coefficient of determination of the fit
+	R2 float64
+	// Estimated time until the error count reaches the failure threshold,
+	// between EarliestFailure and LatestFailure with 95% confidence.
+	// LatestFailure is zero when the trend may be flat.
+	TimeToFailure   time.Duration
+	EarliestFailure time.Duration
+	LatestFailure   time.Duration
+	Message         string
+	CheckedAt       time.Time
+}
+
+type trendSample struct {
+	at    time.Time
+	count float64
+}
+
+// TrendFit is the least squares line through the error counts of a DIMM
+type TrendFit struct {
+	// Errors per hour
+	Slope float64
+	// Half width of the slope's 95% confidence interval
+	SlopeMargin float64
+	// Error count fitted for the latest sample
+	Current float64
+	R2      float64
+	Samples int
+}
+
+// TrendAnalyzer keeps a sliding window of the correctable error counts of
+// each DIMM and fits a line through them
+type TrendAnalyzer struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	samples map[string][]trendSample
+}
+
+func NewTrendAnalyzer(window time.Duration) *TrendAnalyzer {
+	if window <= 0 {
+		window = DefaultTrendWindow
+	}
+	return &TrendAnalyzer{window: window, samples: make(map[string][]trendSample)}
+}
+
+// Record the error count of a DIMM read at the given time and drop the
+// samples that left the window. A count lower than the previous one means
+// the counters were reset, the earlier samples are dropped then.
+func (t *TrendAnalyzer) Record(key string, at time.Time, count int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples := t.samples[key]
+	if n := len(samples); n > 0 && float64(count) < samples[n-1].count {
+		samples = nil
+	}
+	samples = append(samples, trendSample{at: at, count: float64(count)})
+	cutoff := at.Add(-t.window)
+	first := sort.Search(len(samples), func(i int) bool { return !samples[i].at.Before(cutoff) })
+	t.samples[key] = append([]trendSample(nil), samples[first:]...)
+}
+
+// Fit a line through the samples of a DIMM, false with too few samples
+func (t *TrendAnalyzer) Fit(key string) (TrendFit, bool) {
+	t.mu.Lock()
+	samples := t.samples[key]
+	t.mu.Unlock()
+	return fitTrend(samples)
+}
+
+// Ordinary least squares of the error count over the hours since the first
+// sample
+func fitTrend(samples []trendSample) (TrendFit, bool) {
+	n := len(samples)
+	if n < minTrendSamples {
+		return TrendFit{}, false
+	}
+	hours := make([]float64, n)
+	counts := make([]float64, n)
+	for i, s := range samples {
+		hours[i] = s.at.Sub(samples[0].at).Hours()
+		counts[i] = s.count
+	}
+	// Samples read at the same time have no slope
+	sxx := stat.Variance(hours, nil) * float64(n-1)
+	if sxx == 0 {
+		return TrendFit{}, false
+	}
+	intercept, slope := stat.LinearRegression(hours, counts, nil, false)
+
+	fit := TrendFit{Slope: slope, Samples: n, Current: intercept + slope*hours[n-1]}
+	// A flat series is fitted perfectly
+	fit.R2 = stat.RSquared(hours, counts, nil, intercept, slope)
+	if math.IsNaN(fit.R2) {
+		fit.R2 = 1
+	}
+	// Sum of the squared residuals
+	var sse float64
+	for i := range hours {
+		residual := counts[i] - (intercept + slope*hours[i])
+		sse += residual * residual
+	}
+	t := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: float64(n - 2)}
+	fit.SlopeMargin = t.Quantile(0.975) * math.Sqrt(sse/float64(n-2)/sxx)
+	return fit, true
+}
+
+// MemoryTrendCollector reads the correctable ECC error counts of the
+// servers' DIMMs, exports their growth and predicts failures of the DIMMs
+// whose errors grow faster than the slope threshold
+type MemoryTrendCollector struct {
+	servers  []RedfishServer
+	cfg      MemoryTrendConfig
+	analyzer *TrendAnalyzer
+	// Called when a DIMM is predicted to fail
+	onAlert func(PredictedFailureAlert)
+
+	mu sync.Mutex
+	// DIMMs predicted to fail by the previous check, so a DIMM is only
+	// alerted on once until its trend recovers
+	predicted map[string]bool
+}
+
+func NewMemoryTrendCollector(servers []RedfishServer, cfg MemoryTrendConfig, onAlert func(PredictedFailureAlert)) *MemoryTrendCollector {
+	if cfg.FailureThreshold == 0 {
+		cfg.FailureThreshold = DefaultFailureThreshold
+	}
+	return &MemoryTrendCollector{
+		servers:   servers,
+		cfg:       cfg,
+		analyzer:  NewTrendAnalyzer(cfg.Window.Duration),
+		onAlert:   onAlert,
+		predicted: make(map[string]bool),
+	}
+}
+
+// Check all servers every interval until the context is done
+func (m *MemoryTrendCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting memory error trend checks every %v", interval)
+	for {
+		m.Collect(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Context done, stopping memory error trend checks")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Read the error counts of all servers once
+func (m *MemoryTrendCollector) Collect(ctx context.Context) {
+	errs := bulkRun(ctx, m.servers, BulkOptions{}, func(server RedfishServer) error {
+		counts, err := getCorrectableErrorCounts(ctx, server)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for dimm, count := range counts {
+			m.record(server, dimm, now, count)
+		}
+		return nil
+	})
+	for serverIP, err := range errs {
+		log.Printf("Failed to check memory errors on server %s: %v", serverIP, err)
+	}
+}
+
+// Add a reading of a DIMM, update its trend metric and raise an alert when
+// its slope crossed the threshold
+func (m *MemoryTrendCollector) record(server RedfishServer, dimm string, at time.Time, count int) {
+	key := server.ID() + "/" + dimm
+	m.analyzer.Record(key, at, count)
+	fit, ok := m.analyzer.Fit(key)
+	if !ok {
+		return
+	}
+	server.settings().metrics.memoryErrorTrend.WithLabelValues(server.ID(), dimm).Set(fit.Slope)
+
+	failing := fit.Slope > m.cfg.SlopeThreshold
+	m.mu.Lock()
+	wasFailing := m.predicted[key]
+	m.predicted[key] = failing
+	m.mu.Unlock()
+
+	if failing && !wasFailing && m.onAlert != nil {
+		m.onAlert(predictFailure(server, dimm, fit, m.cfg.FailureThreshold, at))
+	}
+}
+
+// Estimate when the fitted error count reaches the failure threshold
+func predictFailure(server RedfishServer, dimm string, fit TrendFit, failureThreshold int, at time.Time) PredictedFailureAlert {
+	alert := PredictedFailureAlert{
+		Server:       server,
+		DIMM:         dimm,
+		SlopePerHour: fit.Slope,
+		SlopeLow:     fit.Slope - fit.SlopeMargin,
+		SlopeHigh:    fit.Slope + fit.SlopeMargin,
+		R2:           fit.R2,
+		CheckedAt:    at,
+	}
+	remaining := math.Max(float64(failureThreshold)-fit.Current, 0)
+	hours := func(slope float64) time.Duration {
+		return time.Duration(remaining / slope * float64(time.Hour))
+	}
+	alert.TimeToFailure = hours(alert.SlopePerHour)
+	alert.EarliestFailure = hours(alert.SlopeHigh)
+	if alert.SlopeLow > 0 {
+		alert.LatestFailure = hours(alert.SlopeLow)
+	}
+
+	latest := "unknown"
+	if alert.LatestFailure > 0 {
+		latest = alert.LatestFailure.Round(time.Minute).String()
+	}
+	alert.Message = fmt.Sprintf("DIMM %s on server %s is predicted to fail: correctable errors grow by %.1f/h (95%% CI %.1f to %.1f, R² %.2f), %d errors expected in %v (between %v and %s)",
+		dimm, server.IP, alert.SlopePerHour, alert.SlopeLow, alert.SlopeHigh, alert.R2, failureThreshold,
+		alert.TimeToFailure.Round(time.Minute), alert.EarliestFailure.Round(time.Minute), latest)
+	return alert
+}
+
+// Get the lifetime correctable ECC error counts of the DIMMs of all systems
+// of a server by memory ID
+func getCorrectableErrorCounts(ctx context.Context, server RedfishServer) (map[string]int, error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	systems, err := c.Service.Systems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systems on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	counts := make(map[string]int)
+	for _, system := range systems {
+		memory, err := system.Memory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get memory of %s on server %s: %w", system.ODataID, server.IP, normalizeRedfishError(err))
+		}
+		for _, dimm := range memory {
+			metrics, err := dimm.Metrics()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get metrics of memory %s on server %s: %w", dimm.ODataID, server.IP, normalizeRedfishError(err))
+			}
+			if metrics == nil {
+				continue
+			}
+			id := dimm.ID
+			if len(systems) > 1 {
+				id = system.ID + "/" + dimm.ID
+			}
+			counts[id] = metrics.LifeTime.CorrectableECCErrorCount
+		}
+	}
+	return counts, nil
+}
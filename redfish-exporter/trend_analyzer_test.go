@@ -0,0 +1,206 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+var trendStart = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// Samples an hour apart with the counts
+func hourlySamples(counts ...float64) []trendSample {
+	samples := make([]trendSample, len(counts))
+	for i, count := range counts {
+		samples[i] = trendSample{at: trendStart.Add(time.Duration(i) * time.Hour), count: count}
+	}
+	return samples
+}
+
+func TestMemoryTrendConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  MemoryTrendConfig
+		wantErr bool
+	}{
+		{"disabled", MemoryTrendConfig{}, false},
+		{"enabled", MemoryTrendConfig{Interval: Duration{time.Hour}, SlopeThreshold: 5}, false},
+		{"no slope threshold", MemoryTrendConfig{Interval: Duration{time.Hour}}, true},
+		{"negative window", MemoryTrendConfig{Window: Duration{-time.Hour}}, true},
+		{"negative failure threshold", MemoryTrendConfig{FailureThreshold: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFitTrend(t *testing.T) {
+	sameTime := hourlySamples(1, 2, 3)
+	for i := range sameTime {
+		sameTime[i].at = trendStart
+	}
+	tests := []struct {
+		name        string
+		samples     []trendSample
+		wantOK      bool
+		wantSlope   float64
+		wantCurrent float64
+		wantR2      float64
+		// Half width of the slope's 95% confidence interval
+		wantMargin float64
+	}{
+		{"too few samples", hourlySamples(1, 2), false, 0, 0, 0, 0},
+		{"samples at the same time", sameTime, false, 0, 0, 0, 0},
+		{"exact line", hourlySamples(10, 20, 30, 40), true, 10, 40, 1, 0},
+		{"flat", hourlySamples(5, 5, 5), true, 0, 5, 1, 0},
+		// t(0.975, 2 df) = 4.303 times the slope's standard error of 0.933
+		{"noisy line", hourlySamples(0, 12, 18, 31), true, 9.9, 30.1, 0.98, 4.013},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fit, ok := fitTrend(tt.samples)
+			if ok != tt.wantOK {
+				t.Fatalf("fitTrend() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if math.Abs(fit.Slope-tt.wantSlope) > 0.01 || math.Abs(fit.Current-tt.wantCurrent) > 0.01 || math.Abs(fit.R2-tt.wantR2) > 0.01 {
+				t.Errorf("fit = %+v, want slope %v, current %v, R² %v", fit, tt.wantSlope, tt.wantCurrent, tt.wantR2)
+			}
+			if math.Abs(fit.SlopeMargin-tt.wantMargin) > 0.01 || fit.Samples != len(tt.samples) {
+				t.Errorf("fit = %+v, want a slope margin of %v", fit, tt.wantMargin)
+			}
+		})
+	}
+}
+
+func TestTrendAnalyzerRecord(t *testing.T) {
+	tests := []struct {
+		name        string
+		counts      []int
+		wantSamples int
+	}{
+		{"samples within the window", []int{1, 2, 3}, 3},
+		{"samples leaving the window", []int{1, 2, 3, 4, 5, 6}, 4},
+		{"counter reset", []int{10, 20, 30, 2, 4}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewTrendAnalyzer(3 * time.Hour)
+			for i, count := range tt.counts {
+				analyzer.Record("DIMM_A1", trendStart.Add(time.Duration(i)*time.Hour), count)
+			}
+			if got := len(analyzer.samples["DIMM_A1"]); got != tt.wantSamples {
+				t.Errorf("%d samples kept, want %d", got, tt.wantSamples)
+			}
+		})
+	}
+}
+
+func TestPredictFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		fit          TrendFit
+		wantTTF      time.Duration
+		wantEarliest time.Duration
+		wantLatest   time.Duration
+	}{
+		{"certain trend", TrendFit{Slope: 100, SlopeMargin: 50, Current: 400}, 6 * time.Hour, 4 * time.Hour, 12 * time.Hour},
+		{"trend that may be flat", TrendFit{Slope: 100, SlopeMargin: 150, Current: 400}, 6 * time.Hour, 2*time.Hour + 24*time.Minute, 0},
+		{"past the threshold", TrendFit{Slope: 100, SlopeMargin: 50, Current: 1200}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := predictFailure(RedfishServer{IP: "https://10.0.0.1"}, "DIMM_A1", tt.fit, 1000, trendStart)
+			if alert.TimeToFailure != tt.wantTTF || alert.EarliestFailure != tt.wantEarliest || alert.LatestFailure != tt.wantLatest {
+				t.Errorf("failure in %v (%v to %v), want %v (%v to %v)", alert.TimeToFailure, alert.EarliestFailure, alert.LatestFailure, tt.wantTTF, tt.wantEarliest, tt.wantLatest)
+			}
+			if alert.Message == "" {
+				t.Error("no message")
+			}
+		})
+	}
+}
+
+func TestMemoryTrendCollector(t *testing.T) {
+	bmc := newFakeBMC(t)
+	bmc.addSystem(map[string]interface{}{"Memory": link("/redfish/v1/Systems/1/Memory")})
+	bmc.addCollection("/redfish/v1/Systems/1/Memory",
+		map[string]interface{}{"Id": "DIMM_A1", "Metrics": link("/redfish/v1/Systems/1/Memory/DIMM_A1/MemoryMetrics")},
+		map[string]interface{}{"Id": "DIMM_A2"},
+	)
+	bmc.resources["/redfish/v1/Systems/1/Memory/DIMM_A1/MemoryMetrics"] = map[string]interface{}{
+		"Id":       "MemoryMetrics",
+		"LifeTime": map[string]int{"CorrectableECCErrorCount": 42},
+	}
+	server := bmc.server()
+
+	counts, err := getCorrectableErrorCounts(context.Background(), server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 1 || counts["DIMM_A1"] != 42 {
+		t.Errorf("counts = %v, want DIMM_A1 without the DIMM lacking metrics", counts)
+	}
+
+	var alerts []PredictedFailureAlert
+	collector := NewMemoryTrendCollector([]RedfishServer{server}, MemoryTrendConfig{SlopeThreshold: 5}, func(alert PredictedFailureAlert) {
+		alerts = append(alerts, alert)
+	})
+	// Hourly readings, each step checks the alerts raised so far
+	steps := []struct {
+		count      int
+		wantAlerts int
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{50, 1},
+		{100, 1},
+		{100, 1},
+		{100, 1},
+		{100, 1},
+		{100, 1},
+		{100, 1},
+		{2, 1},
+		{3, 1},
+		{4, 1},
+		{104, 2},
+	}
+	for i, step := range steps {
+		collector.record(server, "DIMM_A1", trendStart.Add(time.Duration(i)*time.Hour), step.count)
+		if len(alerts) != step.wantAlerts {
+			t.Fatalf("reading %d: %d alerts, want %d", i, len(alerts), step.wantAlerts)
+		}
+	}
+	// The counter reset between the alerts started a new trend
+	if alerts[0].DIMM != "DIMM_A1" || alerts[0].CheckedAt != trendStart.Add(3*time.Hour) {
+		t.Errorf("first alert = %+v, want DIMM_A1 at the fourth reading", alerts[0])
+	}
+	fit, _ := collector.analyzer.Fit(server.ID() + "/DIMM_A1")
+	if got := gaugeValue(t, server.settings().metrics.memoryErrorTrend.WithLabelValues(server.ID(), "DIMM_A1")); got != fit.Slope {
+		t.Errorf("trend metric = %v, want the slope %v", got, fit.Slope)
+	}
+}
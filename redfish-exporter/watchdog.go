@@ -0,0 +1,144 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Consecutive failed checks before a server is marked degraded, when not configured
+const DefaultMaxConsecutiveFailures = 3
+
+// Timing of the subscription checks of the reconcile loop. Servers can
+// override any of the fields, zero fields fall back to the exporter's
+type WatchdogConfig struct {
+	// Time between two checks of a server's subscriptions
+	PollInterval Duration `json:"pollInterval"`
+	// Time until a subscription that was just created or recreated is
+	// checked again, PollInterval when zero
+	MinRecheckInterval Duration `json:"minRecheckInterval"`
+	// Failed checks in a row before the server is marked degraded,
+	// DefaultMaxConsecutiveFailures when zero
+	MaxConsecutiveFailures int `json:"maxConsecutiveFailures"`
+}
+
+// The config with the non-zero fields of override applied
+func (c WatchdogConfig) merge(override *WatchdogConfig) WatchdogConfig {
+	if override == nil {
+		return c
+	}
+	if override.PollInterval.Duration > 0 {
+		c.PollInterval = override.PollInterval
+	}
+	if override.MinRecheckInterval.Duration > 0 {
+		c.MinRecheckInterval = override.MinRecheckInterval
+	}
+	if override.MaxConsecutiveFailures > 0 {
+		c.MaxConsecutiveFailures = override.MaxConsecutiveFailures
+	}
+	return c
+}
+
+// Time until the next check of a subscription that was just created
+func (c WatchdogConfig) recheckInterval() time.Duration {
+	if c.MinRecheckInterval.Duration > 0 && c.MinRecheckInterval.Duration < c.PollInterval.Duration {
+		return c.MinRecheckInterval.Duration
+	}
+	return c.PollInterval.Duration
+}
+
+// Scheduling state of a server in the reconcile loop
+type watchdogState struct {
+	next     time.Time
+	failures int
+	degraded bool
+}
+
+// The watchdog config of a server
+func (r *Reconciler) watchdogFor(server RedfishServer) WatchdogConfig {
+	return r.watchdog.merge(server.Watchdog)
+}
+
+// The shortest interval any server can be due after, the loop's tick
+func (r *Reconciler) tickInterval() time.Duration {
+	tick := r.watchdog.recheckInterval()
+	for _, server := range r.servers {
+		if interval := r.watchdogFor(server).recheckInterval(); interval > 0 && interval < tick {
+			tick = interval
+		}
+	}
+	return tick
+}
+
+// The servers whose next check is due at now
+func (r *Reconciler) dueServers(now time.Time) []RedfishServer {
+	var due []RedfishServer
+	for _, server := range r.servers {
+		state, ok := r.schedule[server.ID()]
+		if !ok || !now.Before(state.next) {
+			due = append(due, server)
+		}
+	}
+	return due
+}
+
+// Schedule the next check of the servers checked at now. Servers whose
+// subscription was just created are checked again after the shorter
+// MinRecheckInterval, servers failing too often are marked degraded
+func (r *Reconciler) scheduleNext(now time.Time, servers []RedfishServer, report ReconcileReport) {
+	created := make(map[string]bool)
+	for _, serverIP := range append(report.Created, report.Recreated...) {
+		created[serverIP] = true
+	}
+	// Servers that weren't checked keep their failure count
+	unchecked := make(map[string]bool)
+	for _, serverIP := range append(report.Skipped, report.InMaintenance...) {
+		unchecked[serverIP] = true
+	}
+	for _, server := range servers {
+		cfg := r.watchdogFor(server)
+		state, ok := r.schedule[server.ID()]
+		if !ok {
+			state = &watchdogState{}
+			r.schedule[server.ID()] = state
+		}
+
+		state.next = now.Add(cfg.PollInterval.Duration)
+		if created[server.ID()] {
+			state.next = now.Add(cfg.recheckInterval())
+		}
+		if unchecked[server.ID()] {
+			continue
+		}
+
+		if _, failed := report.Errors[server.ID()]; !failed {
+			if state.degraded {
+				log.Printf("Server %s recovered, no longer degraded", server.ID())
+				server.settings().metrics.serverDegraded.WithLabelValues(server.ID()).Set(0)
+			}
+			state.failures = 0
+			state.degraded = false
+			continue
+		}
+		state.failures++
+		if !state.degraded && state.failures >= cfg.MaxConsecutiveFailures {
+			log.Printf("Server %s failed %d subscription checks in a row, marking it degraded", server.ID(), state.failures)
+			server.settings().metrics.serverDegraded.WithLabelValues(server.ID()).Set(1)
+			state.degraded = true
+		}
+	}
+}
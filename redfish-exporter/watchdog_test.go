@@ -0,0 +1,101 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatchdogConfigMerge(t *testing.T) {
+	exporter := WatchdogConfig{PollInterval: Duration{time.Minute}, MinRecheckInterval: Duration{10 * time.Second}, MaxConsecutiveFailures: 3}
+	tests := []struct {
+		name        string
+		override    *WatchdogConfig
+		want        WatchdogConfig
+		wantRecheck time.Duration
+	}{
+		{"no override", nil, exporter, 10 * time.Second},
+		{"zero fields kept", &WatchdogConfig{MaxConsecutiveFailures: 5}, WatchdogConfig{PollInterval: Duration{time.Minute}, MinRecheckInterval: Duration{10 * time.Second}, MaxConsecutiveFailures: 5}, 10 * time.Second},
+		{"shorter poll interval", &WatchdogConfig{PollInterval: Duration{5 * time.Second}}, WatchdogConfig{PollInterval: Duration{5 * time.Second}, MinRecheckInterval: Duration{10 * time.Second}, MaxConsecutiveFailures: 3}, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exporter.merge(tt.override)
+			if got != tt.want {
+				t.Errorf("merge() = %+v, want %+v", got, tt.want)
+			}
+			if recheck := got.recheckInterval(); recheck != tt.wantRecheck {
+				t.Errorf("recheckInterval() = %v, want %v", recheck, tt.wantRecheck)
+			}
+		})
+	}
+}
+
+func TestReconcilerSchedule(t *testing.T) {
+	servers := []RedfishServer{
+		{IP: "https://10.0.0.1"},
+		{IP: "https://10.0.0.2", Watchdog: &WatchdogConfig{PollInterval: Duration{5 * time.Minute}, MaxConsecutiveFailures: 1}},
+	}
+	reconciler := NewReconciler(servers, SubscriptionPayload{}, nil)
+	reconciler.SetWatchdog(WatchdogConfig{PollInterval: Duration{time.Minute}, MinRecheckInterval: Duration{10 * time.Second}})
+	if tick := reconciler.tickInterval(); tick != 10*time.Second {
+		t.Errorf("tickInterval() = %v, want 10s", tick)
+	}
+
+	now := time.Now()
+	if due := reconciler.dueServers(now); len(due) != 2 {
+		t.Fatalf("%d servers due before the first check, want 2", len(due))
+	}
+	failure := errors.New("connection refused")
+	reconciler.scheduleNext(now, servers, ReconcileReport{
+		Created: []string{"https://10.0.0.1"},
+		Errors:  map[string]error{"https://10.0.0.2": failure},
+	})
+
+	tests := []struct {
+		after time.Duration
+		want  []string
+	}{
+		{5 * time.Second, nil},
+		{10 * time.Second, []string{"https://10.0.0.1"}},
+		{5 * time.Minute, []string{"https://10.0.0.1", "https://10.0.0.2"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, server := range reconciler.dueServers(now.Add(tt.after)) {
+			got = append(got, server.ID())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("due after %v = %v, want %v", tt.after, got, tt.want)
+		}
+	}
+
+	if state := reconciler.schedule["https://10.0.0.2"]; !state.degraded || state.failures != 1 {
+		t.Errorf("state of the failing server = %+v, want degraded after 1 failure", state)
+	}
+	reconciler.scheduleNext(now, servers[1:], ReconcileReport{Skipped: []string{"https://10.0.0.2"}})
+	if state := reconciler.schedule["https://10.0.0.2"]; !state.degraded {
+		t.Error("skipped server recovered, want it to keep its failures")
+	}
+	reconciler.scheduleNext(now, servers[1:], ReconcileReport{Unchanged: []string{"https://10.0.0.2"}})
+	if state := reconciler.schedule["https://10.0.0.2"]; state.degraded || state.failures != 0 {
+		t.Errorf("state after a successful check = %+v, want recovered", state)
+	}
+}
@@ -0,0 +1,144 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Default number of redfish servers contacted in parallel
+const DefaultMaxConcurrency = 8
+
+// Result of a task run by a WorkerPool, with the index of the task in
+// submission order
+type Result[T any] struct {
+	Index int
+	Value T
+}
+
+// WorkerPool runs tasks with a bounded number of goroutines and collects
+// their results in submission order
+type WorkerPool[T any] struct {
+	sem     chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	tasks   []func() T
+	results []T
+	// Receives each result as its task completes once Results was called
+	resultsChan chan Result[T]
+}
+
+// Create a worker pool running at most concurrency tasks at a time
+func NewWorkerPool[T any](concurrency int) *WorkerPool[T] {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &WorkerPool[T]{sem: make(chan struct{}, concurrency)}
+}
+
+// Queue a task to be submitted by Run
+func (p *WorkerPool[T]) Add(task func() T) {
+	p.tasks = append(p.tasks, task)
+}
+
+// Start a task as soon as a worker is free. Returns the context error if
+// the context is done before the task could be started.
+func (p *WorkerPool[T]) Submit(ctx context.Context, task func() T) error {
+	p.mu.Lock()
+	index := len(p.results)
+	var zero T
+	p.results = append(p.results, zero)
+	p.mu.Unlock()
+
+	// The select below may pick a free worker over a done context
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		result := task()
+		p.mu.Lock()
+		p.results[index] = result
+		resultsChan := p.resultsChan
+		p.mu.Unlock()
+		if resultsChan != nil {
+			resultsChan <- Result[T]{Index: index, Value: result}
+		}
+	}()
+	return nil
+}
+
+// Get a channel receiving the result of each task as soon as it completes,
+// closed by Wait. Must be called before submitting the tasks and read until
+// it is closed, since a worker is only freed once its result is received.
+// Tasks that were never started send no result.
+func (p *WorkerPool[T]) Results() <-chan Result[T] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resultsChan == nil {
+		p.resultsChan = make(chan Result[T])
+	}
+	return p.resultsChan
+}
+
+// Wait for all submitted tasks and return their results in submission
+// order. Tasks that were never started leave the zero value.
+func (p *WorkerPool[T]) Wait() []T {
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resultsChan != nil {
+		close(p.resultsChan)
+		p.resultsChan = nil
+	}
+	results := p.results
+	p.results = nil
+	return results
+}
+
+// Submit all queued tasks, wait for them to complete and return their
+// results in the order they were added. Submission stops when the
+// context is done.
+func (p *WorkerPool[T]) Run(ctx context.Context) []T {
+	tasks := p.tasks
+	p.tasks = nil
+	for i, task := range tasks {
+		if err := p.Submit(ctx, task); err != nil {
+			// Keep one result per task even for those never submitted
+			p.mu.Lock()
+			var zero T
+			for range tasks[i+1:] {
+				p.results = append(p.results, zero)
+			}
+			p.mu.Unlock()
+			break
+		}
+	}
+	return p.Wait()
+}
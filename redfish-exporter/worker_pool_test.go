@@ -0,0 +1,182 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		tasks       int
+	}{
+		{"sequential", 1, 20},
+		{"bounded", 4, 100},
+		{"more workers than tasks", 50, 10},
+		{"invalid concurrency runs one at a time", 0, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := tt.concurrency
+			if limit < 1 {
+				limit = 1
+			}
+			var running, maxRunning atomic.Int32
+			pool := NewWorkerPool[int](tt.concurrency)
+			for i := 0; i < tt.tasks; i++ {
+				pool.Add(func() int {
+					n := running.Add(1)
+					defer running.Add(-1)
+					for {
+						max := maxRunning.Load()
+						if n <= max || maxRunning.CompareAndSwap(max, n) {
+							break
+						}
+					}
+					// Later tasks finish first, the results keep their order
+					time.Sleep(time.Duration(tt.tasks-i) * 100 * time.Microsecond)
+					return i
+				})
+			}
+
+			results := pool.Run(context.Background())
+			if len(results) != tt.tasks {
+				t.Fatalf("Run() returned %d results, want %d", len(results), tt.tasks)
+			}
+			for i, result := range results {
+				if result != i {
+					t.Fatalf("Run()[%d] = %d, want results in submission order", i, result)
+				}
+			}
+			if got := maxRunning.Load(); got > int32(limit) {
+				t.Errorf("%d tasks ran at once, want at most %d", got, limit)
+			}
+		})
+	}
+}
+
+func TestWorkerPoolRunStopsOnCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	release := make(chan struct{})
+	var started atomic.Int32
+
+	pool := NewWorkerPool[int](2)
+	for i := 0; i < 10; i++ {
+		pool.Add(func() int {
+			if started.Add(1) == 2 {
+				cancel()
+			}
+			<-release
+			return i + 1
+		})
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+
+	results := pool.Run(ctx)
+	if len(results) != 10 {
+		t.Fatalf("Run() returned %d results, want one per task", len(results))
+	}
+	if results[0] != 1 || results[1] != 2 {
+		t.Errorf("Run() = %v, want the started tasks' results", results)
+	}
+	for i, result := range results[2:] {
+		if result != 0 {
+			t.Errorf("Run()[%d] = %d, want the zero value for a task never started", i+2, result)
+		}
+	}
+	if got := started.Load(); got != 2 {
+		t.Errorf("%d tasks started, want 2", got)
+	}
+}
+
+func TestWorkerPoolSubmitCanceled(t *testing.T) {
+	pool := NewWorkerPool[int](1)
+	release := make(chan struct{})
+	if err := pool.Submit(context.Background(), func() int { <-release; return 1 }); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// The only worker is busy, so the second task waits until the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Submit(ctx, func() int { return 2 }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if results := pool.Wait(); len(results) != 2 || results[0] != 1 || results[1] != 0 {
+		t.Errorf("Wait() = %v, want [1 0]", results)
+	}
+}
+
+func TestWorkerPoolSubmitDoneContext(t *testing.T) {
+	pool := NewWorkerPool[int](4)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Workers are free, the task still doesn't start
+	for i := 0; i < 10; i++ {
+		if err := pool.Submit(ctx, func() int { return 1 }); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Submit() error = %v, want %v", err, context.Canceled)
+		}
+	}
+	for _, result := range pool.Wait() {
+		if result != 0 {
+			t.Fatal("task ran with a done context")
+		}
+	}
+}
+
+func TestWorkerPoolResults(t *testing.T) {
+	const tasks = 50
+	pool := NewWorkerPool[int](8)
+	results := pool.Results()
+
+	received := make(map[int]int)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range results {
+			received[result.Index] = result.Value
+		}
+	}()
+
+	for i := 0; i < tasks; i++ {
+		pool.Add(func() int { return i * i })
+	}
+	ordered := pool.Run(context.Background())
+	<-done
+
+	if len(received) != tasks {
+		t.Fatalf("Results() delivered %d results, want %d", len(received), tasks)
+	}
+	for i, value := range ordered {
+		if received[i] != value {
+			t.Errorf("Results() index %d = %d, want %d as returned by Run", i, received[i], value)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stmcginnis/gofish/common"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// XGMILinkDownAlert is raised when a link between two GPUs of a server goes
+// down or reports Critical health
+type XGMILinkDownAlert struct {
+	Server    RedfishServer
+	SourceGPU string
+	DestGPU   string
+	Health    common.Health
+	Message   string
+	CheckedAt time.Time
+}
+
+// xgmiLink is a port of a GPU connected to a port of another GPU
+type xgmiLink struct {
+	sourceGPU  string
+	destGPU    string
+	health     common.Health
+	linkStatus redfish.PortLinkStatus
+	speedGbps  float32
+	width      int
+}
+
+func (l xgmiLink) down() bool {
+	return l.health == common.CriticalHealth || l.linkStatus == redfish.LinkDownPortLinkStatus
+}
+
+// XGMITopologyCollector reads the links between the GPUs of the servers'
+// systems from the ports of their processors and exports their health.
+// The ports of a GPU connected to a port of another processor are its
+// XGMI (or NVLink) links.
+type XGMITopologyCollector struct {
+	servers []RedfishServer
+	// Called when a link goes down
+	onAlert func(XGMILinkDownAlert)
+
+	mu sync.Mutex
+	// Links found down by the previous check, so a link is only alerted on
+	// once until it recovers
+	down map[string]bool
+}
+
+func NewXGMITopologyCollector(servers []RedfishServer, onAlert func(XGMILinkDownAlert)) *XGMITopologyCollector {
+	return &XGMITopologyCollector{servers: servers, onAlert: onAlert, down: make(map[string]bool)}
+}
+
+// Check all servers every interval until the context is done
+func (x *XGMITopologyCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting XGMI topology checks every %v", interval)
+	for {
+		x.Collect(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Context done, stopping XGMI topology checks")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Read the GPU links of all servers once
+func (x *XGMITopologyCollector) Collect(ctx context.Context) {
+	errs := bulkRun(ctx, x.servers, BulkOptions{}, func(server RedfishServer) error {
+		links, err := getXGMILinks(ctx, server)
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			x.record(server, link)
+		}
+		return nil
+	})
+	for serverIP, err := range errs {
+		log.Printf("Failed to check XGMI topology on server %s: %v", serverIP, err)
+	}
+}
+
+// Update the metrics of a link and raise an alert when it went down
+func (x *XGMITopologyCollector) record(server RedfishServer, link xgmiLink) {
+	server.settings().metrics.amdXGMILinkHealth.WithLabelValues(server.ID(), link.sourceGPU, link.destGPU).Set(float64(severityRank(string(link.health))))
+	server.settings().metrics.amdXGMILinkSpeed.WithLabelValues(server.ID(), link.sourceGPU, link.destGPU).Set(float64(link.speedGbps))
+	server.settings().metrics.amdXGMILinkWidth.WithLabelValues(server.ID(), link.sourceGPU, link.destGPU).Set(float64(link.width))
+
+	key := server.ID() + "/" + link.sourceGPU + "/" + link.destGPU
+	x.mu.Lock()
+	wasDown := x.down[key]
+	x.down[key] = link.down()
+	x.mu.Unlock()
+
+	if link.down() && !wasDown && x.onAlert != nil {
+		x.onAlert(XGMILinkDownAlert{
+			Server:    server,
+			SourceGPU: link.sourceGPU,
+			DestGPU:   link.destGPU,
+			Health:    link.health,
+			Message:   fmt.Sprintf("XGMI link from GPU %s to GPU %s on server %s is down (health %s, link status %s)", link.sourceGPU, link.destGPU, server.IP, link.health, link.linkStatus),
+			CheckedAt: time.Now(),
+		})
+	}
+}
+
+// Get the links between the GPUs of all systems of a server
+func getXGMILinks(ctx context.Context, server RedfishServer) ([]xgmiLink, error) {
+	c, err := getRedfishClientContext(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", server.IP, err)
+	}
+	defer c.Logout()
+
+	systems, err := c.Service.Systems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systems on server %s: %w", server.IP, normalizeRedfishError(err))
+	}
+	var links []xgmiLink
+	for _, system := range systems {
+		processors, err := system.Processors()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get processors of %s on server %s: %w", system.ODataID, server.IP, normalizeRedfishError(err))
+		}
+		for _, processor := range processors {
+			if processor.ProcessorType != redfish.GPUProcessorType {
+				continue
+			}
+			ports, err := processorPorts(c, processor)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get ports of processor %s on server %s: %w", processor.ODataID, server.IP, normalizeRedfishError(err))
+			}
+			for _, port := range ports {
+				if port.ConnectedPortsCount == 0 {
+					continue
+				}
+				connected, err := port.ConnectedPorts()
+				if err != nil {
+					return nil, fmt.Errorf("failed to get connected ports of %s on server %s: %w", port.ODataID, server.IP, normalizeRedfishError(err))
+				}
+				for _, remote := range connected {
+					destGPU := processorIDFromPortURI(remote.ODataID)
+					if destGPU == "" || destGPU == processor.ID {
+						continue
+					}
+					links = append(links, xgmiLink{
+						sourceGPU:  processor.ID,
+						destGPU:    destGPU,
+						health:     port.Status.Health,
+						linkStatus: port.LinkStatus,
+						speedGbps:  port.CurrentSpeedGbps,
+						width:      port.Width,
+					})
+				}
+			}
+		}
+	}
+	return links, nil
+}
+
+// Get the ports of a processor. gofish expects the Ports collection
+// expanded in the processor while services link to it, so the link is read
+// from the resource.
+func processorPorts(c common.Client, processor *redfish.Processor) ([]*redfish.Port, error) {
+	resp, err := c.Get(processor.ODataID)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var links struct {
+		Ports common.Link
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&links); err != nil {
+		return nil, fmt.Errorf("failed to decode processor %s: %v", processor.ODataID, err)
+	}
+	if links.Ports == "" {
+		return nil, nil
+	}
+	return redfish.ListReferencedPorts(c, string(links.Ports))
+}
+
+// The ID of the processor a port belongs to, e.g. GPU_2 for
+// /redfish/v1/Systems/1/Processors/GPU_2/Ports/XGMI_0. Empty for the ports
+// of other resources such as switches.
+func processorIDFromPortURI(uri string) string {
+	parts := strings.Split(strings.Trim(uri, "/"), "/")
+	for i := 0; i+3 < len(parts); i++ {
+		if parts[i] == "Processors" && parts[i+2] == "Ports" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+/**
+ * Copyright 2024 Advanced Micro Devices, Inc.  All rights reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+**/
+
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+const (
+	gpu0Port = "/redfish/v1/Systems/1/Processors/GPU_0/Ports/XGMI_0"
+	gpu1Port = "/redfish/v1/Systems/1/Processors/GPU_1/Ports/XGMI_0"
+)
+
+// Serve two GPUs linked by their XGMI_0 ports, GPU_0 also has a port
+// connected to a switch
+func addXGMIGPUs(bmc *fakeBMC) {
+	ports := func(gpu string) map[string]interface{} {
+		return map[string]interface{}{"@odata.id": "/redfish/v1/Systems/1/Processors/" + gpu + "/Ports"}
+	}
+	addProcessors(bmc,
+		map[string]interface{}{"Id": "CPU_0", "ProcessorType": "CPU", "Ports": ports("CPU_0")},
+		map[string]interface{}{"Id": "GPU_0", "ProcessorType": "GPU", "Ports": ports("GPU_0")},
+		map[string]interface{}{"Id": "GPU_1", "ProcessorType": "GPU", "Ports": ports("GPU_1")},
+	)
+	port := func(id, remote string) map[string]interface{} {
+		return map[string]interface{}{
+			"Id":               id,
+			"Status":           map[string]string{"State": "Enabled", "Health": "OK"},
+			"LinkStatus":       "LinkUp",
+			"CurrentSpeedGbps": 32,
+			"Width":            16,
+			"Links": map[string]interface{}{
+				"ConnectedPorts":             []map[string]string{{"@odata.id": remote}},
+				"ConnectedPorts@odata.count": 1,
+			},
+		}
+	}
+	bmc.addCollection("/redfish/v1/Systems/1/Processors/CPU_0/Ports", port("XGMI_0", gpu0Port))
+	bmc.addCollection("/redfish/v1/Systems/1/Processors/GPU_0/Ports",
+		port("XGMI_0", gpu1Port),
+		port("PCIe_0", "/redfish/v1/Fabrics/PCIe/Switches/1/Ports/1"),
+	)
+	bmc.addCollection("/redfish/v1/Systems/1/Processors/GPU_1/Ports", port("XGMI_0", gpu0Port))
+	bmc.addCollection("/redfish/v1/Fabrics/PCIe/Switches/1/Ports", map[string]interface{}{"Id": "1"})
+}
+
+func TestProcessorIDFromPortURI(t *testing.T) {
+	tests := map[string]string{
+		gpu0Port: "GPU_0",
+		"/redfish/v1/Systems/1/Processors/GPU_1/Ports/XGMI_0/": "GPU_1",
+		"/redfish/v1/Fabrics/PCIe/Switches/1/Ports/1":          "",
+		"/redfish/v1/Systems/1/Processors/GPU_0":               "",
+		"":                                                     "",
+	}
+	for uri, want := range tests {
+		if got := processorIDFromPortURI(uri); got != want {
+			t.Errorf("processorIDFromPortURI(%q) = %q, want %q", uri, got, want)
+		}
+	}
+}
+
+func TestXGMITopologyCollector(t *testing.T) {
+	bmc := newFakeBMC(t)
+	addXGMIGPUs(bmc)
+	server := bmc.server()
+	var alerts []XGMILinkDownAlert
+	collector := NewXGMITopologyCollector([]RedfishServer{server}, func(alert XGMILinkDownAlert) {
+		alerts = append(alerts, alert)
+	})
+
+	links, err := getXGMILinks(context.Background(), server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("links = %+v, want GPU_0 to GPU_1 and back", links)
+	}
+
+	// Each step sets the health and link status of GPU_0's port, then
+	// checks the alerts raised so far
+	steps := []struct {
+		name       string
+		health     string
+		linkStatus string
+		wantHealth float64
+		wantAlerts int
+	}{
+		{"link up", "OK", "LinkUp", 0, 0},
+		{"link down", "Warning", "LinkDown", 1, 1},
+		{"still down", "Warning", "LinkDown", 1, 1},
+		{"recovered", "OK", "LinkUp", 0, 1},
+		{"critical health", "Critical", "LinkUp", 2, 2},
+	}
+	for _, step := range steps {
+		bmc.setResourceProperty(gpu0Port, "Status", map[string]string{"State": "Enabled", "Health": step.health})
+		bmc.setResourceProperty(gpu0Port, "LinkStatus", step.linkStatus)
+		collector.Collect(context.Background())
+
+		if got := gaugeValue(t, server.settings().metrics.amdXGMILinkHealth.WithLabelValues(server.ID(), "GPU_0", "GPU_1")); got != step.wantHealth {
+			t.Errorf("%s: health = %v, want %v", step.name, got, step.wantHealth)
+		}
+		if got := gaugeValue(t, server.settings().metrics.amdXGMILinkHealth.WithLabelValues(server.ID(), "GPU_1", "GPU_0")); got != 0 {
+			t.Errorf("%s: health of the reverse link = %v, want 0", step.name, got)
+		}
+		if len(alerts) != step.wantAlerts {
+			t.Fatalf("%s: %d alerts, want %d", step.name, len(alerts), step.wantAlerts)
+		}
+	}
+	if alert := alerts[0]; alert.SourceGPU != "GPU_0" || alert.DestGPU != "GPU_1" || alert.Server.IP != server.IP {
+		t.Errorf("alert = %+v, want the link from GPU_0 to GPU_1", alert)
+	}
+	if got := gaugeValue(t, server.settings().metrics.amdXGMILinkSpeed.WithLabelValues(server.ID(), "GPU_0", "GPU_1")); got != 32 {
+		t.Errorf("speed = %v, want 32", got)
+	}
+	if got := gaugeValue(t, server.settings().metrics.amdXGMILinkWidth.WithLabelValues(server.ID(), "GPU_0", "GPU_1")); got != 16 {
+		t.Errorf("width = %v, want 16", got)
+	}
+}
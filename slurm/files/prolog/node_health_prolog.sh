@@ -0,0 +1,22 @@
+#!/bin/bash
+# Slurm prolog refusing jobs on nodes whose server the redfish exporter
+# reports unhealthy. An unreachable exporter doesn't block jobs.
+#
+# The exporter's metrics address, e.g. http://exporter:2112, is read from
+# NODE_HEALTH_URL or /etc/slurm/node_health_url
+
+NODE_HEALTH_URL="${NODE_HEALTH_URL:-$(cat /etc/slurm/node_health_url 2>/dev/null)}"
+NODE_HEALTH_URL="${NODE_HEALTH_URL:-http://127.0.0.1:2112}"
+NODE="${SLURMD_NODENAME:-$(hostname -s)}"
+
+response=$(curl --silent --fail --max-time 20 "${NODE_HEALTH_URL}/node-health/${NODE}")
+if [ $? -ne 0 ]; then
+    echo "node health of ${NODE} unavailable, allowing job" >&2
+    exit 0
+fi
+
+if echo "${response}" | grep -q '"healthy":false'; then
+    echo "node ${NODE} is unhealthy: ${response}" >&2
+    exit 1
+fi
+exit 0